@@ -15,6 +15,11 @@ import (
 var Version = "dev"
 
 func main() {
+	// Schema migrations run as a one-off subcommand instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	// Initialize application using wire
 	app, err := InitializeApp()
 	if err != nil {
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/migrations"
+	"github.com/zhwjimmy/user-center/pkg/logger"
+)
+
+const migrateUsage = `Usage: usercenter migrate <command>
+
+Commands:
+  up          apply all pending migrations
+  down [N]    roll back the latest N migrations (default 1)
+  reset       roll back all migrations
+  status      list migrations and whether they are applied`
+
+// runMigrate runs the migrate subcommand with args and returns the exit code
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	log, err := logger.New(cfg.Logging)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		return 1
+	}
+	defer log.Sync()
+
+	postgres, err := database.OpenPostgreSQL(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer postgres.Close()
+
+	sqlDB, err := postgres.DB.DB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+
+	migrator, err := database.NewMigrator(sqlDB, migrations.FS, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load migrations: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintf(os.Stderr, "Invalid number of migrations: %s\n", args[1])
+				return 2
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Rollback failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+	case "reset":
+		rolledBack, err := migrator.Reset(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Rollback failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", rolledBack)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read migration status: %v\n", err)
+			return 1
+		}
+		for _, status := range statuses {
+			fmt.Printf("%-9s %s\n", status.State, database.MigrationName(status.Source))
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
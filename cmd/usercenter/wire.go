@@ -4,18 +4,26 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"github.com/zhwjimmy/user-center/internal/cache"
 	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/database"
 	"github.com/zhwjimmy/user-center/internal/handler"
+	"github.com/zhwjimmy/user-center/internal/infra"
 	"github.com/zhwjimmy/user-center/internal/kafka"
 	kafkaConfig "github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/consumer"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"github.com/zhwjimmy/user-center/internal/middleware"
+	"github.com/zhwjimmy/user-center/internal/notification"
 	"github.com/zhwjimmy/user-center/internal/repository"
 	"github.com/zhwjimmy/user-center/internal/server"
 	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/tracing"
 	"github.com/zhwjimmy/user-center/pkg/jwt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -29,16 +37,22 @@ type (
 	CORSMiddleware      gin.HandlerFunc
 )
 
-// provideLogger creates a new logger instance
-func provideLogger(cfg *config.Config) (*zap.Logger, error) {
-	config := zap.NewProductionConfig()
-
-	// Set log level
+// provideLogLevel creates the log level, which can be changed at runtime by
+// reloading the config
+func provideLogLevel(cfg *config.Config) zap.AtomicLevel {
 	level, err := zapcore.ParseLevel(cfg.Logging.Level)
 	if err != nil {
 		level = zapcore.InfoLevel
 	}
-	config.Level = zap.NewAtomicLevelAt(level)
+	return zap.NewAtomicLevelAt(level)
+}
+
+// provideLogger creates a new logger instance
+func provideLogger(cfg *config.Config, level zap.AtomicLevel) (*zap.Logger, error) {
+	config := zap.NewProductionConfig()
+
+	// Set log level
+	config.Level = level
 
 	// Set output format
 	if cfg.Logging.Format == "console" {
@@ -55,24 +69,106 @@ func provideLogger(cfg *config.Config) (*zap.Logger, error) {
 	return config.Build()
 }
 
-// provideJWT creates a new JWT manager
-func provideJWT(cfg *config.Config) *jwt.JWT {
-	return jwt.NewJWT(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.Expiry)
+// provideJWT creates a new JWT manager for the configured signing algorithm.
+// Startup fails if the configured key cannot sign and verify a token.
+func provideJWT(cfg *config.Config) (*jwt.JWT, error) {
+	manager, err := newJWT(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := manager.Check(); err != nil {
+		return nil, fmt.Errorf("JWT signing key check failed: %w", err)
+	}
+	return manager, nil
+}
+
+// newJWT loads the keys for the configured signing algorithm
+func newJWT(cfg *config.Config) (*jwt.JWT, error) {
+	switch cfg.JWT.Algorithm {
+	case "", jwt.AlgorithmHS256:
+		return jwt.NewJWT(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.Expiry), nil
+	case jwt.AlgorithmRS256:
+		signingKey, err := jwt.LoadRSAPrivateKey(cfg.JWT.SigningKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys, err := jwt.LoadRSAPublicKeys(cfg.JWT.VerificationKeysDir)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.NewRS256JWT(signingKey, cfg.JWT.SigningKeyID, publicKeys, cfg.JWT.Issuer, cfg.JWT.Expiry)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", cfg.JWT.Algorithm)
+	}
+}
+
+// provideCORSMiddleware creates a new CORS middleware, which follows config reloads
+func provideCORSMiddleware(cors *middleware.ReloadableCORS) middleware.CORSMiddleware {
+	return middleware.CORSMiddleware(cors.Handle)
+}
+
+// provideJSONNamingMiddleware creates a new JSON naming middleware
+func provideJSONNamingMiddleware(cfg *config.Config) middleware.JSONNamingMiddleware {
+	return middleware.JSONNamingMiddleware(middleware.NewJSONNamingMiddleware(cfg))
+}
+
+// provideURLLengthMiddleware creates a new URL length middleware
+func provideURLLengthMiddleware(cfg *config.Config) middleware.URLLengthMiddleware {
+	return middleware.URLLengthMiddleware(middleware.NewURLLengthMiddleware(cfg))
+}
+
+// provideCacheControlMiddleware creates a new cache control middleware
+func provideCacheControlMiddleware(cfg *config.Config) middleware.CacheControlMiddleware {
+	return middleware.CacheControlMiddleware(middleware.NewCacheControlMiddleware(cfg))
+}
+
+// provideCompressionMiddleware creates a new compression middleware
+func provideCompressionMiddleware(cfg *config.Config) middleware.CompressionMiddleware {
+	return middleware.CompressionMiddleware(middleware.NewCompressionMiddleware(cfg))
+}
+
+// provideTimeoutMiddleware creates a new request timeout middleware
+func provideTimeoutMiddleware(cfg *config.Config) middleware.TimeoutMiddleware {
+	return middleware.TimeoutMiddleware(middleware.NewTimeoutMiddleware(cfg.Server.RequestTimeout))
+}
+
+// provideIdempotencyMiddleware creates a new Idempotency-Key middleware
+func provideIdempotencyMiddleware(redis *cache.Redis, cfg *config.Config, logger *zap.Logger) middleware.IdempotencyMiddleware {
+	return middleware.IdempotencyMiddleware(middleware.NewIdempotencyMiddleware(redis, cfg, logger))
+}
+
+// provideReadYourWritesMiddleware creates a new read-your-writes middleware
+func provideReadYourWritesMiddleware(cfg *config.Config) middleware.ReadYourWritesMiddleware {
+	return middleware.ReadYourWritesMiddleware(middleware.NewReadYourWritesMiddleware(cfg))
+}
+
+// provideAuditMiddleware creates a new audit middleware writing to MongoDB
+func provideAuditMiddleware(cfg *config.Config, mongo *database.MongoDB, logger *zap.Logger) middleware.AuditMiddleware {
+	var store middleware.AuditStore
+	if mongo != nil {
+		store = mongo
+	}
+	return middleware.AuditMiddleware(middleware.NewAuditMiddleware(cfg, store, logger))
 }
 
-// provideCORSMiddleware creates a new CORS middleware
-func provideCORSMiddleware(cfg *config.Config) middleware.CORSMiddleware {
-	return middleware.CORSMiddleware(middleware.NewCORSMiddleware(cfg))
+// provideTracingMiddleware creates a new tracing middleware
+func provideTracingMiddleware() middleware.TracingMiddleware {
+	return middleware.TracingMiddleware(middleware.NewTracingMiddleware())
 }
 
 // provideRequestIDMiddleware creates a new request ID middleware
-func provideRequestIDMiddleware() middleware.RequestIDMiddleware {
-	return middleware.RequestIDMiddleware(middleware.NewRequestIDMiddleware())
+func provideRequestIDMiddleware(cfg *config.Config) middleware.RequestIDMiddleware {
+	return middleware.RequestIDMiddleware(middleware.NewRequestIDMiddleware(cfg))
 }
 
-// provideLoggerMiddleware creates a new logger middleware
-func provideLoggerMiddleware(logger *zap.Logger) middleware.LoggerMiddleware {
-	return middleware.LoggerMiddleware(middleware.NewLoggerMiddleware(logger))
+// provideLoggerMiddleware creates a new logger middleware, optionally writing request logs to MongoDB
+func provideLoggerMiddleware(cfg *config.Config, mongo *database.MongoDB, logger *zap.Logger) middleware.LoggerMiddleware {
+	var store middleware.RequestLogStore
+	if mongo != nil {
+		store = mongo
+	}
+	return middleware.LoggerMiddleware(middleware.NewLoggerMiddleware(cfg, store, logger))
 }
 
 // provideRecoveryMiddleware creates a new recovery middleware
@@ -80,9 +176,105 @@ func provideRecoveryMiddleware(logger *zap.Logger) middleware.RecoveryMiddleware
 	return middleware.RecoveryMiddleware(middleware.NewRecoveryMiddleware(logger))
 }
 
-// provideGormDB extracts *gorm.DB from *database.PostgreSQL
-func provideGormDB(pg *database.PostgreSQL) *gorm.DB {
-	return pg.DB
+// provideMongoDB connects to MongoDB. When MongoDB is optional and cannot be
+// reached, the service starts without it and the result is nil.
+func provideMongoDB(cfg *config.Config, status *infra.Status, logger *zap.Logger) (*database.MongoDB, error) {
+	return infra.Connect(status, infra.ComponentMongoDB, logger, func() (*database.MongoDB, error) {
+		return database.NewMongoDB(cfg, logger)
+	})
+}
+
+// provideKafkaService connects to Kafka. When Kafka is optional and cannot be
+// reached, the service starts without it and the result is nil.
+func provideKafkaService(
+	cfg *kafkaConfig.KafkaClientConfig,
+	notifier consumer.Notifier,
+	webhooks consumer.WebhookDispatcher,
+	lag *consumer.LagMonitor,
+	dedup *consumer.Deduplicator,
+	logins *consumer.LoginHistory,
+	status *infra.Status,
+	logger *zap.Logger,
+) (kafka.Service, error) {
+	return infra.Connect(status, infra.ComponentKafka, logger, func() (kafka.Service, error) {
+		return kafka.NewKafkaService(cfg, notifier, webhooks, lag, dedup, logins, logger)
+	})
+}
+
+// provideGormDB extracts *gorm.DB from *database.PostgreSQL and times its
+// queries; the plugin lives in metrics, which depends on the repositories
+func provideGormDB(pg *database.PostgreSQL) (*gorm.DB, error) {
+	if err := pg.DB.Use(metrics.NewGormPlugin(metrics.DBQueryDuration)); err != nil {
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+	return pg.DB, nil
+}
+
+// provideNotificationUserStore lets the notifier look up user preferences and phones
+func provideNotificationUserStore(userService *service.UserService) notification.UserStore {
+	return userService
+}
+
+// provideNotificationQueue lets the notifier enqueue emails, text messages and
+// webhooks as async tasks
+func provideNotificationQueue(client *task.Client) notification.Queue {
+	return client
+}
+
+// provideEventNotifier lets the Kafka consumer send notifications
+func provideEventNotifier(notifier *notification.Notifier) consumer.Notifier {
+	return notifier
+}
+
+// provideWebhookDispatcher lets the Kafka consumer deliver events to webhook subscriptions
+func provideWebhookDispatcher(webhooks *service.WebhookService) consumer.WebhookDispatcher {
+	return webhooks
+}
+
+// provideWebhookQueue lets the webhook service enqueue deliveries as async tasks
+func provideWebhookQueue(client *task.Client) service.WebhookQueue {
+	return client
+}
+
+// provideWebhookStore lets the task server look up webhook subscriptions and
+// count their failed deliveries
+func provideWebhookStore(repo repository.WebhookRepository) task.WebhookStore {
+	return repo
+}
+
+// provideEmailQueue lets the auth service enqueue emails as async tasks
+func provideEmailQueue(client *task.Client) service.EmailQueue {
+	return client
+}
+
+// provideSMSQueue lets phone verification enqueue text messages as async tasks
+func provideSMSQueue(client *task.Client) service.SMSQueue {
+	return client
+}
+
+// provideSessionStore lets the task server purge expired MongoDB sessions
+func provideSessionStore(mongo *database.MongoDB) task.SessionStore {
+	if mongo == nil {
+		return nil
+	}
+	return mongo
+}
+
+// provideUserDataStore lets erasure purge the MongoDB sessions and logs of a user
+func provideUserDataStore(mongo *database.MongoDB) service.UserDataStore {
+	if mongo == nil {
+		return nil
+	}
+	return mongo
+}
+
+// provideSessionRecordStore lets the session limiter record sessions in
+// MongoDB for administrators to review and revoke
+func provideSessionRecordStore(mongo *database.MongoDB) service.SessionStore {
+	if mongo == nil {
+		return nil
+	}
+	return mongo
 }
 
 // provideServer creates a new server instance
@@ -90,6 +282,12 @@ func provideServer(
 	cfg *config.Config,
 	logger *zap.Logger,
 	userHandler *handler.UserHandler,
+	roleHandler *handler.RoleHandler,
+	sessionHandler *handler.SessionHandler,
+	phoneVerificationHandler *handler.PhoneVerificationHandler,
+	auditLogHandler *handler.AuditLogHandler,
+	dataExportHandler *handler.DataExportHandler,
+	webhookHandler *handler.WebhookHandler,
 	healthHandler *handler.HealthHandler,
 	authMiddleware *middleware.AuthMiddleware,
 	corsMiddleware middleware.CORSMiddleware,
@@ -97,12 +295,32 @@ func provideServer(
 	requestIDMiddleware middleware.RequestIDMiddleware,
 	loggerMiddleware middleware.LoggerMiddleware,
 	recoveryMiddleware middleware.RecoveryMiddleware,
+	jsonNamingMiddleware middleware.JSONNamingMiddleware,
+	urlLengthMiddleware middleware.URLLengthMiddleware,
+	cacheControlMiddleware middleware.CacheControlMiddleware,
+	tracingMiddleware middleware.TracingMiddleware,
+	auditMiddleware middleware.AuditMiddleware,
+	compressionMiddleware middleware.CompressionMiddleware,
+	timeoutMiddleware middleware.TimeoutMiddleware,
+	idempotencyMiddleware middleware.IdempotencyMiddleware,
+	readYourWritesMiddleware middleware.ReadYourWritesMiddleware,
 	kafkaService kafka.Service,
+	outboxRelay *service.OutboxRelay,
+	metricsRefresher *metrics.Refresher,
+	taskServer *task.Server,
+	tracer *tracing.Provider,
+	reloader *server.ConfigReloader,
 ) *server.Server {
 	return server.New(
 		cfg,
 		logger,
 		userHandler,
+		roleHandler,
+		sessionHandler,
+		phoneVerificationHandler,
+		auditLogHandler,
+		dataExportHandler,
+		webhookHandler,
 		healthHandler,
 		authMiddleware,
 		corsMiddleware,
@@ -110,7 +328,21 @@ func provideServer(
 		requestIDMiddleware,
 		loggerMiddleware,
 		recoveryMiddleware,
+		jsonNamingMiddleware,
+		urlLengthMiddleware,
+		cacheControlMiddleware,
+		tracingMiddleware,
+		auditMiddleware,
+		compressionMiddleware,
+		timeoutMiddleware,
+		idempotencyMiddleware,
+		readYourWritesMiddleware,
 		kafkaService,
+		outboxRelay,
+		metricsRefresher,
+		taskServer,
+		tracer,
+		reloader,
 	)
 }
 
@@ -122,41 +354,101 @@ func InitializeApp() (*server.Server, error) {
 		kafkaConfig.NewKafkaClientConfig,
 
 		// Logger
+		provideLogLevel,
 		provideLogger,
 
+		// Tracing
+		tracing.NewProvider,
+
 		// JWT Manager
 		provideJWT,
 
 		// Database connections
+		infra.NewStatus,
 		database.NewPostgreSQL,
 		provideGormDB,
-		database.NewMongoDB,
+		provideMongoDB,
 		cache.NewRedis,
 
 		// Kafka
-		kafka.NewKafkaService,
+		provideKafkaService,
+		consumer.NewLagMonitor,
+		consumer.NewDeduplicator,
+		consumer.NewLoginHistory,
+		provideNotificationUserStore,
+		provideNotificationQueue,
+		notification.NewNotifier,
+		provideEventNotifier,
+		provideWebhookDispatcher,
 
 		// Repositories
 		repository.NewUserRepository,
+		repository.NewOutboxRepository,
+		repository.NewRoleRepository,
+		repository.NewAuditLogRepository,
+		repository.NewWebhookRepository,
+		repository.NewTransactor,
 
 		// Services
 		service.NewUserService,
 		service.NewEventService,
 		service.NewAuthService,
+		service.NewRoleService,
+		service.NewAuditLogService,
+		service.NewDataExportService,
+		service.NewErasureService,
+		service.NewPhoneVerificationService,
+		service.NewWebhookService,
+		provideUserDataStore,
+		service.NewSessionLimiter,
+		provideSessionRecordStore,
+		service.NewOutboxRelay,
+
+		// Async tasks
+		task.NewClient,
+		task.NewMailer,
+		task.NewSMSSender,
+		task.NewServer,
+		provideSessionStore,
+		provideEmailQueue,
+		provideSMSQueue,
+		provideWebhookQueue,
+		provideWebhookStore,
+
+		// Metrics
+		metrics.NewRefresher,
 
 		// Handlers
 		handler.NewUserHandler,
+		handler.NewRoleHandler,
+		handler.NewSessionHandler,
+		handler.NewPhoneVerificationHandler,
+		handler.NewAuditLogHandler,
+		handler.NewDataExportHandler,
+		handler.NewWebhookHandler,
 		handler.NewHealthHandler,
 
 		// Middlewares
 		middleware.NewAuthMiddleware,
+		middleware.NewReloadableCORS,
 		provideCORSMiddleware,
+		middleware.NewLimiter,
 		middleware.NewRateLimitMiddleware,
 		provideRequestIDMiddleware,
 		provideLoggerMiddleware,
 		provideRecoveryMiddleware,
+		provideJSONNamingMiddleware,
+		provideURLLengthMiddleware,
+		provideCacheControlMiddleware,
+		provideAuditMiddleware,
+		provideCompressionMiddleware,
+		provideTimeoutMiddleware,
+		provideIdempotencyMiddleware,
+		provideReadYourWritesMiddleware,
+		provideTracingMiddleware,
 
 		// Server
+		server.NewConfigReloader,
 		provideServer,
 	)
 	return &server.Server{}, nil
@@ -0,0 +1,84 @@
+// Package apperrors defines the errors shared between the repository, service
+// and handler layers. Callers check for them with errors.Is; handlers use them
+// to pick the HTTP status of a response.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches a lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when a user would share their email, username
+	// or phone with another user. The error returned may be a
+	// *UserExistsError naming the field.
+	ErrUserExists = errors.New("user already exists")
+	// ErrInvalidCredentials is returned when a login email or password is wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidOldPassword is returned when a password change does not
+	// confirm the current password
+	ErrInvalidOldPassword = errors.New("invalid old password")
+	// ErrInvalidPassword is returned when an action that requires the
+	// user's password is given the wrong one
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrAccountInactive is returned when a deactivated user tries to sign in
+	ErrAccountInactive = errors.New("account is inactive")
+	// ErrEmailNotVerified is returned when a user whose email is not verified
+	// tries to sign in while login requires a verified email
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrUserDataUnavailable is returned when a user cannot be erased, or
+	// their sessions reviewed, because the store of their sessions and logs
+	// is not connected
+	ErrUserDataUnavailable = errors.New("user data store unavailable")
+	// ErrPhoneNotSet is returned when a user without a phone number asks to
+	// verify it
+	ErrPhoneNotSet = errors.New("phone number is not set")
+	// ErrPhoneAlreadyVerified is returned when a verification code is
+	// requested for a phone number that is already verified
+	ErrPhoneAlreadyVerified = errors.New("phone number is already verified")
+	// ErrInvalidVerificationCode is returned when a verification code does
+	// not match the one sent
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+	// ErrVerificationCodeExpired is returned when no verification code is
+	// pending: none was sent, it expired, or too many wrong codes were tried
+	ErrVerificationCodeExpired = errors.New("verification code expired")
+	// ErrInvalidPasswordSetupToken is returned when a password setup token
+	// is unknown, expired or already used
+	ErrInvalidPasswordSetupToken = errors.New("invalid password setup token")
+	// ErrWebhookNotFound is returned when no webhook subscription matches a lookup
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+	// ErrInvalidWebhook is returned when a webhook subscription has a URL
+	// that is not http or https, or an unknown event type
+	ErrInvalidWebhook = errors.New("invalid webhook subscription")
+	// ErrInvalidNotificationCategory is returned when notification
+	// preferences name an unknown category
+	ErrInvalidNotificationCategory = errors.New("invalid notification category")
+	// ErrMandatoryNotification is returned when a user tries to opt out of
+	// security notifications
+	ErrMandatoryNotification = errors.New("security notifications cannot be disabled")
+	// ErrInvalidRole is returned when a role to assign or revoke does not exist
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrRoleNotRevocable is returned when revoking the implicit user role
+	ErrRoleNotRevocable = errors.New("role cannot be revoked")
+	// ErrInvalidTimeRange is returned when a time range does not start
+	// before it ends
+	ErrInvalidTimeRange = errors.New("invalid time range")
+)
+
+// UserExistsError reports that the value of a unique user field is taken. It
+// matches ErrUserExists.
+type UserExistsError struct {
+	Field string
+	Value string
+}
+
+func (e *UserExistsError) Error() string {
+	return fmt.Sprintf("user with %s %s already exists", e.Field, e.Value)
+}
+
+// Is reports whether target is ErrUserExists
+func (e *UserExistsError) Is(target error) bool {
+	return target == ErrUserExists
+}
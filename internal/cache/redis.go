@@ -11,32 +11,42 @@ import (
 	"go.uber.org/zap"
 )
 
+// Redis connection modes, selected by redis.mode
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeCluster    = "cluster"
+	RedisModeSentinel   = "sentinel"
+
+	// RedisModeSingle is the former name of RedisModeStandalone, still accepted in config
+	RedisModeSingle = "single"
+)
+
 // Redis represents Redis cache connection
 type Redis struct {
-	Client *redis.Client
+	Client redis.UniversalClient
 	logger *zap.Logger
 }
 
 // NewRedis creates a new Redis connection
 func NewRedis(cfg *config.Config, logger *zap.Logger) (*Redis, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:         cfg.Redis.Addr,
-		Password:     cfg.Redis.Password,
-		DB:           cfg.Redis.DB,
-		PoolSize:     cfg.Redis.PoolSize,
-		MinIdleConns: cfg.Redis.MinIdleConns,
-	})
+	client, err := newRedisClient(&cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
 	logger.Info("Redis connected successfully",
+		zap.String("mode", redisMode(&cfg.Redis)),
 		zap.String("addr", cfg.Redis.Addr),
+		zap.Strings("addrs", cfg.Redis.Addrs),
 		zap.Int("db", cfg.Redis.DB),
 	)
 
@@ -46,6 +56,59 @@ func NewRedis(cfg *config.Config, logger *zap.Logger) (*Redis, error) {
 	}, nil
 }
 
+// newRedisClient creates the client for the configured mode: a single node,
+// a Redis Cluster or a master discovered through Sentinel
+func newRedisClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	switch mode := redisMode(cfg); mode {
+	case RedisModeStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+		}), nil
+	case RedisModeCluster:
+		if len(cfg.Addrs) < 2 {
+			return nil, fmt.Errorf("redis cluster mode requires at least 2 addrs, got %d", len(cfg.Addrs))
+		}
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("redis cluster mode only supports db 0, got %d", cfg.DB)
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.Addrs,
+			Password:     cfg.Password,
+			PoolSize:     cfg.PoolSize,
+			MinIdleConns: cfg.MinIdleConns,
+		}), nil
+	case RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode requires master_name")
+		}
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel mode requires at least 1 sentinel addr")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.Addrs,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+			PoolSize:      cfg.PoolSize,
+			MinIdleConns:  cfg.MinIdleConns,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", mode)
+	}
+}
+
+// redisMode returns the configured mode, defaulting to a standalone node
+func redisMode(cfg *config.RedisConfig) string {
+	if cfg.Mode == "" || cfg.Mode == RedisModeSingle {
+		return RedisModeStandalone
+	}
+	return cfg.Mode
+}
+
 // Close closes the Redis connection
 func (r *Redis) Close() error {
 	return r.Client.Close()
@@ -95,6 +158,74 @@ func (r *Redis) Get(ctx context.Context, key string, dest interface{}) error {
 	return nil
 }
 
+// CacheItem is a value to store with SetMany
+type CacheItem struct {
+	Value      interface{}
+	Expiration time.Duration
+}
+
+// GetMany retrieves the raw values of keys in a single round trip. Keys that
+// are not cached are left out of the result. GETs are pipelined rather than
+// sent as one MGET so the keys may live in different cluster slots.
+func (r *Redis) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+
+	pipe := r.Client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		r.logger.Error("Failed to get cache keys",
+			zap.Int("count", len(keys)),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to get cache keys: %w", err)
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cache key %s: %w", keys[i], err)
+		}
+		values[keys[i]] = data
+	}
+
+	return values, nil
+}
+
+// SetMany stores each item as JSON with its own expiration in a single round trip
+func (r *Redis) SetMany(ctx context.Context, items map[string]CacheItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	pipe := r.Client.Pipeline()
+	for key, item := range items {
+		data, err := json.Marshal(item.Value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", key, err)
+		}
+		pipe.Set(ctx, key, data, item.Expiration)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.Error("Failed to set cache keys",
+			zap.Int("count", len(items)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to set cache keys: %w", err)
+	}
+
+	return nil
+}
+
 // Delete removes a key from cache
 func (r *Redis) Delete(ctx context.Context, key string) error {
 	if err := r.Client.Del(ctx, key).Err(); err != nil {
@@ -172,6 +303,60 @@ func (r *Redis) IncrementWithExpiry(ctx context.Context, key string, expiration
 	return incrCmd.Val(), nil
 }
 
+// tokenBucketScript implements a token bucket stored in a hash. Tokens refill
+// continuously at ARGV[2] tokens per millisecond up to the ARGV[1] capacity.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate))
+
+return allowed
+`)
+
+// TakeToken takes a token from the bucket stored at key. The bucket holds up to
+// capacity tokens and refills at refillRate tokens per second. It returns true
+// if a token was available.
+func (r *Redis) TakeToken(ctx context.Context, key string, capacity int, refillRate float64, now time.Time) (bool, error) {
+	if capacity <= 0 || refillRate <= 0 {
+		return false, fmt.Errorf("invalid token bucket parameters: capacity=%d, refill_rate=%f", capacity, refillRate)
+	}
+
+	result, err := tokenBucketScript.Run(ctx, r.Client, []string{key},
+		capacity,
+		refillRate/1000,
+		now.UnixMilli(),
+	).Int()
+	if err != nil {
+		r.logger.Error("Failed to take token",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return false, fmt.Errorf("failed to take token: %w", err)
+	}
+
+	return result == 1, nil
+}
+
 // SetExpiry sets expiration for a key
 func (r *Redis) SetExpiry(ctx context.Context, key string, expiration time.Duration) error {
 	if err := r.Client.Expire(ctx, key, expiration).Err(); err != nil {
@@ -213,34 +398,372 @@ func (r *Redis) Keys(ctx context.Context, pattern string) ([]string, error) {
 	return keys, nil
 }
 
+// addSessionScript records a session in a sorted set scored by its start time
+// in milliseconds. Sessions older than ARGV[4] milliseconds are dropped first;
+// if more than ARGV[3] (when positive) remain, the oldest sessions other than
+// the new one are evicted and returned. Scores are kept strictly increasing so
+// sessions started in the same millisecond are still evicted in order.
+var addSessionScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - ttl)
+
+local score = now
+local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+if newest[2] ~= nil and tonumber(newest[2]) >= score then
+	score = tonumber(newest[2]) + 1
+end
+redis.call("ZADD", KEYS[1], score, ARGV[1])
+
+local evicted = {}
+if limit > 0 then
+	local excess = redis.call("ZCARD", KEYS[1]) - limit
+	if excess > 0 then
+		for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+			if excess == 0 then
+				break
+			end
+			if member ~= ARGV[1] then
+				redis.call("ZREM", KEYS[1], member)
+				table.insert(evicted, member)
+				excess = excess - 1
+			end
+		end
+	end
+end
+
+redis.call("PEXPIRE", KEYS[1], ttl)
+
+return evicted
+`)
+
+// AddSession records sessionID as an active session of userID started at now.
+// Sessions live for ttl; when limit is positive and the user has more active
+// sessions than limit, the oldest are evicted. It returns the evicted session IDs.
+func (r *Redis) AddSession(ctx context.Context, userID, sessionID string, now time.Time, ttl time.Duration, limit int) ([]string, error) {
+	key := SessionCacheKeyPrefix + userID
+	evicted, err := addSessionScript.Run(ctx, r.Client, []string{key},
+		sessionID,
+		now.UnixMilli(),
+		limit,
+		ttl.Milliseconds(),
+	).StringSlice()
+	if err != nil {
+		r.logger.Error("Failed to add session",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to add session: %w", err)
+	}
+
+	return evicted, nil
+}
+
+// IsSessionActive checks if sessionID is an active session of userID that
+// started within ttl of now
+func (r *Redis) IsSessionActive(ctx context.Context, userID, sessionID string, now time.Time, ttl time.Duration) (bool, error) {
+	key := SessionCacheKeyPrefix + userID
+	startedAt, err := r.Client.ZScore(ctx, key, sessionID).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to check session",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return int64(startedAt) > now.Add(-ttl).UnixMilli(), nil
+}
+
+// ListSessionStarts returns the start times of userID's sessions that started
+// within ttl of now, oldest first
+func (r *Redis) ListSessionStarts(ctx context.Context, userID string, now time.Time, ttl time.Duration) ([]time.Time, error) {
+	key := SessionCacheKeyPrefix + userID
+	scores, err := r.Client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", now.Add(-ttl).UnixMilli()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		r.logger.Error("Failed to list sessions",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	starts := make([]time.Time, len(scores))
+	for i, z := range scores {
+		starts[i] = time.UnixMilli(int64(z.Score)).UTC()
+	}
+	return starts, nil
+}
+
+// removeOtherSessionsScript removes every session in the sorted set except ARGV[1]
+var removeOtherSessionsScript = redis.NewScript(`
+local removed = 0
+for _, member in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+	if member ~= ARGV[1] then
+		redis.call("ZREM", KEYS[1], member)
+		removed = removed + 1
+	end
+end
+return removed
+`)
+
+// RemoveOtherSessions signs out every active session of userID except
+// keepSessionID. It returns the number of sessions removed.
+func (r *Redis) RemoveOtherSessions(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	key := SessionCacheKeyPrefix + userID
+	removed, err := removeOtherSessionsScript.Run(ctx, r.Client, []string{key}, keepSessionID).Int64()
+	if err != nil {
+		r.logger.Error("Failed to remove sessions",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return 0, fmt.Errorf("failed to remove sessions: %w", err)
+	}
+
+	return removed, nil
+}
+
+// RememberRecent records member in the sorted set at key, scored by now, and
+// trims the set to its size most recent members. It reports whether member was
+// already present and how many members the set held before.
+func (r *Redis) RememberRecent(ctx context.Context, key, member string, now time.Time, size int, ttl time.Duration) (bool, int64, error) {
+	var (
+		score *redis.FloatCmd
+		count *redis.IntCmd
+	)
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		score = pipe.ZScore(ctx, key, member)
+		count = pipe.ZCard(ctx, key)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMilli()), Member: member})
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-size-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	// A member that was not present yet yields redis.Nil from ZSCORE
+	if err != nil && err != redis.Nil {
+		r.logger.Error("Failed to remember recent member",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return false, 0, fmt.Errorf("failed to remember recent member: %w", err)
+	}
+
+	return score.Err() == nil, count.Val(), nil
+}
+
+// PushRecent prepends value, encoded as JSON, to the list at key and trims
+// the list to its size most recent entries
+func (r *Redis) PushRecent(ctx context.Context, key string, value interface{}, size int, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	_, err = r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, data)
+		pipe.LTrim(ctx, key, 0, int64(size-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to push recent entry",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to push recent entry: %w", err)
+	}
+
+	return nil
+}
+
+// RangeRecent returns up to limit JSON entries of the list at key starting at
+// offset, newest first, and the length of the list
+func (r *Redis) RangeRecent(ctx context.Context, key string, offset, limit int) ([][]byte, int64, error) {
+	var (
+		entries *redis.StringSliceCmd
+		length  *redis.IntCmd
+	)
+	_, err := r.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		entries = pipe.LRange(ctx, key, int64(offset), int64(offset+limit-1))
+		length = pipe.LLen(ctx, key)
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to range recent entries",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return nil, 0, fmt.Errorf("failed to range recent entries: %w", err)
+	}
+
+	values := make([][]byte, len(entries.Val()))
+	for i, entry := range entries.Val() {
+		values[i] = []byte(entry)
+	}
+	return values, length.Val(), nil
+}
+
+// LoginActivityKey returns the key of userID's recent logins
+func LoginActivityKey(userID string) string {
+	return LoginHistoryKeyPrefix + userID + ":logins"
+}
+
 // Cache key constants
 const (
-	UserCacheKeyPrefix    = "user:"
-	SessionCacheKeyPrefix = "session:"
-	RateLimitKeyPrefix    = "rate_limit:"
-	TokenBlacklistPrefix  = "token_blacklist:"
+	UserCacheKeyPrefix     = "user:"
+	SessionCacheKeyPrefix  = "session:"
+	RateLimitKeyPrefix     = "rate_limit:"
+	TokenBlacklistPrefix   = "token_blacklist:"
+	LoginHistoryKeyPrefix  = "login_history:"
+	IdempotencyKeyPrefix   = "idempotency:"
+	PhoneCodeKeyPrefix     = "phone_code:"
+	PasswordSetupKeyPrefix = "password_setup:"
 )
 
 // Helper functions for common cache operations
 
 // CacheUser caches user data
-func (r *Redis) CacheUser(ctx context.Context, userID uint, user interface{}, expiration time.Duration) error {
-	key := fmt.Sprintf("%s%d", UserCacheKeyPrefix, userID)
+func (r *Redis) CacheUser(ctx context.Context, userID string, user interface{}, expiration time.Duration) error {
+	key := UserCacheKeyPrefix + userID
 	return r.Set(ctx, key, user, expiration)
 }
 
 // GetCachedUser retrieves cached user data
-func (r *Redis) GetCachedUser(ctx context.Context, userID uint, dest interface{}) error {
-	key := fmt.Sprintf("%s%d", UserCacheKeyPrefix, userID)
+func (r *Redis) GetCachedUser(ctx context.Context, userID string, dest interface{}) error {
+	key := UserCacheKeyPrefix + userID
 	return r.Get(ctx, key, dest)
 }
 
 // InvalidateUserCache removes user from cache
-func (r *Redis) InvalidateUserCache(ctx context.Context, userID uint) error {
-	key := fmt.Sprintf("%s%d", UserCacheKeyPrefix, userID)
+func (r *Redis) InvalidateUserCache(ctx context.Context, userID string) error {
+	key := UserCacheKeyPrefix + userID
 	return r.Delete(ctx, key)
 }
 
+// PurgeUser removes everything cached about a user: their cached profile,
+// sessions and login history. DELs are pipelined rather than sent as one so
+// the keys may live in different cluster slots.
+func (r *Redis) PurgeUser(ctx context.Context, userID string) error {
+	loginHistory := LoginHistoryKeyPrefix + userID
+	keys := []string{
+		UserCacheKeyPrefix + userID,
+		SessionCacheKeyPrefix + userID,
+		loginHistory + ":networks",
+		loginHistory + ":agents",
+		LoginActivityKey(userID),
+		PhoneCodeKeyPrefix + userID,
+	}
+
+	pipe := r.Client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.Error("Failed to purge user cache",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to purge user cache: %w", err)
+	}
+
+	return nil
+}
+
+// StorePhoneCode stores the hash of the phone verification code sent to
+// userID for ttl, replacing any earlier code and its failed attempts
+func (r *Redis) StorePhoneCode(ctx context.Context, userID, codeHash string, ttl time.Duration) error {
+	key := PhoneCodeKeyPrefix + userID
+	pipe := r.Client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "hash", codeHash, "attempts", 0)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.Error("Failed to store phone code",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to store phone code: %w", err)
+	}
+	return nil
+}
+
+// checkPhoneCodeScript compares ARGV[1] with the stored code hash. A match
+// consumes the code; a mismatch counts an attempt and discards the code once
+// ARGV[2] attempts have failed. It returns -1 when no code is stored, 1 on a
+// match and 0 otherwise.
+var checkPhoneCodeScript = redis.NewScript(`
+local hash = redis.call("HGET", KEYS[1], "hash")
+if not hash then
+	return -1
+end
+if hash == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return 1
+end
+if redis.call("HINCRBY", KEYS[1], "attempts", 1) >= tonumber(ARGV[2]) then
+	redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// CheckPhoneCode checks codeHash against the phone verification code stored
+// for userID. It reports whether a code was stored and whether it matched; a
+// matching code is consumed, and the code is discarded after maxAttempts
+// mismatches.
+func (r *Redis) CheckPhoneCode(ctx context.Context, userID, codeHash string, maxAttempts int) (bool, bool, error) {
+	key := PhoneCodeKeyPrefix + userID
+	result, err := checkPhoneCodeScript.Run(ctx, r.Client, []string{key}, codeHash, maxAttempts).Int()
+	if err != nil {
+		r.logger.Error("Failed to check phone code",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return false, false, fmt.Errorf("failed to check phone code: %w", err)
+	}
+	return result >= 0, result == 1, nil
+}
+
+// StorePasswordSetupToken stores the ID of the user a password setup token
+// was issued to under the hash of the token, for ttl
+func (r *Redis) StorePasswordSetupToken(ctx context.Context, tokenHash, userID string, ttl time.Duration) error {
+	key := PasswordSetupKeyPrefix + tokenHash
+	if err := r.Client.Set(ctx, key, userID, ttl).Err(); err != nil {
+		r.logger.Error("Failed to store password setup token",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to store password setup token: %w", err)
+	}
+	return nil
+}
+
+// ConsumePasswordSetupToken returns the ID of the user the password setup
+// token with tokenHash was issued to and deletes the token, so it works only
+// once. It returns an empty ID when the token is unknown or expired.
+func (r *Redis) ConsumePasswordSetupToken(ctx context.Context, tokenHash string) (string, error) {
+	key := PasswordSetupKeyPrefix + tokenHash
+	userID, err := r.Client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to consume password setup token",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return "", fmt.Errorf("failed to consume password setup token: %w", err)
+	}
+	return userID, nil
+}
+
 // SetRateLimit sets rate limit counter
 func (r *Redis) SetRateLimit(ctx context.Context, identifier string, expiration time.Duration) (int64, error) {
 	key := fmt.Sprintf("%s%s", RateLimitKeyPrefix, identifier)
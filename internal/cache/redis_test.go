@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.RedisConfig
+		expectedErr string
+		check       func(t *testing.T, client redis.UniversalClient)
+	}{
+		{
+			name: "standalone is the default",
+			cfg:  config.RedisConfig{Addr: "localhost:6379", DB: 2},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				require.IsType(t, &redis.Client{}, client)
+				opts := client.(*redis.Client).Options()
+				assert.Equal(t, "localhost:6379", opts.Addr)
+				assert.Equal(t, 2, opts.DB)
+			},
+		},
+		{
+			name: "single is accepted as standalone",
+			cfg:  config.RedisConfig{Mode: RedisModeSingle, Addr: "localhost:6379"},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				require.IsType(t, &redis.Client{}, client)
+				assert.Equal(t, "localhost:6379", client.(*redis.Client).Options().Addr)
+			},
+		},
+		{
+			name: "cluster",
+			cfg:  config.RedisConfig{Mode: RedisModeCluster, Addrs: []string{"node-1:6379", "node-2:6379", "node-3:6379"}},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				require.IsType(t, &redis.ClusterClient{}, client)
+				assert.Equal(t, []string{"node-1:6379", "node-2:6379", "node-3:6379"}, client.(*redis.ClusterClient).Options().Addrs)
+			},
+		},
+		{
+			name: "sentinel",
+			cfg:  config.RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster", Addrs: []string{"sentinel-1:26379"}, DB: 1},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				require.IsType(t, &redis.Client{}, client)
+				assert.Equal(t, 1, client.(*redis.Client).Options().DB)
+			},
+		},
+		{
+			name:        "cluster with a single addr",
+			cfg:         config.RedisConfig{Mode: RedisModeCluster, Addrs: []string{"node-1:6379"}},
+			expectedErr: "redis cluster mode requires at least 2 addrs, got 1",
+		},
+		{
+			name:        "cluster with a non-zero db",
+			cfg:         config.RedisConfig{Mode: RedisModeCluster, Addrs: []string{"node-1:6379", "node-2:6379"}, DB: 1},
+			expectedErr: "redis cluster mode only supports db 0, got 1",
+		},
+		{
+			name:        "sentinel without master name",
+			cfg:         config.RedisConfig{Mode: RedisModeSentinel, Addrs: []string{"sentinel-1:26379"}},
+			expectedErr: "redis sentinel mode requires master_name",
+		},
+		{
+			name:        "sentinel without addrs",
+			cfg:         config.RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster"},
+			expectedErr: "redis sentinel mode requires at least 1 sentinel addr",
+		},
+		{
+			name:        "unknown mode",
+			cfg:         config.RedisConfig{Mode: "ring"},
+			expectedErr: "unsupported redis mode: ring",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newRedisClient(&tt.cfg)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			t.Cleanup(func() { client.Close() })
+			tt.check(t, client)
+		})
+	}
+}
+
+func TestRedis_GetManySetMany(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r, err := NewRedis(&config.Config{Redis: config.RedisConfig{Addr: mr.Addr()}}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	ctx := context.Background()
+
+	require.NoError(t, r.SetMany(ctx, map[string]CacheItem{
+		"user:1": {Value: map[string]string{"id": "1"}, Expiration: time.Minute},
+		"user:2": {Value: map[string]string{"id": "2"}, Expiration: time.Hour},
+	}))
+	assert.Equal(t, time.Minute, mr.TTL("user:1"))
+	assert.Equal(t, time.Hour, mr.TTL("user:2"))
+
+	// Partial hits only return the cached keys
+	values, err := r.GetMany(ctx, []string{"user:1", "user:3", "user:2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"user:1": []byte(`{"id":"1"}`),
+		"user:2": []byte(`{"id":"2"}`),
+	}, values)
+
+	values, err = r.GetMany(ctx, []string{"user:3"})
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	values, err = r.GetMany(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+	require.NoError(t, r.SetMany(ctx, nil))
+
+	mr.Close()
+	_, err = r.GetMany(ctx, []string{"user:1"})
+	assert.Error(t, err)
+}
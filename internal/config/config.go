@@ -10,25 +10,58 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server     ServerConfig     `mapstructure:"server"`
-	Database   DatabaseConfig   `mapstructure:"database"`
-	Redis      RedisConfig      `mapstructure:"redis"`
-	Kafka      KafkaConfig      `mapstructure:"kafka"`
-	JWT        JWTConfig        `mapstructure:"jwt"`
-	Logging    LoggingConfig    `mapstructure:"logging"`
-	Monitoring MonitoringConfig `mapstructure:"monitoring"`
-	I18n       I18nConfig       `mapstructure:"i18n"`
-	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
-	CORS       CORSConfig       `mapstructure:"cors"`
-	Task       TaskConfig       `mapstructure:"task"`
+	Server         ServerConfig         `mapstructure:"server"`
+	Database       DatabaseConfig       `mapstructure:"database"`
+	Redis          RedisConfig          `mapstructure:"redis"`
+	Kafka          KafkaConfig          `mapstructure:"kafka"`
+	JWT            JWTConfig            `mapstructure:"jwt"`
+	Auth           AuthConfig           `mapstructure:"auth"`
+	Logging        LoggingConfig        `mapstructure:"logging"`
+	Monitoring     MonitoringConfig     `mapstructure:"monitoring"`
+	Profiling      ProfilingConfig      `mapstructure:"profiling"`
+	I18n           I18nConfig           `mapstructure:"i18n"`
+	RateLimit      RateLimitConfig      `mapstructure:"rate_limit"`
+	CORS           CORSConfig           `mapstructure:"cors"`
+	CacheControl   CacheControlConfig   `mapstructure:"cache_control"`
+	Idempotency    IdempotencyConfig    `mapstructure:"idempotency"`
+	Task           TaskConfig           `mapstructure:"task"`
+	Audit          AuditConfig          `mapstructure:"audit"`
+	Compression    CompressionConfig    `mapstructure:"compression"`
+	SMTP           SMTPConfig           `mapstructure:"smtp"`
+	Notifications  NotificationsConfig  `mapstructure:"notifications"`
+	Outbox         OutboxConfig         `mapstructure:"outbox"`
+	Security       SecurityConfig       `mapstructure:"security"`
+	Infrastructure InfrastructureConfig `mapstructure:"infrastructure"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Host            string        `mapstructure:"host"`
-	Port            int           `mapstructure:"port"`
-	Mode            string        `mapstructure:"mode"` // debug, release, test
-	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	Host            string           `mapstructure:"host"`
+	Port            int              `mapstructure:"port"`
+	Mode            string           `mapstructure:"mode"` // debug, release, test
+	ShutdownTimeout time.Duration    `mapstructure:"shutdown_timeout"`
+	RequestTimeout  time.Duration    `mapstructure:"request_timeout"` // deadline of each request; 0 disables
+	JSONNaming      string           `mapstructure:"json_naming"`     // snake, camel
+	MaxURLLength    int              `mapstructure:"max_url_length"`
+	RequestIDHeader string           `mapstructure:"request_id_header"`
+	MaxFields       int              `mapstructure:"max_fields"`     // maximum entries in a ?fields= sparse fieldset
+	MaxBatchCost    int              `mapstructure:"max_batch_cost"` // maximum ids × fields of a batch lookup
+	BodyLimits      BodyLimitsConfig `mapstructure:"body_limits"`
+	Pagination      PaginationConfig `mapstructure:"pagination"`
+}
+
+// PaginationConfig bounds the page size of list endpoints
+type PaginationConfig struct {
+	DefaultSize int `mapstructure:"default_size"` // used when no size is requested
+	MaxSize     int `mapstructure:"max_size"`     // larger requested sizes are clamped
+}
+
+// BodyLimitsConfig caps request body sizes in bytes. Groups overrides Default
+// for individual route groups (public, protected, admin), e.g. to allow larger
+// uploads. A limit of 0 disables the cap.
+type BodyLimitsConfig struct {
+	Default int64            `mapstructure:"default"`
+	Groups  map[string]int64 `mapstructure:"groups"`
 }
 
 // DatabaseConfig holds database configuration
@@ -48,6 +81,12 @@ type PostgreSQLConfig struct {
 	MaxOpenConns int           `mapstructure:"max_open_conns"`
 	MaxIdleConns int           `mapstructure:"max_idle_conns"`
 	MaxLifetime  time.Duration `mapstructure:"max_lifetime"`
+	// Replicas are DSNs of read replicas that serve SELECTs; writes,
+	// transactions and locking reads always use the primary
+	Replicas []string `mapstructure:"replicas"`
+	// ReadYourWrites sends the reads of a request to the primary once the
+	// request has written, so replica lag cannot hide its own changes
+	ReadYourWrites bool `mapstructure:"read_your_writes"`
 }
 
 // MongoDBConfig holds MongoDB configuration
@@ -58,11 +97,16 @@ type MongoDBConfig struct {
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
+	Mode         string `mapstructure:"mode"` // standalone, cluster, sentinel
 	Addr         string `mapstructure:"addr"`
 	Password     string `mapstructure:"password"`
 	DB           int    `mapstructure:"db"`
 	PoolSize     int    `mapstructure:"pool_size"`
 	MinIdleConns int    `mapstructure:"min_idle_conns"`
+	// Addrs lists the cluster nodes in cluster mode or the sentinels in sentinel mode
+	Addrs []string `mapstructure:"addrs"`
+	// MasterName is the name of the master monitored by the sentinels
+	MasterName string `mapstructure:"master_name"`
 }
 
 // KafkaConfig holds Kafka configuration
@@ -70,20 +114,123 @@ type KafkaConfig struct {
 	Brokers []string          `mapstructure:"brokers"`
 	Topics  map[string]string `mapstructure:"topics"`
 	GroupID string            `mapstructure:"group_id"`
+	DLQ     KafkaDLQConfig    `mapstructure:"dlq"`
+	// LagThreshold marks the consumer degraded once a topic's total lag exceeds it; 0 disables the check
+	LagThreshold int64 `mapstructure:"lag_threshold"`
+	// DedupTTL is how long processed event IDs are remembered to skip redeliveries; 0 disables deduplication
+	DedupTTL time.Duration `mapstructure:"dedup_ttl"`
+	// LoginHistorySize is how many recent login networks and user agents are kept per user to detect new devices; 0 disables the check
+	LoginHistorySize int `mapstructure:"login_history_size"`
+	// Producer holds settings for the asynchronous producer
+	Producer KafkaProducerConfig `mapstructure:"producer"`
+	// TimestampSource selects the Kafka record timestamp: event_time or published_at
+	TimestampSource string `mapstructure:"timestamp_source"`
+	// DrainTimeout bounds how long the consumer keeps handling an in-flight message after Stop
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// Serialization selects how published events are encoded: json or protobuf.
+	// Consumers decode by each message's content-type header regardless.
+	Serialization string `mapstructure:"serialization"`
+}
+
+// KafkaDLQConfig holds dead-letter queue configuration. Messages that fail
+// processing are sent to "<topic>.dlq" and replayed after ReplayDelay until
+// they succeed or have failed MaxAttempts times.
+type KafkaDLQConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	ReplayDelay time.Duration `mapstructure:"replay_delay"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+}
+
+// KafkaProducerConfig holds producer configuration. PublishTimeout bounds how
+// long a synchronous or batch publish waits for Kafka; a caller's earlier
+// context deadline takes precedence. Backpressure selects what
+// PublishUserEventAsync does when the producer input channel is full: block
+// (wait up to BlockTimeout), drop (discard the event) or error (fail
+// immediately).
+type KafkaProducerConfig struct {
+	PublishTimeout time.Duration             `mapstructure:"publish_timeout"`
+	Backpressure   string                    `mapstructure:"backpressure"`
+	BlockTimeout   time.Duration             `mapstructure:"block_timeout"`
+	CircuitBreaker KafkaCircuitBreakerConfig `mapstructure:"circuit_breaker"`
 }
 
-// JWTConfig holds JWT configuration
+// KafkaCircuitBreakerConfig holds the producer circuit breaker configuration.
+// After FailureThreshold consecutive publish failures the breaker opens and
+// publishes fail fast with producer.ErrCircuitOpen; once CoolDown has passed
+// one publish is let through to probe Kafka and closes the breaker on success.
+type KafkaCircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	CoolDown         time.Duration `mapstructure:"cool_down"`
+}
+
+// JWTConfig holds JWT configuration. Algorithm is HS256 (shared Secret) or
+// RS256, where tokens are signed with the private key at SigningKeyPath under
+// SigningKeyID and verified with the public keys in VerificationKeysDir, one
+// "<kid>.pem" file per key, so tokens signed before a key rotation stay valid.
 type JWTConfig struct {
-	Secret string        `mapstructure:"secret"`
-	Expiry time.Duration `mapstructure:"expiry"`
-	Issuer string        `mapstructure:"issuer"`
+	Secret              string        `mapstructure:"secret"`
+	Expiry              time.Duration `mapstructure:"expiry"`
+	Issuer              string        `mapstructure:"issuer"`
+	Algorithm           string        `mapstructure:"algorithm"`
+	SigningKeyPath      string        `mapstructure:"signing_key_path"`
+	SigningKeyID        string        `mapstructure:"signing_key_id"`
+	VerificationKeysDir string        `mapstructure:"verification_keys_dir"`
+}
+
+// AuthConfig holds registration and session rules. When AllowedEmailDomains is
+// set only those domains (and their subdomains) may register;
+// BlockedEmailDomains and, if BlockDisposableEmails is set, the built-in
+// disposable list are rejected. SessionLimits caps concurrent sessions per plan.
+type AuthConfig struct {
+	AllowedEmailDomains   []string                `mapstructure:"allowed_email_domains"`
+	BlockedEmailDomains   []string                `mapstructure:"blocked_email_domains"`
+	BlockDisposableEmails bool                    `mapstructure:"block_disposable_emails"`
+	SessionLimits         map[string]int          `mapstructure:"session_limits"`
+	EmailVerification     EmailVerificationConfig `mapstructure:"email_verification"`
+	PhoneVerification     PhoneVerificationConfig `mapstructure:"phone_verification"`
+	PasswordSetup         PasswordSetupConfig     `mapstructure:"password_setup"`
+}
+
+// EmailVerificationConfig controls what users who have not verified their
+// email may do. Enforce makes the routes that require a verified email reject
+// them; RequireForLogin stops them from logging in at all.
+type EmailVerificationConfig struct {
+	Enforce         bool `mapstructure:"enforce"`
+	RequireForLogin bool `mapstructure:"require_for_login"`
+}
+
+// PhoneVerificationConfig controls the one-time codes texted to users to
+// verify their phone number. A code expires after CodeTTL or after
+// MaxAttempts wrong guesses.
+type PhoneVerificationConfig struct {
+	CodeLength  int           `mapstructure:"code_length"`
+	CodeTTL     time.Duration `mapstructure:"code_ttl"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+}
+
+// PasswordSetupConfig controls the one-time links emailed to users created
+// in bulk to choose their password. URL is the page that reads the token from
+// its token query parameter; a link works once and expires after TokenTTL.
+type PasswordSetupConfig struct {
+	URL      string        `mapstructure:"url"`
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level      string `mapstructure:"level"`
-	Format     string `mapstructure:"format"` // json, console
-	OutputPath string `mapstructure:"output_path"`
+	Level      string               `mapstructure:"level"`
+	Format     string               `mapstructure:"format"` // json, console
+	OutputPath string               `mapstructure:"output_path"`
+	MongoDB    RequestLogSinkConfig `mapstructure:"mongodb"`
+}
+
+// RequestLogSinkConfig controls writing one document per request to the
+// MongoDB logs collection. SampleRate is the fraction of requests written,
+// from 0 to 1.
+type RequestLogSinkConfig struct {
+	Enabled    bool    `mapstructure:"enabled"`
+	SampleRate float64 `mapstructure:"sample_rate"`
 }
 
 // MonitoringConfig holds monitoring configuration
@@ -94,9 +241,10 @@ type MonitoringConfig struct {
 
 // PrometheusConfig holds Prometheus configuration
 type PrometheusConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Port    int    `mapstructure:"port"`
-	Path    string `mapstructure:"path"`
+	Enabled         bool          `mapstructure:"enabled"`
+	Port            int           `mapstructure:"port"`
+	Path            string        `mapstructure:"path"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"` // gauges computed from the database
 }
 
 // TracingConfig holds tracing configuration
@@ -106,6 +254,12 @@ type TracingConfig struct {
 	Service  string `mapstructure:"service"`
 }
 
+// ProfilingConfig holds pprof profiling configuration. The endpoints are open
+// outside release mode; in release mode they require an admin token.
+type ProfilingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
 // I18nConfig holds internationalization configuration
 type I18nConfig struct {
 	DefaultLanguage string   `mapstructure:"default_language"`
@@ -114,10 +268,47 @@ type I18nConfig struct {
 
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Rate    int    `mapstructure:"rate"`
-	Burst   int    `mapstructure:"burst"`
-	Store   string `mapstructure:"store"` // memory, redis
+	Enabled bool                  `mapstructure:"enabled"`
+	Rate    int                   `mapstructure:"rate"`
+	Burst   int                   `mapstructure:"burst"`
+	Store   string                `mapstructure:"store"` // memory, redis
+	Login   LoginThrottleConfig   `mapstructure:"login"`
+	Tenants TenantRateLimitConfig `mapstructure:"tenants"`
+	// Routes limits individual routes, keyed by the name of a built-in limit
+	// (register, password_reset, availability, phone_code) or by a route
+	// pattern such as /api/v1/users/:id
+	Routes map[string]RouteLimit `mapstructure:"routes"`
+}
+
+// RouteLimit allows Rate requests per Window. A Rate of 0 disables the limit.
+type RouteLimit struct {
+	Rate   int           `mapstructure:"rate"`
+	Window time.Duration `mapstructure:"window"`
+}
+
+// TenantRateLimitConfig limits the combined requests of all users of a tenant
+// so one tenant cannot starve the others. Limits overrides the default Rate
+// and Burst for individual tenants, keyed by tenant ID.
+type TenantRateLimitConfig struct {
+	Enabled bool                   `mapstructure:"enabled"`
+	Rate    int                    `mapstructure:"rate"`
+	Burst   int                    `mapstructure:"burst"`
+	Limits  map[string]TenantLimit `mapstructure:"limits"`
+}
+
+// TenantLimit is the request rate per minute and burst allowed for one tenant
+type TenantLimit struct {
+	Rate  int `mapstructure:"rate"`
+	Burst int `mapstructure:"burst"`
+}
+
+// LoginThrottleConfig limits login attempts per client IP, per account and
+// per IP+account pair within a window. A limit of 0 disables that dimension.
+type LoginThrottleConfig struct {
+	Window       time.Duration `mapstructure:"window"`
+	PerIP        int           `mapstructure:"per_ip"`
+	PerAccount   int           `mapstructure:"per_account"`
+	PerIPAccount int           `mapstructure:"per_ip_account"`
 }
 
 // CORSConfig holds CORS configuration
@@ -130,12 +321,149 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// CacheControlConfig holds Cache-Control header configuration for read endpoints.
+// Routes are keyed by the registered route pattern, e.g. "/api/v1/users/:id".
+type CacheControlConfig struct {
+	Default string            `mapstructure:"default"`
+	Routes  map[string]string `mapstructure:"routes"`
+}
+
+// IdempotencyConfig holds Idempotency-Key handling for mutating requests.
+// Responses are replayed to retries with the same key for TTL.
+type IdempotencyConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`
+}
+
+// AuditConfig selects the requests recorded to the audit log. Routes are
+// "METHOD ROUTE" patterns matched against the registered route pattern, e.g.
+// "POST /api/v1/users/login"; "*" matches any method and a trailing "*" any
+// route with that prefix.
+type AuditConfig struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Routes  []string `mapstructure:"routes"`
+}
+
+// CompressionConfig holds response compression configuration. Responses
+// smaller than MinSize bytes are sent uncompressed.
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	MinSize int  `mapstructure:"min_size"`
+}
+
 // TaskConfig holds async task configuration
 type TaskConfig struct {
-	Redis    RedisConfig `mapstructure:"redis"`
-	Queues   []string    `mapstructure:"queues"`
-	Workers  int         `mapstructure:"workers"`
-	LogLevel string      `mapstructure:"log_level"`
+	Redis           RedisConfig   `mapstructure:"redis"`
+	Queues          []string      `mapstructure:"queues"`
+	Workers         int           `mapstructure:"workers"`
+	LogLevel        string        `mapstructure:"log_level"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+}
+
+// SMTPConfig holds the SMTP server emails are sent through. Emails are only
+// logged when Host is empty.
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// NotificationsConfig holds the providers notifications are delivered through
+type NotificationsConfig struct {
+	Email   EmailConfig   `mapstructure:"email"`
+	SMS     SMSConfig     `mapstructure:"sms"`
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+// EmailConfig selects the email provider. Provider is "smtp", "ses", or empty
+// to use SMTP when smtp.host is set and otherwise only log emails.
+type EmailConfig struct {
+	Provider string    `mapstructure:"provider"`
+	SES      SESConfig `mapstructure:"ses"`
+}
+
+// SESConfig holds the AWS SES account emails are sent from. SessionToken is
+// set with the temporary credentials of an assumed IAM role.
+type SESConfig struct {
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"`
+	From            string `mapstructure:"from"`
+}
+
+// SMSConfig selects the SMS provider. Provider is "twilio", or empty to only
+// log text messages.
+type SMSConfig struct {
+	Provider string       `mapstructure:"provider"`
+	Twilio   TwilioConfig `mapstructure:"twilio"`
+}
+
+// TwilioConfig holds the Twilio account text messages are sent from
+type TwilioConfig struct {
+	AccountSID string `mapstructure:"account_sid"`
+	AuthToken  string `mapstructure:"auth_token"`
+	From       string `mapstructure:"from"`
+}
+
+// WebhookConfig holds the endpoint notifications are posted to and how
+// webhook deliveries, including those of webhook subscriptions, are retried.
+// Notifications are not posted when URL is empty; with a Secret, each request
+// is signed.
+type WebhookConfig struct {
+	URL    string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+	// MaxAttempts is how many times a delivery is tried; retries wait
+	// RetryBackoff, doubling after each attempt
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+	// DisableAfter disables a subscription after this many failed delivery
+	// attempts in a row, 0 never disables it
+	DisableAfter int `mapstructure:"disable_after"`
+}
+
+// OutboxConfig holds transactional outbox relay configuration
+type OutboxConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+}
+
+// SecurityConfig holds password hashing and password policy configuration
+type SecurityConfig struct {
+	PasswordAlgorithm string               `mapstructure:"password_algorithm"` // bcrypt or argon2id; existing hashes are upgraded on login
+	BcryptCost        int                  `mapstructure:"bcrypt_cost"`        // clamped to bcrypt's valid range; 0 uses bcrypt's default
+	Argon2            Argon2Config         `mapstructure:"argon2"`
+	Password          PasswordPolicyConfig `mapstructure:"password"`
+	// LogoutOthersOnPasswordChange signs out the user's other sessions when they change their password
+	LogoutOthersOnPasswordChange bool `mapstructure:"logout_others_on_password_change"`
+}
+
+// Argon2Config holds argon2id hashing parameters
+type Argon2Config struct {
+	Memory      uint32 `mapstructure:"memory"` // KiB
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+}
+
+// InfrastructureConfig holds startup requirements of backing services
+type InfrastructureConfig struct {
+	// Optional lists components (mongodb, kafka) the service starts without
+	// when they cannot be reached; health reports them as degraded
+	Optional []string `mapstructure:"optional"`
+}
+
+// PasswordPolicyConfig holds the rules new passwords must satisfy
+type PasswordPolicyConfig struct {
+	MinLength        int  `mapstructure:"min_length"`
+	RequireUppercase bool `mapstructure:"require_uppercase"`
+	RequireLowercase bool `mapstructure:"require_lowercase"`
+	RequireDigit     bool `mapstructure:"require_digit"`
+	RequireSymbol    bool `mapstructure:"require_symbol"`
+	DenyCommon       bool `mapstructure:"deny_common"` // reject well-known passwords such as "password123"
 }
 
 // Load loads configuration from file and environment variables
@@ -165,6 +493,10 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	if err := config.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -175,6 +507,16 @@ func setDefaults() {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.mode", "debug")
 	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.request_timeout", "30s")
+	viper.SetDefault("server.json_naming", "snake")
+	viper.SetDefault("server.max_url_length", 8192)
+	viper.SetDefault("server.request_id_header", "X-Request-ID")
+	viper.SetDefault("server.max_fields", 20)
+	viper.SetDefault("server.max_batch_cost", 1000)
+	viper.SetDefault("server.body_limits.default", 1<<20)
+	viper.SetDefault("server.body_limits.groups", map[string]int64{})
+	viper.SetDefault("server.pagination.default_size", 10)
+	viper.SetDefault("server.pagination.max_size", 100)
 
 	// Database defaults
 	viper.SetDefault("database.postgres.host", "localhost")
@@ -186,11 +528,14 @@ func setDefaults() {
 	viper.SetDefault("database.postgres.max_open_conns", 25)
 	viper.SetDefault("database.postgres.max_idle_conns", 10)
 	viper.SetDefault("database.postgres.max_lifetime", "5m")
+	viper.SetDefault("database.postgres.replicas", []string{})
+	viper.SetDefault("database.postgres.read_your_writes", true)
 
 	viper.SetDefault("database.mongodb.uri", "mongodb://localhost:27017")
 	viper.SetDefault("database.mongodb.database", "usercenter_logs")
 
 	// Redis defaults
+	viper.SetDefault("redis.mode", "standalone")
 	viper.SetDefault("redis.addr", "localhost:6379")
 	viper.SetDefault("redis.password", "")
 	viper.SetDefault("redis.db", 0)
@@ -201,26 +546,61 @@ func setDefaults() {
 	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
 	viper.SetDefault("kafka.topics.user_events", "user.events")
 	viper.SetDefault("kafka.group_id", "usercenter")
+	viper.SetDefault("kafka.timestamp_source", "published_at")
+	viper.SetDefault("kafka.serialization", "json")
+	viper.SetDefault("kafka.drain_timeout", "10s")
+	viper.SetDefault("kafka.dlq.enabled", true)
+	viper.SetDefault("kafka.dlq.replay_delay", "1m")
+	viper.SetDefault("kafka.dlq.max_attempts", 5)
+	viper.SetDefault("kafka.lag_threshold", 1000)
+	viper.SetDefault("kafka.dedup_ttl", "24h")
+	viper.SetDefault("kafka.login_history_size", 10)
+	viper.SetDefault("kafka.producer.publish_timeout", "30s")
+	viper.SetDefault("kafka.producer.backpressure", "error")
+	viper.SetDefault("kafka.producer.block_timeout", "5s")
+	viper.SetDefault("kafka.producer.circuit_breaker.enabled", true)
+	viper.SetDefault("kafka.producer.circuit_breaker.failure_threshold", 5)
+	viper.SetDefault("kafka.producer.circuit_breaker.cool_down", "30s")
 
 	// JWT defaults
 	viper.SetDefault("jwt.secret", "your-secret-key")
 	viper.SetDefault("jwt.expiry", "24h")
 	viper.SetDefault("jwt.issuer", "usercenter")
+	viper.SetDefault("jwt.algorithm", "HS256")
+
+	// Auth defaults
+	viper.SetDefault("auth.allowed_email_domains", []string{})
+	viper.SetDefault("auth.blocked_email_domains", []string{})
+	viper.SetDefault("auth.block_disposable_emails", false)
+	viper.SetDefault("auth.session_limits", map[string]int{"free": 1, "pro": 5})
+	viper.SetDefault("auth.email_verification.enforce", false)
+	viper.SetDefault("auth.email_verification.require_for_login", false)
+	viper.SetDefault("auth.phone_verification.code_length", 6)
+	viper.SetDefault("auth.phone_verification.code_ttl", "5m")
+	viper.SetDefault("auth.phone_verification.max_attempts", 5)
+	viper.SetDefault("auth.password_setup.url", "http://localhost:3000/password-setup")
+	viper.SetDefault("auth.password_setup.token_ttl", "72h")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 	viper.SetDefault("logging.output_path", "logs/usercenter.log")
+	viper.SetDefault("logging.mongodb.enabled", false)
+	viper.SetDefault("logging.mongodb.sample_rate", 0.1)
 
 	// Monitoring defaults
 	viper.SetDefault("monitoring.prometheus.enabled", true)
 	viper.SetDefault("monitoring.prometheus.port", 9090)
 	viper.SetDefault("monitoring.prometheus.path", "/metrics")
+	viper.SetDefault("monitoring.prometheus.refresh_interval", "1m")
 
 	viper.SetDefault("monitoring.tracing.enabled", true)
-	viper.SetDefault("monitoring.tracing.endpoint", "http://localhost:14268/api/traces")
+	viper.SetDefault("monitoring.tracing.endpoint", "http://localhost:4318/v1/traces")
 	viper.SetDefault("monitoring.tracing.service", "usercenter")
 
+	// Profiling defaults
+	viper.SetDefault("profiling.enabled", false)
+
 	// I18n defaults
 	viper.SetDefault("i18n.default_language", "zh-CN")
 	viper.SetDefault("i18n.languages", []string{"zh-CN", "en-US"})
@@ -230,19 +610,87 @@ func setDefaults() {
 	viper.SetDefault("rate_limit.rate", 100)
 	viper.SetDefault("rate_limit.burst", 200)
 	viper.SetDefault("rate_limit.store", "redis")
+	viper.SetDefault("rate_limit.login.window", "15m")
+	viper.SetDefault("rate_limit.login.per_ip", 20)
+	viper.SetDefault("rate_limit.login.per_account", 10)
+	viper.SetDefault("rate_limit.login.per_ip_account", 5)
+	viper.SetDefault("rate_limit.tenants.enabled", false)
+	viper.SetDefault("rate_limit.tenants.rate", 1000)
+	viper.SetDefault("rate_limit.tenants.burst", 2000)
+	for route, limit := range defaultRouteLimits {
+		viper.SetDefault("rate_limit.routes."+route+".rate", limit.Rate)
+		viper.SetDefault("rate_limit.routes."+route+".window", limit.Window.String())
+	}
 
 	// CORS defaults
 	viper.SetDefault("cors.allow_origins", []string{"*"})
 	viper.SetDefault("cors.allow_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
 	viper.SetDefault("cors.allow_headers", []string{"*"})
 	viper.SetDefault("cors.expose_headers", []string{"X-Request-ID"})
-	viper.SetDefault("cors.allow_credentials", true)
+	viper.SetDefault("cors.allow_credentials", false)
 	viper.SetDefault("cors.max_age", 86400)
 
+	// Cache control defaults
+	viper.SetDefault("cache_control.default", "no-store")
+	viper.SetDefault("cache_control.routes", map[string]string{})
+
+	// Idempotency defaults
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.ttl", "24h")
+
+	// Audit defaults: mutations only, which include login and registration
+	viper.SetDefault("audit.enabled", true)
+	viper.SetDefault("audit.routes", []string{"POST *", "PUT *", "PATCH *", "DELETE *"})
+
+	// Compression defaults: opt-in
+	viper.SetDefault("compression.enabled", false)
+	viper.SetDefault("compression.min_size", 1024)
+
 	// Task defaults
+	viper.SetDefault("task.redis.addr", "localhost:6379")
+	viper.SetDefault("task.redis.db", 1)
 	viper.SetDefault("task.queues", []string{"default", "email", "notification"})
 	viper.SetDefault("task.workers", 10)
 	viper.SetDefault("task.log_level", "info")
+	viper.SetDefault("task.cleanup_interval", "1h")
+
+	// SMTP defaults
+	viper.SetDefault("smtp.host", "")
+	viper.SetDefault("smtp.port", 587)
+	viper.SetDefault("smtp.from", "User Center <no-reply@usercenter.local>")
+
+	// Notifications defaults
+	viper.SetDefault("notifications.email.provider", "")
+	viper.SetDefault("notifications.email.ses.region", "us-east-1")
+	viper.SetDefault("notifications.email.ses.from", "User Center <no-reply@usercenter.local>")
+	viper.SetDefault("notifications.sms.provider", "")
+	viper.SetDefault("notifications.webhook.url", "")
+	viper.SetDefault("notifications.webhook.max_attempts", 5)
+	viper.SetDefault("notifications.webhook.retry_backoff", "30s")
+	viper.SetDefault("notifications.webhook.disable_after", 20)
+
+	// Outbox defaults
+	viper.SetDefault("outbox.enabled", true)
+	viper.SetDefault("outbox.poll_interval", "1s")
+	viper.SetDefault("outbox.batch_size", 100)
+	viper.SetDefault("outbox.max_attempts", 10)
+
+	// Security defaults
+	viper.SetDefault("security.password_algorithm", "bcrypt")
+	viper.SetDefault("security.bcrypt_cost", 10)
+	viper.SetDefault("security.argon2.memory", 65536)
+	viper.SetDefault("security.argon2.iterations", 3)
+	viper.SetDefault("security.argon2.parallelism", 2)
+	viper.SetDefault("security.logout_others_on_password_change", true)
+	viper.SetDefault("security.password.min_length", 8)
+	viper.SetDefault("security.password.require_uppercase", true)
+	viper.SetDefault("security.password.require_lowercase", true)
+	viper.SetDefault("security.password.require_digit", true)
+	viper.SetDefault("security.password.require_symbol", false)
+	viper.SetDefault("security.password.deny_common", true)
+
+	// Infrastructure defaults
+	viper.SetDefault("infrastructure.optional", []string{"mongodb"})
 }
 
 // GetDSN returns the PostgreSQL DSN
@@ -250,3 +698,59 @@ func (c *PostgreSQLConfig) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
 }
+
+// fallbackPageSize is the page size when none is requested or configured
+const fallbackPageSize = 10
+
+// Normalize returns the page and size to serve for a requested page and size.
+// Pages start at 1; a size below 1 falls back to DefaultSize and one over
+// MaxSize is clamped to it.
+func (c *PaginationConfig) Normalize(page, size int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = c.DefaultSize
+	}
+	if size < 1 {
+		size = fallbackPageSize
+	}
+	if c.MaxSize > 0 && size > c.MaxSize {
+		size = c.MaxSize
+	}
+	return page, size
+}
+
+// defaultRouteLimits are the limits of the built-in rate limited routes
+var defaultRouteLimits = map[string]RouteLimit{
+	"register":       {Rate: 3, Window: time.Hour},
+	"password_reset": {Rate: 3, Window: time.Hour},
+	"availability":   {Rate: 10, Window: time.Minute},
+	"phone_code":     {Rate: 3, Window: 10 * time.Minute},
+}
+
+// RouteLimit returns the limit of route, falling back to the built-in default.
+// It reports false when the route is not limited or its limit is disabled.
+func (c *RateLimitConfig) RouteLimit(route string) (RouteLimit, bool) {
+	// Viper lowercases map keys, so match routes case-insensitively
+	route = strings.ToLower(route)
+	limit, ok := defaultRouteLimits[route]
+	for key, configured := range c.Routes {
+		if strings.ToLower(key) == route {
+			limit, ok = configured, true
+			break
+		}
+	}
+	if !ok || limit.Rate <= 0 || limit.Window <= 0 {
+		return RouteLimit{}, false
+	}
+	return limit, true
+}
+
+// Limit returns the body size limit of the named route group
+func (c *BodyLimitsConfig) Limit(group string) int64 {
+	if limit, ok := c.Groups[group]; ok {
+		return limit
+	}
+	return c.Default
+}
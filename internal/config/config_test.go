@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginationConfig_Normalize(t *testing.T) {
+	cfg := PaginationConfig{DefaultSize: 20, MaxSize: 50}
+
+	tests := []struct {
+		name         string
+		cfg          PaginationConfig
+		page, size   int
+		expectedPage int
+		expectedSize int
+	}{
+		{name: "within bounds", cfg: cfg, page: 2, size: 30, expectedPage: 2, expectedSize: 30},
+		{name: "zero size falls back to the default", cfg: cfg, page: 1, size: 0, expectedPage: 1, expectedSize: 20},
+		{name: "negative size falls back to the default", cfg: cfg, page: 1, size: -5, expectedPage: 1, expectedSize: 20},
+		{name: "over max size is clamped", cfg: cfg, page: 1, size: 500, expectedPage: 1, expectedSize: 50},
+		{name: "page below 1 starts at 1", cfg: cfg, page: 0, size: 10, expectedPage: 1, expectedSize: 10},
+		{name: "unconfigured", cfg: PaginationConfig{}, page: 1, size: 0, expectedPage: 1, expectedSize: fallbackPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, size := tt.cfg.Normalize(tt.page, tt.size)
+			assert.Equal(t, tt.expectedPage, page)
+			assert.Equal(t, tt.expectedSize, size)
+		})
+	}
+}
+
+func TestRateLimitConfig_RouteLimit(t *testing.T) {
+	cfg := RateLimitConfig{Routes: map[string]RouteLimit{
+		"register":                  {Rate: 10, Window: time.Minute},
+		"phone_code":                {Rate: 0, Window: time.Minute},
+		"/api/v1/users/me/password": {Rate: 5, Window: time.Hour},
+	}}
+
+	limit, ok := cfg.RouteLimit("register")
+	assert.True(t, ok)
+	assert.Equal(t, RouteLimit{Rate: 10, Window: time.Minute}, limit)
+
+	// Unconfigured built-in routes use their defaults
+	limit, ok = cfg.RouteLimit("password_reset")
+	assert.True(t, ok)
+	assert.Equal(t, RouteLimit{Rate: 3, Window: time.Hour}, limit)
+
+	limit, ok = cfg.RouteLimit("/API/v1/users/me/password")
+	assert.True(t, ok)
+	assert.Equal(t, RouteLimit{Rate: 5, Window: time.Hour}, limit)
+
+	_, ok = cfg.RouteLimit("phone_code")
+	assert.False(t, ok)
+	_, ok = cfg.RouteLimit("/api/v1/users/me")
+	assert.False(t, ok)
+}
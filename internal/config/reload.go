@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Watch watches the configuration file read by Load and calls onChange with
+// the reloaded configuration, or the error that prevented loading it, each
+// time the file changes. It reports false when no configuration file was
+// read, in which case there is nothing to watch.
+func Watch(onChange func(*Config, error)) bool {
+	if viper.ConfigFileUsed() == "" {
+		return false
+	}
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		var config Config
+		if err := viper.Unmarshal(&config); err != nil {
+			onChange(nil, fmt.Errorf("error unmarshaling config: %w", err))
+			return
+		}
+		if err := config.resolveSecrets(); err != nil {
+			onChange(nil, err)
+			return
+		}
+		onChange(&config, nil)
+	})
+	viper.WatchConfig()
+	return true
+}
+
+// NonReloadableChanges returns the keys of the sections of updated that differ
+// from current in settings that only take effect on restart. logging.level,
+// rate_limit.enabled, rate_limit.rate, rate_limit.burst and cors are applied
+// at runtime and are not reported.
+func NonReloadableChanges(current, updated *Config) []string {
+	rest := *updated
+	rest.Logging.Level = current.Logging.Level
+	rest.RateLimit.Enabled = current.RateLimit.Enabled
+	rest.RateLimit.Rate = current.RateLimit.Rate
+	rest.RateLimit.Burst = current.RateLimit.Burst
+	rest.CORS = current.CORS
+
+	var changed []string
+	before, after := reflect.ValueOf(*current), reflect.ValueOf(rest)
+	for i := 0; i < before.NumField(); i++ {
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			changed = append(changed, before.Type().Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return changed
+}
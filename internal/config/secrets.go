@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefixes of secret references. A sensitive setting of the form
+// "env:VAR_NAME" is read from the environment variable VAR_NAME and one of the
+// form "file:/path/to/secret" from that file, so the secret itself need not
+// be written into the config file. Any other value is used as is.
+const (
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+)
+
+// resolveSecrets replaces secret references in the sensitive settings with
+// the secrets they refer to
+func (c *Config) resolveSecrets() error {
+	secrets := []struct {
+		key   string
+		value *string
+	}{
+		{"database.postgres.password", &c.Database.Postgres.Password},
+		{"redis.password", &c.Redis.Password},
+		{"task.redis.password", &c.Task.Redis.Password},
+		{"jwt.secret", &c.JWT.Secret},
+		{"smtp.password", &c.SMTP.Password},
+		{"notifications.email.ses.secret_access_key", &c.Notifications.Email.SES.SecretAccessKey},
+		{"notifications.email.ses.session_token", &c.Notifications.Email.SES.SessionToken},
+		{"notifications.sms.twilio.auth_token", &c.Notifications.SMS.Twilio.AuthToken},
+		{"notifications.webhook.secret", &c.Notifications.Webhook.Secret},
+	}
+
+	for _, secret := range secrets {
+		value, err := resolveSecret(*secret.value)
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %w", secret.key, err)
+		}
+		*secret.value = value
+	}
+	return nil
+}
+
+// resolveSecret returns the secret value refers to, or value itself when it
+// is not a reference. A trailing newline in a secret file is dropped.
+func resolveSecret(value string) (string, error) {
+	if name, ok := strings.CutPrefix(value, secretEnvPrefix); ok {
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	}
+
+	if path, ok := strings.CutPrefix(value, secretFilePrefix); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	return value, nil
+}
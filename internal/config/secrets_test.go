@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("USERCENTER_TEST_DB_PASSWORD", "db-secret")
+	secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("jwt-secret\n"), 0o600))
+
+	cfg := &Config{
+		Database: DatabaseConfig{Postgres: PostgreSQLConfig{Password: "env:USERCENTER_TEST_DB_PASSWORD"}},
+		Redis:    RedisConfig{Password: "plain-redis-password"},
+		JWT:      JWTConfig{Secret: "file:" + secretFile},
+	}
+	require.NoError(t, cfg.resolveSecrets())
+
+	assert.Equal(t, "db-secret", cfg.Database.Postgres.Password)
+	assert.Equal(t, "plain-redis-password", cfg.Redis.Password)
+	assert.Equal(t, "jwt-secret", cfg.JWT.Secret)
+}
+
+func TestResolveSecrets_MissingSource(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{
+			name: "missing env var",
+			cfg:  &Config{Redis: RedisConfig{Password: "env:USERCENTER_TEST_UNSET"}},
+			want: "redis.password",
+		},
+		{
+			name: "missing file",
+			cfg:  &Config{JWT: JWTConfig{Secret: "file:" + filepath.Join(t.TempDir(), "missing")}},
+			want: "jwt.secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.resolveSecrets()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"go.uber.org/zap"
+)
+
+// MigrationsTable records the applied migrations. It is the default table of
+// goose, so migrations applied by the goose CLI are known here and vice versa.
+const MigrationsTable = goose.DefaultTablename
+
+// Migrator applies and rolls back the goose migrations of a database. Every
+// run holds a Postgres advisory lock, so instances migrating at the same
+// time take turns instead of applying the same migration twice.
+type Migrator struct {
+	provider *goose.Provider
+	logger   *zap.Logger
+}
+
+// NewMigrator creates a migrator for the goose SQL migrations in the root of
+// fsys, e.g. 001_create_users.sql
+func NewMigrator(db *sql.DB, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration lock: %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(locker),
+		goose.WithDisableGlobalRegistry(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return &Migrator{provider: provider, logger: logger}, nil
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied. Each migration runs in its own transaction unless it is
+// marked "-- +goose NO TRANSACTION", so a failure leaves the migrations
+// before it applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	results, err := m.provider.Up(ctx)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+	m.logResults(results, "Applied migration")
+	return len(results), err
+}
+
+// Down rolls back the latest steps applied migrations and returns how many
+// were rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	for count < steps {
+		result, err := m.provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		m.logResults([]*goose.MigrationResult{result}, "Rolled back migration")
+		count++
+	}
+	return count, nil
+}
+
+// Reset rolls back every applied migration
+func (m *Migrator) Reset(ctx context.Context) (int, error) {
+	results, err := m.provider.DownTo(ctx, 0)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+	m.logResults(results, "Rolled back migration")
+	return len(results), err
+}
+
+// Status reports every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// logResults logs the migrations of results with message
+func (m *Migrator) logResults(results []*goose.MigrationResult, message string) {
+	for _, result := range results {
+		m.logger.Info(message,
+			zap.String("migration", MigrationName(result.Source)),
+			zap.Duration("duration", result.Duration),
+		)
+	}
+}
+
+// MigrationName returns the name of the migration file of source without
+// its extension, e.g. 001_create_users_table
+func MigrationName(source *goose.Source) string {
+	name := path.Base(source.Path)
+	return name[:len(name)-len(path.Ext(name))]
+}
@@ -0,0 +1,126 @@
+//go:build integration
+
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+)
+
+func TestMigrator_UpAndReset(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	sqlDB, err := testDB.DB.DB()
+	require.NoError(t, err)
+	migrator, err := testutils.NewMigrator(sqlDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// SetupTestDB applied every migration already
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Equal(t, goose.StateApplied, status.State, status.Source.Path)
+	}
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+
+	rolledBack, err := migrator.Reset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(statuses), rolledBack)
+
+	// Only the version table is left behind
+	var tables []string
+	require.NoError(t, testDB.DB.Raw(
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'",
+	).Scan(&tables).Error)
+	assert.Equal(t, []string{database.MigrationsTable}, tables)
+
+	var leftovers int64
+	require.NoError(t, testDB.DB.Raw(`
+		SELECT (SELECT COUNT(*) FROM pg_indexes WHERE schemaname = 'public' AND tablename <> ?) +
+		       (SELECT COUNT(*) FROM information_schema.routines WHERE routine_schema = 'public')`,
+		database.MigrationsTable,
+	).Scan(&leftovers).Error)
+	assert.Zero(t, leftovers)
+
+	// The schema can be rebuilt from scratch
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(statuses), applied)
+}
+
+func TestMigrator_Down(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	sqlDB, err := testDB.DB.DB()
+	require.NoError(t, err)
+	migrator, err := testutils.NewMigrator(sqlDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, goose.StatePending, last.State, last.Source.Path)
+	assert.Equal(t, goose.StateApplied, statuses[len(statuses)-2].State)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	sqlDB, err := testDB.DB.DB()
+	require.NoError(t, err)
+	migrator, err := testutils.NewMigrator(sqlDB)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	total, err := migrator.Reset(ctx)
+	require.NoError(t, err)
+
+	// Instances starting together take turns, so each migration is applied once
+	const instances = 4
+	applied := make([]int, instances)
+	errs := make([]error, instances)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	sum := 0
+	for i := 0; i < instances; i++ {
+		require.NoError(t, errs[i])
+		sum += applied[i]
+	}
+	assert.Equal(t, total, sum)
+
+	var rows int64
+	require.NoError(t, testDB.DB.Raw(
+		"SELECT COUNT(*) FROM "+database.MigrationsTable+" WHERE version_id > 0",
+	).Scan(&rows).Error)
+	assert.Equal(t, int64(total), rows)
+}
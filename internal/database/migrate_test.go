@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/migrations"
+	"go.uber.org/zap"
+)
+
+func TestNewMigrator_Repository(t *testing.T) {
+	// Migrations are collected without connecting
+	db, err := sql.Open("pgx", "host=localhost")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db, migrations.FS, zap.NewNop())
+	require.NoError(t, err)
+
+	sources := migrator.provider.ListSources()
+	require.NotEmpty(t, sources)
+	assert.Equal(t, "001_create_users_table", MigrationName(sources[0]))
+	for i, source := range sources {
+		assert.Equal(t, int64(i+1), source.Version, source.Path)
+
+		content, err := fs.ReadFile(migrations.FS, source.Path)
+		require.NoError(t, err)
+		_, down, found := strings.Cut(string(content), "-- +goose Down")
+		assert.True(t, found && strings.TrimSpace(down) != "", "%s cannot be rolled back", source.Path)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/zhwjimmy/user-center/internal/config"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
@@ -37,16 +38,22 @@ func NewMongoDB(cfg *config.Config, logger *zap.Logger) (*MongoDB, error) {
 	}
 
 	database := client.Database(cfg.Database.MongoDB.Database)
+	mongoDB := &MongoDB{
+		Client:   client,
+		Database: database,
+	}
+
+	// Queries still work without the indexes, only slower
+	if err := mongoDB.EnsureAuditLogIndexes(ctx); err != nil {
+		logger.Warn("Failed to create MongoDB indexes", zap.Error(err))
+	}
 
 	logger.Info("MongoDB connected successfully",
 		zap.String("uri", cfg.Database.MongoDB.URI),
 		zap.String("database", cfg.Database.MongoDB.Database),
 	)
 
-	return &MongoDB{
-		Client:   client,
-		Database: database,
-	}, nil
+	return mongoDB, nil
 }
 
 // Close closes the MongoDB connection
@@ -64,6 +71,114 @@ func (m *MongoDB) Collection(name string) *mongo.Collection {
 	return m.Database.Collection(name)
 }
 
+// UserSessionsCollection is the collection user sessions are stored in
+const UserSessionsCollection = "user_sessions"
+
+// DeleteExpiredSessions deletes sessions that expired before now and returns how many were removed
+func (m *MongoDB) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result, err := m.Collection(UserSessionsCollection).DeleteMany(ctx, bson.M{
+		"expires_at": bson.M{"$lt": now},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// InsertSession stores a session
+func (m *MongoDB) InsertSession(ctx context.Context, session *UserSession) error {
+	if _, err := m.Collection(UserSessionsCollection).InsertOne(ctx, session); err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+// ListActiveSessions returns the sessions of a user that are active and
+// unexpired at now, newest first
+func (m *MongoDB) ListActiveSessions(ctx context.Context, userID string, now time.Time) ([]*UserSession, error) {
+	cursor, err := m.Collection(UserSessionsCollection).Find(ctx, bson.M{
+		"user_id":    userID,
+		"is_active":  true,
+		"expires_at": bson.M{"$gt": now},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := []*UserSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeactivateSessions marks the sessions of a user with the given IDs inactive
+func (m *MongoDB) DeactivateSessions(ctx context.Context, userID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := m.Collection(UserSessionsCollection).UpdateMany(ctx,
+		bson.M{"user_id": userID, "_id": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{"is_active": false}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserData deletes the sessions and application log entries of a user
+// and returns how many were removed. Audit log entries are kept.
+func (m *MongoDB) DeleteUserData(ctx context.Context, userID string) (int64, error) {
+	var deleted int64
+	for _, name := range []string{UserSessionsCollection, LogsCollection} {
+		result, err := m.Collection(name).DeleteMany(ctx, bson.M{"user_id": userID})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete user data from %s: %w", name, err)
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
+
+// AuditLogsCollection is the collection audit log entries are stored in
+const AuditLogsCollection = "audit_logs"
+
+// InsertAuditLog stores an audit log entry
+func (m *MongoDB) InsertAuditLog(ctx context.Context, entry *AuditLog) error {
+	if _, err := m.Collection(AuditLogsCollection).InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to insert audit log: %w", err)
+	}
+	return nil
+}
+
+// EnsureAuditLogIndexes creates the indexes audit log queries rely on. Every
+// query sorts newest first, so each filterable field is indexed together with
+// the timestamp.
+func (m *MongoDB) EnsureAuditLogIndexes(ctx context.Context) error {
+	models := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "resource", Value: 1}, {Key: "timestamp", Value: -1}}},
+	}
+	if _, err := m.Collection(AuditLogsCollection).Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create audit log indexes: %w", err)
+	}
+	return nil
+}
+
+// LogsCollection is the collection application log entries are stored in
+const LogsCollection = "logs"
+
+// InsertLogEntry stores an application log entry
+func (m *MongoDB) InsertLogEntry(ctx context.Context, entry *LogEntry) error {
+	if _, err := m.Collection(LogsCollection).InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to insert log entry: %w", err)
+	}
+	return nil
+}
+
 // LogEntry represents a log entry in MongoDB
 type LogEntry struct {
 	ID        string                 `bson:"_id,omitempty"`
@@ -71,15 +186,15 @@ type LogEntry struct {
 	Message   string                 `bson:"message"`
 	Timestamp time.Time              `bson:"timestamp"`
 	RequestID string                 `bson:"request_id,omitempty"`
-	UserID    uint                   `bson:"user_id,omitempty"`
+	UserID    string                 `bson:"user_id,omitempty"`
 	Fields    map[string]interface{} `bson:"fields,omitempty"`
 }
 
-// UserSession represents a user session in MongoDB
+// UserSession represents a user session in MongoDB. Its ID is a hash of the
+// session's token; the token itself is not stored.
 type UserSession struct {
 	ID        string    `bson:"_id,omitempty"`
-	UserID    uint      `bson:"user_id"`
-	Token     string    `bson:"token"`
+	UserID    string    `bson:"user_id"`
 	IP        string    `bson:"ip"`
 	UserAgent string    `bson:"user_agent"`
 	CreatedAt time.Time `bson:"created_at"`
@@ -89,13 +204,13 @@ type UserSession struct {
 
 // AuditLog represents an audit log entry in MongoDB
 type AuditLog struct {
-	ID        string                 `bson:"_id,omitempty"`
-	UserID    uint                   `bson:"user_id,omitempty"`
-	Action    string                 `bson:"action"`
-	Resource  string                 `bson:"resource"`
-	Details   map[string]interface{} `bson:"details,omitempty"`
-	IP        string                 `bson:"ip,omitempty"`
-	UserAgent string                 `bson:"user_agent,omitempty"`
-	Timestamp time.Time              `bson:"timestamp"`
-	RequestID string                 `bson:"request_id,omitempty"`
+	ID        string                 `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    string                 `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Action    string                 `bson:"action" json:"action"`
+	Resource  string                 `bson:"resource" json:"resource"`
+	Details   map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	IP        string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string                 `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Timestamp time.Time              `bson:"timestamp" json:"timestamp"`
+	RequestID string                 `bson:"request_id,omitempty" json:"request_id,omitempty"`
 }
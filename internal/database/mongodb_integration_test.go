@@ -0,0 +1,110 @@
+//go:build integration
+
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoDB_DeleteExpiredSessions(t *testing.T) {
+	testMongo := testutils.SetupTestMongo(t)
+	defer testMongo.Cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	sessions := testMongo.DB.Collection(database.UserSessionsCollection)
+
+	_, err := sessions.InsertMany(ctx, []interface{}{
+		database.UserSession{ID: "expired-1", ExpiresAt: now.Add(-time.Hour)},
+		database.UserSession{ID: "expired-2", ExpiresAt: now.Add(-time.Minute)},
+		database.UserSession{ID: "valid", ExpiresAt: now.Add(time.Hour), IsActive: true},
+	})
+	require.NoError(t, err)
+
+	deleted, err := testMongo.DB.DeleteExpiredSessions(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	var remaining []database.UserSession
+	cursor, err := sessions.Find(ctx, bson.M{})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &remaining))
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "valid", remaining[0].ID)
+}
+
+func TestMongoDB_DeleteUserData(t *testing.T) {
+	testMongo := testutils.SetupTestMongo(t)
+	defer testMongo.Cleanup()
+
+	ctx := context.Background()
+	sessions := testMongo.DB.Collection(database.UserSessionsCollection)
+	logs := testMongo.DB.Collection(database.LogsCollection)
+
+	_, err := sessions.InsertMany(ctx, []interface{}{
+		database.UserSession{ID: "erased", UserID: "user-1"},
+		database.UserSession{ID: "kept", UserID: "user-2"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, testMongo.DB.InsertLogEntry(ctx, &database.LogEntry{ID: "erased", UserID: "user-1", Message: "login"}))
+	require.NoError(t, testMongo.DB.InsertAuditLog(ctx, &database.AuditLog{UserID: "user-1", Action: "POST /api/v1/users/login"}))
+
+	deleted, err := testMongo.DB.DeleteUserData(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	count, err := sessions.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = logs.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Audit entries are the record of the erasure and are kept
+	count, err = testMongo.DB.Collection(database.AuditLogsCollection).CountDocuments(ctx, bson.M{"user_id": "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMongoDB_ActiveSessions(t *testing.T) {
+	testMongo := testutils.SetupTestMongo(t)
+	defer testMongo.Cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, session := range []*database.UserSession{
+		{ID: "older", UserID: "user-1", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour), IsActive: true},
+		{ID: "newer", UserID: "user-1", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), IsActive: true},
+		{ID: "expired", UserID: "user-1", CreatedAt: now.Add(-3 * time.Hour), ExpiresAt: now.Add(-time.Hour), IsActive: true},
+		{ID: "signed-out", UserID: "user-1", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "other-user", UserID: "user-2", CreatedAt: now, ExpiresAt: now.Add(time.Hour), IsActive: true},
+	} {
+		require.NoError(t, testMongo.DB.InsertSession(ctx, session))
+	}
+
+	active, err := testMongo.DB.ListActiveSessions(ctx, "user-1", now)
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, "newer", active[0].ID)
+	assert.Equal(t, "older", active[1].ID)
+
+	require.NoError(t, testMongo.DB.DeactivateSessions(ctx, "user-1", []string{"newer", "other-user"}))
+
+	active, err = testMongo.DB.ListActiveSessions(ctx, "user-1", now)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "older", active[0].ID)
+
+	// Only the user's own sessions are deactivated
+	active, err = testMongo.DB.ListActiveSessions(ctx, "user-2", now)
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+}
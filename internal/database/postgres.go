@@ -1,11 +1,14 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/tracing"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -15,10 +18,68 @@ import (
 // PostgreSQL represents PostgreSQL database connection
 type PostgreSQL struct {
 	DB *gorm.DB
+	// replicas are the read replica pools queries are routed to
+	replicas []*sql.DB
 }
 
-// NewPostgreSQL creates a new PostgreSQL connection
+// NewPostgreSQL creates a new PostgreSQL connection. Outside release mode the
+// schema is brought up to date with AutoMigrate for convenience; release
+// deployments apply the versioned migrations with "usercenter migrate up".
 func NewPostgreSQL(cfg *config.Config, zapLogger *zap.Logger) (*PostgreSQL, error) {
+	pg, err := OpenPostgreSQL(cfg, zapLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Server.Mode == "release" {
+		zapLogger.Info("Skipping AutoMigrate in release mode; apply migrations with \"usercenter migrate up\"")
+		return pg, nil
+	}
+
+	if err := AutoMigrate(pg.DB); err != nil {
+		pg.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return pg, nil
+}
+
+// autoMigrateExtras are the parts of the versioned migrations AutoMigrate
+// cannot express: the generated full-text search column with its index, and
+// the foreign key that removes role assignments with their user
+var autoMigrateExtras = []string{
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(username, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(email, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'user_roles_user_id_fkey') THEN
+			DELETE FROM user_roles WHERE user_id NOT IN (SELECT id FROM users);
+			ALTER TABLE user_roles ADD CONSTRAINT user_roles_user_id_fkey
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+		END IF;
+	END $$`,
+}
+
+// AutoMigrate brings the schema up to date from the models, for development
+// and tests. The result matches what the versioned migrations create.
+func AutoMigrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.User{}, &model.OutboxEvent{}, &model.Role{}, &model.UserRole{}, &model.WebhookSubscription{}); err != nil {
+		return err
+	}
+	for _, statement := range autoMigrateExtras {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenPostgreSQL connects to PostgreSQL without touching the schema
+func OpenPostgreSQL(cfg *config.Config, zapLogger *zap.Logger) (*PostgreSQL, error) {
 	dsn := cfg.Database.Postgres.GetDSN()
 
 	// Configure GORM logger
@@ -35,11 +96,18 @@ func NewPostgreSQL(cfg *config.Config, zapLogger *zap.Logger) (*PostgreSQL, erro
 	// Open database connection
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
+		// Map driver errors such as unique violations to gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
+	// Trace queries as children of the request span
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
 	// Get underlying sql.DB for connection pool configuration
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -56,36 +124,84 @@ func NewPostgreSQL(cfg *config.Config, zapLogger *zap.Logger) (*PostgreSQL, erro
 		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
 	}
 
-	// Auto migrate models
-	if err := db.AutoMigrate(&model.User{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	pg := &PostgreSQL{DB: db}
+
+	// Route reads to replicas, if any
+	if len(cfg.Database.Postgres.Replicas) > 0 {
+		pools := make([]gorm.ConnPool, 0, len(cfg.Database.Postgres.Replicas))
+		for i, replicaDSN := range cfg.Database.Postgres.Replicas {
+			replica, err := openReplica(replicaDSN, &cfg.Database.Postgres)
+			if err != nil {
+				pg.Close()
+				return nil, fmt.Errorf("failed to connect to PostgreSQL replica %d: %w", i, err)
+			}
+			pg.replicas = append(pg.replicas, replica)
+			pools = append(pools, replica)
+		}
+
+		if err := UseReplicas(db, pools...); err != nil {
+			pg.Close()
+			return nil, fmt.Errorf("failed to register read replicas: %w", err)
+		}
 	}
 
 	zapLogger.Info("PostgreSQL connected successfully",
 		zap.String("host", cfg.Database.Postgres.Host),
 		zap.Int("port", cfg.Database.Postgres.Port),
 		zap.String("database", cfg.Database.Postgres.DBName),
+		zap.Int("replicas", len(pg.replicas)),
 	)
 
-	return &PostgreSQL{DB: db}, nil
+	return pg, nil
+}
+
+// openReplica opens the connection pool of a read replica, sized like the
+// primary's
+func openReplica(dsn string, cfg *config.PostgreSQLConfig) (*sql.DB, error) {
+	replica, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.MaxLifetime)
+
+	return sqlDB, nil
 }
 
-// Close closes the database connection
+// Close closes the database connections
 func (p *PostgreSQL) Close() error {
 	sqlDB, err := p.DB.DB()
 	if err != nil {
 		return err
 	}
-	return sqlDB.Close()
+
+	errs := []error{sqlDB.Close()}
+	for _, replica := range p.replicas {
+		errs = append(errs, replica.Close())
+	}
+	return errors.Join(errs...)
 }
 
-// Health checks the database health
+// Health checks the health of the primary and the read replicas
 func (p *PostgreSQL) Health() error {
 	sqlDB, err := p.DB.DB()
 	if err != nil {
 		return err
 	}
-	return sqlDB.Ping()
+
+	errs := []error{sqlDB.Ping()}
+	for i, replica := range p.replicas {
+		if err := replica.Ping(); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GormZapWriter implements GORM logger interface for Zap
@@ -0,0 +1,49 @@
+//go:build integration
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+)
+
+func TestAutoMigrate_MatchesMigrations(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	// Start from an empty schema, as a development database would
+	sqlDB, err := testDB.DB.DB()
+	require.NoError(t, err)
+	migrator, err := testutils.NewMigrator(sqlDB)
+	require.NoError(t, err)
+	_, err = migrator.Reset(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, database.AutoMigrate(testDB.DB))
+	// Running it again on an up to date schema changes nothing
+	require.NoError(t, database.AutoMigrate(testDB.DB))
+
+	require.NoError(t, testDB.DB.Create(&model.Role{Name: "support"}).Error)
+	user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+	require.NoError(t, testDB.DB.Create(user).Error)
+	require.NoError(t, testDB.DB.Create(&model.UserRole{UserID: user.ID, Role: "support"}).Error)
+
+	// Full-text search has its generated column
+	var matches int64
+	require.NoError(t, testDB.DB.Model(&model.User{}).
+		Where("search_vector @@ to_tsquery('simple', ?)", "alice:*").
+		Count(&matches).Error)
+	assert.Equal(t, int64(1), matches)
+
+	// Role assignments go with their user
+	require.NoError(t, testDB.DB.Unscoped().Delete(&model.User{}, "id = ?", user.ID).Error)
+	var roles int64
+	require.NoError(t, testDB.DB.Model(&model.UserRole{}).Where("user_id = ?", user.ID).Count(&roles).Error)
+	assert.Zero(t, roles)
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type (
+	primaryKey      struct{}
+	writeTrackerKey struct{}
+)
+
+// writeTracker records whether a context has been used for a write
+type writeTracker struct {
+	wrote atomic.Bool
+}
+
+// UsePrimary returns a context whose reads go to the primary database
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// WithReadYourWrites returns a context whose reads go to the primary database
+// once it has been used for a write, so replica lag cannot hide the changes
+// of a request from the rest of that request
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writeTrackerKey{}, &writeTracker{})
+}
+
+// UseReplicas routes the reads of db to the given replica connection pools,
+// used in turn, with dbresolver. Writes, transactions and locking reads stay
+// on the primary, and so do reads whose context asks for it with UsePrimary
+// or WithReadYourWrites.
+func UseReplicas(db *gorm.DB, replicas ...gorm.ConnPool) error {
+	dialectors := make([]gorm.Dialector, len(replicas))
+	for i, replica := range replicas {
+		dialectors[i] = postgres.New(postgres.Config{Conn: replica})
+	}
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.StrictRoundRobinPolicy(),
+	})); err != nil {
+		return err
+	}
+	return db.Use(readYourWrites{})
+}
+
+// readYourWrites is a GORM plugin that sends the reads of contexts from
+// UsePrimary and WithReadYourWrites back to the primary after dbresolver has
+// picked a replica
+type readYourWrites struct{}
+
+// Name implements gorm.Plugin
+func (readYourWrites) Name() string {
+	return "usercenter:read_your_writes"
+}
+
+// Initialize implements gorm.Plugin by registering the routing callbacks
+func (readYourWrites) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("usercenter:read_primary", readPrimary); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("usercenter:read_primary", readPrimary); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Create().After("gorm:create").Register("usercenter:track_write", trackWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("usercenter:track_write", trackWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("usercenter:track_write", trackWrite); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().After("gorm:raw").Register("usercenter:track_write", trackWrite); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readPrimary points the statement back at the primary when its context asks
+// for it
+func readPrimary(db *gorm.DB) {
+	if db.Error != nil || !readsPrimary(db.Statement.Context) {
+		return
+	}
+	dbresolver.Write.ModifyStatement(db.Statement)
+}
+
+// readsPrimary reports whether reads with ctx have to see the primary
+func readsPrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return true
+	}
+	tracker, _ := ctx.Value(writeTrackerKey{}).(*writeTracker)
+	return tracker != nil && tracker.wrote.Load()
+}
+
+// trackWrite marks the statement's context as having written
+func trackWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if tracker, ok := db.Statement.Context.Value(writeTrackerKey{}).(*writeTracker); ok {
+		tracker.wrote.Store(true)
+	}
+}
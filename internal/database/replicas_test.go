@@ -0,0 +1,180 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+var errNoRows = errors.New("recording pool returns no rows")
+
+// recordingPool is a gorm.ConnPool that records the statements sent to it.
+// Writes succeed; reads fail since no rows can be faked.
+type recordingPool struct {
+	statements []string
+}
+
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errNoRows
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.statements = append(p.statements, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.statements = append(p.statements, query)
+	return nil, errNoRows
+}
+
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	p.statements = append(p.statements, query)
+	return nil
+}
+
+// newReplicatedDB returns a database whose primary and replicas record the
+// statements they receive
+func newReplicatedDB(t *testing.T, replicas int) (*gorm.DB, *recordingPool, []*recordingPool) {
+	primary := &recordingPool{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: primary}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	replicaPools := make([]*recordingPool, replicas)
+	connPools := make([]gorm.ConnPool, replicas)
+	for i := range replicaPools {
+		replicaPools[i] = &recordingPool{}
+		connPools[i] = replicaPools[i]
+	}
+	require.NoError(t, database.UseReplicas(db, connPools...))
+
+	return db, primary, replicaPools
+}
+
+func TestUseReplicas_RepositoryRouting(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(ctx context.Context, repo repository.UserRepository)
+		onReplica bool
+	}{
+		{
+			name:      "get by id",
+			call:      func(ctx context.Context, repo repository.UserRepository) { _, _ = repo.GetByID(ctx, "user-1") },
+			onReplica: true,
+		},
+		{
+			name: "list",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _, _ = repo.List(ctx, &dto.UserListRequest{Page: 1, Size: 10, Sort: "created_at", Order: "desc"})
+			},
+			onReplica: true,
+		},
+		{
+			name: "search",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _, _ = repo.Search(ctx, "alice", true, 0, 10)
+			},
+			onReplica: true,
+		},
+		{
+			name: "count",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _ = repo.ExistsByEmail(ctx, "alice@example.com")
+			},
+			onReplica: true,
+		},
+		{
+			name: "create",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _ = repo.Create(ctx, &model.User{Username: "alice", Email: "alice@example.com"})
+			},
+		},
+		{
+			name: "update",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _ = repo.Update(ctx, &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"})
+			},
+		},
+		{
+			name: "delete",
+			call: func(ctx context.Context, repo repository.UserRepository) { _ = repo.Delete(ctx, "user-1") },
+		},
+		{
+			name: "read forced to the primary",
+			call: func(ctx context.Context, repo repository.UserRepository) {
+				_, _ = repo.GetByID(database.UsePrimary(ctx), "user-1")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, primary, replicas := newReplicatedDB(t, 1)
+
+			tt.call(context.Background(), repository.NewUserRepository(db))
+
+			if tt.onReplica {
+				assert.Empty(t, primary.statements)
+				assert.NotEmpty(t, replicas[0].statements)
+			} else {
+				assert.NotEmpty(t, primary.statements)
+				assert.Empty(t, replicas[0].statements)
+			}
+		})
+	}
+}
+
+func TestUseReplicas_RoundRobin(t *testing.T) {
+	db, primary, replicas := newReplicatedDB(t, 2)
+
+	for i := 0; i < 4; i++ {
+		var users []*model.User
+		db.Find(&users)
+	}
+
+	assert.Empty(t, primary.statements)
+	assert.Len(t, replicas[0].statements, 2)
+	assert.Len(t, replicas[1].statements, 2)
+}
+
+func TestUseReplicas_LockingReadsUsePrimary(t *testing.T) {
+	db, primary, replicas := newReplicatedDB(t, 1)
+
+	var users []*model.User
+	db.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&users)
+
+	assert.Len(t, primary.statements, 1)
+	assert.Empty(t, replicas[0].statements)
+}
+
+func TestUseReplicas_ReadYourWrites(t *testing.T) {
+	db, primary, replicas := newReplicatedDB(t, 1)
+	ctx := database.WithReadYourWrites(context.Background())
+	var users []*model.User
+
+	// Reads go to the replica until the request writes
+	db.WithContext(ctx).Find(&users)
+	assert.Len(t, replicas[0].statements, 1)
+
+	require.NoError(t, db.WithContext(ctx).Exec("UPDATE users SET is_active = false").Error)
+	db.WithContext(ctx).Find(&users)
+	assert.Len(t, primary.statements, 2)
+	assert.Len(t, replicas[0].statements, 1)
+
+	// Other requests keep reading from the replica
+	db.WithContext(database.WithReadYourWrites(context.Background())).Find(&users)
+	assert.Len(t, replicas[0].statements, 2)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/database"
+)
+
+// AuditLogListRequest represents audit log query with pagination and filters.
+// From is inclusive and To is exclusive; both are RFC 3339 timestamps.
+type AuditLogListRequest struct {
+	Page     int       `form:"page" example:"1"`
+	Size     int       `form:"size" example:"10"`
+	UserID   string    `form:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Action   string    `form:"action" example:"DELETE /api/v1/admin/users/:id"`
+	Resource string    `form:"resource" example:"/api/v1/admin/users/550e8400-e29b-41d4-a716-446655440000"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-02-01T00:00:00Z"`
+}
+
+// AuditLogListResponse represents a page of audit log entries, newest first
+type AuditLogListResponse struct {
+	AuditLogs  []*database.AuditLog `json:"audit_logs"`
+	Pagination *PaginationResponse  `json:"pagination"`
+	Message    string               `json:"message"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+// BulkItemResult reports the outcome of a single item in a bulk operation
+type BulkItemResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkSummary counts the outcomes of a bulk operation
+type BulkSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// BulkResult represents the per-item report of a bulk operation
+type BulkResult struct {
+	Summary BulkSummary      `json:"summary"`
+	Items   []BulkItemResult `json:"items"`
+}
+
+// AddSuccess records a successful item
+func (r *BulkResult) AddSuccess(index int, id string, status int) {
+	r.Items = append(r.Items, BulkItemResult{Index: index, ID: id, Status: status})
+	r.Summary.Total++
+	r.Summary.Succeeded++
+}
+
+// AddFailure records a failed item
+func (r *BulkResult) AddFailure(index int, status int, message string) {
+	r.Items = append(r.Items, BulkItemResult{Index: index, Status: status, Error: message})
+	r.Summary.Total++
+	r.Summary.Failed++
+}
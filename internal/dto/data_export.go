@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/model"
+)
+
+// DataExport is the bundle of a user's personal data returned by the
+// self-service data export. It leaves out credentials and internal
+// identifiers such as audit entry and request IDs.
+type DataExport struct {
+	ExportedAt              time.Time                     `json:"exported_at"`
+	Profile                 *model.PublicUser             `json:"profile"`
+	Roles                   []string                      `json:"roles"`
+	NotificationPreferences model.NotificationPreferences `json:"notification_preferences"`
+	SMSNotifications        bool                          `json:"sms_notifications"`
+	Sessions                []DataExportSession           `json:"sessions"`
+	ActivityHistory         []DataExportActivity          `json:"activity_history"`
+}
+
+// DataExportSession describes one of the user's active sessions
+type DataExportSession struct {
+	StartedAt time.Time `json:"started_at"`
+}
+
+// DataExportActivity is one audited request made by the user
+type DataExportActivity struct {
+	Action    string      `json:"action"`
+	Resource  string      `json:"resource"`
+	Status    interface{} `json:"status,omitempty"`
+	IP        string      `json:"ip,omitempty"`
+	UserAgent string      `json:"user_agent,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+// PaginationResponse represents pagination information. OutOfRange is set
+// when the requested page lies past the last one, so the empty page is not
+// mistaken for the end of the results.
+type PaginationResponse struct {
+	Page       int   `json:"page"`
+	Size       int   `json:"size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+	OutOfRange bool  `json:"out_of_range,omitempty"`
+}
+
+// NewPagination returns the pagination information of page, holding up to
+// size items, out of total items. Without results there are no pages, yet
+// page 1 is still in range. Past the last page HasPrev points back at the
+// results and HasNext is false.
+func NewPagination(page, size int, total int64) *PaginationResponse {
+	totalPages := 0
+	if size > 0 {
+		totalPages = int((total + int64(size) - 1) / int64(size))
+	}
+
+	return &PaginationResponse{
+		Page:       page,
+		Size:       size,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1 && totalPages > 0,
+		OutOfRange: page > 1 && page > totalPages,
+	}
+}
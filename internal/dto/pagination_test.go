@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     int
+		size     int
+		total    int64
+		expected PaginationResponse
+	}{
+		{
+			name:     "no results",
+			page:     1,
+			size:     10,
+			total:    0,
+			expected: PaginationResponse{Page: 1, Size: 10},
+		},
+		{
+			name:     "no results past the first page",
+			page:     2,
+			size:     10,
+			total:    0,
+			expected: PaginationResponse{Page: 2, Size: 10, OutOfRange: true},
+		},
+		{
+			name:     "single partial page",
+			page:     1,
+			size:     10,
+			total:    3,
+			expected: PaginationResponse{Page: 1, Size: 10, Total: 3, TotalPages: 1},
+		},
+		{
+			name:     "first of several pages",
+			page:     1,
+			size:     10,
+			total:    25,
+			expected: PaginationResponse{Page: 1, Size: 10, Total: 25, TotalPages: 3, HasNext: true},
+		},
+		{
+			name:     "middle page",
+			page:     2,
+			size:     10,
+			total:    25,
+			expected: PaginationResponse{Page: 2, Size: 10, Total: 25, TotalPages: 3, HasNext: true, HasPrev: true},
+		},
+		{
+			name:     "partial last page",
+			page:     3,
+			size:     10,
+			total:    25,
+			expected: PaginationResponse{Page: 3, Size: 10, Total: 25, TotalPages: 3, HasPrev: true},
+		},
+		{
+			name:     "exact multiple last page",
+			page:     3,
+			size:     10,
+			total:    30,
+			expected: PaginationResponse{Page: 3, Size: 10, Total: 30, TotalPages: 3, HasPrev: true},
+		},
+		{
+			name:     "exact multiple past the last page",
+			page:     4,
+			size:     10,
+			total:    30,
+			expected: PaginationResponse{Page: 4, Size: 10, Total: 30, TotalPages: 3, HasPrev: true, OutOfRange: true},
+		},
+		{
+			name:     "far past the last page",
+			page:     50,
+			size:     10,
+			total:    25,
+			expected: PaginationResponse{Page: 50, Size: 10, Total: 25, TotalPages: 3, HasPrev: true, OutOfRange: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, &tt.expected, NewPagination(tt.page, tt.size, tt.total))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+// SessionResponse describes one of a user's active sessions
+type SessionResponse struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionListResponse represents a user's active sessions, newest first
+type SessionListResponse struct {
+	UserID   string            `json:"user_id"`
+	Sessions []SessionResponse `json:"sessions"`
+	Message  string            `json:"message"`
+}
+
+// RevokeSessionsResponse represents the outcome of signing out a user
+type RevokeSessionsResponse struct {
+	UserID  string `json:"user_id"`
+	Revoked int    `json:"revoked"`
+	Message string `json:"message"`
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/zhwjimmy/user-center/internal/model"
+import (
+	"encoding/json"
+
+	"github.com/zhwjimmy/user-center/internal/model"
+)
 
 // RegisterRequest represents user registration request
 type RegisterRequest struct {
@@ -20,6 +24,7 @@ type LoginRequest struct {
 
 // UpdateUserRequest represents user update request
 type UpdateUserRequest struct {
+	Username  *string `json:"username,omitempty" binding:"omitempty,min=3,max=50" example:"newusername"`
 	FirstName *string `json:"first_name,omitempty" binding:"omitempty,max=50" example:"John"`
 	LastName  *string `json:"last_name,omitempty" binding:"omitempty,max=50" example:"Doe"`
 	Avatar    *string `json:"avatar,omitempty" binding:"omitempty,max=255" example:"https://example.com/avatar.jpg"`
@@ -32,10 +37,61 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required,min=8,max=50" example:"newpassword123"`
 }
 
+// DeleteAccountRequest represents a self-service account deletion request.
+// The current password confirms the deletion.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required" example:"password123"`
+}
+
+// BulkCreateUserRequest describes one user to create in a bulk request. The
+// user is emailed a one-time link to choose their password, unless a password
+// hash from another system is given with its algorithm; it is then stored as is.
+type BulkCreateUserRequest struct {
+	Username          string  `json:"username" binding:"required,min=3,max=50" example:"testuser"`
+	Email             string  `json:"email" binding:"required,email,max=100" example:"test@example.com"`
+	FirstName         *string `json:"first_name,omitempty" binding:"omitempty,max=50" example:"John"`
+	LastName          *string `json:"last_name,omitempty" binding:"omitempty,max=50" example:"Doe"`
+	Phone             *string `json:"phone,omitempty" binding:"omitempty,max=20" example:"+1234567890"`
+	PasswordHash      string  `json:"password_hash,omitempty" binding:"required_with=PasswordAlgorithm,max=255" example:"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"`
+	PasswordAlgorithm string  `json:"password_algorithm,omitempty" binding:"required_with=PasswordHash,omitempty,oneof=bcrypt argon2id" example:"bcrypt"`
+}
+
+// SetupPasswordRequest sets the password of a user created in bulk with the
+// one-time token of the link they were emailed
+type SetupPasswordRequest struct {
+	Token    string `json:"token" binding:"required,max=100" example:"q3Jr0x8c5b2Vh7tKz1WmYp4dNfLs9aEuGiHoJkRnT6U"`
+	Password string `json:"password" binding:"required,min=8,max=50" example:"securepassword123"`
+}
+
+// BulkCreateUsersRequest represents an admin bulk user creation request.
+// Rows are validated individually so one bad row does not reject the batch.
+type BulkCreateUsersRequest struct {
+	Users []BulkCreateUserRequest `json:"users" binding:"required,min=1,max=100"`
+}
+
+// BatchGetUsersRequest represents a lookup of several users by ID
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100,dive,required" example:"8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c"`
+}
+
+// UpdateNotificationPreferencesRequest represents a notification preferences update.
+// Only the listed categories change; security mail cannot be disabled. SMS
+// opts in or out of also receiving notifications on the verified phone and
+// is left unchanged when omitted.
+type UpdateNotificationPreferencesRequest struct {
+	Preferences model.NotificationPreferences `json:"preferences" swaggertype:"object,boolean" example:"marketing:false"`
+	SMS         *bool                         `json:"sms,omitempty" example:"true"`
+}
+
+// UpdateUserStatusRequest represents admin user status update request
+type UpdateUserStatusRequest struct {
+	Status model.UserStatus `json:"status" binding:"required" example:"suspended"`
+}
+
 // UserListRequest represents user list request with pagination and filters
 type UserListRequest struct {
-	Page     int              `form:"page,default=1" binding:"min=1" example:"1"`
-	Size     int              `form:"size,default=10" binding:"min=1,max=100" example:"10"`
+	Page     int              `form:"page" example:"1"`
+	Size     int              `form:"size" example:"10"`
 	Sort     string           `form:"sort,default=created_at" example:"created_at"`
 	Order    string           `form:"order,default=desc" binding:"oneof=asc desc" example:"desc"`
 	Search   string           `form:"search" example:"john"`
@@ -43,11 +99,37 @@ type UserListRequest struct {
 	IsActive *bool            `form:"is_active" example:"true"`
 }
 
-// RegisterResponse represents user registration response
+// UserSearchRequest represents a paginated user search
+type UserSearchRequest struct {
+	Query string `form:"q" binding:"required,max=100" example:"john"`
+	Page  int    `form:"page" example:"1"`
+	Size  int    `form:"size" example:"10"`
+}
+
+// VerifyPhoneRequest carries the code texted to verify the user's phone number
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required,numeric,max=10" example:"123456"`
+}
+
+// CheckAvailabilityRequest asks whether a username or an email, exactly one
+// of them, is free to register. Both follow the registration rules.
+type CheckAvailabilityRequest struct {
+	Username string `form:"username" binding:"omitempty,min=3,max=50" example:"testuser"`
+	Email    string `form:"email" binding:"omitempty,email,max=100" example:"test@example.com"`
+}
+
+// CheckAvailabilityResponse reports whether a username or email is free to register
+type CheckAvailabilityResponse struct {
+	Available bool `json:"available" example:"true"`
+}
+
+// RegisterResponse represents user registration response. Warnings lists
+// best-effort steps that failed without failing the registration.
 type RegisterResponse struct {
-	User    *model.PublicUser `json:"user"`
-	Token   string            `json:"token"`
-	Message string            `json:"message"`
+	User     *model.PublicUser `json:"user"`
+	Token    string            `json:"token"`
+	Message  string            `json:"message"`
+	Warnings []string          `json:"warnings,omitempty" example:"Welcome email could not be sent"`
 }
 
 // LoginResponse represents user login response
@@ -63,6 +145,47 @@ type UserResponse struct {
 	Message string            `json:"message"`
 }
 
+// UpdateUserResponse represents user update response. Token is a new token
+// carrying the new username, set only when the username changed.
+type UpdateUserResponse struct {
+	User    *model.PublicUser `json:"user"`
+	Token   string            `json:"token,omitempty"`
+	Message string            `json:"message"`
+}
+
+// PartialUserResponse represents a single user restricted to the fields requested with ?fields=
+type PartialUserResponse struct {
+	User    map[string]json.RawMessage `json:"user" swaggertype:"object"`
+	Message string                     `json:"message"`
+}
+
+// NotificationPreferencesResponse represents the effective notification preferences
+type NotificationPreferencesResponse struct {
+	Preferences model.NotificationPreferences `json:"preferences" swaggertype:"object,boolean"`
+	SMS         bool                          `json:"sms"`
+	Message     string                        `json:"message"`
+}
+
+// DeleteUserRequest represents the options of an admin user deletion. A hard
+// delete is permanent and must be confirmed by repeating the user ID.
+type DeleteUserRequest struct {
+	Hard    bool   `form:"hard" example:"false"`
+	Confirm string `form:"confirm" example:"8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c"`
+}
+
+// LoginActivityRequest represents a page of the current user's login activity
+type LoginActivityRequest struct {
+	Page int `form:"page" example:"1"`
+	Size int `form:"size" example:"10"`
+}
+
+// LoginActivityResponse represents a page of the current user's recent logins, newest first
+type LoginActivityResponse struct {
+	Logins     []model.LoginActivity `json:"logins"`
+	Pagination *PaginationResponse   `json:"pagination"`
+	Message    string                `json:"message"`
+}
+
 // UserListResponse represents user list response
 type UserListResponse struct {
 	Users      []*model.PublicUser `json:"users"`
@@ -70,21 +193,68 @@ type UserListResponse struct {
 	Message    string              `json:"message"`
 }
 
-// PaginationResponse represents pagination information
-type PaginationResponse struct {
-	Page       int   `json:"page"`
-	Size       int   `json:"size"`
-	Total      int64 `json:"total"`
-	TotalPages int   `json:"total_pages"`
-	HasNext    bool  `json:"has_next"`
-	HasPrev    bool  `json:"has_prev"`
+// PartialUserListResponse represents a user list restricted to the fields requested with ?fields=
+type PartialUserListResponse struct {
+	Users      []map[string]json.RawMessage `json:"users" swaggertype:"array,object"`
+	Pagination *PaginationResponse          `json:"pagination"`
+	Message    string                       `json:"message"`
+}
+
+// PartialBatchUsersResponse represents the users found by a batch lookup,
+// restricted to the fields the caller may see and requested with ?fields=.
+// Missing lists the requested IDs that matched no user.
+type PartialBatchUsersResponse struct {
+	Users   []map[string]json.RawMessage `json:"users" swaggertype:"array,object"`
+	Missing []string                     `json:"missing"`
+	Message string                       `json:"message"`
+}
+
+// Bulk creation row results
+const (
+	BulkResultCreated = "created"
+	BulkResultFailed  = "failed"
+)
+
+// BulkCreateResult reports the outcome of one row of a bulk creation request
+type BulkCreateResult struct {
+	Index    int               `json:"index" example:"0"`
+	Status   string            `json:"status" example:"created"`
+	User     *model.PublicUser `json:"user,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Fields   []FieldError      `json:"fields,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// BulkCreateUsersResponse represents the per-row results of a bulk creation request
+type BulkCreateUsersResponse struct {
+	Results []BulkCreateResult `json:"results"`
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Message string             `json:"message"`
 }
 
 // ErrorResponse represents error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    string `json:"code,omitempty"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Code      string `json:"code,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError describes a single request field that failed validation
+type FieldError struct {
+	Field   string `json:"field" example:"password"`
+	Rule    string `json:"rule" example:"min"`
+	Message string `json:"message" example:"password must be at least 8 characters long"`
+}
+
+// ValidationErrorResponse represents a request validation failure with per-field details
+type ValidationErrorResponse struct {
+	Error     string       `json:"error"`
+	Message   string       `json:"message"`
+	Code      string       `json:"code,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
 }
 
 // SuccessResponse represents success response
@@ -95,8 +265,27 @@ type SuccessResponse struct {
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status    string            `json:"status"`
-	Version   string            `json:"version"`
-	Timestamp string            `json:"timestamp"`
-	Checks    map[string]string `json:"checks"`
+	Status    string                 `json:"status"`
+	Version   string                 `json:"version"`
+	Timestamp string                 `json:"timestamp"`
+	Checks    map[string]CheckDetail `json:"checks"`
+}
+
+// CheckDetail represents the result of checking a single dependency
+type CheckDetail struct {
+	Status    string  `json:"status" example:"healthy"`
+	LatencyMS float64 `json:"latency_ms" example:"1.25"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// AssignRoleRequest represents admin role assignment request
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"support"`
+}
+
+// UserRolesResponse represents a user's effective roles
+type UserRolesResponse struct {
+	UserID  string   `json:"user_id"`
+	Roles   []string `json:"roles" example:"admin,user"`
+	Message string   `json:"message"`
 }
@@ -0,0 +1,34 @@
+package dto
+
+import "github.com/zhwjimmy/user-center/internal/model"
+
+// CreateWebhookRequest represents a webhook subscription request. Without
+// event types the subscription receives every user event; without a secret
+// one is generated.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required" example:"https://crm.example.com/hooks/usercenter"`
+	EventTypes []string `json:"event_types" example:"user.registered,user.deleted"`
+	Secret     string   `json:"secret" example:"whsec_0123456789abcdef"`
+}
+
+// UpdateWebhookRequest represents changes to a webhook subscription; omitted
+// fields are left unchanged. Setting active re-enables a disabled subscription.
+type UpdateWebhookRequest struct {
+	URL        *string   `json:"url" example:"https://crm.example.com/hooks/usercenter"`
+	EventTypes *[]string `json:"event_types" example:"user.registered"`
+	Active     *bool     `json:"active" example:"true"`
+}
+
+// WebhookResponse represents a webhook subscription. Secret is only returned
+// when the subscription is created.
+type WebhookResponse struct {
+	Webhook *model.WebhookSubscription `json:"webhook"`
+	Secret  string                     `json:"secret,omitempty"`
+	Message string                     `json:"message"`
+}
+
+// WebhookListResponse represents every webhook subscription, oldest first
+type WebhookListResponse struct {
+	Webhooks []*model.WebhookSubscription `json:"webhooks"`
+	Message  string                       `json:"message"`
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"go.uber.org/zap"
+)
+
+// AuditLogHandler handles audit log queries
+type AuditLogHandler struct {
+	auditLogService *service.AuditLogService
+	pagination      config.PaginationConfig
+	logger          *zap.Logger
+}
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler(auditLogService *service.AuditLogService, cfg *config.Config, logger *zap.Logger) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditLogService: auditLogService,
+		pagination:      cfg.Server.Pagination,
+		logger:          logger,
+	}
+}
+
+// ListAuditLogs handles querying the audit log
+// @Summary List audit logs
+// @Description Get a paginated list of audit log entries, newest first (admin only)
+// @Tags admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param size query int false "Page size, clamped to server.pagination.max_size" default(10)
+// @Param user_id query string false "User ID"
+// @Param action query string false "Action, e.g. DELETE /api/v1/admin/users/:id"
+// @Param resource query string false "Request path"
+// @Param from query string false "Earliest timestamp, inclusive (RFC 3339)"
+// @Param to query string false "Latest timestamp, exclusive (RFC 3339)"
+// @Success 200 {object} dto.AuditLogListResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	var req dto.AuditLogListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Error("Invalid audit log request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+	req.Page, req.Size = h.pagination.Normalize(req.Page, req.Size)
+
+	entries, total, err := h.auditLogService.ListAuditLogs(c.Request.Context(), &req)
+	if err != nil {
+		if clientGone(c, err, h.logger) {
+			return
+		}
+
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidTimeRange):
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "from must be before to",
+			})
+		case errors.Is(err, repository.ErrAuditLogUnavailable):
+			response.Error(c, http.StatusServiceUnavailable, dto.ErrorResponse{
+				Error:   "Service Unavailable",
+				Message: "Audit log is unavailable",
+			})
+		default:
+			response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: "Failed to list audit logs",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AuditLogListResponse{
+		AuditLogs:  entries,
+		Pagination: dto.NewPagination(req.Page, req.Size, total),
+		Message:    "Audit logs retrieved successfully",
+	})
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"go.uber.org/zap"
+)
+
+// fakeAuditLogRepository records the filter it was queried with
+type fakeAuditLogRepository struct {
+	filter *repository.AuditLogFilter
+	err    error
+}
+
+func (r *fakeAuditLogRepository) List(ctx context.Context, filter repository.AuditLogFilter) ([]*database.AuditLog, int64, error) {
+	r.filter = &filter
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	return []*database.AuditLog{{Action: "PUT /api/v1/users/me", Timestamp: time.Now()}}, 41, nil
+}
+
+func TestAuditLogHandler_ListAuditLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		query        string
+		repoErr      error
+		expectedCode int
+		wantFilter   *repository.AuditLogFilter
+		wantBody     string
+	}{
+		{
+			name:         "filters and pagination",
+			query:        "?user_id=user-1&action=PUT+/api/v1/users/me&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&page=3&size=20",
+			expectedCode: http.StatusOK,
+			wantFilter: &repository.AuditLogFilter{
+				UserID: "user-1",
+				Action: "PUT /api/v1/users/me",
+				From:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Offset: 40,
+				Limit:  20,
+			},
+		},
+		{
+			name:         "reversed time range",
+			query:        "?from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "malformed time",
+			query:        "?from=yesterday",
+			expectedCode: http.StatusBadRequest,
+			wantBody:     response.ValidationErrorCode,
+		},
+		{
+			name:         "page size over the cap is clamped",
+			query:        "?size=500",
+			expectedCode: http.StatusOK,
+			wantFilter:   &repository.AuditLogFilter{Limit: 20},
+		},
+		{
+			name:         "zero page size falls back to the default",
+			query:        "?size=0&page=0",
+			expectedCode: http.StatusOK,
+			wantFilter:   &repository.AuditLogFilter{Limit: 15},
+		},
+		{
+			name:         "store unavailable",
+			repoErr:      repository.ErrAuditLogUnavailable,
+			expectedCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAuditLogRepository{err: tt.repoErr}
+			cfg := &config.Config{Server: config.ServerConfig{Pagination: config.PaginationConfig{DefaultSize: 15, MaxSize: 20}}}
+			h := NewAuditLogHandler(service.NewAuditLogService(repo, zap.NewNop()), cfg, zap.NewNop())
+
+			r := gin.New()
+			r.GET("/admin/audit-logs", h.ListAuditLogs)
+
+			w := doJSON(r, http.MethodGet, "/admin/audit-logs"+tt.query, nil)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.wantFilter != nil {
+				assert.Equal(t, tt.wantFilter, repo.filter)
+				assert.Contains(t, w.Body.String(), `"total_pages":3`)
+			}
+			if tt.wantBody != "" {
+				assert.Contains(t, w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"go.uber.org/zap"
+)
+
+// clientGone reports whether err was caused by the client canceling the
+// request, e.g. by disconnecting. Such requests are not server errors: they
+// are logged at debug level and aborted without a body since nobody reads it.
+func (h *UserHandler) clientGone(c *gin.Context, err error) bool {
+	return clientGone(c, err, h.log(c))
+}
+
+// clientGone reports whether err was caused by the client canceling the
+// request and if so aborts it, see UserHandler.clientGone
+func clientGone(c *gin.Context, err error, logger *zap.Logger) bool {
+	if !errors.Is(err, context.Canceled) && !errors.Is(c.Request.Context().Err(), context.Canceled) {
+		return false
+	}
+
+	logger.Debug("Request canceled by client",
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.FullPath()),
+		zap.Error(err),
+	)
+
+	if c.Writer.Written() {
+		c.Abort()
+	} else {
+		c.AbortWithStatus(response.StatusClientClosedRequest)
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"github.com/zhwjimmy/user-center/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DataExportHandler handles self-service personal data exports
+type DataExportHandler struct {
+	dataExportService *service.DataExportService
+	logger            *zap.Logger
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(dataExportService *service.DataExportService, logger *zap.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+		logger:            logger,
+	}
+}
+
+// ExportData handles exporting the current user's personal data
+// @Summary Export my data
+// @Description Download the current user's personal data as JSON: profile, roles, notification preferences, active sessions and recent activity. Password hashes and internal IDs are left out.
+// @Tags users
+// @Produce json
+// @Success 200 {object} dto.DataExport
+// @Header 200 {string} Content-Disposition "attachment; filename=user-data.json"
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/export [get]
+func (h *DataExportHandler) ExportData(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	log := logger.FromContext(c, h.logger)
+	export, err := h.dataExportService.Export(c.Request.Context(), claims.(*jwt.Claims).UserID)
+	if err != nil {
+		if clientGone(c, err, log) {
+			return
+		}
+		log.Error("Failed to export user data", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to export user data",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-data-%s.json"`, time.Now().UTC().Format("20060102")))
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// fixedAuditLogRepository returns entries for every query
+type fixedAuditLogRepository struct {
+	entries []*database.AuditLog
+}
+
+func (r *fixedAuditLogRepository) List(ctx context.Context, filter repository.AuditLogFilter) ([]*database.AuditLog, int64, error) {
+	return r.entries, int64(len(r.entries)), nil
+}
+
+func TestDataExportHandler_ExportData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	redis, _ := testutils.NewMiniRedis(t)
+	logger := zap.NewNop()
+
+	user := &model.User{
+		ID:           "user-1",
+		Username:     "alice",
+		Email:        "alice@example.com",
+		PasswordHash: "$2a$10$secret-hash",
+		IsActive:     true,
+	}
+	repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil)
+
+	sessions := service.NewSessionLimiter(redis, nil, &config.Config{}, logger)
+	require.NoError(t, sessions.Start(context.Background(), user, "token-1"))
+
+	audit := &fixedAuditLogRepository{entries: []*database.AuditLog{{
+		ID:        "65a1b2c3d4e5f6a7b8c9d0e1",
+		UserID:    "user-1",
+		Action:    "PUT /api/v1/users/me",
+		Resource:  "/api/v1/users/me",
+		Details:   map[string]interface{}{"status": 200},
+		IP:        "203.0.113.7",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		RequestID: "req-1",
+	}}}
+
+	userService := service.NewUserService(repo, service.NewEventService(testutils.NewFakeOutboxRepository(), logger), testutils.FakeTransactor{}, redis, logger)
+	h := NewDataExportHandler(service.NewDataExportService(userService, nil, sessions, audit, logger), logger)
+
+	r := gin.New()
+	r.GET("/users/me/export", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1"})
+		c.Next()
+	}, h.ExportData)
+
+	w := doJSON(r, http.MethodGet, "/users/me/export", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	body := w.Body.String()
+	assert.NotContains(t, body, "password")
+	assert.NotContains(t, body, user.PasswordHash)
+	assert.NotContains(t, body, "65a1b2c3d4e5f6a7b8c9d0e1")
+	assert.NotContains(t, body, "req-1")
+
+	var export struct {
+		Profile         map[string]interface{}   `json:"profile"`
+		Roles           []string                 `json:"roles"`
+		Sessions        []map[string]interface{} `json:"sessions"`
+		ActivityHistory []map[string]interface{} `json:"activity_history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, "alice", export.Profile["username"])
+	assert.Equal(t, "alice@example.com", export.Profile["email"])
+	assert.Equal(t, []string{model.RoleUser}, export.Roles)
+	assert.Len(t, export.Sessions, 1)
+	if assert.Len(t, export.ActivityHistory, 1) {
+		assert.Equal(t, "PUT /api/v1/users/me", export.ActivityHistory[0]["action"])
+		assert.Equal(t, float64(200), export.ActivityHistory[0]["status"])
+	}
+}
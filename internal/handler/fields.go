@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"go.uber.org/zap"
+)
+
+// fieldsQuery is the query parameter selecting a sparse fieldset
+const fieldsQuery = "fields"
+
+// parseFields parses the comma-separated ?fields= sparse fieldset. It returns
+// nil when no fieldset was requested. The list is rejected if it has more than
+// maxFields entries, repeats a field or names a field that cannot be selected.
+func parseFields(c *gin.Context, maxFields int) ([]string, error) {
+	raw, ok := c.GetQuery(fieldsQuery)
+	if !ok {
+		return nil, nil
+	}
+
+	// Count before splitting so an oversized list is rejected without allocating it
+	if count := strings.Count(raw, ",") + 1; count > maxFields {
+		return nil, fmt.Errorf("too many fields requested: %d, maximum is %d", count, maxFields)
+	}
+
+	fields := strings.Split(raw, ",")
+	seen := make(map[string]struct{}, len(fields))
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil, fmt.Errorf("empty field name")
+		}
+		if !isSelectableField(field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		if _, dup := seen[field]; dup {
+			return nil, fmt.Errorf("duplicate field %q", field)
+		}
+		seen[field] = struct{}{}
+		fields[i] = field
+	}
+
+	return fields, nil
+}
+
+// isSelectableField reports whether field may appear in a sparse fieldset
+func isSelectableField(field string) bool {
+	for _, f := range model.PublicUserFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldsError writes a 400 response for an invalid sparse fieldset
+func fieldsError(c *gin.Context, err error) {
+	response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+		Error:   "Bad Request",
+		Message: fmt.Sprintf("Invalid fields parameter: %s", err.Error()),
+	})
+}
+
+// writeUser writes a single user, restricted to fields when a sparse fieldset was requested
+func (h *UserHandler) writeUser(c *gin.Context, user *model.PublicUser, fields []string, message string) {
+	if fields == nil {
+		c.JSON(http.StatusOK, dto.UserResponse{
+			User:    user,
+			Message: message,
+		})
+		return
+	}
+
+	selected, err := user.Select(fields)
+	if err != nil {
+		h.log(c).Error("Failed to select user fields", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to get user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PartialUserResponse{
+		User:    selected,
+		Message: message,
+	})
+}
+
+// userView returns the view of user the caller may see and the fields of it
+// to write for a request of fields: the owner view for the user themselves
+// and staff, the public view without the owner-only fields for anyone else
+func (h *UserHandler) userView(c *gin.Context, user *model.User, fields []string) (*model.PublicUser, []string) {
+	if h.canViewOwnerFields(c, user.ID) {
+		return user.ToOwnerView(), fields
+	}
+	return user.ToPublicView(), model.PublicViewFields(fields)
+}
+
+// selectUserViews returns the view of each of users the caller may see,
+// restricted to fields, or to every field they may see when fields is nil
+func (h *UserHandler) selectUserViews(c *gin.Context, users []*model.User, fields []string) ([]map[string]json.RawMessage, error) {
+	selected := make([]map[string]json.RawMessage, len(users))
+	for i, user := range users {
+		view, viewFields := h.userView(c, user, fields)
+		if viewFields == nil {
+			viewFields = model.PublicUserFields
+		}
+
+		var err error
+		if selected[i], err = view.Select(viewFields); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
@@ -10,6 +10,9 @@ import (
 	"github.com/zhwjimmy/user-center/internal/cache"
 	"github.com/zhwjimmy/user-center/internal/database"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/infra"
+	"github.com/zhwjimmy/user-center/internal/kafka/consumer"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +22,9 @@ type HealthHandler struct {
 	postgres *database.PostgreSQL
 	mongodb  *database.MongoDB
 	redis    *cache.Redis
+	lag      *consumer.LagMonitor
+	jwt      *jwt.JWT
+	infra    *infra.Status
 }
 
 // NewHealthHandler creates a new health handler
@@ -27,12 +33,18 @@ func NewHealthHandler(
 	postgres *database.PostgreSQL,
 	mongodb *database.MongoDB,
 	redis *cache.Redis,
+	lag *consumer.LagMonitor,
+	jwtManager *jwt.JWT,
+	status *infra.Status,
 ) *HealthHandler {
 	return &HealthHandler{
 		logger:   logger,
 		postgres: postgres,
 		mongodb:  mongodb,
 		redis:    redis,
+		lag:      lag,
+		jwt:      jwtManager,
+		infra:    status,
 	}
 }
 
@@ -46,34 +58,30 @@ func NewHealthHandler(
 // @Failure 503 {object} dto.HealthResponse
 // @Router /health [get]
 func (h *HealthHandler) Health(c *gin.Context) {
-	checks := make(map[string]string)
-	overallStatus := "healthy"
-
-	// Check PostgreSQL
-	if err := h.checkPostgreSQL(); err != nil {
-		checks["postgresql"] = "unhealthy: " + err.Error()
-		overallStatus = "unhealthy"
-		h.logger.Error("PostgreSQL health check failed", zap.Error(err))
-	} else {
-		checks["postgresql"] = "healthy"
-	}
-
-	// Check MongoDB
-	if err := h.checkMongoDB(); err != nil {
-		checks["mongodb"] = "unhealthy: " + err.Error()
-		overallStatus = "unhealthy"
-		h.logger.Error("MongoDB health check failed", zap.Error(err))
-	} else {
-		checks["mongodb"] = "healthy"
+	checks := map[string]dto.CheckDetail{
+		"postgresql": runCheck(h.checkPostgreSQL, "healthy", "unhealthy"),
+		"mongodb":    runCheck(h.checkMongoDB, "healthy", h.failStatus(infra.ComponentMongoDB)),
+		"redis":      runCheck(h.checkRedis, "healthy", "unhealthy"),
+		// Kafka can only be missing when it is optional and was down at startup
+		"kafka": runCheck(h.checkKafka, "healthy", "degraded"),
+		// Kafka consumer lag only degrades the service, it is still able to serve requests
+		"kafka_consumer_lag": runCheck(h.lag.Check, "healthy", "degraded"),
 	}
 
-	// Check Redis
-	if err := h.checkRedis(); err != nil {
-		checks["redis"] = "unhealthy: " + err.Error()
-		overallStatus = "unhealthy"
-		h.logger.Error("Redis health check failed", zap.Error(err))
-	} else {
-		checks["redis"] = "healthy"
+	overallStatus := "healthy"
+	for name, check := range checks {
+		switch check.Status {
+		case "unhealthy":
+			overallStatus = "unhealthy"
+			h.logger.Error("Health check failed",
+				zap.String("dependency", name),
+				zap.String("error", check.Error),
+			)
+		case "degraded":
+			if overallStatus == "healthy" {
+				overallStatus = "degraded"
+			}
+		}
 	}
 
 	response := dto.HealthResponse{
@@ -102,30 +110,22 @@ func (h *HealthHandler) Health(c *gin.Context) {
 // @Router /ready [get]
 func (h *HealthHandler) Ready(c *gin.Context) {
 	// For readiness, we check if all critical dependencies are available
-	checks := make(map[string]string)
-	overallStatus := "ready"
-
-	// Check PostgreSQL (critical for user operations)
-	if err := h.checkPostgreSQL(); err != nil {
-		checks["postgresql"] = "not ready: " + err.Error()
-		overallStatus = "not ready"
-	} else {
-		checks["postgresql"] = "ready"
+	checks := map[string]dto.CheckDetail{
+		// PostgreSQL is critical for user operations
+		"postgresql": runCheck(h.checkPostgreSQL, "ready", "not ready"),
+		// Redis is critical for caching and sessions
+		"redis": runCheck(h.checkRedis, "ready", "not ready"),
+		// MongoDB is not critical for basic operations, so we don't fail readiness for it
+		"mongodb": runCheck(h.checkMongoDB, "ready", "degraded"),
+		// Without a working signing key no one can log in
+		"jwt": runCheck(h.checkJWT, "ready", "not ready"),
 	}
 
-	// Check Redis (critical for caching and sessions)
-	if err := h.checkRedis(); err != nil {
-		checks["redis"] = "not ready: " + err.Error()
-		overallStatus = "not ready"
-	} else {
-		checks["redis"] = "ready"
-	}
-
-	// MongoDB is not critical for basic operations, so we don't fail readiness for it
-	if err := h.checkMongoDB(); err != nil {
-		checks["mongodb"] = "degraded: " + err.Error()
-	} else {
-		checks["mongodb"] = "ready"
+	overallStatus := "ready"
+	for _, check := range checks {
+		if check.Status == "not ready" {
+			overallStatus = "not ready"
+		}
 	}
 
 	response := dto.HealthResponse{
@@ -157,31 +157,45 @@ func (h *HealthHandler) Live(c *gin.Context) {
 		Status:    "alive",
 		Version:   "1.0.0",
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Checks: map[string]string{
-			"service": "alive",
+		Checks: map[string]dto.CheckDetail{
+			"service": {Status: "alive"},
 		},
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// checkPostgreSQL checks PostgreSQL connectivity
-func (h *HealthHandler) checkPostgreSQL() error {
-	if h.postgres == nil {
-		return fmt.Errorf("postgres client not initialized")
+// runCheck times check and reports okStatus, or failStatus with the error
+func runCheck(check func() error, okStatus, failStatus string) dto.CheckDetail {
+	start := time.Now()
+	err := check()
+	detail := dto.CheckDetail{
+		Status:    okStatus,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
 	}
 
-	db, err := h.postgres.DB.DB()
 	if err != nil {
-		return err
+		detail.Status = failStatus
+		detail.Error = err.Error()
 	}
+	return detail
+}
 
-	return db.Ping()
+// checkPostgreSQL checks the connectivity of PostgreSQL and its read replicas
+func (h *HealthHandler) checkPostgreSQL() error {
+	if h.postgres == nil {
+		return fmt.Errorf("postgres client not initialized")
+	}
+
+	return h.postgres.Health()
 }
 
 // checkMongoDB checks MongoDB connectivity
 func (h *HealthHandler) checkMongoDB() error {
 	if h.mongodb == nil {
+		if err := h.infra.Err(infra.ComponentMongoDB); err != nil {
+			return fmt.Errorf("mongodb unavailable at startup: %w", err)
+		}
 		return fmt.Errorf("mongodb client not initialized")
 	}
 
@@ -191,6 +205,23 @@ func (h *HealthHandler) checkMongoDB() error {
 	return h.mongodb.Client.Ping(ctx, nil)
 }
 
+// checkKafka reports whether Kafka was unavailable at startup
+func (h *HealthHandler) checkKafka() error {
+	if err := h.infra.Err(infra.ComponentKafka); err != nil {
+		return fmt.Errorf("kafka unavailable at startup: %w", err)
+	}
+	return nil
+}
+
+// failStatus is the /health status of a failing component: optional
+// components only degrade the service
+func (h *HealthHandler) failStatus(component string) string {
+	if h.infra.Optional(component) {
+		return "degraded"
+	}
+	return "unhealthy"
+}
+
 // checkRedis checks Redis connectivity
 func (h *HealthHandler) checkRedis() error {
 	if h.redis == nil {
@@ -202,3 +233,12 @@ func (h *HealthHandler) checkRedis() error {
 
 	return h.redis.Client.Ping(ctx).Err()
 }
+
+// checkJWT checks that tokens can be signed and verified
+func (h *HealthHandler) checkJWT() error {
+	if h.jwt == nil {
+		return fmt.Errorf("jwt manager not initialized")
+	}
+
+	return h.jwt.Check()
+}
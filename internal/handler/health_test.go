@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appconfig "github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/infra"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/consumer"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+func TestHealthHandler_Health_ConsumerLag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lag := consumer.NewLagMonitor(&config.KafkaClientConfig{LagThreshold: 100}, zap.NewNop())
+	h := NewHealthHandler(zap.NewNop(), nil, nil, nil, lag, nil, nil)
+
+	r := gin.New()
+	r.GET("/health", h.Health)
+
+	check := func() dto.CheckDetail {
+		w := doJSON(r, http.MethodGet, "/health", nil)
+		var resp dto.HealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Checks["kafka_consumer_lag"]
+	}
+
+	assert.Equal(t, "healthy", check().Status)
+
+	lag.Record("user.events", 0, 500)
+	detail := check()
+	assert.Equal(t, "degraded", detail.Status)
+	assert.Equal(t, "user.events lag 500 exceeds threshold 100", detail.Error)
+}
+
+func TestHealthHandler_CheckDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewHealthHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		path     string
+		handle   gin.HandlerFunc
+		expected []string
+	}{
+		{path: "/health", handle: h.Health, expected: []string{"postgresql", "mongodb", "redis", "kafka", "kafka_consumer_lag"}},
+		{path: "/ready", handle: h.Ready, expected: []string{"postgresql", "mongodb", "redis", "jwt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r := gin.New()
+			r.GET(tt.path, tt.handle)
+
+			w := doJSON(r, http.MethodGet, tt.path, nil)
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+			var raw struct {
+				Checks map[string]map[string]interface{} `json:"checks"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+			require.Len(t, raw.Checks, len(tt.expected))
+
+			for _, name := range tt.expected {
+				check, ok := raw.Checks[name]
+				require.True(t, ok, "missing check %s", name)
+
+				latency, ok := check["latency_ms"].(float64)
+				require.True(t, ok, "check %s has no latency_ms", name)
+				assert.GreaterOrEqual(t, latency, float64(0))
+				assert.NotEmpty(t, check["status"])
+			}
+
+			// Uninitialized clients are reported as down with the reason
+			assert.Equal(t, "postgres client not initialized", raw.Checks["postgresql"]["error"])
+		})
+	}
+}
+
+func TestHealthHandler_Health_OptionalInfrastructureDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	status, err := infra.NewStatus(&appconfig.Config{
+		Infrastructure: appconfig.InfrastructureConfig{Optional: []string{infra.ComponentMongoDB, infra.ComponentKafka}},
+	})
+	require.NoError(t, err)
+
+	// Simulate both optional components failing at startup
+	for _, component := range []string{infra.ComponentMongoDB, infra.ComponentKafka} {
+		_, err := infra.Connect(status, component, zap.NewNop(), func() (*struct{}, error) {
+			return nil, errors.New("connection refused")
+		})
+		require.NoError(t, err)
+	}
+
+	h := NewHealthHandler(zap.NewNop(), nil, nil, nil, nil, nil, status)
+	r := gin.New()
+	r.GET("/health", h.Health)
+
+	w := doJSON(r, http.MethodGet, "/health", nil)
+	var resp dto.HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, "degraded", resp.Checks["mongodb"].Status)
+	assert.Equal(t, "mongodb unavailable at startup: connection refused", resp.Checks["mongodb"].Error)
+	assert.Equal(t, "degraded", resp.Checks["kafka"].Status)
+	assert.Equal(t, "kafka unavailable at startup: connection refused", resp.Checks["kafka"].Error)
+}
+
+func TestHealthHandler_Ready_JWT(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		manager  *jwt.JWT
+		expected string
+	}{
+		{name: "valid key", manager: jwt.NewJWT("test-secret", "test", time.Hour), expected: "ready"},
+		{name: "empty secret", manager: jwt.NewJWT("", "test", time.Hour), expected: "not ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(zap.NewNop(), nil, nil, nil, nil, tt.manager, nil)
+			r := gin.New()
+			r.GET("/ready", h.Ready)
+
+			w := doJSON(r, http.MethodGet, "/ready", nil)
+			var resp dto.HealthResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expected, resp.Checks["jwt"].Status)
+		})
+	}
+}
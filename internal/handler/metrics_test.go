@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMetrics_ExposedAfterAuthOperations(t *testing.T) {
+	env := newTestEnv(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, user *model.User) (*model.User, error) {
+			user.ID = "user-1"
+			return user, nil
+		})
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "existing@example.com").
+		Return(&model.User{ID: "user-2", Email: "existing@example.com", PasswordHash: string(hash), IsActive: true}, nil).
+		Times(2)
+
+	r := gin.New()
+	r.POST("/register", env.handler.Register)
+	r.POST("/login", env.handler.Login)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	w := doJSON(r, http.MethodPost, "/register", map[string]string{
+		"username": "newuser",
+		"email":    "new@example.com",
+		"password": "password123",
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/login", map[string]string{"email": "existing@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/login", map[string]string{"email": "existing@example.com", "password": "wrong-password"})
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	metricsOutput := string(body)
+
+	assert.Contains(t, metricsOutput, "usercenter_registrations_total")
+	assert.Contains(t, metricsOutput, `usercenter_logins_total{result="success"}`)
+	assert.Contains(t, metricsOutput, `usercenter_logins_total{result="failure"}`)
+	assert.Contains(t, metricsOutput, `usercenter_operation_duration_seconds_count{operation="register"}`)
+	assert.Contains(t, metricsOutput, "usercenter_active_users")
+}
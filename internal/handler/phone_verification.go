@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"github.com/zhwjimmy/user-center/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PhoneVerificationHandler handles verifying the current user's phone number
+type PhoneVerificationHandler struct {
+	phoneVerificationService *service.PhoneVerificationService
+	logger                   *zap.Logger
+}
+
+// NewPhoneVerificationHandler creates a new phone verification handler
+func NewPhoneVerificationHandler(phoneVerificationService *service.PhoneVerificationService, logger *zap.Logger) *PhoneVerificationHandler {
+	return &PhoneVerificationHandler{
+		phoneVerificationService: phoneVerificationService,
+		logger:                   logger,
+	}
+}
+
+// SendCode handles texting a verification code to the current user's phone
+// @Summary Send phone verification code
+// @Description Text a one-time code to the current user's phone number. A new code replaces the previous one.
+// @Tags users
+// @Produce json
+// @Success 202 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/phone/send-code [post]
+func (h *PhoneVerificationHandler) SendCode(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	if err := h.phoneVerificationService.SendCode(c.Request.Context(), claims.(*jwt.Claims).UserID); err != nil {
+		h.writeError(c, err, "Failed to send verification code")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.SuccessResponse{
+		Message: "Verification code sent",
+	})
+}
+
+// VerifyCode handles verifying the current user's phone with a texted code
+// @Summary Verify phone number
+// @Description Verify the current user's phone number with the code texted to it
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyPhoneRequest true "Verification code"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 410 {object} dto.ErrorResponse
+// @Failure 422 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/phone/verify [post]
+func (h *PhoneVerificationHandler) VerifyCode(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	var req dto.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	user, err := h.phoneVerificationService.VerifyCode(c.Request.Context(), claims.(*jwt.Claims).UserID, req.Code)
+	if err != nil {
+		h.writeError(c, err, "Failed to verify phone number")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserResponse{
+		User:    user.ToPublicUser(),
+		Message: "Phone number verified",
+	})
+}
+
+// writeError maps a phone verification error to a response
+func (h *PhoneVerificationHandler) writeError(c *gin.Context, err error, message string) {
+	log := logger.FromContext(c, h.logger)
+	if clientGone(c, err, log) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+			Error:   "Not Found",
+			Message: "User not found",
+		})
+	case errors.Is(err, apperrors.ErrPhoneNotSet):
+		response.Error(c, http.StatusUnprocessableEntity, dto.ErrorResponse{
+			Error:   "Unprocessable Entity",
+			Message: "Add a phone number to your profile first",
+		})
+	case errors.Is(err, apperrors.ErrPhoneAlreadyVerified):
+		response.Error(c, http.StatusConflict, dto.ErrorResponse{
+			Error:   "Conflict",
+			Message: "Phone number is already verified",
+		})
+	case errors.Is(err, apperrors.ErrInvalidVerificationCode):
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid verification code",
+		})
+	case errors.Is(err, apperrors.ErrVerificationCodeExpired):
+		response.Error(c, http.StatusGone, dto.ErrorResponse{
+			Error:   "Gone",
+			Message: "Verification code expired, request a new one",
+		})
+	default:
+		log.Error(message, zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: message,
+		})
+	}
+}
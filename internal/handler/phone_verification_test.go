@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// fakeSMSQueue records enqueued text messages
+type fakeSMSQueue struct {
+	sent []task.SMSPayload
+}
+
+func (q *fakeSMSQueue) EnqueueSMS(ctx context.Context, payload task.SMSPayload) error {
+	q.sent = append(q.sent, payload)
+	return nil
+}
+
+func TestPhoneVerificationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	phone := "+15551234567"
+	users := map[string]*model.User{
+		"user-1": {ID: "user-1", Username: "alice", Phone: &phone, IsActive: true},
+		"user-2": {ID: "user-2", Username: "bob", IsActive: true},
+	}
+	repo := mock.NewMockUserRepository(gomock.NewController(t))
+	repo.EXPECT().GetByID(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.User, error) {
+			return users[id], nil
+		}).AnyTimes()
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			return user, nil
+		}).AnyTimes()
+
+	redis, _ := testutils.NewMiniRedis(t)
+	sms := &fakeSMSQueue{}
+	logger := zap.NewNop()
+	phoneVerificationService := service.NewPhoneVerificationService(repo,
+		service.NewEventService(testutils.NewFakeOutboxRepository(), logger), testutils.FakeTransactor{},
+		redis, sms, &config.Config{}, logger)
+	h := NewPhoneVerificationHandler(phoneVerificationService, logger)
+
+	callerID := "user-1"
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: callerID})
+	})
+	r.POST("/users/me/phone/send-code", h.SendCode)
+	r.POST("/users/me/phone/verify", h.VerifyCode)
+
+	// Nothing to verify before a code is sent
+	w := doJSON(r, http.MethodPost, "/users/me/phone/verify", map[string]string{"code": "123456"})
+	assert.Equal(t, http.StatusGone, w.Code, w.Body.String())
+
+	w = doJSON(r, http.MethodPost, "/users/me/phone/send-code", nil)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.Len(t, sms.sent, 1)
+	code := regexp.MustCompile(`\d{6}`).FindString(sms.sent[0].Body)
+	require.NotEmpty(t, code)
+
+	w = doJSON(r, http.MethodPost, "/users/me/phone/verify", map[string]string{"code": "not-a-code"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	w = doJSON(r, http.MethodPost, "/users/me/phone/verify", map[string]string{"code": wrong})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = doJSON(r, http.MethodPost, "/users/me/phone/verify", map[string]string{"code": code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"phone_verified":true`)
+
+	w = doJSON(r, http.MethodPost, "/users/me/phone/send-code", nil)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	// Users need a phone number to verify
+	callerID = "user-2"
+	w = doJSON(r, http.MethodPost, "/users/me/phone/send-code", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+}
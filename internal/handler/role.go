@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"go.uber.org/zap"
+)
+
+// RoleHandler handles user role administration
+type RoleHandler struct {
+	roleService *service.RoleService
+	logger      *zap.Logger
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleService *service.RoleService, logger *zap.Logger) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+		logger:      logger,
+	}
+}
+
+// GetUserRoles handles listing a user's roles
+// @Summary Get user roles
+// @Description Get a user's effective roles (admin or support)
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.UserRolesResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/roles [get]
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	id := c.Param("id")
+
+	roles, err := h.roleService.GetUserRoles(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get user roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserRolesResponse{
+		UserID:  id,
+		Roles:   roles,
+		Message: "User roles retrieved successfully",
+	})
+}
+
+// AssignRole handles giving a role to a user
+// @Summary Assign role
+// @Description Give a role to a user (admin only). It applies to tokens issued afterwards.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body dto.AssignRoleRequest true "Role"
+// @Success 200 {object} dto.UserRolesResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/roles [post]
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid assign role request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	roles, err := h.roleService.AssignRole(c.Request.Context(), id, req.Role)
+	if err != nil {
+		h.writeError(c, err, "Failed to assign role")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserRolesResponse{
+		UserID:  id,
+		Roles:   roles,
+		Message: "Role assigned successfully",
+	})
+}
+
+// RevokeRole handles taking a role away from a user
+// @Summary Revoke role
+// @Description Take a role away from a user (admin only). Tokens issued before keep the role until they expire.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role path string true "Role" Enums(admin, support)
+// @Success 200 {object} dto.UserRolesResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/roles/{role} [delete]
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	id := c.Param("id")
+
+	roles, err := h.roleService.RevokeRole(c.Request.Context(), id, c.Param("role"))
+	if err != nil {
+		h.writeError(c, err, "Failed to revoke role")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserRolesResponse{
+		UserID:  id,
+		Roles:   roles,
+		Message: "Role revoked successfully",
+	})
+}
+
+// writeError maps a role service error to a response
+func (h *RoleHandler) writeError(c *gin.Context, err error, message string) {
+	if clientGone(c, err, h.logger) {
+		return
+	}
+	h.logger.Error(message, zap.Error(err))
+
+	switch {
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+			Error:   "Not Found",
+			Message: "User not found",
+		})
+	case errors.Is(err, apperrors.ErrInvalidRole), errors.Is(err, apperrors.ErrRoleNotRevocable):
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: message,
+		})
+	}
+}
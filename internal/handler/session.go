@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SessionHandler handles the administration of users' sessions
+type SessionHandler struct {
+	sessions    *service.SessionLimiter
+	userService *service.UserService
+	logger      *zap.Logger
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(sessions *service.SessionLimiter, userService *service.UserService, logger *zap.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessions:    sessions,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+// ListSessions handles listing a user's active sessions
+// @Summary List user sessions
+// @Description List a user's active sessions, newest first, with the IP and user agent each was started from
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.SessionListResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	if _, err := h.userService.GetUserByID(ctx, id); err != nil {
+		h.writeError(c, err, "Failed to list sessions")
+		return
+	}
+
+	sessions, err := h.sessions.ListActive(ctx, id)
+	if err != nil {
+		h.writeError(c, err, "Failed to list sessions")
+		return
+	}
+
+	resp := dto.SessionListResponse{
+		UserID:   id,
+		Sessions: make([]dto.SessionResponse, len(sessions)),
+		Message:  "Sessions retrieved successfully",
+	}
+	for i, session := range sessions {
+		resp.Sessions[i] = dto.SessionResponse{
+			ID:        session.ID,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeSessions handles signing a user out of every session
+// @Summary Revoke user sessions
+// @Description Sign a user out of every session, e.g. when the account is compromised. Each session's token is blacklisted until it expires.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.RevokeSessionsResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/sessions [delete]
+func (h *SessionHandler) RevokeSessions(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+
+	if _, err := h.userService.GetUserByID(ctx, id); err != nil {
+		h.writeError(c, err, "Failed to revoke sessions")
+		return
+	}
+
+	revoked, err := h.sessions.RevokeAll(ctx, id)
+	if err != nil {
+		h.writeError(c, err, "Failed to revoke sessions")
+		return
+	}
+	c.Set(response.AuditDetailsKey, map[string]interface{}{"revoked": revoked})
+
+	c.JSON(http.StatusOK, dto.RevokeSessionsResponse{
+		UserID:  id,
+		Revoked: revoked,
+		Message: "Sessions revoked successfully",
+	})
+}
+
+// writeError maps a session error to a response
+func (h *SessionHandler) writeError(c *gin.Context, err error, message string) {
+	log := logger.FromContext(c, h.logger)
+	if clientGone(c, err, log) {
+		return
+	}
+	log.Error(message, zap.Error(err))
+
+	switch {
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+			Error:   "Not Found",
+			Message: "User not found",
+		})
+	case errors.Is(err, apperrors.ErrUserDataUnavailable):
+		response.Error(c, http.StatusServiceUnavailable, dto.ErrorResponse{
+			Error:   "Service Unavailable",
+			Message: "Sessions cannot be managed right now",
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: message,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// newTestSessionRouter serves the admin session routes for user-1, the only
+// existing user, recording sessions in store when it is not nil
+func newTestSessionRouter(t *testing.T, store service.SessionStore) (*gin.Engine, *service.SessionLimiter, *map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	repo.EXPECT().GetByID(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, id string) (*model.User, error) {
+			if id != "user-1" {
+				return nil, apperrors.ErrUserNotFound
+			}
+			return &model.User{ID: id, Username: "alice", IsActive: true}, nil
+		}).AnyTimes()
+	redis, _ := testutils.NewMiniRedis(t)
+	logger := zap.NewNop()
+
+	sessions := service.NewSessionLimiter(redis, store, &config.Config{}, logger)
+	userService := service.NewUserService(repo, service.NewEventService(testutils.NewFakeOutboxRepository(), logger), testutils.FakeTransactor{}, redis, logger)
+	h := NewSessionHandler(sessions, userService, logger)
+
+	var auditDetails map[string]interface{}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		if details, ok := c.Get(response.AuditDetailsKey); ok {
+			auditDetails = details.(map[string]interface{})
+		}
+	})
+	r.GET("/admin/users/:id/sessions", h.ListSessions)
+	r.DELETE("/admin/users/:id/sessions", h.RevokeSessions)
+
+	return r, sessions, &auditDetails
+}
+
+func TestSessionHandler_ListAndRevokeSessions(t *testing.T) {
+	store := testutils.NewFakeSessionStore()
+	r, sessions, auditDetails := newTestSessionRouter(t, store)
+
+	user := &model.User{ID: "user-1"}
+	for _, token := range []string{"token-1", "token-2"} {
+		require.NoError(t, sessions.Start(context.Background(), user, token))
+	}
+
+	w := doJSON(r, http.MethodGet, "/admin/users/user-1/sessions", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list dto.SessionListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, "user-1", list.UserID)
+	require.Len(t, list.Sessions, 2)
+	assert.True(t, list.Sessions[0].ExpiresAt.After(list.Sessions[0].CreatedAt))
+	assert.NotContains(t, w.Body.String(), "token-1", "tokens are never exposed")
+
+	w = doJSON(r, http.MethodDelete, "/admin/users/user-1/sessions", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var revoked dto.RevokeSessionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revoked))
+	assert.Equal(t, 2, revoked.Revoked)
+	assert.Equal(t, map[string]interface{}{"revoked": 2}, *auditDetails)
+
+	for _, token := range []string{"token-1", "token-2"} {
+		active, err := sessions.IsActive(context.Background(), "user-1", token)
+		require.NoError(t, err)
+		assert.False(t, active, token)
+	}
+
+	w = doJSON(r, http.MethodGet, "/admin/users/user-1/sessions", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Empty(t, list.Sessions)
+}
+
+func TestSessionHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		store        service.SessionStore
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "list unknown user", store: testutils.NewFakeSessionStore(), method: http.MethodGet, path: "/admin/users/user-2/sessions", expectedCode: http.StatusNotFound},
+		{name: "revoke unknown user", store: testutils.NewFakeSessionStore(), method: http.MethodDelete, path: "/admin/users/user-2/sessions", expectedCode: http.StatusNotFound},
+		{name: "list without store", method: http.MethodGet, path: "/admin/users/user-1/sessions", expectedCode: http.StatusServiceUnavailable},
+		{name: "revoke without store", method: http.MethodDelete, path: "/admin/users/user-1/sessions", expectedCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newTestSessionRouter(t, tt.store)
+
+			w := doJSON(r, tt.method, tt.path, nil)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
@@ -1,37 +1,74 @@
 package handler
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/dto"
 	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/response"
 	"github.com/zhwjimmy/user-center/internal/service"
 	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"github.com/zhwjimmy/user-center/pkg/logger"
 	"go.uber.org/zap"
 )
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
-	userService *service.UserService
-	authService *service.AuthService
-	logger      *zap.Logger
+	userService    *service.UserService
+	authService    *service.AuthService
+	erasureService *service.ErasureService
+	pagination     config.PaginationConfig
+	maxFields      int
+	maxCost        int
+	logger         *zap.Logger
 }
 
 // NewUserHandler creates a new user handler
 func NewUserHandler(
 	userService *service.UserService,
 	authService *service.AuthService,
+	erasureService *service.ErasureService,
+	cfg *config.Config,
 	logger *zap.Logger,
 ) *UserHandler {
+	maxFields := cfg.Server.MaxFields
+	if maxFields <= 0 {
+		maxFields = len(model.PublicUserFields)
+	}
+
+	// Without a budget only the ID cap applies
+	maxCost := cfg.Server.MaxBatchCost
+	if maxCost <= 0 {
+		maxCost = maxBatchIDs * len(model.PublicUserFields)
+	}
+
 	return &UserHandler{
-		userService: userService,
-		authService: authService,
-		logger:      logger,
+		userService:    userService,
+		authService:    authService,
+		erasureService: erasureService,
+		pagination:     cfg.Server.Pagination,
+		maxFields:      maxFields,
+		maxCost:        maxCost,
+		logger:         logger,
 	}
 }
 
+// log returns the logger of the request, which carries its request and user IDs
+func (h *UserHandler) log(c *gin.Context) *zap.Logger {
+	return logger.FromContext(c, h.logger)
+}
+
 // Register handles user registration
 // @Summary Register a new user
 // @Description Register a new user with username, email, and password
@@ -40,48 +77,107 @@ func NewUserHandler(
 // @Produce json
 // @Param request body dto.RegisterRequest true "Registration request"
 // @Success 201 {object} dto.RegisterResponse
-// @Failure 400 {object} dto.ErrorResponse
+// @Header 201 {string} Location "URL of the created user"
+// @Failure 400 {object} dto.ValidationErrorResponse
 // @Failure 409 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /users/register [post]
 func (h *UserHandler) Register(c *gin.Context) {
 	var req dto.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid registration request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Error:   "Bad Request",
-			Message: err.Error(),
-		})
+		h.log(c).Error("Invalid registration request", zap.Error(err))
+		response.ValidationError(c, err)
 		return
 	}
 
-	user, token, err := h.authService.Register(c.Request.Context(), &req)
+	user, token, warnings, err := h.authService.Register(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Registration failed", zap.Error(err))
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Registration failed", zap.Error(err))
+
+		// Password policy and email domain rejections carry field errors
+		if response.FieldErrors(err) != nil {
+			response.ValidationError(c, err)
+			return
+		}
 
 		// Check for specific errors
-		if err.Error() == "user already exists" {
-			c.JSON(http.StatusConflict, dto.ErrorResponse{
+		if errors.Is(err, apperrors.ErrUserExists) {
+			response.Error(c, http.StatusConflict, dto.ErrorResponse{
 				Error:   "Conflict",
-				Message: "User with this email or username already exists",
+				Message: "User with this email, username or phone already exists",
 			})
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to register user",
 		})
 		return
 	}
 
+	c.Header("Location", "/api/v1/users/"+user.ID)
 	c.JSON(http.StatusCreated, dto.RegisterResponse{
-		User:    user.ToPublicUser(),
-		Token:   token,
-		Message: "User registered successfully",
+		User:     user.ToPublicUser(),
+		Token:    token,
+		Message:  "User registered successfully",
+		Warnings: warnings,
 	})
 }
 
+// CheckAvailability handles checking whether a username or email is free
+// @Summary Check username or email availability
+// @Description Check whether a username or email, exactly one of them, is free to register. Inputs are validated and normalized as in registration. Rate limited per client IP.
+// @Tags users
+// @Produce json
+// @Param username query string false "Username"
+// @Param email query string false "Email"
+// @Success 200 {object} dto.CheckAvailabilityResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/check-availability [get]
+func (h *UserHandler) CheckAvailability(c *gin.Context) {
+	var req dto.CheckAvailabilityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	if (req.Username == "") == (req.Email == "") {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Provide either a username or an email",
+		})
+		return
+	}
+
+	available, err := h.authService.CheckAvailability(c.Request.Context(), &req)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+
+		// Email domain rejections carry field errors
+		if response.FieldErrors(err) != nil {
+			response.ValidationError(c, err)
+			return
+		}
+
+		h.log(c).Error("Failed to check availability", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to check availability",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CheckAvailabilityResponse{Available: available})
+}
+
 // Login handles user login
 // @Summary User login
 // @Description Authenticate user with email and password
@@ -90,34 +186,49 @@ func (h *UserHandler) Register(c *gin.Context) {
 // @Produce json
 // @Param request body dto.LoginRequest true "Login request"
 // @Success 200 {object} dto.LoginResponse
-// @Failure 400 {object} dto.ErrorResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /users/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req dto.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid login request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Error:   "Bad Request",
-			Message: err.Error(),
-		})
+		h.log(c).Error("Invalid login request", zap.Error(err))
+		response.ValidationError(c, err)
 		return
 	}
 
 	user, token, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Login failed", zap.Error(err))
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Login failed", zap.Error(err))
 
-		if err.Error() == "invalid credentials" {
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidCredentials):
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Invalid email or password",
 			})
 			return
+		case errors.Is(err, apperrors.ErrAccountInactive):
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "Account is not active",
+			})
+			return
+		case errors.Is(err, apperrors.ErrEmailNotVerified):
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "Email address is not verified",
+				Code:    response.EmailNotVerifiedCode,
+			})
+			return
 		}
 
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to login",
 		})
@@ -131,13 +242,15 @@ func (h *UserHandler) Login(c *gin.Context) {
 	})
 }
 
-// GetUser handles getting user by ID
+// GetUser handles getting user by ID. The user themselves and admins get the
+// full profile; other users and anonymous callers get the public view.
 // @Summary Get user by ID
-// @Description Get user information by ID
+// @Description Get user information by ID. Email, phone, verification flags, last login and admin flag are only returned to the user themselves and to admins.
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param id path int true "User ID"
+// @Param id path string true "User ID"
+// @Param fields query string false "Comma-separated fields to return"
 // @Success 200 {object} dto.UserResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
@@ -145,39 +258,220 @@ func (h *UserHandler) Login(c *gin.Context) {
 // @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	fields, err := parseFields(c, h.maxFields)
 	if err != nil {
-		h.logger.Error("Invalid user ID", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+		fieldsError(c, err)
+		return
+	}
+
+	// User IDs are UUIDs; anything else would only fail in the database
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		h.log(c).Error("Invalid user ID", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
 			Error:   "Bad Request",
 			Message: "Invalid user ID",
 		})
 		return
 	}
 
-	user, err := h.userService.GetUserByID(c.Request.Context(), strconv.FormatUint(id, 10))
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Error("Failed to get user", zap.Error(err))
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to get user", zap.Error(err))
 
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
 				Error:   "Not Found",
 				Message: "User not found",
 			})
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to get user",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.UserResponse{
-		User:    user.ToPublicUser(),
-		Message: "User retrieved successfully",
+	view, viewFields := h.userView(c, user, fields)
+	h.writeUser(c, view, viewFields, "User retrieved successfully")
+}
+
+// LookupUser handles looking up a user by email or username
+// @Summary Look up user by email or username
+// @Description Find a user by exact email or username. Exactly one of the two query parameters is used; email wins when both are given.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param email query string false "Email address"
+// @Param username query string false "Username"
+// @Param fields query string false "Comma-separated fields to return"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/lookup [get]
+func (h *UserHandler) LookupUser(c *gin.Context) {
+	fields, err := parseFields(c, h.maxFields)
+	if err != nil {
+		fieldsError(c, err)
+		return
+	}
+
+	email := strings.TrimSpace(c.Query("email"))
+	username := strings.TrimSpace(c.Query("username"))
+
+	var user *model.User
+	switch {
+	case email != "":
+		user, err = h.userService.GetUserByEmail(c.Request.Context(), email)
+	case username != "":
+		user, err = h.userService.GetUserByUsername(c.Request.Context(), username)
+	default:
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Either email or username is required",
+		})
+		return
+	}
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to look up user", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to look up user",
+		})
+		return
+	}
+
+	view, viewFields := h.userView(c, user, fields)
+	h.writeUser(c, view, viewFields, "User retrieved successfully")
+}
+
+// canViewOwnerFields reports whether the caller may see the owner-only fields
+// of the user with id: the caller is that user, is an administrator, or has
+// the support role. Anonymous callers may not.
+func (h *UserHandler) canViewOwnerFields(c *gin.Context, id string) bool {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return false
+	}
+	return claims.(*jwt.Claims).UserID == id || isStaff(c)
+}
+
+// isStaff reports whether the caller is an administrator or has the support role
+func isStaff(c *gin.Context) bool {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return false
+	}
+
+	userClaims := claims.(*jwt.Claims)
+	return userClaims.IsAdmin || userClaims.HasRole(model.RoleAdmin, model.RoleSupport)
+}
+
+// maxBatchIDs is the most users a single batch lookup may request
+const maxBatchIDs = 100
+
+// BatchGetUsers handles looking up several users by ID
+// @Summary Get users by IDs
+// @Description Get up to 100 users by ID, in request order, along with the IDs that matched no user. The cost of a request, the number of IDs times the number of fields returned, must stay within the configured budget.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.BatchGetUsersRequest true "User IDs"
+// @Param fields query string false "Comma-separated fields to return"
+// @Success 200 {object} dto.PartialBatchUsersResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/batch [post]
+func (h *UserHandler) BatchGetUsers(c *gin.Context) {
+	fields, err := parseFields(c, h.maxFields)
+	if err != nil {
+		fieldsError(c, err)
+		return
+	}
+
+	var req dto.BatchGetUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid batch user request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	// Without a fieldset every public field is returned
+	fieldCount := len(fields)
+	if fields == nil {
+		fieldCount = len(model.PublicUserFields)
+	}
+	if cost := len(req.IDs) * fieldCount; cost > h.maxCost {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error: "Bad Request",
+			Message: fmt.Sprintf("Request too costly: %d ids × %d fields = %d, maximum is %d",
+				len(req.IDs), fieldCount, cost, h.maxCost),
+		})
+		return
+	}
+
+	users, err := h.userService.GetUsersByIDs(c.Request.Context(), req.IDs)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to get users by IDs", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to get users",
+		})
+		return
+	}
+
+	found := make(map[string]bool, len(users))
+	for _, user := range users {
+		found[user.ID] = true
+	}
+
+	missing := make([]string, 0)
+	for _, id := range req.IDs {
+		if !found[id] {
+			missing = append(missing, id)
+			// Report a repeated missing ID once
+			found[id] = true
+		}
+	}
+
+	// Like GetUser, only the user themselves and staff see owner-only fields
+	selected, err := h.selectUserViews(c, users, fields)
+	if err != nil {
+		h.log(c).Error("Failed to select user fields", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to get users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PartialBatchUsersResponse{
+		Users:   selected,
+		Missing: missing,
+		Message: "Users retrieved successfully",
 	})
 }
 
@@ -187,15 +481,23 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 // @Tags users
 // @Accept json
 // @Produce json
+// @Param fields query string false "Comma-separated fields to return"
 // @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /users/me [get]
 func (h *UserHandler) GetCurrentUser(c *gin.Context) {
+	fields, err := parseFields(c, h.maxFields)
+	if err != nil {
+		fieldsError(c, err)
+		return
+	}
+
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 			Error:   "Unauthorized",
 			Message: "Invalid token",
 		})
@@ -205,37 +507,38 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	userClaims := claims.(*jwt.Claims)
 	user, err := h.userService.GetUserByID(c.Request.Context(), userClaims.UserID)
 	if err != nil {
-		h.logger.Error("Failed to get current user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to get current user", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to get user",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.UserResponse{
-		User:    user.ToPublicUser(),
-		Message: "User retrieved successfully",
-	})
+	h.writeUser(c, user.ToPublicUser(), fields, "User retrieved successfully")
 }
 
 // UpdateUser handles updating user information
 // @Summary Update user
-// @Description Update current user information
+// @Description Update current user information. Changing the username returns a new token carrying it; earlier tokens keep the old username claim until they expire.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param request body dto.UpdateUserRequest true "Update request"
-// @Success 200 {object} dto.UserResponse
-// @Failure 400 {object} dto.ErrorResponse
+// @Success 200 {object} dto.UpdateUserResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Security BearerAuth
 // @Router /users/me [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 			Error:   "Unauthorized",
 			Message: "Invalid token",
 		})
@@ -244,27 +547,50 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	var req dto.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid update request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Error:   "Bad Request",
-			Message: err.Error(),
-		})
+		h.log(c).Error("Invalid update request", zap.Error(err))
+		response.ValidationError(c, err)
 		return
 	}
 
 	userClaims := claims.(*jwt.Claims)
 	user, err := h.userService.UpdateUser(c.Request.Context(), userClaims.UserID, &req)
 	if err != nil {
-		h.logger.Error("Failed to update user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to update user", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserExists) {
+			response.Error(c, http.StatusConflict, dto.ErrorResponse{
+				Error:   "Conflict",
+				Message: "Username already taken",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to update user",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.UserResponse{
+	// The username is a token claim, so hand out a token carrying the new one.
+	// The change is already saved; without a token the client can refresh.
+	var token string
+	if req.Username != nil && user.Username != userClaims.Username {
+		token, err = h.authService.ReissueToken(c.Request.Context(), user)
+		if err != nil {
+			h.log(c).Error("Failed to reissue token after username change",
+				zap.String("user_id", user.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.UpdateUserResponse{
 		User:    user.ToPublicUser(),
+		Token:   token,
 		Message: "User updated successfully",
 	})
 }
@@ -276,12 +602,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
-// @Param size query int false "Page size" default(10)
+// @Param size query int false "Page size, clamped to server.pagination.max_size" default(10)
 // @Param sort query string false "Sort field" default(created_at)
 // @Param order query string false "Sort order (asc/desc)" default(desc)
 // @Param search query string false "Search term"
-// @Param status query string false "User status"
+// @Param status query string false "User status" Enums(active, inactive, suspended, deleted)
 // @Param is_active query bool false "User active status"
+// @Param fields query string false "Comma-separated fields to return for each user"
 // @Success 200 {object} dto.UserListResponse
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
@@ -290,21 +617,31 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	var req dto.UserListRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		h.logger.Error("Invalid list request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+		h.log(c).Error("Invalid list request", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
 			Error:   "Bad Request",
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// Set defaults if not provided
-	if req.Page < 1 {
-		req.Page = 1
+	// Status binds as a plain string, so unknown values must be rejected here
+	if req.Status != "" && !req.Status.IsValid() {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid user status",
+		})
+		return
 	}
-	if req.Size < 1 {
-		req.Size = 10
+
+	fields, err := parseFields(c, h.maxFields)
+	if err != nil {
+		fieldsError(c, err)
+		return
 	}
+
+	// Set defaults if not provided
+	req.Page, req.Size = h.pagination.Normalize(req.Page, req.Size)
 	if req.Sort == "" {
 		req.Sort = "created_at"
 	}
@@ -314,42 +651,190 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	users, total, err := h.userService.ListUsers(c.Request.Context(), &req)
 	if err != nil {
-		h.logger.Error("Failed to list users", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to list users", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to list users",
 		})
 		return
 	}
 
+	pagination := dto.NewPagination(req.Page, req.Size, total)
+
 	// Convert to public users
 	publicUsers := make([]*model.PublicUser, len(users))
 	for i, user := range users {
 		publicUsers[i] = user.ToPublicUser()
 	}
 
-	// Calculate pagination
-	totalPages := int(total) / req.Size
-	if int(total)%req.Size > 0 {
-		totalPages++
+	if fields == nil {
+		c.JSON(http.StatusOK, dto.UserListResponse{
+			Users:      publicUsers,
+			Pagination: pagination,
+			Message:    "Users retrieved successfully",
+		})
+		return
 	}
 
-	pagination := &dto.PaginationResponse{
-		Page:       req.Page,
-		Size:       req.Size,
-		Total:      total,
-		TotalPages: totalPages,
-		HasNext:    req.Page < totalPages,
-		HasPrev:    req.Page > 1,
+	selected := make([]map[string]json.RawMessage, len(publicUsers))
+	for i, user := range publicUsers {
+		if selected[i], err = user.Select(fields); err != nil {
+			h.log(c).Error("Failed to select user fields", zap.Error(err))
+			response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: "Failed to list users",
+			})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, dto.UserListResponse{
-		Users:      publicUsers,
+	c.JSON(http.StatusOK, dto.PartialUserListResponse{
+		Users:      selected,
 		Pagination: pagination,
 		Message:    "Users retrieved successfully",
 	})
 }
 
+// SearchUsers handles searching users with pagination
+// @Summary Search users
+// @Description Search users by username or name, best matches first; admin and support staff also search emails. Words match as prefixes; queries under three characters match substrings instead. Other users' email, phone, admin and verification fields are left out unless the caller is staff.
+// @Tags users
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number" default(1)
+// @Param size query int false "Page size, clamped to server.pagination.max_size" default(10)
+// @Success 200 {object} dto.PartialUserListResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/search [get]
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	var req dto.UserSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.log(c).Error("Invalid search request", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+		return
+	}
+	req.Page, req.Size = h.pagination.Normalize(req.Page, req.Size)
+
+	// Matching emails would let any user find out who has an account
+	users, total, err := h.userService.SearchUsers(c.Request.Context(), &req, isStaff(c))
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to search users", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to search users",
+		})
+		return
+	}
+
+	selected, err := h.selectUserViews(c, users, nil)
+	if err != nil {
+		h.log(c).Error("Failed to select user fields", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to search users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PartialUserListResponse{
+		Users:      selected,
+		Pagination: dto.NewPagination(req.Page, req.Size, total),
+		Message:    "Users retrieved successfully",
+	})
+}
+
+// exportColumns is the header row of the user CSV export
+var exportColumns = []string{"id", "username", "email", "status", "created_at", "last_login_at"}
+
+// ExportUsers handles admin user CSV export
+// @Summary Export users as CSV
+// @Description Stream users matching the list filters as CSV (admin only)
+// @Tags admin
+// @Produce text/csv
+// @Param search query string false "Search term"
+// @Param status query string false "User status"
+// @Param is_active query bool false "Active filter"
+// @Success 200 {file} file
+// @Header 200 {string} Content-Disposition "attachment; filename=users.csv"
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/export [get]
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	var req dto.UserListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.log(c).Error("Invalid export users request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	// Status binds as a plain string, so unknown values must be rejected here
+	if req.Status != "" && !req.Status.IsValid() {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid user status",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users-%s.csv"`, time.Now().UTC().Format("20060102")))
+	c.Status(http.StatusOK)
+
+	// Rows are written straight to the response; once streaming has started
+	// a failure can only be logged and the response cut short
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(exportColumns); err != nil {
+		h.log(c).Error("Failed to write users export header", zap.Error(err))
+		return
+	}
+
+	err := h.userService.ExportUsers(c.Request.Context(), &req, func(user *model.User) error {
+		lastLoginAt := ""
+		if user.LastLoginAt != nil {
+			lastLoginAt = user.LastLoginAt.UTC().Format(time.RFC3339)
+		}
+		return w.Write([]string{
+			user.ID,
+			csvCell(user.Username),
+			csvCell(user.Email),
+			user.GetStatus(),
+			user.CreatedAt.UTC().Format(time.RFC3339),
+			lastLoginAt,
+		})
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil && !h.clientGone(c, err) {
+		h.log(c).Error("Failed to export users", zap.Error(err))
+	}
+}
+
+// csvCell neutralizes a user-supplied value that a spreadsheet would run as
+// a formula, such as =HYPERLINK(...), by prefixing it with a quote
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
 // ChangePassword handles password change
 // @Summary Change password
 // @Description Change current user password
@@ -358,7 +843,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 // @Produce json
 // @Param request body dto.ChangePasswordRequest true "Change password request"
 // @Success 200 {object} dto.SuccessResponse
-// @Failure 400 {object} dto.ErrorResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Security BearerAuth
@@ -366,7 +851,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	claims, exists := c.Get("claims")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 			Error:   "Unauthorized",
 			Message: "Invalid token",
 		})
@@ -375,28 +860,34 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 
 	var req dto.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Error("Invalid change password request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-			Error:   "Bad Request",
-			Message: err.Error(),
-		})
+		h.log(c).Error("Invalid change password request", zap.Error(err))
+		response.ValidationError(c, err)
 		return
 	}
 
 	userClaims := claims.(*jwt.Claims)
-	err := h.authService.ChangePassword(c.Request.Context(), userClaims.UserID, &req)
+	err := h.authService.ChangePassword(c.Request.Context(), userClaims.UserID, c.GetString("token"), &req)
 	if err != nil {
-		h.logger.Error("Failed to change password", zap.Error(err))
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to change password", zap.Error(err))
 
-		if err.Error() == "invalid old password" {
-			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			response.ValidationError(c, err)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrInvalidOldPassword) {
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
 				Error:   "Bad Request",
 				Message: "Invalid old password",
 			})
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
 			Error:   "Internal Server Error",
 			Message: "Failed to change password",
 		})
@@ -407,3 +898,588 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		Message: "Password changed successfully",
 	})
 }
+
+// SetupPassword handles setting a password from a password setup link
+// @Summary Set up password
+// @Description Set the password of a user created in bulk with the one-time token of the link they were emailed. The token works once and expires after auth.password_setup.token_ttl.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.SetupPasswordRequest true "Password setup request"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /users/password-setup [post]
+func (h *UserHandler) SetupPassword(c *gin.Context) {
+	var req dto.SetupPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid password setup request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	if err := h.authService.SetupPassword(c.Request.Context(), &req); err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			response.ValidationError(c, err)
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrInvalidPasswordSetupToken) {
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Password setup link is invalid or expired",
+			})
+			return
+		}
+
+		h.log(c).Error("Failed to set up password", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to set up password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Password set successfully",
+	})
+}
+
+// DeleteAccount handles self-service account deletion
+// @Summary Delete own account
+// @Description Soft delete the current user's account after confirming the password. The token used is invalidated and a confirmation email is sent.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.DeleteAccountRequest true "Current password"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me [delete]
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	var req dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid delete account request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	userClaims := claims.(*jwt.Claims)
+	err := h.authService.DeleteAccount(c.Request.Context(), userClaims.UserID, c.GetString("token"), &req)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to delete account", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrInvalidPassword) {
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Invalid password",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to delete account",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Account deleted successfully",
+	})
+}
+
+// GetNotificationPreferences handles getting the current user's notification preferences
+// @Summary Get notification preferences
+// @Description Get the current user's email notification preferences per category and whether notifications are also texted
+// @Tags users
+// @Produce json
+// @Success 200 {object} dto.NotificationPreferencesResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications [get]
+func (h *UserHandler) GetNotificationPreferences(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	userClaims := claims.(*jwt.Claims)
+	settings, err := h.userService.GetNotificationPreferences(c.Request.Context(), userClaims.UserID)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to get notification preferences", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to get notification preferences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NotificationPreferencesResponse{
+		Preferences: settings.Preferences,
+		SMS:         settings.SMS,
+		Message:     "Notification preferences retrieved successfully",
+	})
+}
+
+// GetLoginActivity handles listing the current user's recent logins
+// @Summary Get login activity
+// @Description Get the current user's recent logins, newest first, flagging logins from a new network or device. Only the latest 100 logins are kept.
+// @Tags users
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param size query int false "Page size, clamped to server.pagination.max_size" default(10)
+// @Success 200 {object} dto.LoginActivityResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/activity [get]
+func (h *UserHandler) GetLoginActivity(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	var req dto.LoginActivityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.log(c).Error("Invalid login activity request", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	req.Page, req.Size = h.pagination.Normalize(req.Page, req.Size)
+
+	userClaims := claims.(*jwt.Claims)
+	logins, total, err := h.userService.ListLoginActivity(c.Request.Context(), userClaims.UserID, req.Page, req.Size)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to list login activity", zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to get login activity",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.LoginActivityResponse{
+		Logins:     logins,
+		Pagination: dto.NewPagination(req.Page, req.Size, total),
+		Message:    "Login activity retrieved successfully",
+	})
+}
+
+// UpdateNotificationPreferences handles updating the current user's notification preferences
+// @Summary Update notification preferences
+// @Description Opt in or out of email notification categories and text messages; security mail cannot be disabled
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body dto.UpdateNotificationPreferencesRequest true "Notification preferences"
+// @Success 200 {object} dto.NotificationPreferencesResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications [put]
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+			Error:   "Unauthorized",
+			Message: "Invalid token",
+		})
+		return
+	}
+
+	var req dto.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid notification preferences request", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userClaims := claims.(*jwt.Claims)
+	settings, err := h.userService.UpdateNotificationPreferences(c.Request.Context(), userClaims.UserID, req.Preferences, req.SMS)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to update notification preferences", zap.Error(err))
+
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidNotificationCategory):
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Invalid notification category",
+			})
+		case errors.Is(err, apperrors.ErrMandatoryNotification):
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Security notifications cannot be disabled",
+			})
+		case errors.Is(err, apperrors.ErrUserNotFound):
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+		default:
+			response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: "Failed to update notification preferences",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NotificationPreferencesResponse{
+		Preferences: settings.Preferences,
+		SMS:         settings.SMS,
+		Message:     "Notification preferences updated successfully",
+	})
+}
+
+// UpdateUserStatus handles admin user status update
+// @Summary Update user status
+// @Description Update a user's status (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body dto.UpdateUserStatusRequest true "Update status request"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/status [put]
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid update status request", zap.Error(err))
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !req.Status.IsValid() {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "Invalid user status",
+		})
+		return
+	}
+
+	user, err := h.userService.UpdateUserStatus(c.Request.Context(), id, req.Status)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to update user status", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to update user status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserResponse{
+		User:    user.ToPublicUser(),
+		Message: "User status updated successfully",
+	})
+}
+
+// RestoreUser handles restoring a soft-deleted user
+// @Summary Restore user
+// @Description Undo the soft delete of a user and reactivate them (admin only). Fails if the user is not deleted, or if another user has since taken their email or username.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id := c.Param("id")
+
+	user, err := h.userService.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to restore user", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "Deleted user not found",
+			})
+			return
+		}
+
+		if errors.Is(err, apperrors.ErrUserExists) {
+			response.Error(c, http.StatusConflict, dto.ErrorResponse{
+				Error:   "Conflict",
+				Message: "Another user now has this email or username",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to restore user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserResponse{
+		User:    user.ToPublicUser(),
+		Message: "User restored successfully",
+	})
+}
+
+// BulkCreateUsers handles admin bulk user creation
+// @Summary Create users in bulk
+// @Description Create up to 100 users at once (admin only). Each user is emailed a one-time link to choose their password, unless a bcrypt or argon2id password_hash is imported with its password_algorithm. Rows are validated and created independently; the response reports each row's outcome with 200 when every row was created, 207 for a mix and 400 when every row failed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body dto.BulkCreateUsersRequest true "Bulk create request"
+// @Success 200 {object} dto.BulkCreateUsersResponse
+// @Success 207 {object} dto.BulkCreateUsersResponse
+// @Failure 400 {object} dto.BulkCreateUsersResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/bulk [post]
+func (h *UserHandler) BulkCreateUsers(c *gin.Context) {
+	var req dto.BulkCreateUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log(c).Error("Invalid bulk create request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	// Rows failing validation are reported without being created
+	results := make([]dto.BulkCreateResult, len(req.Users))
+	valid := make([]dto.BulkCreateUserRequest, 0, len(req.Users))
+	validIndex := make([]int, 0, len(req.Users))
+	for i := range req.Users {
+		if err := binding.Validator.ValidateStruct(&req.Users[i]); err != nil {
+			results[i] = dto.BulkCreateResult{
+				Index:  i,
+				Status: dto.BulkResultFailed,
+				Error:  "validation failed",
+				Fields: response.FieldErrors(err),
+			}
+			continue
+		}
+		valid = append(valid, req.Users[i])
+		validIndex = append(validIndex, i)
+	}
+
+	for i, result := range h.authService.BulkCreateUsers(c.Request.Context(), valid) {
+		result.Index = validIndex[i]
+		results[result.Index] = result
+	}
+
+	resp := dto.BulkCreateUsersResponse{Results: results}
+	for _, result := range results {
+		if result.Status == dto.BulkResultCreated {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+	resp.Message = fmt.Sprintf("Created %d of %d users", resp.Created, len(results))
+
+	c.JSON(response.BulkStatus(dto.BulkSummary{
+		Total:     len(results),
+		Succeeded: resp.Created,
+		Failed:    resp.Failed,
+	}), resp)
+}
+
+// DeleteUser handles admin user deletion
+// @Summary Delete user
+// @Description Soft delete a user, who can be restored (admin only). With hard=true the user is permanently deleted instead, along with their stored sessions, logs and cached data, e.g. for GDPR erasure requests; confirm must repeat the user ID. Soft-deleted users can be hard deleted too.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param hard query bool false "Permanently delete the user"
+// @Param confirm query string false "The user ID again, required with hard=true"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id := c.Param("id")
+
+	var req dto.DeleteUserRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if claims, exists := c.Get("claims"); exists && claims.(*jwt.Claims).UserID == id {
+		response.Error(c, http.StatusConflict, dto.ErrorResponse{
+			Error:   "Conflict",
+			Message: "Cannot delete your own account",
+		})
+		return
+	}
+
+	if req.Hard {
+		h.eraseUser(c, id, req.Confirm)
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), id); err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to delete user", zap.Error(err))
+
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+			return
+		}
+
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: "Failed to delete user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "User deleted successfully",
+	})
+}
+
+// eraseUser permanently deletes the user with id once confirm repeats it
+func (h *UserHandler) eraseUser(c *gin.Context, id, confirm string) {
+	c.Set(response.AuditDetailsKey, map[string]interface{}{"hard": true})
+
+	if confirm != id {
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: "A hard delete must be confirmed by repeating the user ID in confirm",
+		})
+		return
+	}
+
+	if err := h.erasureService.EraseUser(c.Request.Context(), id); err != nil {
+		if h.clientGone(c, err) {
+			return
+		}
+		h.log(c).Error("Failed to erase user", zap.Error(err))
+
+		switch {
+		case errors.Is(err, apperrors.ErrUserNotFound):
+			response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+				Error:   "Not Found",
+				Message: "User not found",
+			})
+		case errors.Is(err, apperrors.ErrUserDataUnavailable):
+			response.Error(c, http.StatusServiceUnavailable, dto.ErrorResponse{
+				Error:   "Service Unavailable",
+				Message: "User data cannot be erased right now",
+			})
+		default:
+			response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+				Error:   "Internal Server Error",
+				Message: "Failed to erase user",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "User permanently deleted",
+	})
+}
@@ -0,0 +1,1831 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/middleware"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testEnv bundles a user handler with its mocked dependencies
+type testEnv struct {
+	handler  *UserHandler
+	repo     *mock.MockUserRepository
+	outbox   *testutils.FakeOutboxRepository
+	redis    *miniredis.Miniredis
+	emails   *fakeEmailQueue
+	userData *fakeUserDataStore
+}
+
+// fakeEmailQueue records enqueued emails, or fails every enqueue when err is set
+type fakeEmailQueue struct {
+	sent []task.EmailPayload
+	err  error
+}
+
+func (q *fakeEmailQueue) EnqueueEmail(ctx context.Context, payload task.EmailPayload) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.sent = append(q.sent, payload)
+	return nil
+}
+
+// fakeUserDataStore records the users whose data was purged
+type fakeUserDataStore struct {
+	purged []string
+}
+
+func (s *fakeUserDataStore) DeleteUserData(ctx context.Context, userID string) (int64, error) {
+	s.purged = append(s.purged, userID)
+	return 1, nil
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	return newTestEnvWithConfig(t, &config.Config{})
+}
+
+func newTestEnvWithConfig(t *testing.T, cfg *config.Config) *testEnv {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	repo.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repository.UserRepository) error) error {
+			return fn(repo)
+		}).AnyTimes()
+	outbox := testutils.NewFakeOutboxRepository()
+	redis, mr := testutils.NewMiniRedis(t)
+	emails := &fakeEmailQueue{}
+	logger := zap.NewNop()
+
+	eventService := service.NewEventService(outbox, logger)
+	userService := service.NewUserService(repo, eventService, testutils.FakeTransactor{}, redis, logger)
+	authService, err := service.NewAuthService(userService, eventService, testutils.FakeTransactor{}, jwt.NewJWT("test-secret", "test", 0), nil, nil, emails, redis, cfg, logger)
+	require.NoError(t, err)
+	userData := &fakeUserDataStore{}
+	erasureService := service.NewErasureService(repo, eventService, testutils.FakeTransactor{}, redis, userData, logger)
+
+	return &testEnv{
+		handler:  NewUserHandler(userService, authService, erasureService, cfg, logger),
+		repo:     repo,
+		outbox:   outbox,
+		redis:    mr,
+		emails:   emails,
+		userData: userData,
+	}
+}
+
+func doJSON(r *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserHandler_Register_Location(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			user.ID = "8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c"
+			return user, nil
+		})
+
+	r := gin.New()
+	r.POST("/register", env.handler.Register)
+
+	w := doJSON(r, http.MethodPost, "/register", map[string]string{
+		"username": "newuser",
+		"email":    "new@example.com",
+		"password": "password123",
+	})
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/users/8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c", w.Header().Get("Location"))
+}
+
+func TestUserHandler_Register_Idempotent(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			user.ID = "8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c"
+			return user, nil
+		})
+
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour}}
+	r := gin.New()
+	r.POST("/register", middleware.NewIdempotencyMiddleware(redis, cfg, zap.NewNop()), env.handler.Register)
+
+	register := func() *httptest.ResponseRecorder {
+		body := `{"username":"newuser","email":"new@example.com","password":"password123"}`
+		req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "signup-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := register()
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+
+	retry := register()
+	assert.Equal(t, first.Code, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+	assert.Len(t, env.outbox.EventsOfType(string(event.UserRegistered)), 1)
+}
+
+func TestUserHandler_Register_WelcomeEmailWarning(t *testing.T) {
+	tests := []struct {
+		name             string
+		enqueueErr       error
+		expectedWarnings []string
+	}{
+		{name: "email queued", expectedWarnings: nil},
+		{name: "email enqueue fails", enqueueErr: errors.New("redis: connection refused"), expectedWarnings: []string{"Welcome email could not be sent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.emails.err = tt.enqueueErr
+			env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+			env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+			env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+					user.ID = "8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c"
+					return user, nil
+				})
+
+			r := gin.New()
+			r.POST("/register", env.handler.Register)
+
+			w := doJSON(r, http.MethodPost, "/register", map[string]string{
+				"username": "newuser",
+				"email":    "new@example.com",
+				"password": "password123",
+			})
+
+			require.Equal(t, http.StatusCreated, w.Code)
+			var resp dto.RegisterResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "8f2c7a8e-3b1d-4a5e-9c6f-1d2e3f4a5b6c", resp.User.ID)
+			assert.NotEmpty(t, resp.Token)
+			assert.Equal(t, tt.expectedWarnings, resp.Warnings)
+			assert.Len(t, env.outbox.EventsOfType(string(event.UserRegistered)), 1)
+
+			if tt.enqueueErr == nil {
+				require.Len(t, env.emails.sent, 1)
+				assert.Equal(t, task.TemplateWelcome, env.emails.sent[0].Template)
+				assert.NotContains(t, w.Body.String(), "warnings")
+			}
+		})
+	}
+}
+
+func TestUserHandler_Register_DuplicateEmailDifferentCase(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "existing@example.com").
+		Return(&model.User{ID: "user-1", Email: "existing@example.com"}, nil)
+
+	r := gin.New()
+	r.POST("/register", env.handler.Register)
+
+	w := doJSON(r, http.MethodPost, "/register", map[string]string{
+		"username": "newuser",
+		"email":    "Existing@Example.COM",
+		"password": "password123",
+	})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, env.outbox.EventsOfType(string(event.UserRegistered)))
+}
+
+func TestUserHandler_Register_ConcurrentDuplicate(t *testing.T) {
+	tests := []struct {
+		name         string
+		createErr    error
+		expectedCode int
+	}{
+		// The pre-checks passed but a concurrent registration took the email first
+		{name: "unique constraint violated", createErr: apperrors.ErrUserExists, expectedCode: http.StatusConflict},
+		{name: "other create failure", createErr: errors.New("failed to create user: connection reset"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+			env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+			env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, tt.createErr)
+
+			r := gin.New()
+			r.POST("/register", env.handler.Register)
+
+			w := doJSON(r, http.MethodPost, "/register", map[string]string{
+				"username": "newuser",
+				"email":    "new@example.com",
+				"password": "password123",
+			})
+
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			assert.Empty(t, env.outbox.EventsOfType(string(event.UserRegistered)))
+		})
+	}
+}
+
+func TestUserHandler_UpdateUserStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		body         map[string]string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+		expectEvent  bool
+	}{
+		{
+			name:   "valid status",
+			userID: "user-1",
+			body:   map[string]string{"status": "suspended"},
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").
+					Return(&model.User{ID: "user-1", Username: "testuser", IsActive: true}, nil)
+				repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, user *model.User) (*model.User, error) {
+						return user, nil
+					})
+			},
+			expectedCode: http.StatusOK,
+			expectEvent:  true,
+		},
+		{
+			name:         "invalid status",
+			userID:       "user-1",
+			body:         map[string]string{"status": "banned"},
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "user not found",
+			userID: "missing",
+			body:   map[string]string{"status": "active"},
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "missing").
+					Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.PUT("/admin/users/:id/status", env.handler.UpdateUserStatus)
+
+			w := doJSON(r, http.MethodPut, "/admin/users/"+tt.userID+"/status", tt.body)
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			events := env.outbox.EventsOfType(string(event.UserStatusChanged))
+			if !tt.expectEvent {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			var statusEvent event.UserStatusChangedEvent
+			require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &statusEvent))
+			assert.Equal(t, "active", statusEvent.OldStatus)
+			assert.Equal(t, "suspended", statusEvent.NewStatus)
+		})
+	}
+}
+
+func TestUserHandler_DeleteUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       string
+		adminID      string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+		expectEvent  bool
+	}{
+		{
+			name:    "successful deletion",
+			userID:  "user-1",
+			adminID: "admin-1",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").
+					Return(&model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}, nil)
+				repo.EXPECT().Delete(gomock.Any(), "user-1").Return(nil)
+			},
+			expectedCode: http.StatusOK,
+			expectEvent:  true,
+		},
+		{
+			name:         "self deletion rejected",
+			userID:       "admin-1",
+			adminID:      "admin-1",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:    "user not found",
+			userID:  "missing",
+			adminID: "admin-1",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "missing").
+					Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+			require.NoError(t, env.redis.Set(cache.UserCacheKeyPrefix+tt.userID, `{}`))
+
+			r := gin.New()
+			r.DELETE("/admin/users/:id", func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: tt.adminID})
+				c.Next()
+			}, env.handler.DeleteUser)
+
+			w := doJSON(r, http.MethodDelete, "/admin/users/"+tt.userID, nil)
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			events := env.outbox.EventsOfType(string(event.UserDeleted))
+			if !tt.expectEvent {
+				assert.Empty(t, events)
+				assert.True(t, env.redis.Exists(cache.UserCacheKeyPrefix+tt.userID))
+				return
+			}
+
+			require.Len(t, events, 1)
+			var deletedEvent event.UserDeletedEvent
+			require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &deletedEvent))
+			assert.Equal(t, "user-1", deletedEvent.UserID)
+			assert.Equal(t, "testuser", deletedEvent.Username)
+			assert.False(t, env.redis.Exists(cache.UserCacheKeyPrefix+tt.userID))
+		})
+	}
+}
+
+func TestUserHandler_UpdateUser_Username(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.repo.EXPECT().GetByID(gomock.Any(), "user-1").
+		Return(&model.User{ID: "user-1", Username: "alice", Email: "alice@example.com", IsActive: true}, nil).Times(3)
+	env.repo.EXPECT().ExistsByUsername(gomock.Any(), "bob").Return(true, nil)
+	env.repo.EXPECT().ExistsByUsername(gomock.Any(), "alice2").Return(false, nil)
+	env.repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, user *model.User) (*model.User, error) {
+			return user, nil
+		}).Times(2)
+
+	r := gin.New()
+	r.PUT("/users/me", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1", Username: "alice"})
+		c.Next()
+	}, env.handler.UpdateUser)
+
+	w := doJSON(r, http.MethodPut, "/users/me", map[string]interface{}{"username": "bob"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var resp dto.UpdateUserResponse
+	w = doJSON(r, http.MethodPut, "/users/me", map[string]interface{}{"first_name": "Alice"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Token)
+
+	w = doJSON(r, http.MethodPut, "/users/me", map[string]interface{}{"username": "alice2"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "alice2", resp.User.Username)
+	assert.NotEmpty(t, resp.Token)
+}
+
+func TestUserHandler_NotificationPreferences(t *testing.T) {
+	env := newTestEnv(t)
+
+	stored := &model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}
+	env.repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(stored, nil).AnyTimes()
+	env.repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, user *model.User) (*model.User, error) {
+			return user, nil
+		}).Times(2)
+
+	r := gin.New()
+	withClaims := func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1"})
+		c.Next()
+	}
+	r.GET("/users/me/notifications", withClaims, env.handler.GetNotificationPreferences)
+	r.PUT("/users/me/notifications", withClaims, env.handler.UpdateNotificationPreferences)
+
+	w := doJSON(r, http.MethodPut, "/users/me/notifications", map[string]interface{}{
+		"preferences": map[string]bool{"marketing": false},
+		"sms":         true,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.NotificationPreferences{model.NotificationMarketing: false}, stored.NotificationPreferences)
+	assert.True(t, stored.SMSNotifications)
+
+	// Omitting sms leaves it unchanged
+	w = doJSON(r, http.MethodPut, "/users/me/notifications", map[string]interface{}{
+		"preferences": map[string]bool{"account": true},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, stored.SMSNotifications)
+
+	w = doJSON(r, http.MethodGet, "/users/me/notifications", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Preferences map[string]bool `json:"preferences"`
+		SMS         bool            `json:"sms"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]bool{"security": true, "account": true, "marketing": false}, resp.Preferences)
+	assert.True(t, resp.SMS)
+
+	// Security mail is mandatory and unknown categories are rejected
+	w = doJSON(r, http.MethodPut, "/users/me/notifications", map[string]interface{}{
+		"preferences": map[string]bool{"security": false},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(r, http.MethodPut, "/users/me/notifications", map[string]interface{}{
+		"preferences": map[string]bool{"newsletter": false},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserHandler_ListUsers_Status(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+	}{
+		{
+			name:  "valid status",
+			query: "?status=active",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().List(gomock.Any(), gomock.Any()).Return([]*model.User{}, int64(0), nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid status",
+			query:        "?status=foo",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.GET("/users", env.handler.ListUsers)
+
+			w := doJSON(r, http.MethodGet, "/users"+tt.query, nil)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestUserHandler_SearchUsers(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		offset         int
+		limit          int
+		expectedCode   int
+		expectedPaging dto.PaginationResponse
+	}{
+		{
+			name:           "first page",
+			query:          "?q=jordan&size=2",
+			offset:         0,
+			limit:          2,
+			expectedCode:   http.StatusOK,
+			expectedPaging: dto.PaginationResponse{Page: 1, Size: 2, Total: 5, TotalPages: 3, HasNext: true},
+		},
+		{
+			name:           "last page",
+			query:          "?q=jordan&page=3&size=2",
+			offset:         4,
+			limit:          2,
+			expectedCode:   http.StatusOK,
+			expectedPaging: dto.PaginationResponse{Page: 3, Size: 2, Total: 5, TotalPages: 3, HasPrev: true},
+		},
+		{
+			name:           "default page size",
+			query:          "?q=jordan",
+			offset:         0,
+			limit:          10,
+			expectedCode:   http.StatusOK,
+			expectedPaging: dto.PaginationResponse{Page: 1, Size: 10, Total: 5, TotalPages: 1},
+		},
+		{
+			name:         "missing query",
+			query:        "?page=1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.expectedCode == http.StatusOK {
+				env.repo.EXPECT().SearchFullText(gomock.Any(), "jordan", false, tt.offset, tt.limit).
+					Return([]*model.User{{ID: "user-1", Username: "jordan"}}, int64(5), nil)
+			}
+
+			r := gin.New()
+			r.GET("/users/search", env.handler.SearchUsers)
+
+			w := doJSON(r, http.MethodGet, "/users/search"+tt.query, nil)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp dto.UserListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Users, 1)
+			assert.Equal(t, "jordan", resp.Users[0].Username)
+			assert.Equal(t, &tt.expectedPaging, resp.Pagination)
+		})
+	}
+}
+
+func TestUserHandler_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   map[string]string
+		handle func(*UserHandler) gin.HandlerFunc
+		field  string
+	}{
+		{
+			name:   "register with short password",
+			method: http.MethodPost,
+			path:   "/register",
+			body:   map[string]string{"username": "newuser", "email": "new@example.com", "password": "short"},
+			handle: func(h *UserHandler) gin.HandlerFunc { return h.Register },
+			field:  "password",
+		},
+		{
+			name:   "change password with short new password",
+			method: http.MethodPut,
+			path:   "/users/me/password",
+			body:   map[string]string{"old_password": "password123", "new_password": "short"},
+			handle: func(h *UserHandler) gin.HandlerFunc { return h.ChangePassword },
+			field:  "new_password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+
+			r := gin.New()
+			r.Handle(tt.method, tt.path, func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: "user-1"})
+				c.Next()
+			}, tt.handle(env.handler))
+
+			w := doJSON(r, tt.method, tt.path, tt.body)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp dto.ValidationErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Fields, 1)
+			assert.Equal(t, tt.field, resp.Fields[0].Field)
+			assert.Equal(t, "min", resp.Fields[0].Rule)
+			assert.Equal(t, tt.field+" must be at least 8 characters long", resp.Fields[0].Message)
+		})
+	}
+}
+
+func TestUserHandler_PasswordPolicy(t *testing.T) {
+	env := newTestEnvWithConfig(t, &config.Config{Security: config.SecurityConfig{
+		Password: config.PasswordPolicyConfig{MinLength: 8, RequireUppercase: true, DenyCommon: true},
+	}})
+
+	r := gin.New()
+	r.POST("/register", env.handler.Register)
+
+	w := doJSON(r, http.MethodPost, "/register", map[string]string{
+		"username": "newuser",
+		"email":    "new@example.com",
+		"password": "password123",
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp dto.ValidationErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []dto.FieldError{
+		{Field: "password", Rule: "uppercase", Message: "password must contain an uppercase letter"},
+		{Field: "password", Rule: "common", Message: "password is too common"},
+	}, resp.Fields)
+}
+
+func TestUserHandler_ListUsers_Fields(t *testing.T) {
+	tooMany := strings.TrimSuffix(strings.Repeat("id,", 5000), ",")
+
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+		expectedKeys []string
+	}{
+		{
+			name:  "selected fields",
+			query: "?fields=id,username",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().List(gomock.Any(), gomock.Any()).
+					Return([]*model.User{{ID: "user-1", Username: "alice", Email: "alice@example.com"}}, int64(1), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedKeys: []string{"id", "username"},
+		},
+		{
+			name:         "over-long fields list",
+			query:        "?fields=" + tooMany,
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "duplicate field",
+			query:        "?fields=id,email,id",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown field",
+			query:        "?fields=id,password_hash",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnvWithConfig(t, &config.Config{Server: config.ServerConfig{MaxFields: 5}})
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.GET("/users", env.handler.ListUsers)
+
+			w := doJSON(r, http.MethodGet, "/users"+tt.query, nil)
+			require.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedKeys == nil {
+				return
+			}
+
+			var resp dto.PartialUserListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Users, 1)
+			keys := make([]string, 0, len(resp.Users[0]))
+			for key := range resp.Users[0] {
+				keys = append(keys, key)
+			}
+			assert.ElementsMatch(t, tt.expectedKeys, keys)
+		})
+	}
+}
+
+func TestUserHandler_BatchGetUsers_Cost(t *testing.T) {
+	ids := func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("user-%d", i)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		ids          []string
+		expectLookup bool
+		expectedCode int
+	}{
+		{
+			name:         "within budget",
+			query:        "?fields=id,username",
+			ids:          ids(50),
+			expectLookup: true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "within ID cap but over budget with fieldset",
+			query:        "?fields=id,username,email,first_name,last_name",
+			ids:          ids(50),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "within ID cap but over budget with all fields",
+			ids:          ids(50),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "over ID cap",
+			query:        "?fields=id",
+			ids:          ids(101),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnvWithConfig(t, &config.Config{Server: config.ServerConfig{MaxBatchCost: 200}})
+			if tt.expectLookup {
+				env.repo.EXPECT().GetByIDs(gomock.Any(), tt.ids).
+					Return([]*model.User{{ID: "user-0", Username: "alice"}}, nil)
+			}
+
+			r := gin.New()
+			r.POST("/users/batch", env.handler.BatchGetUsers)
+
+			w := doJSON(r, http.MethodPost, "/users/batch"+tt.query, dto.BatchGetUsersRequest{IDs: tt.ids})
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if !tt.expectLookup {
+				return
+			}
+
+			var resp dto.PartialBatchUsersResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Users, 1)
+			assert.Len(t, resp.Users[0], 2)
+		})
+	}
+}
+
+func TestUserHandler_BatchGetUsers_CacheAndMissing(t *testing.T) {
+	env := newTestEnv(t)
+	r := gin.New()
+	r.POST("/users/batch", env.handler.BatchGetUsers)
+
+	type batchResponse struct {
+		Users   []*model.PublicUser `json:"users"`
+		Missing []string            `json:"missing"`
+	}
+	lookup := func(ids ...string) batchResponse {
+		t.Helper()
+		w := doJSON(r, http.MethodPost, "/users/batch", dto.BatchGetUsersRequest{IDs: ids})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp batchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	usernames := func(resp batchResponse) []string {
+		names := make([]string, len(resp.Users))
+		for i, user := range resp.Users {
+			names[i] = user.Username
+		}
+		return names
+	}
+
+	alice := &model.User{ID: "user-1", Username: "alice"}
+	bob := &model.User{ID: "user-2", Username: "bob"}
+	carol := &model.User{ID: "user-3", Username: "carol"}
+
+	// Cold cache: every ID is loaded in one query
+	env.repo.EXPECT().GetByIDs(gomock.Any(), []string{"user-1", "user-2", "ghost"}).Return([]*model.User{bob, alice}, nil)
+	resp := lookup("user-1", "user-2", "ghost")
+	assert.Equal(t, []string{"alice", "bob"}, usernames(resp))
+	assert.Equal(t, []string{"ghost"}, resp.Missing)
+
+	// Warm cache: only the misses are queried, duplicates once
+	env.repo.EXPECT().GetByIDs(gomock.Any(), []string{"user-3", "ghost"}).Return([]*model.User{carol}, nil)
+	resp = lookup("user-3", "user-1", "ghost", "user-1", "ghost")
+	assert.Equal(t, []string{"carol", "alice"}, usernames(resp))
+	assert.Equal(t, []string{"ghost"}, resp.Missing)
+}
+
+func TestUserHandler_BatchGetUsers_Views(t *testing.T) {
+	env := newTestEnv(t)
+	r := gin.New()
+	r.POST("/users/batch", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1"})
+		c.Next()
+	}, env.handler.BatchGetUsers)
+
+	phone := "+1234567890"
+	env.repo.EXPECT().GetByIDs(gomock.Any(), []string{"user-1", "user-2"}).Return([]*model.User{
+		{ID: "user-1", Username: "alice", Email: "alice@example.com", IsActive: true},
+		{ID: "user-2", Username: "bob", Email: "bob@example.com", Phone: &phone, IsAdmin: true, IsActive: true},
+	}, nil)
+
+	w := doJSON(r, http.MethodPost, "/users/batch?fields=username,email,phone,is_admin", dto.BatchGetUsersRequest{IDs: []string{"user-1", "user-2"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Users, 2)
+
+	// The caller sees their own email, but only the public fields of others
+	assert.Equal(t, "alice@example.com", resp.Users[0]["email"])
+	assert.Equal(t, map[string]interface{}{"username": "bob"}, resp.Users[1])
+}
+
+func TestUserHandler_BatchGetUsers_MaxIDs(t *testing.T) {
+	env := newTestEnv(t)
+	r := gin.New()
+	r.POST("/users/batch", env.handler.BatchGetUsers)
+
+	ids := make([]string, maxBatchIDs+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+	}
+
+	// Rejected before any lookup
+	w := doJSON(r, http.MethodPost, "/users/batch?fields=id", dto.BatchGetUsersRequest{IDs: ids})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	env.repo.EXPECT().GetByIDs(gomock.Any(), ids[:maxBatchIDs]).Return(nil, nil)
+	w = doJSON(r, http.MethodPost, "/users/batch?fields=id", dto.BatchGetUsersRequest{IDs: ids[:maxBatchIDs]})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp dto.PartialBatchUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Users)
+	assert.Len(t, resp.Missing, maxBatchIDs)
+}
+
+func TestUserHandler_CheckAvailability(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+		available    bool
+	}{
+		{
+			name:  "username available",
+			query: "?username=newuser",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().ExistsByUsername(gomock.Any(), "newuser").Return(false, nil)
+			},
+			expectedCode: http.StatusOK,
+			available:    true,
+		},
+		{
+			name:  "username taken",
+			query: "?username=takenuser",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().ExistsByUsername(gomock.Any(), "takenuser").Return(true, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "email normalized as in registration",
+			query: "?email=Taken@Example.com",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "taken@example.com").Return(true, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "email available",
+			query: "?email=new@example.com",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "new@example.com").Return(false, nil)
+			},
+			expectedCode: http.StatusOK,
+			available:    true,
+		},
+		{
+			name:         "username too short",
+			query:        "?username=ab",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "malformed email",
+			query:        "?email=not-an-email",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "neither given",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "both given",
+			query:        "?username=newuser&email=new@example.com",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.GET("/users/check-availability", env.handler.CheckAvailability)
+
+			w := doJSON(r, http.MethodGet, "/users/check-availability"+tt.query, nil)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			// The response says nothing beyond availability
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, map[string]interface{}{"available": tt.available}, resp)
+		})
+	}
+}
+
+func TestUserHandler_HardDeleteUser(t *testing.T) {
+	user := &model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}
+
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(*mock.MockUserRepository)
+		expectedCode int
+		expectPurge  bool
+		expectEvent  bool
+	}{
+		{
+			name:  "erased",
+			query: "?hard=true&confirm=user-1",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil)
+				repo.EXPECT().HardDelete(gomock.Any(), "user-1").Return(nil)
+			},
+			expectedCode: http.StatusOK,
+			expectPurge:  true,
+			expectEvent:  true,
+		},
+		{
+			name:  "soft-deleted user erased",
+			query: "?hard=true&confirm=user-1",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(user, nil)
+				repo.EXPECT().HardDelete(gomock.Any(), "user-1").Return(nil)
+			},
+			expectedCode: http.StatusOK,
+			expectPurge:  true,
+		},
+		{
+			name:         "unconfirmed",
+			query:        "?hard=true",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "confirmation names another user",
+			query:        "?hard=true&confirm=user-2",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "user not found",
+			query: "?hard=true&confirm=user-1",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+			require.NoError(t, env.redis.Set(cache.UserCacheKeyPrefix+"user-1", `{}`))
+			_, err := env.redis.Lpush(cache.LoginActivityKey("user-1"), `{}`)
+			require.NoError(t, err)
+
+			r := gin.New()
+			var details interface{}
+			r.DELETE("/admin/users/:id", func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: "admin-1"})
+				c.Next()
+				details, _ = c.Get(response.AuditDetailsKey)
+			}, env.handler.DeleteUser)
+
+			w := doJSON(r, http.MethodDelete, "/admin/users/user-1"+tt.query, nil)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			assert.Equal(t, map[string]interface{}{"hard": true}, details)
+
+			if tt.expectPurge {
+				assert.Equal(t, []string{"user-1"}, env.userData.purged)
+				assert.False(t, env.redis.Exists(cache.UserCacheKeyPrefix+"user-1"))
+				assert.False(t, env.redis.Exists(cache.LoginActivityKey("user-1")))
+			} else {
+				assert.Empty(t, env.userData.purged)
+				assert.True(t, env.redis.Exists(cache.LoginActivityKey("user-1")))
+			}
+
+			events := env.outbox.EventsOfType(string(event.UserDeleted))
+			if tt.expectEvent {
+				assert.Len(t, events, 1)
+			} else {
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
+
+func TestUserHandler_HardDeleteUser_StoreUnavailable(t *testing.T) {
+	env := newTestEnv(t)
+	env.handler.erasureService = service.NewErasureService(env.repo, nil, nil, nil, nil, zap.NewNop())
+
+	r := gin.New()
+	r.DELETE("/admin/users/:id", env.handler.DeleteUser)
+
+	w := doJSON(r, http.MethodDelete, "/admin/users/user-1?hard=true&confirm=user-1", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestUserHandler_RestoreUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		setupMock    func(repo *mock.MockUserRepository)
+		expectedCode int
+	}{
+		{
+			name: "restored",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(&model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"}, nil)
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "alice@example.com").Return(false, nil)
+				repo.EXPECT().ExistsByUsername(gomock.Any(), "alice").Return(false, nil)
+				repo.EXPECT().Restore(gomock.Any(), "user-1").Return(nil)
+				repo.EXPECT().UpdateActiveStatus(gomock.Any(), "user-1", true).Return(nil)
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(&model.User{ID: "user-1", Username: "alice", IsActive: true}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "not deleted or unknown",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "email taken",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(&model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"}, nil)
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "alice@example.com").Return(true, nil)
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.POST("/admin/users/:id/restore", env.handler.RestoreUser)
+
+			w := doJSON(r, http.MethodPost, "/admin/users/user-1/restore", nil)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_DeleteAccount(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		password     string
+		deleted      bool
+		expectedCode int
+	}{
+		{name: "deleted", password: "alice-password", deleted: true, expectedCode: http.StatusOK},
+		{name: "wrong password", password: "guess", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.repo.EXPECT().GetByID(gomock.Any(), "user-1").
+				Return(&model.User{ID: "user-1", Username: "alice", PasswordHash: string(hash), IsActive: true}, nil).AnyTimes()
+			if tt.deleted {
+				env.repo.EXPECT().Delete(gomock.Any(), "user-1").Return(nil)
+			}
+
+			r := gin.New()
+			r.DELETE("/users/me", func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: "user-1"})
+				c.Set("token", "token-1")
+				c.Next()
+			}, env.handler.DeleteAccount)
+
+			w := doJSON(r, http.MethodDelete, "/users/me", map[string]string{"password": tt.password})
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_GetUser_Views(t *testing.T) {
+	lastLogin := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	phone := "+1234567890"
+	const (
+		aliceID = "8d3c6f2e-5b1a-4c7e-9f0d-2a4b6c8e0f13"
+		adminID = "1f0e2d3c-4b5a-4697-8879-6a5b4c3d2e1f"
+		bobID   = "c2b7e1a4-93d6-4f58-a0e2-7b9d3c5f1e86"
+	)
+	users := map[string]*model.User{
+		aliceID: {ID: aliceID, Username: "alice", Email: "alice@example.com", Phone: &phone, EmailVerified: true, LastLoginAt: &lastLogin, IsActive: true},
+		adminID: {ID: adminID, Username: "admin", Email: "admin@example.com", IsAdmin: true, IsActive: true},
+		bobID:   {ID: bobID, Username: "bob", Email: "bob@example.com", IsActive: true},
+	}
+	ownerOnly := []string{"email", "phone", "is_admin", "email_verified", "phone_verified", "last_login_at"}
+
+	tests := []struct {
+		name      string
+		callerID  string
+		query     string
+		wantOwner bool
+	}{
+		{name: "owner", callerID: aliceID, wantOwner: true},
+		{name: "admin", callerID: adminID, wantOwner: true},
+		{name: "stranger", callerID: bobID},
+		{name: "anonymous"},
+		{name: "stranger with fields", callerID: bobID, query: "?fields=username,email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.repo.EXPECT().GetByID(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, id string) (*model.User, error) {
+					return users[id], nil
+				}).AnyTimes()
+
+			r := gin.New()
+			r.GET("/users/:id", func(c *gin.Context) {
+				if tt.callerID != "" {
+					c.Set("claims", &jwt.Claims{UserID: tt.callerID, IsAdmin: users[tt.callerID].IsAdministrator()})
+				}
+				c.Next()
+			}, env.handler.GetUser)
+
+			w := doJSON(r, http.MethodGet, "/users/"+aliceID+tt.query, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var resp struct {
+				User map[string]interface{} `json:"user"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "alice", resp.User["username"])
+			if tt.wantOwner {
+				assert.Equal(t, "alice@example.com", resp.User["email"])
+				assert.Equal(t, true, resp.User["email_verified"])
+				assert.Contains(t, resp.User, "last_login_at")
+				assert.Contains(t, resp.User, "is_admin")
+				return
+			}
+			for _, field := range ownerOnly {
+				assert.NotContains(t, resp.User, field)
+			}
+		})
+	}
+}
+
+func TestUserHandler_SearchUsers_Views(t *testing.T) {
+	phone := "+1234567890"
+	ownerOnly := []string{"email", "phone", "is_admin", "email_verified", "phone_verified", "last_login_at"}
+
+	tests := []struct {
+		name      string
+		claims    *jwt.Claims
+		withEmail bool
+		owners    []bool
+	}{
+		{name: "stranger", claims: &jwt.Claims{UserID: "user-3"}, owners: []bool{false, false}},
+		{name: "one of the matches", claims: &jwt.Claims{UserID: "user-1"}, owners: []bool{true, false}},
+		{name: "support", claims: &jwt.Claims{UserID: "user-3", Roles: []string{model.RoleSupport}}, withEmail: true, owners: []bool{true, true}},
+		{name: "admin", claims: &jwt.Claims{UserID: "user-3", IsAdmin: true}, withEmail: true, owners: []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			// Only staff search emails
+			env.repo.EXPECT().SearchFullText(gomock.Any(), "jordan", tt.withEmail, 0, 10).
+				Return([]*model.User{
+					{ID: "user-1", Username: "jordan", Email: "jordan@example.com", Phone: &phone, IsActive: true},
+					{ID: "user-2", Username: "jordan_w", Email: "jw@example.com", IsActive: true},
+				}, int64(2), nil)
+
+			r := gin.New()
+			r.GET("/users/search", func(c *gin.Context) {
+				c.Set("claims", tt.claims)
+				c.Next()
+			}, env.handler.SearchUsers)
+
+			w := doJSON(r, http.MethodGet, "/users/search?q=jordan", nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var resp struct {
+				Users []map[string]interface{} `json:"users"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Users, 2)
+			for i, owner := range tt.owners {
+				assert.Contains(t, resp.Users[i], "username")
+				if owner {
+					assert.Contains(t, resp.Users[i], "email", i)
+					continue
+				}
+				for _, field := range ownerOnly {
+					assert.NotContains(t, resp.Users[i], field, i)
+				}
+			}
+		})
+	}
+}
+
+func TestUserHandler_LookupUser(t *testing.T) {
+	alice := &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com", IsActive: true}
+
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(repo *mock.MockUserRepository)
+		expectedCode int
+	}{
+		{
+			name:  "by email",
+			query: "?email=Alice@Example.com",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByEmail(gomock.Any(), "alice@example.com").Return(alice, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "by username",
+			query: "?username=alice",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByUsername(gomock.Any(), "alice").Return(alice, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "not found",
+			query: "?username=nobody",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByUsername(gomock.Any(), "nobody").Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "missing params",
+			query:        "?email=",
+			setupMock:    func(repo *mock.MockUserRepository) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setupMock(env.repo)
+
+			r := gin.New()
+			r.GET("/admin/users/lookup", func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: "admin-1", Roles: []string{model.RoleAdmin}})
+				c.Next()
+			}, env.handler.LookupUser)
+
+			w := doJSON(r, http.MethodGet, "/admin/users/lookup"+tt.query, nil)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				User map[string]interface{} `json:"user"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "user-1", resp.User["id"])
+			assert.Equal(t, "alice@example.com", resp.User["email"])
+		})
+	}
+}
+
+func TestUserHandler_ClientCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	env.handler.logger = zap.New(core)
+	env.repo.EXPECT().GetByID(gomock.Any(), "5a9e0c3b-7d21-4f86-b4e3-0c1d2e3f4a5b").
+		Return(nil, fmt.Errorf("failed to get user by ID: %w", context.Canceled))
+
+	r := gin.New()
+	r.GET("/users/:id", env.handler.GetUser)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+"5a9e0c3b-7d21-4f86-b4e3-0c1d2e3f4a5b", nil).WithContext(ctx))
+
+	assert.NotEqual(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Zero(t, logs.FilterLevelExact(zapcore.ErrorLevel).Len())
+	assert.Equal(t, 1, logs.FilterMessage("Request canceled by client").Len())
+}
+
+func TestUserHandler_Register_EmailDomain(t *testing.T) {
+	env := newTestEnvWithConfig(t, &config.Config{Auth: config.AuthConfig{BlockDisposableEmails: true}})
+
+	r := gin.New()
+	r.POST("/register", env.handler.Register)
+
+	w := doJSON(r, http.MethodPost, "/register", map[string]string{
+		"username": "newuser",
+		"email":    "newuser@mailinator.com",
+		"password": "password123",
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp dto.ValidationErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []dto.FieldError{
+		{Field: "email", Rule: "email_domain", Message: "email addresses at mailinator.com are not allowed"},
+	}, resp.Fields)
+}
+
+func TestUserHandler_BulkCreateUsers_PartialFailure(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			user.ID = "user-new"
+			return user, nil
+		})
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "existing@example.com").
+		Return(&model.User{ID: "user-1", Email: "existing@example.com"}, nil)
+
+	r := gin.New()
+	r.POST("/admin/users/bulk", env.handler.BulkCreateUsers)
+
+	w := doJSON(r, http.MethodPost, "/admin/users/bulk", map[string]interface{}{
+		"users": []map[string]string{
+			{"username": "newuser", "email": "new@example.com"},
+			{"username": "existing", "email": "existing@example.com"},
+			{"username": "newuser2", "email": "NEW@example.com"},
+			{"username": "bad", "email": "not-an-email"},
+		},
+	})
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+
+	var resp dto.BulkCreateUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Created)
+	assert.Equal(t, 3, resp.Failed)
+	require.Len(t, resp.Results, 4)
+
+	assert.Equal(t, dto.BulkResultCreated, resp.Results[0].Status)
+	assert.Equal(t, "user-new", resp.Results[0].User.ID)
+
+	assert.Equal(t, dto.BulkResultFailed, resp.Results[1].Status)
+	assert.Equal(t, "user with email existing@example.com already exists", resp.Results[1].Error)
+
+	assert.Equal(t, dto.BulkResultFailed, resp.Results[2].Status)
+	assert.Equal(t, "email appears more than once in the request", resp.Results[2].Error)
+
+	assert.Equal(t, 3, resp.Results[3].Index)
+	assert.Equal(t, dto.BulkResultFailed, resp.Results[3].Status)
+	require.Len(t, resp.Results[3].Fields, 1)
+	assert.Equal(t, "email", resp.Results[3].Fields[0].Field)
+
+	// Only the created user is sent a password setup email
+	require.Len(t, env.emails.sent, 1)
+	assert.Equal(t, "new@example.com", env.emails.sent[0].To)
+	assert.Equal(t, task.TemplatePasswordSetup, env.emails.sent[0].Template)
+	assert.NotContains(t, env.emails.sent[0].Data, "password")
+	assert.Contains(t, env.emails.sent[0].Data["link"], "token=")
+	assert.Len(t, env.outbox.EventsOfType(string(event.UserRegistered)), 1)
+}
+
+func TestUserHandler_BulkCreateUsers_AllFailed(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "existing@example.com").
+		Return(&model.User{ID: "user-1", Email: "existing@example.com"}, nil)
+
+	r := gin.New()
+	r.POST("/admin/users/bulk", env.handler.BulkCreateUsers)
+
+	w := doJSON(r, http.MethodPost, "/admin/users/bulk", map[string]interface{}{
+		"users": []map[string]string{
+			{"username": "existing", "email": "existing@example.com"},
+			{"username": "bad", "email": "not-an-email"},
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp dto.BulkCreateUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Created)
+	assert.Equal(t, 2, resp.Failed)
+	assert.Empty(t, env.emails.sent)
+}
+
+func TestUserHandler_SetupPassword(t *testing.T) {
+	env := newTestEnvWithConfig(t, &config.Config{
+		Auth: config.AuthConfig{
+			PasswordSetup: config.PasswordSetupConfig{URL: "https://app.example.com/password-setup?lang=en", TokenTTL: time.Hour},
+		},
+		Security: config.SecurityConfig{Password: config.PasswordPolicyConfig{RequireDigit: true}},
+	})
+
+	var created *model.User
+	env.repo.EXPECT().GetByEmail(gomock.Any(), "new@example.com").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().GetByUsername(gomock.Any(), "newuser").Return(nil, apperrors.ErrUserNotFound)
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			user.ID = "user-new"
+			created = user
+			return user, nil
+		})
+
+	r := gin.New()
+	r.POST("/admin/users/bulk", env.handler.BulkCreateUsers)
+	r.POST("/users/password-setup", env.handler.SetupPassword)
+
+	w := doJSON(r, http.MethodPost, "/admin/users/bulk", map[string]interface{}{
+		"users": []map[string]string{{"username": "newuser", "email": "new@example.com"}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, env.emails.sent, 1)
+
+	link, err := url.Parse(env.emails.sent[0].Data["link"])
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", link.Host)
+	assert.Equal(t, "en", link.Query().Get("lang"))
+	token := link.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	// Only a hash of the token is stored, for the configured lifetime
+	keys := env.redis.Keys()
+	require.Len(t, keys, 1)
+	assert.NotContains(t, keys[0], token)
+	assert.Equal(t, time.Hour, env.redis.TTL(keys[0]))
+
+	// A password rejected by the policy does not spend the token
+	w = doJSON(r, http.MethodPost, "/users/password-setup", dto.SetupPasswordRequest{Token: token, Password: "password"})
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	env.repo.EXPECT().GetByID(gomock.Any(), "user-new").Return(created, nil)
+	env.repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) { return user, nil })
+
+	w = doJSON(r, http.MethodPost, "/users/password-setup", dto.SetupPasswordRequest{Token: token, Password: "n3w-Secret-pass"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(created.PasswordHash), []byte("n3w-Secret-pass")))
+	assert.Len(t, env.outbox.EventsOfType(string(event.UserPasswordChanged)), 1)
+
+	// The link works once
+	w = doJSON(r, http.MethodPost, "/users/password-setup", dto.SetupPasswordRequest{Token: token, Password: "an0ther-Secret-pass"})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Password setup link is invalid or expired")
+}
+
+func TestUserHandler_BulkCreateUsers_PreHashedPasswords(t *testing.T) {
+	env := newTestEnv(t)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	argonHasher, err := service.NewPasswordHasher(config.SecurityConfig{
+		PasswordAlgorithm: service.HashAlgorithmArgon2id,
+		Argon2:            config.Argon2Config{Memory: 1024, Iterations: 1, Parallelism: 1},
+	})
+	require.NoError(t, err)
+	argonHash, err := argonHasher.Hash("bob-password")
+	require.NoError(t, err)
+
+	created := make(map[string]*model.User)
+	for _, name := range []string{"alice", "bob"} {
+		env.repo.EXPECT().GetByEmail(gomock.Any(), name+"@example.com").Return(nil, apperrors.ErrUserNotFound)
+		env.repo.EXPECT().GetByUsername(gomock.Any(), name).Return(nil, apperrors.ErrUserNotFound)
+	}
+	env.repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+			user.ID = "user-" + user.Username
+			created[user.Email] = user
+			return user, nil
+		}).Times(2)
+
+	r := gin.New()
+	r.POST("/admin/users/bulk", env.handler.BulkCreateUsers)
+	r.POST("/login", env.handler.Login)
+
+	w := doJSON(r, http.MethodPost, "/admin/users/bulk", map[string]interface{}{
+		"users": []map[string]string{
+			{"username": "alice", "email": "alice@example.com", "password_hash": string(bcryptHash), "password_algorithm": "bcrypt"},
+			{"username": "bob", "email": "bob@example.com", "password_hash": argonHash, "password_algorithm": "argon2id"},
+			{"username": "carol", "email": "carol@example.com", "password_hash": "not-a-hash", "password_algorithm": "bcrypt"},
+			{"username": "dave", "email": "dave@example.com", "password_hash": string(bcryptHash)},
+		},
+	})
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+
+	var resp dto.BulkCreateUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Created)
+	assert.Equal(t, "password_hash is not a valid bcrypt hash", resp.Results[2].Error)
+	require.Len(t, resp.Results[3].Fields, 1)
+	assert.Equal(t, "password_algorithm", resp.Results[3].Fields[0].Field)
+
+	// Hashes are stored as is and no password setup email is sent
+	assert.Equal(t, string(bcryptHash), created["alice@example.com"].PasswordHash)
+	assert.Equal(t, argonHash, created["bob@example.com"].PasswordHash)
+	assert.Empty(t, env.emails.sent)
+
+	// Both users log in with their old passwords, and the hashes are upgraded
+	// to the configured bcrypt cost
+	for email, password := range map[string]string{"alice@example.com": "alice-password", "bob@example.com": "bob-password"} {
+		user := created[email]
+		imported := user.PasswordHash
+		env.repo.EXPECT().GetByEmail(gomock.Any(), email).Return(user, nil)
+		env.repo.EXPECT().GetByID(gomock.Any(), user.ID).DoAndReturn(func(context.Context, string) (*model.User, error) {
+			current := *user
+			return &current, nil
+		})
+		env.repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) { return u, nil })
+
+		w := doJSON(r, http.MethodPost, "/login", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, w.Code, email)
+
+		assert.NotEqual(t, imported, user.PasswordHash, email)
+		cost, err := bcrypt.Cost([]byte(user.PasswordHash))
+		require.NoError(t, err)
+		assert.Equal(t, bcrypt.DefaultCost, cost, email)
+	}
+}
+
+func TestUserHandler_ExportUsers(t *testing.T) {
+	env := newTestEnv(t)
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	lastLoginAt := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	seeded := []*model.User{
+		{ID: "user-1", Username: "alice", Email: "alice@example.com", PasswordHash: "secret-hash", IsActive: true, CreatedAt: createdAt, LastLoginAt: &lastLoginAt},
+		{ID: "user-2", Username: "bob", Email: "bob@example.com", PasswordHash: "secret-hash", CreatedAt: createdAt},
+		{ID: "user-3", Username: "=HYPERLINK(\"https://evil.example\")", Email: "+1@example.com", PasswordHash: "secret-hash", CreatedAt: createdAt},
+	}
+	env.repo.EXPECT().Iterate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *dto.UserListRequest, _ int, fn func([]*model.User) error) error {
+			assert.Equal(t, "example", req.Search)
+			require.NotNil(t, req.IsActive)
+			// Deliver the users in two batches
+			for _, user := range seeded {
+				if err := fn([]*model.User{user}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	r := gin.New()
+	r.GET("/admin/users/export", env.handler.ExportUsers)
+
+	w := doJSON(r, http.MethodGet, "/admin/users/export?search=example&is_active=true", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"users-")
+
+	body := w.Body.String()
+	assert.NotContains(t, body, "secret-hash")
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"id", "username", "email", "status", "created_at", "last_login_at"}, records[0])
+	assert.Equal(t, []string{"user-1", "alice", "alice@example.com", "active", "2024-01-02T03:04:05Z", "2024-02-03T04:05:06Z"}, records[1])
+	assert.Equal(t, []string{"user-2", "bob", "bob@example.com", "inactive", "2024-01-02T03:04:05Z", ""}, records[2])
+
+	// Values a spreadsheet would run as formulas are quoted
+	assert.Equal(t, `'=HYPERLINK("https://evil.example")`, records[3][1])
+	assert.Equal(t, "'+1@example.com", records[3][2])
+}
+
+func TestUserHandler_ErrorMapping(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	withClaims := func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1"})
+		c.Set("token", "token-1")
+		c.Next()
+	}
+
+	tests := []struct {
+		name         string
+		user         *model.User
+		lookupErr    error
+		method       string
+		path         string
+		body         interface{}
+		expectedCode int
+	}{
+		{
+			name:         "login with unknown email",
+			lookupErr:    apperrors.ErrUserNotFound,
+			method:       http.MethodPost,
+			path:         "/users/login",
+			body:         map[string]string{"email": "bob@example.com", "password": "alice-password"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "login with wrong password",
+			user:         &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: string(hash), IsActive: true},
+			method:       http.MethodPost,
+			path:         "/users/login",
+			body:         map[string]string{"email": "alice@example.com", "password": "guess-password"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "login to inactive account",
+			user:         &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: string(hash)},
+			method:       http.MethodPost,
+			path:         "/users/login",
+			body:         map[string]string{"email": "alice@example.com", "password": "alice-password"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "login when the lookup fails",
+			lookupErr:    errors.New("connection refused"),
+			method:       http.MethodPost,
+			path:         "/users/login",
+			body:         map[string]string{"email": "alice@example.com", "password": "alice-password"},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "wrong old password",
+			user:         &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: string(hash), IsActive: true},
+			method:       http.MethodPut,
+			path:         "/users/me/password",
+			body:         map[string]string{"old_password": "guess-password", "new_password": "New-Password-3"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "user not found",
+			lookupErr:    apperrors.ErrUserNotFound,
+			method:       http.MethodGet,
+			path:         "/users/5a9e0c3b-7d21-4f86-b4e3-0c1d2e3f4a5b",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "user ID is not a UUID",
+			method:       http.MethodGet,
+			path:         "/users/42",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.repo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(tt.user, tt.lookupErr).AnyTimes()
+			env.repo.EXPECT().GetByID(gomock.Any(), gomock.Any()).Return(tt.user, tt.lookupErr).AnyTimes()
+
+			r := gin.New()
+			r.POST("/users/login", env.handler.Login)
+			r.PUT("/users/me/password", withClaims, env.handler.ChangePassword)
+			r.GET("/users/:id", env.handler.GetUser)
+
+			w := doJSON(r, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_GetLoginActivity(t *testing.T) {
+	env := newTestEnvWithConfig(t, &config.Config{Server: config.ServerConfig{
+		Pagination: config.PaginationConfig{DefaultSize: 10, MaxSize: 10},
+	}})
+
+	// Seed 12 logins, oldest first, as the Kafka consumer records them
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		entry, err := json.Marshal(model.LoginActivity{
+			Time:      start.Add(time.Duration(i) * time.Hour),
+			IPAddress: fmt.Sprintf("192.0.2.%d", i),
+			UserAgent: "curl/8.0",
+			Anomalous: i == 11,
+		})
+		require.NoError(t, err)
+		_, err = env.redis.Lpush(cache.LoginActivityKey("user-1"), string(entry))
+		require.NoError(t, err)
+	}
+
+	r := gin.New()
+	r.GET("/users/me/activity", func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: "user-1"})
+		c.Next()
+	}, env.handler.GetLoginActivity)
+
+	type activityResponse struct {
+		Logins     []model.LoginActivity   `json:"logins"`
+		Pagination *dto.PaginationResponse `json:"pagination"`
+	}
+
+	// Newest first
+	w := doJSON(r, http.MethodGet, "/users/me/activity?size=5", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp activityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Logins, 5)
+	for i, login := range resp.Logins {
+		assert.Equal(t, start.Add(time.Duration(11-i)*time.Hour), login.Time)
+	}
+	assert.True(t, resp.Logins[0].Anomalous)
+	assert.False(t, resp.Logins[1].Anomalous)
+	assert.Equal(t, &dto.PaginationResponse{Page: 1, Size: 5, Total: 12, TotalPages: 3, HasNext: true}, resp.Pagination)
+
+	// The last page holds the oldest logins
+	w = doJSON(r, http.MethodGet, "/users/me/activity?size=5&page=3", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = activityResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Logins, 2)
+	assert.Equal(t, "192.0.2.1", resp.Logins[0].IPAddress)
+	assert.Equal(t, "192.0.2.0", resp.Logins[1].IPAddress)
+
+	// Page size is clamped
+	w = doJSON(r, http.MethodGet, "/users/me/activity?size=500", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = activityResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Logins, 10)
+	assert.Equal(t, 10, resp.Pagination.Size)
+}
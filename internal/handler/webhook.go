@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler handles outbound webhook subscription administration
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	logger         *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *service.WebhookService, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// ListWebhooks handles listing webhook subscriptions
+// @Summary List webhooks
+// @Description List the outbound webhook subscriptions (admin only)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} dto.WebhookListResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebhookListResponse{
+		Webhooks: webhooks,
+		Message:  "Webhooks retrieved successfully",
+	})
+}
+
+// CreateWebhook handles subscribing a URL to user events
+// @Summary Create webhook
+// @Description Subscribe a URL to user events (admin only). Deliveries are signed with the returned secret, which is not shown again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateWebhookRequest true "Webhook subscription"
+// @Success 201 {object} dto.WebhookResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid create webhook request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		h.writeError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.WebhookResponse{
+		Webhook: webhook,
+		Secret:  webhook.Secret,
+		Message: "Webhook created successfully",
+	})
+}
+
+// GetWebhook handles getting a webhook subscription
+// @Summary Get webhook
+// @Description Get an outbound webhook subscription (admin only)
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.writeError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebhookResponse{
+		Webhook: webhook,
+		Message: "Webhook retrieved successfully",
+	})
+}
+
+// UpdateWebhook handles changing a webhook subscription
+// @Summary Update webhook
+// @Description Change an outbound webhook subscription (admin only). Setting active to true re-enables a subscription disabled after failed deliveries.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param request body dto.UpdateWebhookRequest true "Changes"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 400 {object} dto.ValidationErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid update webhook request", zap.Error(err))
+		response.ValidationError(c, err)
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), service.WebhookUpdate{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Active:     req.Active,
+	})
+	if err != nil {
+		h.writeError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebhookResponse{
+		Webhook: webhook,
+		Message: "Webhook updated successfully",
+	})
+}
+
+// DeleteWebhook handles deleting a webhook subscription
+// @Summary Delete webhook
+// @Description Delete an outbound webhook subscription (admin only). Queued deliveries to it are dropped.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		h.writeError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// writeError maps a webhook service error to a response
+func (h *WebhookHandler) writeError(c *gin.Context, err error, message string) {
+	if clientGone(c, err, h.logger) {
+		return
+	}
+	h.logger.Error(message, zap.Error(err))
+
+	switch {
+	case errors.Is(err, apperrors.ErrWebhookNotFound):
+		response.Error(c, http.StatusNotFound, dto.ErrorResponse{
+			Error:   "Not Found",
+			Message: "Webhook not found",
+		})
+	case errors.Is(err, apperrors.ErrInvalidWebhook):
+		response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Bad Request",
+			Message: err.Error(),
+		})
+	default:
+		response.Error(c, http.StatusInternalServerError, dto.ErrorResponse{
+			Error:   "Internal Server Error",
+			Message: message,
+		})
+	}
+}
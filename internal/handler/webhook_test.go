@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+func newTestWebhookRouter(repo *testutils.FakeWebhookRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewWebhookHandler(service.NewWebhookService(repo, nil, zap.NewNop()), zap.NewNop())
+
+	r := gin.New()
+	r.GET("/admin/webhooks", h.ListWebhooks)
+	r.POST("/admin/webhooks", h.CreateWebhook)
+	r.GET("/admin/webhooks/:id", h.GetWebhook)
+	r.PUT("/admin/webhooks/:id", h.UpdateWebhook)
+	r.DELETE("/admin/webhooks/:id", h.DeleteWebhook)
+	return r
+}
+
+func TestWebhookHandler_CreateWebhook(t *testing.T) {
+	r := newTestWebhookRouter(testutils.NewFakeWebhookRepository())
+
+	w := doJSON(r, http.MethodPost, "/admin/webhooks", dto.CreateWebhookRequest{
+		URL:        "https://crm.example.com/hooks",
+		EventTypes: []string{"user.registered"},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created dto.WebhookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Secret)
+
+	// The secret is only shown once
+	w = doJSON(r, http.MethodGet, "/admin/webhooks/"+created.Webhook.ID, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), created.Secret)
+	assert.NotContains(t, w.Body.String(), `"secret"`)
+
+	w = doJSON(r, http.MethodPost, "/admin/webhooks", dto.CreateWebhookRequest{URL: "https://crm.example.com/hooks", EventTypes: []string{"user.exploded"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "unknown event type user.exploded")
+
+	w = doJSON(r, http.MethodPost, "/admin/webhooks", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var invalid dto.ValidationErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invalid))
+	assert.Equal(t, response.ValidationErrorCode, invalid.Code)
+	require.NotEmpty(t, invalid.Fields)
+	assert.Equal(t, "url", invalid.Fields[0].Field)
+}
+
+func TestWebhookHandler_UpdateAndDeleteWebhook(t *testing.T) {
+	repo := testutils.NewFakeWebhookRepository()
+	r := newTestWebhookRouter(repo)
+
+	w := doJSON(r, http.MethodPost, "/admin/webhooks", dto.CreateWebhookRequest{URL: "https://crm.example.com/hooks"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := repo.Subscriptions[0].ID
+
+	w = doJSON(r, http.MethodPut, "/admin/webhooks/"+id, map[string]bool{"active": false})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, repo.Subscriptions[0].Active)
+	assert.Equal(t, "https://crm.example.com/hooks", repo.Subscriptions[0].URL)
+
+	w = doJSON(r, http.MethodPut, "/admin/webhooks/missing", map[string]bool{"active": true})
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	w = doJSON(r, http.MethodDelete, "/admin/webhooks/"+id, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doJSON(r, http.MethodDelete, "/admin/webhooks/"+id, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	w = doJSON(r, http.MethodGet, "/admin/webhooks", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"webhooks":[],"message":"Webhooks retrieved successfully"}`, w.Body.String())
+}
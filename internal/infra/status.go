@@ -0,0 +1,111 @@
+package infra
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// Infrastructure components
+const (
+	ComponentPostgreSQL = "postgresql"
+	ComponentRedis      = "redis"
+	ComponentMongoDB    = "mongodb"
+	ComponentKafka      = "kafka"
+)
+
+// optionalComponents are the components the service can run without.
+// PostgreSQL and Redis back every request and are always required.
+var optionalComponents = map[string]bool{
+	ComponentMongoDB: true,
+	ComponentKafka:   true,
+}
+
+// Status records which infrastructure components are optional and which of
+// them were unavailable at startup
+type Status struct {
+	optional map[string]bool
+	mu       sync.RWMutex
+	down     map[string]error
+}
+
+// NewStatus creates the infrastructure status from infrastructure.optional
+func NewStatus(cfg *config.Config) (*Status, error) {
+	optional := make(map[string]bool, len(cfg.Infrastructure.Optional))
+	for _, name := range cfg.Infrastructure.Optional {
+		if !optionalComponents[name] {
+			return nil, fmt.Errorf("infrastructure component %q cannot be optional", name)
+		}
+		optional[name] = true
+	}
+
+	return &Status{
+		optional: optional,
+		down:     make(map[string]error),
+	}, nil
+}
+
+// Optional reports whether the service may start without the component
+func (s *Status) Optional(name string) bool {
+	return s != nil && s.optional[name]
+}
+
+// Err returns why the component was unavailable at startup, or nil
+func (s *Status) Err(name string) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.down[name]
+}
+
+// Degraded returns the components that were unavailable at startup
+func (s *Status) Degraded() []string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.down))
+	for name := range s.down {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// markDown records that the component could not be connected
+func (s *Status) markDown(name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down[name] = err
+}
+
+// Connect runs connect for the named component. If it fails and the
+// component is optional, the failure is logged and recorded and the zero
+// value is returned without an error, so the service starts degraded.
+func Connect[T any](status *Status, name string, logger *zap.Logger, connect func() (T, error)) (T, error) {
+	client, err := connect()
+	if err == nil {
+		return client, nil
+	}
+
+	var zero T
+	if !status.Optional(name) {
+		return zero, err
+	}
+
+	status.markDown(name, err)
+	logger.Warn("Optional infrastructure unavailable, starting in degraded mode",
+		zap.String("component", name),
+		zap.Error(err),
+	)
+	return zero, nil
+}
@@ -0,0 +1,68 @@
+package infra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+type fakeClient struct{}
+
+func newTestStatus(t *testing.T, optional ...string) *Status {
+	t.Helper()
+
+	status, err := NewStatus(&config.Config{
+		Infrastructure: config.InfrastructureConfig{Optional: optional},
+	})
+	require.NoError(t, err)
+	return status
+}
+
+func TestConnect_OptionalComponentDown(t *testing.T) {
+	status := newTestStatus(t, ComponentMongoDB)
+	connectErr := errors.New("connection refused")
+
+	client, err := Connect(status, ComponentMongoDB, zap.NewNop(), func() (*fakeClient, error) {
+		return nil, connectErr
+	})
+	require.NoError(t, err)
+	assert.Nil(t, client)
+
+	assert.Equal(t, []string{ComponentMongoDB}, status.Degraded())
+	assert.Equal(t, connectErr, status.Err(ComponentMongoDB))
+}
+
+func TestConnect_RequiredComponentDown(t *testing.T) {
+	status := newTestStatus(t, ComponentMongoDB)
+	connectErr := errors.New("no brokers available")
+
+	_, err := Connect(status, ComponentKafka, zap.NewNop(), func() (*fakeClient, error) {
+		return nil, connectErr
+	})
+	assert.Equal(t, connectErr, err)
+	assert.Empty(t, status.Degraded())
+}
+
+func TestConnect_ComponentUp(t *testing.T) {
+	status := newTestStatus(t, ComponentKafka)
+
+	client, err := Connect(status, ComponentKafka, zap.NewNop(), func() (*fakeClient, error) {
+		return &fakeClient{}, nil
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+	assert.NoError(t, status.Err(ComponentKafka))
+}
+
+func TestNewStatus_RejectsRequiredComponents(t *testing.T) {
+	for _, name := range []string{ComponentPostgreSQL, ComponentRedis, "elasticsearch"} {
+		_, err := NewStatus(&config.Config{
+			Infrastructure: config.InfrastructureConfig{Optional: []string{name}},
+		})
+		assert.Error(t, err, name)
+	}
+}
@@ -7,6 +7,19 @@ import (
 	"github.com/zhwjimmy/user-center/internal/config"
 )
 
+// 消息时间戳来源
+const (
+	TimestampSourceEventTime   = "event_time"   // 业务动作发生的时间
+	TimestampSourcePublishedAt = "published_at" // 消息发送的时间
+)
+
+// 异步发送缓冲区已满时的背压策略
+const (
+	BackpressureBlock = "block" // 等待缓冲区空闲，最长等待 BackpressureTimeout
+	BackpressureDrop  = "drop"  // 丢弃事件并计入指标
+	BackpressureError = "error" // 立即返回错误
+)
+
 // KafkaClientConfig Kafka客户端配置
 type KafkaClientConfig struct {
 	Brokers       []string
@@ -19,6 +32,40 @@ type KafkaClientConfig struct {
 	FlushMessages int
 	FlushBytes    int
 	Compression   sarama.CompressionCodec
+
+	// Kafka 消息时间戳来源
+	TimestampSource string
+
+	// 事件编码格式：json 或 protobuf
+	Serialization string
+
+	// 停止消费时等待正在处理的消息完成的最长时间
+	DrainTimeout time.Duration
+
+	// 同步发送（含批量发送）的最长等待时间，调用方 ctx 的截止时间更早时以 ctx 为准
+	PublishTimeout time.Duration
+
+	// 异步发送背压策略
+	Backpressure        string
+	BackpressureTimeout time.Duration
+
+	// 熔断配置：连续失败 BreakerThreshold 次后熔断，BreakerCoolDown 后放行一次探测，0 表示不熔断
+	BreakerThreshold int
+	BreakerCoolDown  time.Duration
+
+	// 消费积压告警阈值，主题总积压超过该值时健康检查降级
+	LagThreshold int64
+
+	// 已处理事件 ID 的保留时长，在此期间重复投递的事件会被跳过，0 表示不去重
+	DedupTTL time.Duration
+
+	// 每个用户保留的最近登录网段和 User-Agent 数量，用于识别新设备登录，0 表示关闭检测
+	LoginHistorySize int
+
+	// 死信队列配置
+	DLQEnabled     bool
+	DLQReplayDelay time.Duration
+	DLQMaxAttempts int
 }
 
 // NewKafkaClientConfig 创建Kafka客户端配置
@@ -34,9 +81,37 @@ func NewKafkaClientConfig(cfg *config.Config) *KafkaClientConfig {
 		FlushMessages: 100,
 		FlushBytes:    1024 * 1024, // 1MB
 		Compression:   sarama.CompressionSnappy,
+
+		TimestampSource: cfg.Kafka.TimestampSource,
+		Serialization:   cfg.Kafka.Serialization,
+		DrainTimeout:    cfg.Kafka.DrainTimeout,
+
+		PublishTimeout:      cfg.Kafka.Producer.PublishTimeout,
+		Backpressure:        cfg.Kafka.Producer.Backpressure,
+		BackpressureTimeout: cfg.Kafka.Producer.BlockTimeout,
+
+		BreakerThreshold: breakerThreshold(cfg.Kafka.Producer.CircuitBreaker),
+		BreakerCoolDown:  cfg.Kafka.Producer.CircuitBreaker.CoolDown,
+
+		LagThreshold: cfg.Kafka.LagThreshold,
+		DedupTTL:     cfg.Kafka.DedupTTL,
+
+		LoginHistorySize: cfg.Kafka.LoginHistorySize,
+
+		DLQEnabled:     cfg.Kafka.DLQ.Enabled,
+		DLQReplayDelay: cfg.Kafka.DLQ.ReplayDelay,
+		DLQMaxAttempts: cfg.Kafka.DLQ.MaxAttempts,
 	}
 }
 
+// breakerThreshold 返回熔断阈值，未启用时为 0
+func breakerThreshold(cfg config.KafkaCircuitBreakerConfig) int {
+	if !cfg.Enabled {
+		return 0
+	}
+	return cfg.FailureThreshold
+}
+
 // NewProducerConfig 创建生产者配置
 func (c *KafkaClientConfig) NewProducerConfig() *sarama.Config {
 	config := sarama.NewConfig()
@@ -85,6 +160,14 @@ func (c *KafkaClientConfig) NewConsumerConfig() *sarama.Config {
 	return config
 }
 
+// MessageTimestamp 按配置的时间戳来源选择 Kafka 消息时间戳，缺少事件时间时使用发送时间
+func (c *KafkaClientConfig) MessageTimestamp(eventTime, publishedAt time.Time) time.Time {
+	if c.TimestampSource == TimestampSourceEventTime && !eventTime.IsZero() {
+		return eventTime
+	}
+	return publishedAt
+}
+
 // GetTopicName 获取主题名称
 func (c *KafkaClientConfig) GetTopicName(key string) string {
 	if topic, exists := c.Topics[key]; exists {
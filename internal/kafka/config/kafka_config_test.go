@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaClientConfig_MessageTimestamp(t *testing.T) {
+	eventTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishedAt := eventTime.Add(time.Minute)
+
+	cfg := &KafkaClientConfig{TimestampSource: TimestampSourcePublishedAt}
+	assert.Equal(t, publishedAt, cfg.MessageTimestamp(eventTime, publishedAt))
+
+	cfg.TimestampSource = TimestampSourceEventTime
+	assert.Equal(t, eventTime, cfg.MessageTimestamp(eventTime, publishedAt))
+	assert.Equal(t, publishedAt, cfg.MessageTimestamp(time.Time{}, publishedAt))
+}
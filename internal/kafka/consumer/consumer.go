@@ -3,14 +3,21 @@ package consumer
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/zhwjimmy/user-center/internal/kafka/config"
 	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"go.uber.org/zap"
 )
 
+// claimRetryBackoff 消息既无法处理也无法转入死信队列时，结束分区认领前的等待时间，
+// 避免重新加入消费者组后立即再次失败
+const claimRetryBackoff = time.Second
+
 // MessageHandler 消息处理器接口
 type MessageHandler interface {
 	HandleUserRegistered(ctx context.Context, event *event.UserRegisteredEvent) error
@@ -32,13 +39,19 @@ type KafkaConsumer struct {
 	consumerGroup sarama.ConsumerGroup
 	config        *config.KafkaClientConfig
 	handler       MessageHandler
+	dlq           DLQPublisher
+	lag           *LagMonitor
+	dedup         *Deduplicator
+	drainTimeout  time.Duration
+	retryBackoff  time.Duration
 	logger        *zap.Logger
 	wg            sync.WaitGroup
 	cancel        context.CancelFunc
 }
 
-// NewKafkaConsumer 创建Kafka消费者
-func NewKafkaConsumer(cfg *config.KafkaClientConfig, handler MessageHandler, logger *zap.Logger) (Consumer, error) {
+// NewKafkaConsumer 创建Kafka消费者，dlq 为 nil 时处理失败的消息仅记录日志，
+// lag 为 nil 时不记录消费积压，dedup 为 nil 时不跳过重复事件
+func NewKafkaConsumer(cfg *config.KafkaClientConfig, handler MessageHandler, dlq DLQPublisher, lag *LagMonitor, dedup *Deduplicator, logger *zap.Logger) (Consumer, error) {
 	consumerConfig := cfg.NewConsumerConfig()
 
 	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, consumerConfig)
@@ -50,6 +63,11 @@ func NewKafkaConsumer(cfg *config.KafkaClientConfig, handler MessageHandler, log
 		consumerGroup: consumerGroup,
 		config:        cfg,
 		handler:       handler,
+		dlq:           dlq,
+		lag:           lag,
+		dedup:         dedup,
+		drainTimeout:  cfg.DrainTimeout,
+		retryBackoff:  claimRetryBackoff,
 		logger:        logger,
 	}
 
@@ -105,24 +123,31 @@ func (c *KafkaConsumer) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop 停止消费者
+// Stop 停止消费者，等待正在处理的消息完成（最长 drainTimeout）并提交位移后再关闭消费者组
 func (c *KafkaConsumer) Stop() error {
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.wg.Wait()
 
 	if err := c.consumerGroup.Close(); err != nil {
 		c.logger.Error("Failed to close consumer group", zap.Error(err))
 		return err
 	}
 
-	c.wg.Wait()
 	c.logger.Info("Kafka consumer stopped successfully")
 	return nil
 }
 
-// Setup 消费者组设置
-func (c *KafkaConsumer) Setup(sarama.ConsumerGroupSession) error {
+// Setup 消费者组设置，为分配到的分区初始化计数指标，使其从 0 开始上报
+func (c *KafkaConsumer) Setup(session sarama.ConsumerGroupSession) error {
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			labels := []string{topic, strconv.Itoa(int(partition))}
+			metrics.ConsumerMessagesTotal.WithLabelValues(labels...)
+			metrics.ConsumerErrorsTotal.WithLabelValues(labels...)
+		}
+	}
 	return nil
 }
 
@@ -131,100 +156,207 @@ func (c *KafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error {
 	return nil
 }
 
-// ConsumeClaim 消费消息
+// ConsumeClaim 消费消息。消息既无法处理也无法转入死信队列时返回错误结束本次会话，
+// 该消息及其后的消息均不标记，重新加入消费者组后从已提交位移重新投递
 func (c *KafkaConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	partition := strconv.Itoa(int(claim.Partition()))
+	consumed := metrics.ConsumerMessagesTotal.WithLabelValues(claim.Topic(), partition)
+	failed := metrics.ConsumerErrorsTotal.WithLabelValues(claim.Topic(), partition)
+
+	// 在收到第一条消息前按已提交位移上报积压，分区没有已提交位移时跳过
+	if committed := claim.InitialOffset(); committed >= 0 {
+		c.lag.Record(claim.Topic(), claim.Partition(), max(claim.HighWaterMarkOffset()-committed, 0))
+	}
+
 	for {
 		select {
 		case message := <-claim.Messages():
 			if message == nil {
 				return nil
 			}
+			consumed.Inc()
 
-			if err := c.processMessage(session.Context(), message); err != nil {
-				c.logger.Error("Failed to process message",
-					zap.String("topic", message.Topic),
-					zap.Int32("partition", message.Partition),
-					zap.Int64("offset", message.Offset),
-					zap.Error(err),
-				)
-				// 继续处理下一条消息，不中断消费
-				continue
+			marked, err := c.processMessage(session, message)
+			if err != nil {
+				failed.Inc()
+			}
+			if marked {
+				c.lag.Record(claim.Topic(), claim.Partition(), max(claim.HighWaterMarkOffset()-message.Offset-1, 0))
 			}
 
-			// 标记消息已处理
-			session.MarkMessage(message, "")
+			// 会话在处理期间结束时，立即提交已处理消息的位移，避免重启后重复处理
+			if session.Context().Err() != nil {
+				session.Commit()
+				return nil
+			}
+
+			// 跳过未标记的消息会使后续标记越过它提交位移，导致事件丢失
+			if !marked {
+				select {
+				case <-time.After(c.retryBackoff):
+				case <-session.Context().Done():
+				}
+				session.Commit()
+				return fmt.Errorf("failed to process message at offset %d of %s/%d: %w",
+					message.Offset, message.Topic, message.Partition, err)
+			}
 
 		case <-session.Context().Done():
+			session.Commit()
 			return nil
 		}
 	}
 }
 
-// processMessage 处理消息
-func (c *KafkaConsumer) processMessage(ctx context.Context, message *sarama.ConsumerMessage) error {
+// processMessage 处理一条消息，处理完成或转入死信队列后标记，返回是否已标记及处理错误。
+// 会话结束后仍有 drainTimeout 的时间完成正在处理的消息。
+func (c *KafkaConsumer) processMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) (bool, error) {
+	ctx, cancel := drainContext(session.Context(), c.drainTimeout)
+	defer cancel()
+
+	claimed, err := c.dedup.Claim(ctx, message)
+	if err != nil {
+		// 事件仍在处理中，不标记，稍后重新投递
+		return false, err
+	}
+	if !claimed {
+		// 重复投递的事件已处理过，直接确认
+		session.MarkMessage(message, "")
+		return true, nil
+	}
+
+	err = dispatchMessage(ctx, c.handler, c.logger, message)
+	if err == nil {
+		c.dedup.Confirm(ctx, message)
+		session.MarkMessage(message, "")
+		return true, nil
+	}
+
+	c.dedup.Release(ctx, message)
+	c.logger.Error("Failed to process message",
+		zap.String("topic", message.Topic),
+		zap.Int32("partition", message.Partition),
+		zap.Int64("offset", message.Offset),
+		zap.Error(err),
+	)
+
+	// 因停止超时而中断的消息不标记，重启后重新投递
+	if ctx.Err() == nil && c.deadLetter(ctx, message, err) {
+		session.MarkMessage(message, "")
+		return true, err
+	}
+	// 不标记消息，由 ConsumeClaim 结束本次会话后重新投递
+	return false, err
+}
+
+// drainContext 返回处理单条消息的上下文，parent 结束后再等待 timeout 才取消
+func drainContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// deadLetter 将处理失败的消息转入死信队列，返回是否成功转入
+func (c *KafkaConsumer) deadLetter(ctx context.Context, message *sarama.ConsumerMessage, cause error) bool {
+	if c.dlq == nil {
+		return false
+	}
+
+	if err := c.dlq.PublishToDLQ(ctx, message, 1, cause); err != nil {
+		c.logger.Error("Failed to publish message to DLQ",
+			zap.String("topic", message.Topic),
+			zap.Int64("offset", message.Offset),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
+
+// dispatchMessage 按事件类型将消息分发给处理器
+func dispatchMessage(ctx context.Context, handler MessageHandler, logger *zap.Logger, message *sarama.ConsumerMessage) error {
 	// 获取事件类型
-	eventType := c.getEventType(message.Headers)
+	eventType := getHeader(message.Headers, "event_type")
 
-	c.logger.Debug("Processing message",
+	logger.Debug("Processing message",
 		zap.String("topic", message.Topic),
 		zap.String("event_type", eventType),
 		zap.Int32("partition", message.Partition),
 		zap.Int64("offset", message.Offset),
 	)
 
+	// 按 content-type 选择解码器，无法解码的消息按处理失败转入死信队列
+	serializer, err := event.SerializerForContentType(getHeader(message.Headers, event.HeaderContentType))
+	if err != nil {
+		return err
+	}
+
 	switch event.EventType(eventType) {
 	case event.UserRegistered:
 		var userEvent event.UserRegisteredEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user registered event: %w", err)
 		}
-		return c.handler.HandleUserRegistered(ctx, &userEvent)
+		return handler.HandleUserRegistered(ctx, &userEvent)
 
 	case event.UserLoggedIn:
 		var userEvent event.UserLoggedInEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user logged in event: %w", err)
 		}
-		return c.handler.HandleUserLoggedIn(ctx, &userEvent)
+		return handler.HandleUserLoggedIn(ctx, &userEvent)
 
 	case event.UserPasswordChanged:
 		var userEvent event.UserPasswordChangedEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user password changed event: %w", err)
 		}
-		return c.handler.HandleUserPasswordChanged(ctx, &userEvent)
+		return handler.HandleUserPasswordChanged(ctx, &userEvent)
 
 	case event.UserStatusChanged:
 		var userEvent event.UserStatusChangedEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user status changed event: %w", err)
 		}
-		return c.handler.HandleUserStatusChanged(ctx, &userEvent)
+		return handler.HandleUserStatusChanged(ctx, &userEvent)
 
 	case event.UserDeleted:
 		var userEvent event.UserDeletedEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user deleted event: %w", err)
 		}
-		return c.handler.HandleUserDeleted(ctx, &userEvent)
+		return handler.HandleUserDeleted(ctx, &userEvent)
 
 	case event.UserUpdated:
 		var userEvent event.UserUpdatedEvent
-		if err := userEvent.FromJSON(message.Value); err != nil {
+		if err := serializer.Unmarshal(message.Value, &userEvent); err != nil {
 			return fmt.Errorf("failed to unmarshal user updated event: %w", err)
 		}
-		return c.handler.HandleUserUpdated(ctx, &userEvent)
+		return handler.HandleUserUpdated(ctx, &userEvent)
 
 	default:
-		c.logger.Warn("Unknown event type", zap.String("event_type", eventType))
+		logger.Warn("Unknown event type", zap.String("event_type", eventType))
 		return nil // 忽略未知事件类型
 	}
 }
 
-// getEventType 从消息头获取事件类型
-func (c *KafkaConsumer) getEventType(headers []*sarama.RecordHeader) string {
+// getHeader 从消息头获取指定键的值
+func getHeader(headers []*sarama.RecordHeader, key string) string {
 	for _, header := range headers {
-		if string(header.Key) == "event_type" {
+		if string(header.Key) == key {
 			return string(header.Value)
 		}
 	}
@@ -0,0 +1,149 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"go.uber.org/zap"
+)
+
+// recordingHandler 记录收到的用户注册事件
+type recordingHandler struct {
+	MessageHandler
+	registered []*event.UserRegisteredEvent
+}
+
+func (h *recordingHandler) HandleUserRegistered(ctx context.Context, e *event.UserRegisteredEvent) error {
+	h.registered = append(h.registered, e)
+	return nil
+}
+
+func TestDispatchMessage_DecodesByContentType(t *testing.T) {
+	original := &event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test", "req-1", "user-1"),
+		Username:  "testuser",
+		Email:     "test@example.com",
+	}
+
+	tests := []struct {
+		name        string
+		serializer  event.Serializer
+		contentType string
+	}{
+		{name: "json", serializer: event.JSONSerializer{}, contentType: event.ContentTypeJSON},
+		{name: "protobuf", serializer: event.ProtobufSerializer{}, contentType: event.ContentTypeProtobuf},
+		{name: "legacy message without content type", serializer: event.JSONSerializer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.serializer.Marshal(original)
+			require.NoError(t, err)
+
+			headers := []*sarama.RecordHeader{{Key: []byte("event_type"), Value: []byte(event.UserRegistered)}}
+			if tt.contentType != "" {
+				headers = append(headers, &sarama.RecordHeader{Key: []byte(event.HeaderContentType), Value: []byte(tt.contentType)})
+			}
+
+			handler := &recordingHandler{}
+			err = dispatchMessage(context.Background(), handler, zap.NewNop(), &sarama.ConsumerMessage{Value: value, Headers: headers})
+			require.NoError(t, err)
+
+			require.Len(t, handler.registered, 1)
+			assert.Equal(t, "testuser", handler.registered[0].Username)
+			assert.Equal(t, "user-1", handler.registered[0].UserID)
+		})
+	}
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		handler := &recordingHandler{}
+		err := dispatchMessage(context.Background(), handler, zap.NewNop(), &sarama.ConsumerMessage{
+			Value: []byte("<event/>"),
+			Headers: []*sarama.RecordHeader{
+				{Key: []byte("event_type"), Value: []byte(event.UserRegistered)},
+				{Key: []byte(event.HeaderContentType), Value: []byte("application/xml")},
+			},
+		})
+		assert.Error(t, err)
+		assert.Empty(t, handler.registered)
+	})
+}
+
+// blockingHandler 在处理用户注册事件时等待 release 关闭或上下文取消
+type blockingHandler struct {
+	MessageHandler
+	started chan struct{}
+	release chan struct{}
+	ctxErr  error
+}
+
+func (h *blockingHandler) HandleUserRegistered(ctx context.Context, e *event.UserRegisteredEvent) error {
+	close(h.started)
+	select {
+	case <-h.release:
+	case <-ctx.Done():
+	}
+	h.ctxErr = ctx.Err()
+	return h.ctxErr
+}
+
+// consumeUntilCanceled 在处理 offset 为 42 的消息期间取消会话，返回会话和处理器
+func consumeUntilCanceled(t *testing.T, drainTimeout time.Duration, release bool) (*fakeSession, *blockingHandler, *fakeDLQPublisher) {
+	t.Helper()
+
+	handler := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	dlq := &fakeDLQPublisher{}
+	c := &KafkaConsumer{handler: handler, dlq: dlq, drainTimeout: drainTimeout, logger: zap.NewNop()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := &fakeSession{ctx: ctx}
+
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, 1), initial: sarama.OffsetNewest}
+	msg := newRegisteredMessage(t)
+	msg.Offset = 42
+	claim.messages <- msg
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ConsumeClaim(session, claim)
+	}()
+
+	<-handler.started
+	cancel()
+	if release {
+		close(handler.release)
+	}
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeClaim did not return after cancellation")
+	}
+	return session, handler, dlq
+}
+
+func TestKafkaConsumer_ConsumeClaimCommitsInFlightMessage(t *testing.T) {
+	session, handler, _ := consumeUntilCanceled(t, time.Second, true)
+
+	// 处理器在停止期间完成，上下文未被取消
+	assert.NoError(t, handler.ctxErr)
+	assert.Equal(t, []int64{42}, session.marked)
+	assert.Equal(t, []int64{42}, session.committed)
+}
+
+func TestKafkaConsumer_ConsumeClaimDrainTimeout(t *testing.T) {
+	session, handler, dlq := consumeUntilCanceled(t, 10*time.Millisecond, false)
+
+	// 超过停止等待时间的消息被中断，不标记也不转入死信队列，重启后重新投递
+	assert.ErrorIs(t, handler.ctxErr, context.Canceled)
+	assert.Empty(t, session.marked)
+	assert.Empty(t, session.committed)
+	assert.Empty(t, dlq.published)
+}
@@ -0,0 +1,160 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// processedEventKeyPrefix 已处理事件 ID 在 Redis 中的键前缀
+const processedEventKeyPrefix = "kafka:processed:"
+
+// releaseTimeout 撤销已处理标记的超时时间，会话或停止等待结束后仍会执行
+const releaseTimeout = 5 * time.Second
+
+// processingTTL 处理中标记的有效期。处理中途崩溃时标记在此之后过期，
+// 重新投递的事件得以处理，而不是在整个 DedupTTL 内被当作重复事件丢弃
+const processingTTL = time.Minute
+
+// 事件 ID 键的取值：处理中，或已处理完成
+const (
+	eventProcessing = "processing"
+	eventProcessed  = "processed"
+)
+
+// errEventInProgress 事件正由另一次投递处理，或上次处理中途崩溃且标记尚未过期
+var errEventInProgress = errors.New("event is being processed by another delivery")
+
+// Deduplicator 基于 Redis SetNX 记录已处理的事件 ID，跳过重复投递的事件
+type Deduplicator struct {
+	redis  *cache.Redis
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewDeduplicator 创建事件去重器，DedupTTL 为 0 时返回 nil，即不去重
+func NewDeduplicator(redis *cache.Redis, cfg *config.KafkaClientConfig, logger *zap.Logger) *Deduplicator {
+	if cfg.DedupTTL <= 0 {
+		return nil
+	}
+	return &Deduplicator{
+		redis:  redis,
+		ttl:    cfg.DedupTTL,
+		logger: logger,
+	}
+}
+
+// Claim 将消息中的事件标记为处理中，有效期为 processingTTL，处理成功后由 Confirm
+// 延长为 DedupTTL。事件此前已处理完成时返回 false 并累加重复计数；事件仍处于处理中时
+// 返回 errEventInProgress，消息稍后重新投递。无事件 ID 或 Redis 不可用时放行，
+// 宁可重复处理也不丢消息
+func (d *Deduplicator) Claim(ctx context.Context, message *sarama.ConsumerMessage) (bool, error) {
+	if d == nil {
+		return true, nil
+	}
+
+	eventID := messageEventID(message)
+	if eventID == "" {
+		return true, nil
+	}
+
+	key := processedEventKeyPrefix + eventID
+	first, err := d.redis.SetNX(ctx, key, eventProcessing, min(processingTTL, d.ttl))
+	if err != nil {
+		d.logger.Warn("Failed to check event idempotency, processing anyway",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+		return true, nil
+	}
+	if first {
+		return true, nil
+	}
+
+	var state string
+	if err := d.redis.Get(ctx, key, &state); err != nil {
+		// 标记在两次调用之间过期，按首次投递处理
+		d.logger.Warn("Failed to read event idempotency key, processing anyway",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+		return true, nil
+	}
+	if state != eventProcessed {
+		return false, errEventInProgress
+	}
+
+	eventType := getHeader(message.Headers, "event_type")
+	metrics.DuplicateEventsSkippedTotal.WithLabelValues(eventType).Inc()
+	d.logger.Info("Skipping duplicate event",
+		zap.String("event_id", eventID),
+		zap.String("event_type", eventType),
+	)
+	return false, nil
+}
+
+// Confirm 将处理成功的事件标记为已处理，在 DedupTTL 内跳过其重复投递。
+// 失败时仅记录日志，处理中标记过期后重复投递的事件会再次处理
+func (d *Deduplicator) Confirm(ctx context.Context, message *sarama.ConsumerMessage) {
+	if d == nil {
+		return
+	}
+
+	eventID := messageEventID(message)
+	if eventID == "" {
+		return
+	}
+
+	if err := d.redis.Set(ctx, processedEventKeyPrefix+eventID, eventProcessed, d.ttl); err != nil {
+		d.logger.Warn("Failed to confirm processed event",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+	}
+}
+
+// Release 撤销处理失败事件的已处理标记，使其重新投递时可再次处理。
+// ctx 在停止超时后已取消，因此不随 ctx 取消，仅受 releaseTimeout 限制
+func (d *Deduplicator) Release(ctx context.Context, message *sarama.ConsumerMessage) {
+	if d == nil {
+		return
+	}
+
+	eventID := messageEventID(message)
+	if eventID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if err := d.redis.Delete(ctx, processedEventKeyPrefix+eventID); err != nil {
+		d.logger.Warn("Failed to release event idempotency key",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+	}
+}
+
+// messageEventID 按 content-type 解码消息体并读取事件 ID，无法解码时返回空字符串
+func messageEventID(message *sarama.ConsumerMessage) string {
+	serializer, err := event.SerializerForContentType(getHeader(message.Headers, event.HeaderContentType))
+	if err != nil {
+		return ""
+	}
+	decoded, err := event.NewEvent(event.EventType(getHeader(message.Headers, "event_type")))
+	if err != nil {
+		return ""
+	}
+	if err := serializer.Unmarshal(message.Value, decoded); err != nil {
+		return ""
+	}
+	return decoded.GetBaseEvent().ID
+}
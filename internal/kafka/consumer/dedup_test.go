@@ -0,0 +1,145 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// consumeAll 依次消费 messages 中的消息
+func consumeAll(t *testing.T, c *KafkaConsumer, messages ...*sarama.ConsumerMessage) *fakeSession {
+	t.Helper()
+
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, len(messages))}
+	for _, msg := range messages {
+		claim.messages <- msg
+	}
+	close(claim.messages)
+
+	session := &fakeSession{ctx: context.Background()}
+	require.NoError(t, c.ConsumeClaim(session, claim))
+	return session
+}
+
+func TestKafkaConsumer_SkipsDuplicateEvents(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	dedup := NewDeduplicator(redis, &config.KafkaClientConfig{DedupTTL: time.Hour}, zap.NewNop())
+	handler := &fakeHandler{}
+	c := &KafkaConsumer{handler: handler, dedup: dedup, logger: zap.NewNop()}
+
+	skipped := metrics.DuplicateEventsSkippedTotal.WithLabelValues(string(event.UserRegistered))
+	before := testutil.ToFloat64(skipped)
+
+	// 同一事件被投递两次
+	msg := newRegisteredMessage(t)
+	redelivered := *msg
+	redelivered.Offset = msg.Offset + 1
+	session := consumeAll(t, c, msg, &redelivered)
+
+	assert.Equal(t, 1, handler.calls)
+	assert.Equal(t, before+1, testutil.ToFloat64(skipped))
+	// 重复事件同样被确认，不会再次投递
+	assert.Len(t, session.marked, 2)
+}
+
+func TestMessageEventID_DecodesByContentType(t *testing.T) {
+	original := &event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test", "req-1", "user-1"),
+		Username:  "testuser",
+	}
+
+	for _, serializer := range []event.Serializer{event.JSONSerializer{}, event.ProtobufSerializer{}} {
+		value, err := serializer.Marshal(original)
+		require.NoError(t, err)
+
+		message := &sarama.ConsumerMessage{Value: value, Headers: []*sarama.RecordHeader{
+			{Key: []byte("event_type"), Value: []byte(event.UserRegistered)},
+			{Key: []byte(event.HeaderContentType), Value: []byte(serializer.ContentType())},
+		}}
+		assert.Equal(t, original.ID, messageEventID(message), serializer.ContentType())
+	}
+
+	// 无法解码的消息不去重
+	assert.Empty(t, messageEventID(&sarama.ConsumerMessage{Value: []byte("not an event")}))
+}
+
+func TestKafkaConsumer_RetriesFailedEventOnRedelivery(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	dedup := NewDeduplicator(redis, &config.KafkaClientConfig{DedupTTL: time.Hour}, zap.NewNop())
+	handler := &fakeHandler{errs: []error{errors.New("boom")}}
+	c := &KafkaConsumer{handler: handler, dedup: dedup, logger: zap.NewNop()}
+
+	// 处理失败的事件结束会话且不会被记为已处理，重新投递时再次处理
+	msg := newRegisteredMessage(t)
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- msg
+	session := &fakeSession{ctx: context.Background()}
+	assert.Error(t, c.ConsumeClaim(session, claim))
+	assert.Empty(t, session.marked)
+
+	session = consumeAll(t, c, msg)
+	assert.Equal(t, 2, handler.calls)
+	assert.Len(t, session.marked, 1)
+}
+
+func TestDeduplicator_ReleaseAfterCancel(t *testing.T) {
+	redis, mr := testutils.NewMiniRedis(t)
+	dedup := NewDeduplicator(redis, &config.KafkaClientConfig{DedupTTL: time.Hour}, zap.NewNop())
+	msg := newRegisteredMessage(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	claimed, err := dedup.Claim(ctx, msg)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// 停止超时后上下文已取消，标记仍需撤销，否则重新投递时被当作重复事件跳过
+	cancel()
+	dedup.Release(ctx, msg)
+	assert.Empty(t, mr.Keys())
+}
+
+func TestKafkaConsumer_RedeliversEventAfterCrash(t *testing.T) {
+	redis, mr := testutils.NewMiniRedis(t)
+	dedup := NewDeduplicator(redis, &config.KafkaClientConfig{DedupTTL: time.Hour}, zap.NewNop())
+	handler := &fakeHandler{}
+	c := &KafkaConsumer{handler: handler, dedup: dedup, logger: zap.NewNop()}
+
+	// 上次投递标记事件为处理中后崩溃，未处理也未撤销标记
+	msg := newRegisteredMessage(t)
+	claimed, err := dedup.Claim(context.Background(), msg)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	// 标记过期前重新投递的事件不处理也不标记，会话结束后再次投递
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- msg
+	session := &fakeSession{ctx: context.Background()}
+	assert.ErrorIs(t, c.ConsumeClaim(session, claim), errEventInProgress)
+	assert.Empty(t, session.marked)
+	assert.Zero(t, handler.calls)
+
+	// 处理中标记过期后事件得到处理，并在 DedupTTL 内跳过重复投递
+	mr.FastForward(processingTTL)
+	consumeAll(t, c, msg)
+	assert.Equal(t, 1, handler.calls)
+	assert.Equal(t, time.Hour, mr.TTL(processedEventKeyPrefix+messageEventID(msg)))
+
+	consumeAll(t, c, msg)
+	assert.Equal(t, 1, handler.calls)
+}
+
+func TestNewDeduplicator_DisabledWithoutTTL(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	assert.Nil(t, NewDeduplicator(redis, &config.KafkaClientConfig{}, zap.NewNop()))
+}
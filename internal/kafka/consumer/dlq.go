@@ -0,0 +1,303 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// 死信消息头
+const (
+	HeaderDLQAttempts   = "dlq_attempts"
+	HeaderDLQError      = "dlq_error"
+	HeaderOriginalTopic = "original_topic"
+)
+
+// DLQTopic 返回主题对应的死信主题名称
+func DLQTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// DLQPublisher 死信发布接口
+type DLQPublisher interface {
+	// PublishToDLQ 将消息写入原主题的死信主题，attempts 为已失败的处理次数
+	PublishToDLQ(ctx context.Context, msg *sarama.ConsumerMessage, attempts int, cause error) error
+	Close() error
+}
+
+// KafkaDLQPublisher 基于同步生产者的死信发布实现
+type KafkaDLQPublisher struct {
+	producer sarama.SyncProducer
+}
+
+// NewKafkaDLQPublisher 创建死信发布者
+func NewKafkaDLQPublisher(cfg *config.KafkaClientConfig) (DLQPublisher, error) {
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, cfg.NewProducerConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka dlq producer: %w", err)
+	}
+
+	return &KafkaDLQPublisher{producer: producer}, nil
+}
+
+// PublishToDLQ 将消息写入死信主题
+func (p *KafkaDLQPublisher) PublishToDLQ(ctx context.Context, msg *sarama.ConsumerMessage, attempts int, cause error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, _, err := p.producer.SendMessage(newDLQMessage(msg, attempts, cause))
+	return err
+}
+
+// Close 关闭死信发布者
+func (p *KafkaDLQPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// newDLQMessage 构造死信消息，保留原消息的键、内容和业务消息头
+func newDLQMessage(msg *sarama.ConsumerMessage, attempts int, cause error) *sarama.ProducerMessage {
+	originalTopic := originalTopic(msg)
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+3)
+	for _, header := range msg.Headers {
+		switch string(header.Key) {
+		case HeaderDLQAttempts, HeaderDLQError, HeaderOriginalTopic:
+			continue
+		}
+		headers = append(headers, sarama.RecordHeader{Key: header.Key, Value: header.Value})
+	}
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderOriginalTopic), Value: []byte(originalTopic)},
+		sarama.RecordHeader{Key: []byte(HeaderDLQAttempts), Value: []byte(strconv.Itoa(attempts))},
+		sarama.RecordHeader{Key: []byte(HeaderDLQError), Value: []byte(cause.Error())},
+	)
+
+	message := &sarama.ProducerMessage{
+		Topic:     DLQTopic(originalTopic),
+		Value:     sarama.ByteEncoder(msg.Value),
+		Headers:   headers,
+		Timestamp: time.Now(),
+	}
+	if msg.Key != nil {
+		message.Key = sarama.ByteEncoder(msg.Key)
+	}
+	return message
+}
+
+// originalTopic 返回消息最初所属的主题
+func originalTopic(msg *sarama.ConsumerMessage) string {
+	if topic := getHeader(msg.Headers, HeaderOriginalTopic); topic != "" {
+		return topic
+	}
+	return msg.Topic
+}
+
+// dlqAttempts 返回消息已失败的处理次数
+func dlqAttempts(msg *sarama.ConsumerMessage) int {
+	attempts, err := strconv.Atoi(getHeader(msg.Headers, HeaderDLQAttempts))
+	if err != nil || attempts < 1 {
+		return 1
+	}
+	return attempts
+}
+
+// DLQReplayer 死信重放消费者
+//
+// 以独立的消费者组读取 "<topic>.dlq"，在消息进入死信队列 replayDelay 之后
+// 通过正常的处理器重新处理：成功则确认；失败则递增重试次数重新入队，
+// 达到 maxAttempts 后丢弃并记录错误日志。
+type DLQReplayer struct {
+	consumerGroup sarama.ConsumerGroup
+	config        *config.KafkaClientConfig
+	handler       MessageHandler
+	publisher     DLQPublisher
+	retryBackoff  time.Duration
+	logger        *zap.Logger
+	wg            sync.WaitGroup
+	cancel        context.CancelFunc
+}
+
+// NewDLQReplayer 创建死信重放消费者
+func NewDLQReplayer(cfg *config.KafkaClientConfig, handler MessageHandler, publisher DLQPublisher, logger *zap.Logger) (*DLQReplayer, error) {
+	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID+".dlq-replay", cfg.NewConsumerConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka dlq consumer group: %w", err)
+	}
+
+	return &DLQReplayer{
+		consumerGroup: consumerGroup,
+		config:        cfg,
+		handler:       handler,
+		publisher:     publisher,
+		retryBackoff:  claimRetryBackoff,
+		logger:        logger,
+	}, nil
+}
+
+// Start 启动死信重放
+func (r *DLQReplayer) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+
+	topics := []string{DLQTopic(r.config.GetTopicName("user_events"))}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				if err := r.consumerGroup.Consume(ctx, topics, r); err != nil {
+					r.logger.Error("Error consuming DLQ messages", zap.Error(err))
+					return
+				}
+			}
+		}
+	}()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			select {
+			case err := <-r.consumerGroup.Errors():
+				r.logger.Error("DLQ consumer group error", zap.Error(err))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	r.logger.Info("Kafka DLQ replayer started",
+		zap.Strings("topics", topics),
+		zap.Duration("replay_delay", r.config.DLQReplayDelay),
+		zap.Int("max_attempts", r.config.DLQMaxAttempts),
+	)
+	return nil
+}
+
+// Stop 停止死信重放
+func (r *DLQReplayer) Stop() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	if err := r.consumerGroup.Close(); err != nil {
+		r.logger.Error("Failed to close DLQ consumer group", zap.Error(err))
+		return err
+	}
+
+	r.wg.Wait()
+	r.logger.Info("Kafka DLQ replayer stopped successfully")
+	return nil
+}
+
+// Setup 消费者组设置
+func (r *DLQReplayer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup 消费者组清理
+func (r *DLQReplayer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim 按顺序重放死信消息。消息需要重新入队却失败时返回错误结束本次会话，
+// 不标记该消息，重新加入消费者组后从已提交位移再次重放
+func (r *DLQReplayer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	depth := metrics.DLQDepth.WithLabelValues(claim.Topic(), strconv.Itoa(int(claim.Partition())))
+
+	for {
+		select {
+		case message := <-claim.Messages():
+			if message == nil {
+				return nil
+			}
+
+			// 等待消息达到重放时间
+			if wait := time.Until(message.Timestamp.Add(r.config.DLQReplayDelay)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-session.Context().Done():
+					timer.Stop()
+					return nil
+				}
+			}
+
+			if err := r.replay(session.Context(), message); err != nil {
+				r.logger.Error("Failed to requeue DLQ message",
+					zap.String("topic", message.Topic),
+					zap.Int32("partition", message.Partition),
+					zap.Int64("offset", message.Offset),
+					zap.Error(err),
+				)
+
+				// 跳过未标记的消息会使后续标记越过它提交位移，导致死信消息丢失
+				select {
+				case <-time.After(r.retryBackoff):
+				case <-session.Context().Done():
+				}
+				session.Commit()
+				return fmt.Errorf("failed to requeue DLQ message at offset %d of %s/%d: %w",
+					message.Offset, message.Topic, message.Partition, err)
+			}
+
+			session.MarkMessage(message, "")
+			depth.Set(float64(max(claim.HighWaterMarkOffset()-message.Offset-1, 0)))
+
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// replay 重新处理一条死信消息，仅在消息需要重新入队却失败时返回错误
+func (r *DLQReplayer) replay(ctx context.Context, message *sarama.ConsumerMessage) error {
+	err := dispatchMessage(ctx, r.handler, r.logger, message)
+	if err == nil {
+		metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplaySuccess).Inc()
+		r.logger.Info("DLQ message reprocessed",
+			zap.String("topic", message.Topic),
+			zap.Int64("offset", message.Offset),
+		)
+		return nil
+	}
+
+	attempts := dlqAttempts(message) + 1
+	if attempts >= r.config.DLQMaxAttempts {
+		metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplayExhausted).Inc()
+		r.logger.Error("Discarding DLQ message after max attempts",
+			zap.String("topic", message.Topic),
+			zap.Int64("offset", message.Offset),
+			zap.String("event_type", getHeader(message.Headers, "event_type")),
+			zap.Int("attempts", attempts),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	if err := r.publisher.PublishToDLQ(ctx, message, attempts, err); err != nil {
+		return err
+	}
+	metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplayRequeued).Inc()
+	r.logger.Warn("DLQ message failed again, requeued",
+		zap.String("topic", message.Topic),
+		zap.Int64("offset", message.Offset),
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	)
+	return nil
+}
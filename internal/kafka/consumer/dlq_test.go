@@ -0,0 +1,253 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// fakeHandler 按预设结果处理用户注册事件
+type fakeHandler struct {
+	MessageHandler
+	errs  []error
+	calls int
+}
+
+func (h *fakeHandler) HandleUserRegistered(ctx context.Context, e *event.UserRegisteredEvent) error {
+	h.calls++
+	if len(h.errs) == 0 {
+		return nil
+	}
+	err := h.errs[0]
+	h.errs = h.errs[1:]
+	return err
+}
+
+// fakeDLQPublisher 记录写入死信队列的消息，err 不为空时写入失败
+type fakeDLQPublisher struct {
+	published []*sarama.ProducerMessage
+	err       error
+}
+
+func (p *fakeDLQPublisher) PublishToDLQ(ctx context.Context, msg *sarama.ConsumerMessage, attempts int, cause error) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, newDLQMessage(msg, attempts, cause))
+	return nil
+}
+
+func (p *fakeDLQPublisher) Close() error {
+	return nil
+}
+
+func newTestReplayer(handler MessageHandler, publisher DLQPublisher, maxAttempts int) *DLQReplayer {
+	return &DLQReplayer{
+		config:    &config.KafkaClientConfig{DLQMaxAttempts: maxAttempts},
+		handler:   handler,
+		publisher: publisher,
+		logger:    zap.NewNop(),
+	}
+}
+
+func newRegisteredMessage(t *testing.T) *sarama.ConsumerMessage {
+	value, err := json.Marshal(&event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test", "req-1", "user-1"),
+		Username:  "testuser",
+		Email:     "test@example.com",
+	})
+	require.NoError(t, err)
+
+	return &sarama.ConsumerMessage{
+		Topic: "user.events",
+		Key:   []byte("user-1"),
+		Value: value,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("event_type"), Value: []byte(event.UserRegistered)},
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+// toConsumerMessage 模拟从死信主题读取已发布的消息
+func toConsumerMessage(t *testing.T, msg *sarama.ProducerMessage, offset int64) *sarama.ConsumerMessage {
+	value, err := msg.Value.Encode()
+	require.NoError(t, err)
+
+	headers := make([]*sarama.RecordHeader, len(msg.Headers))
+	for i := range msg.Headers {
+		headers[i] = &msg.Headers[i]
+	}
+	return &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Offset:    offset,
+		Value:     value,
+		Headers:   headers,
+		Timestamp: msg.Timestamp,
+	}
+}
+
+func TestKafkaConsumer_DeadLettersFailedMessage(t *testing.T) {
+	publisher := &fakeDLQPublisher{}
+	c := &KafkaConsumer{dlq: publisher, logger: zap.NewNop()}
+
+	ok := c.deadLetter(context.Background(), newRegisteredMessage(t), errors.New("smtp down"))
+
+	assert.True(t, ok)
+	require.Len(t, publisher.published, 1)
+	msg := toConsumerMessage(t, publisher.published[0], 0)
+	assert.Equal(t, "user.events.dlq", msg.Topic)
+	assert.Equal(t, "user.events", getHeader(msg.Headers, HeaderOriginalTopic))
+	assert.Equal(t, "1", getHeader(msg.Headers, HeaderDLQAttempts))
+	assert.Equal(t, "smtp down", getHeader(msg.Headers, HeaderDLQError))
+	assert.Equal(t, string(event.UserRegistered), getHeader(msg.Headers, "event_type"))
+}
+
+func TestKafkaConsumer_StopsClaimWhenDeadLetterFails(t *testing.T) {
+	handler := &fakeHandler{errs: []error{errors.New("smtp down")}}
+	c := &KafkaConsumer{handler: handler, dlq: &fakeDLQPublisher{err: errors.New("broker down")}, logger: zap.NewNop()}
+
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, 2)}
+	first := newRegisteredMessage(t)
+	second := newRegisteredMessage(t)
+	second.Offset = 1
+	claim.messages <- first
+	claim.messages <- second
+	session := &fakeSession{ctx: context.Background()}
+
+	// 无法处理也无法转入死信队列的消息结束会话，后续消息不会越过它标记
+	assert.ErrorContains(t, c.ConsumeClaim(session, claim), "smtp down")
+	assert.Equal(t, 1, handler.calls)
+	assert.Empty(t, session.marked)
+}
+
+func TestDLQReplayer_ReplaySuccess(t *testing.T) {
+	handler := &fakeHandler{}
+	publisher := &fakeDLQPublisher{}
+	r := newTestReplayer(handler, publisher, 3)
+
+	dead := &fakeDLQPublisher{}
+	require.NoError(t, dead.PublishToDLQ(context.Background(), newRegisteredMessage(t), 1, errors.New("boom")))
+
+	before := testutil.ToFloat64(metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplaySuccess))
+	err := r.replay(context.Background(), toConsumerMessage(t, dead.published[0], 0))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, handler.calls)
+	assert.Empty(t, publisher.published)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplaySuccess)))
+}
+
+func TestDLQReplayer_BoundedRefailure(t *testing.T) {
+	failure := errors.New("still failing")
+	handler := &fakeHandler{errs: []error{failure, failure, failure, failure, failure}}
+	publisher := &fakeDLQPublisher{}
+	r := newTestReplayer(handler, publisher, 4)
+
+	require.NoError(t, publisher.PublishToDLQ(context.Background(), newRegisteredMessage(t), 1, errors.New("boom")))
+
+	exhausted := testutil.ToFloat64(metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplayExhausted))
+
+	// 持续重放最新入队的死信消息，直到不再重新入队
+	for offset := 0; offset < len(publisher.published); offset++ {
+		require.NoError(t, r.replay(context.Background(), toConsumerMessage(t, publisher.published[offset], int64(offset))))
+	}
+
+	// 首次失败 1 次，重放失败 3 次后达到上限 4 次
+	assert.Equal(t, 3, handler.calls)
+	require.Len(t, publisher.published, 3)
+	last := toConsumerMessage(t, publisher.published[2], 2)
+	assert.Equal(t, "3", getHeader(last.Headers, HeaderDLQAttempts))
+	assert.Equal(t, "user.events.dlq", last.Topic)
+	assert.Equal(t, "user.events", getHeader(last.Headers, HeaderOriginalTopic))
+	assert.Equal(t, "still failing", getHeader(last.Headers, HeaderDLQError))
+	assert.Equal(t, exhausted+1, testutil.ToFloat64(metrics.DLQReplaysTotal.WithLabelValues(metrics.DLQReplayExhausted)))
+}
+
+// fakeSession 最小化的消费者组会话
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx       context.Context
+	claims    map[string][]int32
+	marked    []int64
+	committed []int64
+}
+
+func (s *fakeSession) Context() context.Context { return s.ctx }
+
+func (s *fakeSession) Claims() map[string][]int32 { return s.claims }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+// Commit 记录提交时已标记的位移
+func (s *fakeSession) Commit() {
+	s.committed = append([]int64(nil), s.marked...)
+}
+
+// fakeClaim 最小化的分区认领
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	topic    string
+	messages chan *sarama.ConsumerMessage
+	hwm      int64
+	initial  int64
+}
+
+func (c *fakeClaim) Topic() string                            { return c.topic }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return c.hwm }
+func (c *fakeClaim) InitialOffset() int64                     { return c.initial }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestDLQReplayer_ConsumeClaimStopsWhenRequeueFails(t *testing.T) {
+	handler := &fakeHandler{errs: []error{errors.New("still failing")}}
+	r := newTestReplayer(handler, &fakeDLQPublisher{err: errors.New("broker down")}, 3)
+
+	dead := &fakeDLQPublisher{}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, dead.PublishToDLQ(context.Background(), newRegisteredMessage(t), 1, errors.New("boom")))
+	}
+
+	claim := &fakeClaim{topic: "user.events.dlq", messages: make(chan *sarama.ConsumerMessage, 2), hwm: 2}
+	claim.messages <- toConsumerMessage(t, dead.published[0], 0)
+	claim.messages <- toConsumerMessage(t, dead.published[1], 1)
+	session := &fakeSession{ctx: context.Background()}
+
+	// 无法重新入队的死信消息结束会话，后续消息不会越过它标记
+	assert.ErrorContains(t, r.ConsumeClaim(session, claim), "broker down")
+	assert.Equal(t, 1, handler.calls)
+	assert.Empty(t, session.marked)
+}
+
+func TestDLQReplayer_ConsumeClaimReportsDepth(t *testing.T) {
+	r := newTestReplayer(&fakeHandler{}, &fakeDLQPublisher{}, 3)
+
+	dead := &fakeDLQPublisher{}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, dead.PublishToDLQ(context.Background(), newRegisteredMessage(t), 1, errors.New("boom")))
+	}
+
+	claim := &fakeClaim{topic: "user.events.dlq", messages: make(chan *sarama.ConsumerMessage, 3), hwm: 3}
+	claim.messages <- toConsumerMessage(t, dead.published[0], 0)
+	claim.messages <- toConsumerMessage(t, dead.published[1], 1)
+	close(claim.messages)
+	session := &fakeSession{ctx: context.Background()}
+
+	require.NoError(t, r.ConsumeClaim(session, claim))
+
+	assert.Equal(t, []int64{0, 1}, session.marked)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DLQDepth.WithLabelValues("user.events.dlq", "0")))
+}
@@ -2,21 +2,44 @@ package consumer
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/notification"
+	"github.com/zhwjimmy/user-center/internal/task"
 	"go.uber.org/zap"
 )
 
+// Notifier 通知发送接口，按用户偏好选择邮件、短信、Webhook 等渠道
+type Notifier interface {
+	Notify(ctx context.Context, n *notification.Notification) error
+}
+
+// WebhookDispatcher 将事件投递给订阅了该事件类型的外部 Webhook
+type WebhookDispatcher interface {
+	DispatchEvent(ctx context.Context, eventType string, body []byte) error
+}
+
 // UserEventHandler 用户事件处理器
 type UserEventHandler struct {
-	logger *zap.Logger
+	notifier Notifier
+	webhooks WebhookDispatcher
+	logins   *LoginHistory
+	logger   *zap.Logger
 	// 可以注入其他服务，如邮件服务、通知服务等
 }
 
-// NewUserEventHandler 创建用户事件处理器
-func NewUserEventHandler(logger *zap.Logger) MessageHandler {
+// NewUserEventHandler 创建用户事件处理器，webhooks 为 nil 时不投递 Webhook
+func NewUserEventHandler(notifier Notifier, webhooks WebhookDispatcher, logins *LoginHistory, logger *zap.Logger) MessageHandler {
 	return &UserEventHandler{
-		logger: logger,
+		notifier: notifier,
+		webhooks: webhooks,
+		logins:   logins,
+		logger:   logger,
 	}
 }
 
@@ -30,26 +53,26 @@ func (h *UserEventHandler) HandleUserRegistered(ctx context.Context, event *even
 	)
 
 	// 业务逻辑处理
-	// 1. 发送欢迎邮件
-	if err := h.sendWelcomeEmail(ctx, event); err != nil {
-		h.logger.Error("Failed to send welcome email",
+	// 欢迎邮件在注册请求中直接投递，以便入队失败时告知客户端
+	// 1. 初始化用户配置
+	if err := h.initializeUserSettings(ctx, event); err != nil {
+		h.logger.Error("Failed to initialize user settings",
 			zap.String("user_id", event.UserID),
 			zap.Error(err),
 		)
-		// 不返回错误，避免阻塞消息处理
 	}
 
-	// 2. 初始化用户配置
-	if err := h.initializeUserSettings(ctx, event); err != nil {
-		h.logger.Error("Failed to initialize user settings",
+	// 2. 记录用户注册统计
+	if err := h.recordUserRegistrationStats(ctx, event); err != nil {
+		h.logger.Error("Failed to record user registration stats",
 			zap.String("user_id", event.UserID),
 			zap.Error(err),
 		)
 	}
 
-	// 3. 记录用户注册统计
-	if err := h.recordUserRegistrationStats(ctx, event); err != nil {
-		h.logger.Error("Failed to record user registration stats",
+	// 3. 投递 Webhook
+	if err := h.dispatchWebhook(ctx, event); err != nil {
+		h.logger.Error("Failed to dispatch webhook",
 			zap.String("user_id", event.UserID),
 			zap.Error(err),
 		)
@@ -92,6 +115,14 @@ func (h *UserEventHandler) HandleUserLoggedIn(ctx context.Context, event *event.
 		)
 	}
 
+	// 4. 投递 Webhook
+	if err := h.dispatchWebhook(ctx, event); err != nil {
+		h.logger.Error("Failed to dispatch webhook",
+			zap.String("user_id", event.UserID),
+			zap.Error(err),
+		)
+	}
+
 	return nil
 }
 
@@ -120,6 +151,14 @@ func (h *UserEventHandler) HandleUserPasswordChanged(ctx context.Context, event
 		)
 	}
 
+	// 3. 投递 Webhook
+	if err := h.dispatchWebhook(ctx, event); err != nil {
+		h.logger.Error("Failed to dispatch webhook",
+			zap.String("user_id", event.UserID),
+			zap.Error(err),
+		)
+	}
+
 	return nil
 }
 
@@ -150,6 +189,14 @@ func (h *UserEventHandler) HandleUserStatusChanged(ctx context.Context, event *e
 		)
 	}
 
+	// 3. 投递 Webhook
+	if err := h.dispatchWebhook(ctx, event); err != nil {
+		h.logger.Error("Failed to dispatch webhook",
+			zap.String("user_id", event.UserID),
+			zap.Error(err),
+		)
+	}
+
 	return nil
 }
 
@@ -178,6 +225,14 @@ func (h *UserEventHandler) HandleUserDeleted(ctx context.Context, event *event.U
 		)
 	}
 
+	// 3. 投递 Webhook
+	if err := h.dispatchWebhook(ctx, event); err != nil {
+		h.logger.Error("Failed to dispatch webhook",
+			zap.String("user_id", event.UserID),
+			zap.Error(err),
+		)
+	}
+
 	return nil
 }
 
@@ -212,12 +267,6 @@ func (h *UserEventHandler) HandleUserUpdated(ctx context.Context, event *event.U
 
 // 以下是具体的业务逻辑实现示例（实际实现需要根据业务需求调整）
 
-func (h *UserEventHandler) sendWelcomeEmail(ctx context.Context, event *event.UserRegisteredEvent) error {
-	// 实现发送欢迎邮件的逻辑
-	h.logger.Debug("Sending welcome email", zap.String("email", event.Email))
-	return nil
-}
-
 func (h *UserEventHandler) initializeUserSettings(ctx context.Context, event *event.UserRegisteredEvent) error {
 	// 实现初始化用户设置的逻辑
 	h.logger.Debug("Initializing user settings", zap.String("user_id", event.UserID))
@@ -242,16 +291,56 @@ func (h *UserEventHandler) updateLastLoginTime(ctx context.Context, event *event
 	return nil
 }
 
+// checkAnomalousLogin 将登录与用户最近的登录历史比对，来自新网段或新 User-Agent 时发送新设备登录提醒
 func (h *UserEventHandler) checkAnomalousLogin(ctx context.Context, event *event.UserLoggedInEvent) error {
-	// 实现检查异常登录的逻辑
-	h.logger.Debug("Checking anomalous login", zap.String("user_id", event.UserID))
-	return nil
+	if h.logins == nil {
+		return nil
+	}
+
+	check, err := h.logins.Record(ctx, event.UserID, event.IPAddress, event.UserAgent, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record login history: %w", err)
+	}
+	if !check.Anomalous() {
+		return nil
+	}
+
+	if check.NewNetwork {
+		metrics.AnomalousLoginsTotal.WithLabelValues(metrics.AnomalousLoginNewNetwork).Inc()
+	}
+	if check.NewUserAgent {
+		metrics.AnomalousLoginsTotal.WithLabelValues(metrics.AnomalousLoginNewUserAgent).Inc()
+	}
+	h.logger.Warn("Login from new device",
+		zap.String("user_id", event.UserID),
+		zap.String("ip_address", event.IPAddress),
+		zap.String("user_agent", event.UserAgent),
+		zap.Bool("new_network", check.NewNetwork),
+		zap.Bool("new_user_agent", check.NewUserAgent),
+	)
+
+	return h.notifier.Notify(ctx, &notification.Notification{
+		UserID:   event.UserID,
+		Email:    event.Email,
+		Category: model.NotificationSecurity,
+		Template: task.TemplateNewDeviceLogin,
+		Data: map[string]string{
+			"username":   event.Username,
+			"ip_address": event.IPAddress,
+			"user_agent": event.UserAgent,
+			"time":       event.Timestamp.UTC().Format(time.RFC1123),
+		},
+	})
 }
 
 func (h *UserEventHandler) sendPasswordChangeNotification(ctx context.Context, event *event.UserPasswordChangedEvent) error {
-	// 实现发送密码变更通知的逻辑
-	h.logger.Debug("Sending password change notification", zap.String("email", event.Email))
-	return nil
+	return h.notifier.Notify(ctx, &notification.Notification{
+		UserID:   event.UserID,
+		Email:    event.Email,
+		Category: model.NotificationSecurity,
+		Template: task.TemplatePasswordChanged,
+		Data:     map[string]string{"username": event.Username},
+	})
 }
 
 func (h *UserEventHandler) recordSecurityLog(ctx context.Context, event *event.UserPasswordChangedEvent) error {
@@ -261,9 +350,17 @@ func (h *UserEventHandler) recordSecurityLog(ctx context.Context, event *event.U
 }
 
 func (h *UserEventHandler) sendStatusChangeNotification(ctx context.Context, event *event.UserStatusChangedEvent) error {
-	// 实现发送状态变更通知的逻辑
-	h.logger.Debug("Sending status change notification", zap.String("email", event.Email))
-	return nil
+	return h.notifier.Notify(ctx, &notification.Notification{
+		UserID:   event.UserID,
+		Email:    event.Email,
+		Category: model.NotificationAccount,
+		Template: task.TemplateStatusChanged,
+		Data: map[string]string{
+			"username":   event.Username,
+			"old_status": event.OldStatus,
+			"new_status": event.NewStatus,
+		},
+	})
 }
 
 func (h *UserEventHandler) updateUserStatusCache(ctx context.Context, event *event.UserStatusChangedEvent) error {
@@ -279,9 +376,13 @@ func (h *UserEventHandler) cleanupUserData(ctx context.Context, event *event.Use
 }
 
 func (h *UserEventHandler) sendAccountDeletionConfirmation(ctx context.Context, event *event.UserDeletedEvent) error {
-	// 实现发送账户删除确认邮件的逻辑
-	h.logger.Debug("Sending account deletion confirmation", zap.String("email", event.Email))
-	return nil
+	return h.notifier.Notify(ctx, &notification.Notification{
+		UserID:   event.UserID,
+		Email:    event.Email,
+		Category: model.NotificationSecurity,
+		Template: task.TemplateAccountDeleted,
+		Data:     map[string]string{"username": event.Username},
+	})
 }
 
 func (h *UserEventHandler) updateUserCache(ctx context.Context, event *event.UserUpdatedEvent) error {
@@ -290,8 +391,20 @@ func (h *UserEventHandler) updateUserCache(ctx context.Context, event *event.Use
 	return nil
 }
 
+// syncUserInfoToExternalSystems 通过 Webhook 将用户信息变更推送给外部系统
 func (h *UserEventHandler) syncUserInfoToExternalSystems(ctx context.Context, event *event.UserUpdatedEvent) error {
-	// 实现同步用户信息到外部系统的逻辑
-	h.logger.Debug("Syncing user info to external systems", zap.String("user_id", event.UserID))
-	return nil
+	return h.dispatchWebhook(ctx, event)
+}
+
+// dispatchWebhook 将事件的 JSON 编码投递给订阅了该事件类型的 Webhook
+func (h *UserEventHandler) dispatchWebhook(ctx context.Context, e interface{ GetBaseEvent() *event.BaseEvent }) error {
+	if h.webhooks == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+	return h.webhooks.DispatchEvent(ctx, string(e.GetBaseEvent().Type), body)
 }
@@ -0,0 +1,213 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/notification"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// fakeNotifier 记录已发送通知的模板
+type fakeNotifier struct {
+	sent []*notification.Notification
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, msg *notification.Notification) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func (n *fakeNotifier) templates() []string {
+	var templates []string
+	for _, msg := range n.sent {
+		templates = append(templates, msg.Template)
+	}
+	return templates
+}
+
+func newTestEventHandler() (*UserEventHandler, *fakeNotifier) {
+	notifier := &fakeNotifier{}
+	return &UserEventHandler{notifier: notifier, logger: zap.NewNop()}, notifier
+}
+
+// fakeWebhookDispatcher 记录投递的事件类型和内容
+type fakeWebhookDispatcher struct {
+	types  []string
+	bodies [][]byte
+}
+
+func (d *fakeWebhookDispatcher) DispatchEvent(ctx context.Context, eventType string, body []byte) error {
+	d.types = append(d.types, eventType)
+	d.bodies = append(d.bodies, body)
+	return nil
+}
+
+func TestUserEventHandler_DispatchesWebhooks(t *testing.T) {
+	webhooks := &fakeWebhookDispatcher{}
+	h := &UserEventHandler{notifier: &fakeNotifier{}, webhooks: webhooks, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	require.NoError(t, h.HandleUserRegistered(ctx, &event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test", "", "user-1"),
+		Username:  "alice",
+		Email:     "alice@example.com",
+	}))
+	require.NoError(t, h.HandleUserUpdated(ctx, &event.UserUpdatedEvent{
+		BaseEvent: event.NewBaseEvent(event.UserUpdated, "test", "", "user-1"),
+		Username:  "alice",
+	}))
+	require.NoError(t, h.HandleUserDeleted(ctx, &event.UserDeletedEvent{
+		BaseEvent: event.NewBaseEvent(event.UserDeleted, "test", "", "user-1"),
+		Username:  "alice",
+	}))
+
+	assert.Equal(t, []string{"user.registered", "user.updated", "user.deleted"}, webhooks.types)
+
+	var registered event.UserRegisteredEvent
+	require.NoError(t, json.Unmarshal(webhooks.bodies[0], &registered))
+	assert.Equal(t, "user-1", registered.UserID)
+	assert.Equal(t, "alice@example.com", registered.Email)
+}
+
+func TestUserEventHandler_Notifications(t *testing.T) {
+	h, notifier := newTestEventHandler()
+	ctx := context.Background()
+
+	err := h.HandleUserStatusChanged(ctx, &event.UserStatusChangedEvent{
+		BaseEvent: event.NewBaseEvent(event.UserStatusChanged, "test", "", "user-1"),
+		Username:  "alice",
+		Email:     "test@example.com",
+		OldStatus: "active",
+		NewStatus: "suspended",
+	})
+	require.NoError(t, err)
+
+	err = h.HandleUserPasswordChanged(ctx, &event.UserPasswordChangedEvent{
+		BaseEvent: event.NewBaseEvent(event.UserPasswordChanged, "test", "", "user-1"),
+		Username:  "alice",
+		Email:     "test@example.com",
+	})
+	require.NoError(t, err)
+
+	// 状态变更属于 account 类，密码变更属于不可退订的 security 类
+	assert.Equal(t, []*notification.Notification{
+		{
+			UserID:   "user-1",
+			Email:    "test@example.com",
+			Category: model.NotificationAccount,
+			Template: task.TemplateStatusChanged,
+			Data:     map[string]string{"username": "alice", "old_status": "active", "new_status": "suspended"},
+		},
+		{
+			UserID:   "user-1",
+			Email:    "test@example.com",
+			Category: model.NotificationSecurity,
+			Template: task.TemplatePasswordChanged,
+			Data:     map[string]string{"username": "alice"},
+		},
+	}, notifier.sent)
+}
+
+func TestUserEventHandler_AnomalousLogin(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	h, notifier := newTestEventHandler()
+	h.logins = NewLoginHistory(redis, &config.KafkaClientConfig{LoginHistorySize: 3}, zap.NewNop())
+
+	const firefox = "Mozilla/5.0 Firefox/128.0"
+
+	tests := []struct {
+		name      string
+		ipAddress string
+		userAgent string
+		alert     bool
+	}{
+		{name: "first login", ipAddress: "203.0.113.10", userAgent: firefox, alert: false},
+		{name: "repeat IP", ipAddress: "203.0.113.10", userAgent: firefox, alert: false},
+		{name: "same network", ipAddress: "203.0.113.77", userAgent: firefox, alert: false},
+		{name: "new IP", ipAddress: "198.51.100.5", userAgent: firefox, alert: true},
+		{name: "new user agent", ipAddress: "198.51.100.5", userAgent: "curl/8.5.0", alert: true},
+	}
+
+	// 各场景依次登录同一用户，共享登录历史
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier.sent = nil
+
+			err := h.HandleUserLoggedIn(context.Background(), &event.UserLoggedInEvent{
+				BaseEvent: event.NewBaseEvent(event.UserLoggedIn, "test", "", "user-1"),
+				Email:     "test@example.com",
+				IPAddress: tt.ipAddress,
+				UserAgent: tt.userAgent,
+			})
+			require.NoError(t, err)
+
+			if tt.alert {
+				assert.Equal(t, []string{task.TemplateNewDeviceLogin}, notifier.templates())
+			} else {
+				assert.Empty(t, notifier.sent)
+			}
+		})
+	}
+}
+
+func TestLoginHistory_CapsHistory(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	history := NewLoginHistory(redis, &config.KafkaClientConfig{LoginHistorySize: 2}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, ip := range []string{"192.0.2.1", "198.51.100.1", "203.0.113.1"} {
+		_, err := history.Record(ctx, "user-1", ip, "", now.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+	}
+
+	// 最早的网段已被挤出历史
+	check, err := history.Record(ctx, "user-1", "192.0.2.1", "", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, check.NewNetwork)
+	assert.True(t, check.Anomalous())
+
+	check, err = history.Record(ctx, "user-1", "203.0.113.1", "", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, check.Anomalous())
+}
+
+func TestLoginHistory_RecordsActivity(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	history := NewLoginHistory(redis, &config.KafkaClientConfig{LoginHistorySize: 3}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := history.Record(ctx, "user-1", "192.0.2.1", "curl/8.0", now)
+	require.NoError(t, err)
+	_, err = history.Record(ctx, "user-1", "198.51.100.1", "curl/8.0", now.Add(time.Minute))
+	require.NoError(t, err)
+
+	// 最新的登录在前，并带有比对结果
+	entries, total, err := redis.RangeRecent(ctx, cache.LoginActivityKey("user-1"), 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, entries, 2)
+
+	var latest, first model.LoginActivity
+	require.NoError(t, json.Unmarshal(entries[0], &latest))
+	require.NoError(t, json.Unmarshal(entries[1], &first))
+	assert.Equal(t, model.LoginActivity{Time: now.Add(time.Minute), IPAddress: "198.51.100.1", UserAgent: "curl/8.0", Anomalous: true}, latest)
+	assert.Equal(t, model.LoginActivity{Time: now, IPAddress: "192.0.2.1", UserAgent: "curl/8.0"}, first)
+}
+
+func TestNewLoginHistory_DisabledWithoutSize(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	assert.Nil(t, NewLoginHistory(redis, &config.KafkaClientConfig{}, zap.NewNop()))
+}
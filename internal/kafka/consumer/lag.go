@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// LagMonitor 记录消费者各分区的积压量，主题总积压超过阈值时将其标记为降级
+type LagMonitor struct {
+	threshold int64
+	logger    *zap.Logger
+
+	mu       sync.RWMutex
+	lags     map[string]map[int32]int64 // topic -> partition -> lag
+	degraded map[string]int64           // 超过阈值的主题及其总积压
+}
+
+// NewLagMonitor 创建积压监控，阈值为 0 时只上报指标不做降级判断
+func NewLagMonitor(cfg *config.KafkaClientConfig, logger *zap.Logger) *LagMonitor {
+	return &LagMonitor{
+		threshold: cfg.LagThreshold,
+		logger:    logger,
+		lags:      make(map[string]map[int32]int64),
+		degraded:  make(map[string]int64),
+	}
+}
+
+// Record 记录分区积压，并在主题总积压越过阈值时记录告警日志
+func (m *LagMonitor) Record(topic string, partition int32, lag int64) {
+	if m == nil {
+		return
+	}
+	metrics.ConsumerLag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	partitions, ok := m.lags[topic]
+	if !ok {
+		partitions = make(map[int32]int64)
+		m.lags[topic] = partitions
+	}
+	partitions[partition] = lag
+
+	if m.threshold <= 0 {
+		return
+	}
+
+	var total int64
+	for _, l := range partitions {
+		total += l
+	}
+
+	_, wasDegraded := m.degraded[topic]
+	switch {
+	case total > m.threshold:
+		if !wasDegraded {
+			m.logger.Warn("Kafka consumer lag exceeds threshold",
+				zap.String("topic", topic),
+				zap.Int64("lag", total),
+				zap.Int64("threshold", m.threshold),
+			)
+		}
+		m.degraded[topic] = total
+	case wasDegraded:
+		m.logger.Info("Kafka consumer lag back under threshold",
+			zap.String("topic", topic),
+			zap.Int64("lag", total),
+			zap.Int64("threshold", m.threshold),
+		)
+		delete(m.degraded, topic)
+	}
+}
+
+// Check 返回积压超过阈值的主题，全部正常时返回 nil
+func (m *LagMonitor) Check() error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.degraded) == 0 {
+		return nil
+	}
+
+	topics := make([]string, 0, len(m.degraded))
+	for topic, lag := range m.degraded {
+		topics = append(topics, fmt.Sprintf("%s lag %d", topic, lag))
+	}
+	sort.Strings(topics)
+
+	return fmt.Errorf("%s exceeds threshold %d", strings.Join(topics, ", "), m.threshold)
+}
@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
+)
+
+func TestLagMonitor_Check(t *testing.T) {
+	m := NewLagMonitor(&config.KafkaClientConfig{LagThreshold: 100}, zap.NewNop())
+	assert.NoError(t, m.Check())
+
+	// 阈值按主题内所有分区的积压之和判断
+	m.Record("lag.test", 0, 60)
+	assert.NoError(t, m.Check())
+	m.Record("lag.test", 1, 60)
+	assert.EqualError(t, m.Check(), "lag.test lag 120 exceeds threshold 100")
+
+	m.Record("lag.test", 1, 0)
+	assert.NoError(t, m.Check())
+}
+
+func TestLagMonitor_ZeroThresholdNeverDegrades(t *testing.T) {
+	m := NewLagMonitor(&config.KafkaClientConfig{}, zap.NewNop())
+	m.Record("lag.disabled", 0, 1_000_000)
+	assert.NoError(t, m.Check())
+}
+
+func TestKafkaConsumer_ConsumeClaimReportsLag(t *testing.T) {
+	lag := NewLagMonitor(&config.KafkaClientConfig{LagThreshold: 1000}, zap.NewNop())
+	c := &KafkaConsumer{handler: &fakeHandler{}, lag: lag, logger: zap.NewNop()}
+
+	// 模拟消费者远落后于高水位
+	claim := &fakeClaim{topic: "user.events", messages: make(chan *sarama.ConsumerMessage, 1), hwm: 5000}
+	msg := newRegisteredMessage(t)
+	msg.Offset = 10
+	claim.messages <- msg
+	close(claim.messages)
+
+	require.NoError(t, c.ConsumeClaim(&fakeSession{ctx: context.Background()}, claim))
+
+	assert.Equal(t, float64(4989), testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues("user.events", "0")))
+	assert.EqualError(t, lag.Check(), "user.events lag 4989 exceeds threshold 1000")
+}
+
+func TestKafkaConsumer_ConsumeClaimReportsLagBeforeFirstMessage(t *testing.T) {
+	c := &KafkaConsumer{handler: &fakeHandler{}, lag: NewLagMonitor(&config.KafkaClientConfig{}, zap.NewNop()), logger: zap.NewNop()}
+
+	// 已提交位移为 100，高水位为 150，尚未收到新消息
+	claim := &fakeClaim{topic: "lag.committed", messages: make(chan *sarama.ConsumerMessage), hwm: 150, initial: 100}
+	close(claim.messages)
+
+	require.NoError(t, c.ConsumeClaim(&fakeSession{ctx: context.Background()}, claim))
+	assert.Equal(t, float64(50), testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues("lag.committed", "0")))
+}
+
+func TestKafkaConsumer_ConsumeClaimCountsMessages(t *testing.T) {
+	handler := &fakeHandler{errs: []error{errors.New("boom")}}
+	c := &KafkaConsumer{handler: handler, dlq: &fakeDLQPublisher{}, logger: zap.NewNop()}
+
+	session := &fakeSession{ctx: context.Background(), claims: map[string][]int32{"metrics.test": {0}}}
+	require.NoError(t, c.Setup(session))
+
+	consumed := metrics.ConsumerMessagesTotal.WithLabelValues("metrics.test", "0")
+	failed := metrics.ConsumerErrorsTotal.WithLabelValues("metrics.test", "0")
+	consumedBefore, failedBefore := testutil.ToFloat64(consumed), testutil.ToFloat64(failed)
+
+	// 第一条消息处理失败并转入死信队列，第二条成功
+	claim := &fakeClaim{topic: "metrics.test", messages: make(chan *sarama.ConsumerMessage, 2), initial: sarama.OffsetNewest}
+	claim.messages <- newRegisteredMessage(t)
+	claim.messages <- newRegisteredMessage(t)
+	close(claim.messages)
+	require.NoError(t, c.ConsumeClaim(session, claim))
+
+	assert.Equal(t, 2, handler.calls)
+	assert.Equal(t, consumedBefore+2, testutil.ToFloat64(consumed))
+	assert.Equal(t, failedBefore+1, testutil.ToFloat64(failed))
+}
@@ -0,0 +1,112 @@
+package consumer
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"go.uber.org/zap"
+)
+
+// loginHistoryTTL 用户长期未登录时登录历史的保留时长
+const loginHistoryTTL = 90 * 24 * time.Hour
+
+// LoginActivitySize 每个用户保留的最近登录记录数量，供用户查看自己的登录活动
+const LoginActivitySize = 100
+
+// 同一网段内的地址视为同一来源，避免动态分配地址导致误报
+var (
+	ipv4NetworkMask = net.CIDRMask(24, 32)
+	ipv6NetworkMask = net.CIDRMask(48, 128)
+)
+
+// LoginCheck 登录与用户最近登录历史的比对结果
+type LoginCheck struct {
+	// First 用户此前没有登录记录
+	First bool
+	// NewNetwork 登录来自最近未出现过的网段
+	NewNetwork bool
+	// NewUserAgent 登录使用了最近未出现过的 User-Agent
+	NewUserAgent bool
+}
+
+// Anomalous 判断是否需要提醒用户，首次登录没有可比对的历史，不视为异常
+func (c LoginCheck) Anomalous() bool {
+	return !c.First && (c.NewNetwork || c.NewUserAgent)
+}
+
+// LoginHistory 在 Redis 中保存每个用户最近登录的网段和 User-Agent，用于识别新设备登录
+type LoginHistory struct {
+	redis  *cache.Redis
+	size   int
+	logger *zap.Logger
+}
+
+// NewLoginHistory 创建登录历史，LoginHistorySize 为 0 时返回 nil，即不检测
+func NewLoginHistory(redis *cache.Redis, cfg *config.KafkaClientConfig, logger *zap.Logger) *LoginHistory {
+	if cfg.LoginHistorySize <= 0 {
+		return nil
+	}
+	return &LoginHistory{
+		redis:  redis,
+		size:   cfg.LoginHistorySize,
+		logger: logger,
+	}
+}
+
+// Record 将本次登录与用户的最近登录历史比对后写入历史，每类历史只保留最近 size 条。
+// 缺少 IP 或 User-Agent 时不比对对应项。本次登录连同比对结果另存入登录活动，
+// 保留最近 LoginActivitySize 条
+func (h *LoginHistory) Record(ctx context.Context, userID, ipAddress, userAgent string, now time.Time) (LoginCheck, error) {
+	check := LoginCheck{First: true}
+	key := cache.LoginHistoryKeyPrefix + userID
+
+	if ipAddress != "" {
+		seen, count, err := h.redis.RememberRecent(ctx, key+":networks", loginNetwork(ipAddress), now, h.size, loginHistoryTTL)
+		if err != nil {
+			return LoginCheck{}, err
+		}
+		check.First = check.First && count == 0
+		check.NewNetwork = !seen
+	}
+
+	if userAgent != "" {
+		seen, count, err := h.redis.RememberRecent(ctx, key+":agents", userAgent, now, h.size, loginHistoryTTL)
+		if err != nil {
+			return LoginCheck{}, err
+		}
+		check.First = check.First && count == 0
+		check.NewUserAgent = !seen
+	}
+
+	activity := model.LoginActivity{
+		Time:      now.UTC(),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Anomalous: check.Anomalous(),
+	}
+	// 登录活动仅供展示，写入失败不影响新设备提醒
+	if err := h.redis.PushRecent(ctx, cache.LoginActivityKey(userID), activity, LoginActivitySize, loginHistoryTTL); err != nil {
+		h.logger.Warn("Failed to record login activity",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+	}
+
+	return check, nil
+}
+
+// loginNetwork 返回 IP 所在网段，IPv4 取 /24，IPv6 取 /48，无法解析时原样返回
+func loginNetwork(ipAddress string) string {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return ipAddress
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(ipv4NetworkMask), Mask: ipv4NetworkMask}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(ipv6NetworkMask), Mask: ipv6NetworkMask}).String()
+}
@@ -0,0 +1,110 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// HeaderContentType 标识消息体编码的消息头，消费者据此选择解码器
+const HeaderContentType = "content-type"
+
+// 消息体编码格式
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// 消息体的 content-type
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Serializer 事件序列化接口
+type Serializer interface {
+	// ContentType 返回写入 content-type 消息头的值
+	ContentType() string
+	// Marshal 序列化事件，event 为指向事件结构的指针
+	Marshal(event interface{}) ([]byte, error)
+	// Unmarshal 将消息体解码到 event 指向的事件结构
+	Unmarshal(data []byte, event interface{}) error
+}
+
+// NewSerializer 按配置的编码格式创建序列化器，空值表示 JSON
+func NewSerializer(format string) (Serializer, error) {
+	switch format {
+	case "", FormatJSON:
+		return JSONSerializer{}, nil
+	case FormatProtobuf:
+		return ProtobufSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported event serialization: %s", format)
+	}
+}
+
+// SerializerForContentType 按消息的 content-type 选择解码器。
+// 没有 content-type 的旧消息按 JSON 解码。
+func SerializerForContentType(contentType string) (Serializer, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSONSerializer{}, nil
+	case ContentTypeProtobuf:
+		return ProtobufSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported event content type: %s", contentType)
+	}
+}
+
+// NewEvent 按事件类型创建空的事件结构
+func NewEvent(eventType EventType) (interface{ GetBaseEvent() *BaseEvent }, error) {
+	switch eventType {
+	case UserRegistered:
+		return &UserRegisteredEvent{}, nil
+	case UserLoggedIn:
+		return &UserLoggedInEvent{}, nil
+	case UserPasswordChanged:
+		return &UserPasswordChangedEvent{}, nil
+	case UserStatusChanged:
+		return &UserStatusChangedEvent{}, nil
+	case UserDeleted:
+		return &UserDeletedEvent{}, nil
+	case UserUpdated:
+		return &UserUpdatedEvent{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported event type: %s", eventType)
+	}
+}
+
+// Transcode 将 JSON 编码的事件（如 outbox 中保存的事件）转换为 serializer 的编码
+func Transcode(payload []byte, eventType EventType, serializer Serializer) ([]byte, error) {
+	if serializer.ContentType() == ContentTypeJSON {
+		return payload, nil
+	}
+
+	e, err := NewEvent(eventType)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, e); err != nil {
+		return nil, fmt.Errorf("failed to decode event payload: %w", err)
+	}
+	return serializer.Marshal(e)
+}
+
+// JSONSerializer JSON 序列化器
+type JSONSerializer struct{}
+
+// ContentType 返回 JSON 的 content-type
+func (JSONSerializer) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Marshal 将事件编码为 JSON
+func (JSONSerializer) Marshal(event interface{}) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// Unmarshal 从 JSON 解码事件
+func (JSONSerializer) Unmarshal(data []byte, event interface{}) error {
+	return json.Unmarshal(data, event)
+}
@@ -0,0 +1,231 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ProtobufSerializer protobuf 序列化器，消息定义见 user_events.proto
+type ProtobufSerializer struct{}
+
+// ContentType 返回 protobuf 的 content-type
+func (ProtobufSerializer) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+// Marshal 将事件编码为 protobuf
+func (ProtobufSerializer) Marshal(event interface{}) ([]byte, error) {
+	fields, err := eventFields(event)
+	if err != nil {
+		return nil, err
+	}
+	return appendFields(nil, fields)
+}
+
+// Unmarshal 从 protobuf 解码事件，未知字段会被忽略
+func (ProtobufSerializer) Unmarshal(data []byte, event interface{}) error {
+	fields, err := eventFields(event)
+	if err != nil {
+		return err
+	}
+	return consumeFields(data, fields)
+}
+
+// protoField 描述 protobuf 消息中的一个字段及其对应的 Go 字段，只有一个指针非空
+type protoField struct {
+	num     protowire.Number
+	str     *string
+	time    *time.Time
+	timePtr **time.Time
+	values  *map[string]interface{}
+	base    *BaseEvent
+}
+
+// baseEventFields 返回 BaseEvent 消息的字段
+func baseEventFields(e *BaseEvent) []protoField {
+	return []protoField{
+		{num: 1, str: &e.ID},
+		{num: 2, str: (*string)(&e.Type)},
+		{num: 3, str: &e.Source},
+		{num: 4, time: &e.Timestamp},
+		{num: 5, time: &e.EventTime},
+		{num: 6, timePtr: &e.PublishedAt},
+		{num: 7, str: &e.Version},
+		{num: 8, str: &e.RequestID},
+		{num: 9, str: &e.UserID},
+		{num: 10, values: &e.Data},
+	}
+}
+
+// eventFields 返回事件对应 protobuf 消息的字段，字段编号与 user_events.proto 一致
+func eventFields(event interface{}) ([]protoField, error) {
+	switch e := event.(type) {
+	case *UserRegisteredEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+			{num: 4, str: &e.FirstName},
+			{num: 5, str: &e.LastName},
+		}, nil
+	case *UserLoggedInEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+			{num: 4, str: &e.IPAddress},
+			{num: 5, str: &e.UserAgent},
+		}, nil
+	case *UserPasswordChangedEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+			{num: 4, str: &e.IPAddress},
+		}, nil
+	case *UserStatusChangedEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+			{num: 4, str: &e.OldStatus},
+			{num: 5, str: &e.NewStatus},
+		}, nil
+	case *UserDeletedEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+		}, nil
+	case *UserUpdatedEvent:
+		return []protoField{
+			{num: 1, base: &e.BaseEvent},
+			{num: 2, str: &e.Username},
+			{num: 3, str: &e.Email},
+			{num: 4, values: &e.Changes},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported event type: %T", event)
+	}
+}
+
+// appendFields 按 proto3 规则编码字段，零值字段不写入
+func appendFields(b []byte, fields []protoField) ([]byte, error) {
+	for _, f := range fields {
+		value, ok, err := f.encode()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		b = protowire.AppendTag(b, f.num, protowire.BytesType)
+		b = protowire.AppendBytes(b, value)
+	}
+	return b, nil
+}
+
+// encode 编码字段的值，零值返回 false
+func (f protoField) encode() ([]byte, bool, error) {
+	switch {
+	case f.str != nil:
+		return []byte(*f.str), *f.str != "", nil
+	case f.time != nil:
+		if f.time.IsZero() {
+			return nil, false, nil
+		}
+		value, err := proto.Marshal(timestamppb.New(*f.time))
+		return value, true, err
+	case f.timePtr != nil:
+		if *f.timePtr == nil {
+			return nil, false, nil
+		}
+		value, err := proto.Marshal(timestamppb.New(**f.timePtr))
+		return value, true, err
+	case f.values != nil:
+		if *f.values == nil {
+			return nil, false, nil
+		}
+		s, err := structpb.NewStruct(*f.values)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to encode field %d: %w", f.num, err)
+		}
+		value, err := proto.Marshal(s)
+		return value, true, err
+	case f.base != nil:
+		value, err := appendFields(nil, baseEventFields(f.base))
+		return value, true, err
+	default:
+		return nil, false, nil
+	}
+}
+
+// consumeFields 解码消息并写入对应字段
+func consumeFields(data []byte, fields []protoField) error {
+	byNum := make(map[protowire.Number]protoField, len(fields))
+	for _, f := range fields {
+		byNum[f.num] = f
+	}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		f, ok := byNum[num]
+		if !ok || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := f.decode(value); err != nil {
+			return fmt.Errorf("failed to decode field %d: %w", num, err)
+		}
+	}
+	return nil
+}
+
+// decode 将字段的值写入对应的 Go 字段
+func (f protoField) decode(value []byte) error {
+	switch {
+	case f.str != nil:
+		*f.str = string(value)
+	case f.time != nil, f.timePtr != nil:
+		var ts timestamppb.Timestamp
+		if err := proto.Unmarshal(value, &ts); err != nil {
+			return err
+		}
+		t := ts.AsTime()
+		if f.time != nil {
+			*f.time = t
+		} else {
+			*f.timePtr = &t
+		}
+	case f.values != nil:
+		var s structpb.Struct
+		if err := proto.Unmarshal(value, &s); err != nil {
+			return err
+		}
+		*f.values = s.AsMap()
+	case f.base != nil:
+		return consumeFields(value, baseEventFields(f.base))
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testEvents 返回每种事件类型的示例事件
+func testEvents() []interface{ GetBaseEvent() *BaseEvent } {
+	base := func(eventType EventType) BaseEvent {
+		b := NewBaseEvent(eventType, "user-center", "req-1", "user-1")
+		b.Data["attempt"] = float64(2)
+		b.MarkPublished(b.EventTime.Add(time.Second))
+		return b
+	}
+
+	return []interface{ GetBaseEvent() *BaseEvent }{
+		&UserRegisteredEvent{BaseEvent: base(UserRegistered), Username: "alice", Email: "alice@example.com", FirstName: "Alice"},
+		&UserLoggedInEvent{BaseEvent: base(UserLoggedIn), Username: "alice", Email: "alice@example.com", IPAddress: "10.0.0.1", UserAgent: "curl/8.0"},
+		&UserPasswordChangedEvent{BaseEvent: base(UserPasswordChanged), Username: "alice", Email: "alice@example.com", IPAddress: "10.0.0.1"},
+		&UserStatusChangedEvent{BaseEvent: base(UserStatusChanged), Username: "alice", Email: "alice@example.com", OldStatus: "active", NewStatus: "suspended"},
+		&UserDeletedEvent{BaseEvent: base(UserDeleted), Username: "alice", Email: "alice@example.com"},
+		&UserUpdatedEvent{
+			BaseEvent: base(UserUpdated),
+			Username:  "alice",
+			Email:     "alice@example.com",
+			Changes: map[string]interface{}{
+				"first_name": map[string]interface{}{"old": "Al", "new": "Alice"},
+				"phone":      nil,
+			},
+		},
+	}
+}
+
+// assertSameEvent 比较两个事件，时间按时刻比较以忽略时区差异
+func assertSameEvent(t *testing.T, expected, actual interface{ GetBaseEvent() *BaseEvent }) {
+	t.Helper()
+
+	want, got := *expected.GetBaseEvent(), *actual.GetBaseEvent()
+	assert.True(t, want.Timestamp.Equal(got.Timestamp))
+	assert.True(t, want.EventTime.Equal(got.EventTime))
+	require.NotNil(t, got.PublishedAt)
+	assert.True(t, want.PublishedAt.Equal(*got.PublishedAt))
+
+	// 时间字段已比较，其余字段按 JSON 比较
+	for _, e := range []*BaseEvent{expected.GetBaseEvent(), actual.GetBaseEvent()} {
+		e.Timestamp, e.EventTime, e.PublishedAt = time.Time{}, time.Time{}, nil
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatProtobuf} {
+		serializer, err := NewSerializer(format)
+		require.NoError(t, err)
+
+		for _, original := range testEvents() {
+			eventType := original.GetBaseEvent().Type
+			t.Run(format+"/"+string(eventType), func(t *testing.T) {
+				data, err := serializer.Marshal(original)
+				require.NoError(t, err)
+
+				decoder, err := SerializerForContentType(serializer.ContentType())
+				require.NoError(t, err)
+
+				decoded, err := NewEvent(eventType)
+				require.NoError(t, err)
+				require.NoError(t, decoder.Unmarshal(data, decoded))
+
+				assertSameEvent(t, original, decoded)
+			})
+		}
+	}
+}
+
+func TestProtobufSerializer_SkipsUnknownFields(t *testing.T) {
+	original := &UserDeletedEvent{
+		BaseEvent: NewBaseEvent(UserDeleted, "user-center", "req-1", "user-1"),
+		Username:  "alice",
+		Email:     "alice@example.com",
+	}
+	data, err := ProtobufSerializer{}.Marshal(original)
+	require.NoError(t, err)
+
+	// 新版本生产者可能写入当前版本不认识的字段
+	data = protowire.AppendTag(data, 99, protowire.VarintType)
+	data = protowire.AppendVarint(data, 7)
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+
+	var decoded UserDeletedEvent
+	require.NoError(t, ProtobufSerializer{}.Unmarshal(data, &decoded))
+	assert.Equal(t, "alice", decoded.Username)
+	assert.Equal(t, "user-1", decoded.UserID)
+
+	assert.Error(t, ProtobufSerializer{}.Unmarshal([]byte{0x0a, 0x05, 'a'}, &decoded))
+}
+
+func TestTranscode(t *testing.T) {
+	original := &UserStatusChangedEvent{
+		BaseEvent: NewBaseEvent(UserStatusChanged, "user-center", "req-1", "user-1"),
+		Username:  "alice",
+		OldStatus: "active",
+		NewStatus: "suspended",
+	}
+	payload, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	unchanged, err := Transcode(payload, UserStatusChanged, JSONSerializer{})
+	require.NoError(t, err)
+	assert.Equal(t, payload, unchanged)
+
+	data, err := Transcode(payload, UserStatusChanged, ProtobufSerializer{})
+	require.NoError(t, err)
+
+	var decoded UserStatusChangedEvent
+	require.NoError(t, ProtobufSerializer{}.Unmarshal(data, &decoded))
+	assert.Equal(t, "suspended", decoded.NewStatus)
+	assert.True(t, original.EventTime.Equal(decoded.EventTime))
+
+	_, err = Transcode(payload, EventType("user.unknown"), ProtobufSerializer{})
+	assert.Error(t, err)
+}
+
+func TestSerializerSelection(t *testing.T) {
+	_, err := NewSerializer("avro")
+	assert.Error(t, err)
+
+	legacy, err := SerializerForContentType("")
+	require.NoError(t, err)
+	assert.Equal(t, ContentTypeJSON, legacy.ContentType())
+
+	_, err = SerializerForContentType("text/plain")
+	assert.Error(t, err)
+}
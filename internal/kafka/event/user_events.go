@@ -2,6 +2,7 @@ package event
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,15 +22,20 @@ const (
 )
 
 // BaseEvent 基础事件结构
+//
+// EventTime 为业务动作发生的时间，PublishedAt 为消息发送到 Kafka 的时间；
+// 事件经 outbox 中继时两者可能相差较大。Timestamp 与 EventTime 相同，保留以兼容旧消费者。
 type BaseEvent struct {
-	ID        string                 `json:"id"`
-	Type      EventType              `json:"type"`
-	Source    string                 `json:"source"`
-	Timestamp time.Time              `json:"timestamp"`
-	Version   string                 `json:"version"`
-	RequestID string                 `json:"request_id,omitempty"`
-	UserID    string                 `json:"user_id,omitempty"`
-	Data      map[string]interface{} `json:"data"`
+	ID          string                 `json:"id"`
+	Type        EventType              `json:"type"`
+	Source      string                 `json:"source"`
+	Timestamp   time.Time              `json:"timestamp"`
+	EventTime   time.Time              `json:"event_time"`
+	PublishedAt *time.Time             `json:"published_at,omitempty"`
+	Version     string                 `json:"version"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	UserID      string                 `json:"user_id,omitempty"`
+	Data        map[string]interface{} `json:"data"`
 }
 
 // UserRegisteredEvent 用户注册事件
@@ -77,18 +83,21 @@ type UserDeletedEvent struct {
 // UserUpdatedEvent 用户更新事件
 type UserUpdatedEvent struct {
 	BaseEvent
-	Username string                 `json:"username"`
-	Email    string                 `json:"email"`
-	Changes  map[string]interface{} `json:"changes"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// Changes 变更的字段，键为字段的 JSON 名，值为 {"old": 旧值, "new": 新值}
+	Changes map[string]interface{} `json:"changes"`
 }
 
 // NewBaseEvent 创建基础事件
 func NewBaseEvent(eventType EventType, source, requestID, userID string) BaseEvent {
+	now := time.Now()
 	return BaseEvent{
 		ID:        generateEventID(),
 		Type:      eventType,
 		Source:    source,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		EventTime: now,
 		Version:   "1.0",
 		RequestID: requestID,
 		UserID:    userID,
@@ -96,6 +105,44 @@ func NewBaseEvent(eventType EventType, source, requestID, userID string) BaseEve
 	}
 }
 
+// GetBaseEvent 返回事件的基础字段，便于统一处理嵌入 BaseEvent 的各类事件
+func (e *BaseEvent) GetBaseEvent() *BaseEvent {
+	return e
+}
+
+// MarkPublished 记录事件的发送时间
+func (e *BaseEvent) MarkPublished(t time.Time) {
+	e.PublishedAt = &t
+}
+
+// StampPublished 在已序列化的事件中写入 published_at，并返回事件的 event_time。
+// 缺少 event_time 的旧事件返回零值。
+func StampPublished(payload []byte, publishedAt time.Time) ([]byte, time.Time, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode event payload: %w", err)
+	}
+
+	var eventTime time.Time
+	if raw, ok := fields["event_time"]; ok {
+		if err := json.Unmarshal(raw, &eventTime); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to decode event_time: %w", err)
+		}
+	}
+
+	published, err := json.Marshal(publishedAt)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	fields["published_at"] = published
+
+	stamped, err := json.Marshal(fields)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to encode event payload: %w", err)
+	}
+	return stamped, eventTime, nil
+}
+
 // ToJSON 将事件转换为JSON
 func (e *BaseEvent) ToJSON() ([]byte, error) {
 	return json.Marshal(e)
@@ -0,0 +1,60 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStampPublished(t *testing.T) {
+	loggedIn := &UserLoggedInEvent{
+		BaseEvent: NewBaseEvent(UserLoggedIn, "test", "req-1", "user-1"),
+		Username:  "testuser",
+		Email:     "test@example.com",
+	}
+	payload, err := json.Marshal(loggedIn)
+	require.NoError(t, err)
+
+	// 事件先写入 outbox，稍后由中继发送
+	publishedAt := loggedIn.EventTime.Add(2 * time.Second)
+	stamped, eventTime, err := StampPublished(payload, publishedAt)
+	require.NoError(t, err)
+	assert.True(t, eventTime.Equal(loggedIn.EventTime))
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(stamped, &fields))
+	assert.Contains(t, fields, "event_time")
+	assert.Contains(t, fields, "published_at")
+
+	var decoded UserLoggedInEvent
+	require.NoError(t, json.Unmarshal(stamped, &decoded))
+	require.NotNil(t, decoded.PublishedAt)
+	assert.False(t, decoded.EventTime.IsZero())
+	assert.True(t, decoded.EventTime.Before(*decoded.PublishedAt))
+	assert.Equal(t, "testuser", decoded.Username)
+	assert.Equal(t, "user-1", decoded.UserID)
+}
+
+func TestStampPublished_LegacyPayload(t *testing.T) {
+	stamped, eventTime, err := StampPublished([]byte(`{"id":"evt-1","type":"user.deleted"}`), time.Now())
+	require.NoError(t, err)
+	assert.True(t, eventTime.IsZero())
+	assert.Contains(t, string(stamped), `"published_at"`)
+
+	_, _, err = StampPublished([]byte("not json"), time.Now())
+	assert.Error(t, err)
+}
+
+func TestBaseEvent_MarkPublished(t *testing.T) {
+	base := NewBaseEvent(UserRegistered, "test", "", "user-1")
+	assert.Nil(t, base.PublishedAt)
+	assert.Equal(t, base.Timestamp, base.EventTime)
+
+	publishedAt := base.EventTime.Add(time.Millisecond)
+	base.GetBaseEvent().MarkPublished(publishedAt)
+	require.NotNil(t, base.PublishedAt)
+	assert.False(t, base.PublishedAt.Before(base.EventTime))
+}
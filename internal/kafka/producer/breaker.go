@@ -0,0 +1,108 @@
+package producer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/metrics"
+)
+
+// ErrCircuitOpen 熔断器打开，发布被直接拒绝
+var ErrCircuitOpen = errors.New("kafka producer circuit breaker is open")
+
+// circuitBreaker 生产者熔断器
+//
+// 连续失败达到阈值后打开，冷却期内的发布直接返回 ErrCircuitOpen，避免在 Kafka
+// 不可用时阻塞调用方。冷却期结束后放行一次探测，成功则关闭，失败则重新打开；
+// 探测结果未返回时每个冷却期最多再放行一次。
+type circuitBreaker struct {
+	threshold int
+	coolDown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+}
+
+// newCircuitBreaker 创建熔断器，阈值不大于 0 时返回 nil 表示不熔断
+func newCircuitBreaker(threshold int, coolDown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		coolDown:  coolDown,
+		now:       time.Now,
+	}
+}
+
+// allow 判断是否放行本次发布
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+
+	now := b.now()
+	if now.Before(b.openUntil) {
+		metrics.ProducerShortCircuitedTotal.Inc()
+		return false
+	}
+
+	// 放行一次探测，下一次探测至少再等一个冷却期
+	b.openUntil = now.Add(b.coolDown)
+	return true
+}
+
+// success 记录一次发布成功，返回熔断器是否因此关闭
+func (b *circuitBreaker) success() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if !b.open {
+		return false
+	}
+
+	b.open = false
+	metrics.ProducerCircuitOpen.Set(0)
+	return true
+}
+
+// failure 记录一次发布失败，返回熔断器是否因此打开
+func (b *circuitBreaker) failure() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.open {
+		// 探测失败，重新进入冷却期
+		b.openUntil = b.now().Add(b.coolDown)
+		return false
+	}
+	if b.failures < b.threshold {
+		return false
+	}
+
+	b.open = true
+	b.openUntil = b.now().Add(b.coolDown)
+	metrics.ProducerCircuitOpen.Set(1)
+	return true
+}
@@ -0,0 +1,174 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"go.uber.org/zap"
+)
+
+var errBrokerDown = errors.New("kafka: broker not available")
+
+// stubAsyncProducer 模拟异步生产者，failing 时或消息 key 为 failKey 时返回发送失败
+type stubAsyncProducer struct {
+	sarama.AsyncProducer
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+	failing   atomic.Bool
+	failKey   string
+	sent      atomic.Int32
+}
+
+func newStubAsyncProducer() *stubAsyncProducer {
+	p := &stubAsyncProducer{
+		input:     make(chan *sarama.ProducerMessage),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+	go func() {
+		for msg := range p.input {
+			p.sent.Add(1)
+			if p.failing.Load() || msg.Key == sarama.StringEncoder(p.failKey) {
+				p.errors <- &sarama.ProducerError{Msg: msg, Err: errBrokerDown}
+			} else {
+				p.successes <- msg
+			}
+		}
+	}()
+	return p
+}
+
+func (p *stubAsyncProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *stubAsyncProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *stubAsyncProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func (p *stubAsyncProducer) Close() error {
+	close(p.input)
+	return nil
+}
+
+// newStubbedProducer 创建使用模拟生产者的生产者，熔断器使用可控时钟，threshold 为 0 时不熔断
+func newStubbedProducer(t *testing.T, threshold int, coolDown time.Duration, now *time.Time) (*KafkaProducer, *stubAsyncProducer) {
+	stub := newStubAsyncProducer()
+	breaker := newCircuitBreaker(threshold, coolDown)
+	if breaker != nil {
+		breaker.now = func() time.Time { return *now }
+	}
+
+	p := &KafkaProducer{
+		producer:   stub,
+		config:     &config.KafkaClientConfig{Backpressure: config.BackpressureError},
+		serializer: event.JSONSerializer{},
+		breaker:    breaker,
+		logger:     zap.NewNop(),
+		closed:     make(chan struct{}),
+	}
+	p.wg.Add(2)
+	go p.handleSuccesses()
+	go p.handleErrors()
+	t.Cleanup(func() { require.NoError(t, p.Close()) })
+
+	return p, stub
+}
+
+func testUserEvent() *event.UserRegisteredEvent {
+	return &event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test-source", "test-request-id", "test-user-id"),
+	}
+}
+
+func TestKafkaProducer_CircuitBreaker_TripsAndRecovers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, stub := newStubbedProducer(t, 3, time.Minute, &now)
+	ctx := context.Background()
+
+	// 连续失败达到阈值后熔断
+	stub.failing.Store(true)
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, p.PublishUserEvent(ctx, testUserEvent()), errBrokerDown)
+	}
+
+	// 冷却期内直接拒绝，不再发送到 Kafka
+	assert.ErrorIs(t, p.PublishUserEvent(ctx, testUserEvent()), ErrCircuitOpen)
+	assert.ErrorIs(t, p.PublishMessage(ctx, &Message{EventType: event.UserRegistered, Payload: []byte(`{}`)}), ErrCircuitOpen)
+	assert.Equal(t, int32(3), stub.sent.Load())
+
+	// 冷却期结束后的探测失败，重新进入冷却期
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, p.PublishUserEvent(ctx, testUserEvent()), errBrokerDown)
+	assert.ErrorIs(t, p.PublishUserEvent(ctx, testUserEvent()), ErrCircuitOpen)
+	assert.Equal(t, int32(4), stub.sent.Load())
+
+	// Kafka 恢复后探测成功，熔断器关闭
+	stub.failing.Store(false)
+	now = now.Add(time.Minute)
+	assert.NoError(t, p.PublishUserEvent(ctx, testUserEvent()))
+	assert.NoError(t, p.PublishUserEvent(ctx, testUserEvent()))
+	assert.Equal(t, int32(6), stub.sent.Load())
+}
+
+func TestKafkaProducer_CircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p, stub := newStubbedProducer(t, 2, time.Minute, &now)
+	ctx := context.Background()
+
+	// 失败不连续时不熔断
+	for i := 0; i < 3; i++ {
+		stub.failing.Store(true)
+		assert.ErrorIs(t, p.PublishUserEvent(ctx, testUserEvent()), errBrokerDown)
+		stub.failing.Store(false)
+		assert.NoError(t, p.PublishUserEvent(ctx, testUserEvent()))
+	}
+	assert.Equal(t, int32(6), stub.sent.Load())
+}
+
+func TestKafkaProducer_CircuitBreaker_AsyncFailsFast(t *testing.T) {
+	input := make(chan *sarama.ProducerMessage, 1)
+	input <- &sarama.ProducerMessage{}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	p := &KafkaProducer{
+		producer: &fullAsyncProducer{input: input},
+		config: &config.KafkaClientConfig{
+			Backpressure:        config.BackpressureBlock,
+			BackpressureTimeout: 20 * time.Millisecond,
+		},
+		serializer: event.JSONSerializer{},
+		breaker:    breaker,
+		logger:     zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	// 缓冲区持续已满时，阻塞超时计为失败
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, p.PublishUserEventAsync(ctx, testUserEvent()), ErrInputChannelFull)
+	}
+
+	// 熔断后立即返回，不再等待缓冲区
+	start := time.Now()
+	assert.ErrorIs(t, p.PublishUserEventAsync(ctx, testUserEvent()), ErrCircuitOpen)
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestNewCircuitBreaker_Disabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+	assert.Nil(t, b)
+
+	// 未启用时始终放行
+	for i := 0; i < 10; i++ {
+		assert.False(t, b.failure())
+	}
+	assert.True(t, b.allow())
+}
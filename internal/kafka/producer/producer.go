@@ -2,6 +2,7 @@ package producer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,27 +10,54 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/zhwjimmy/user-center/internal/kafka/config"
 	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"go.uber.org/zap"
 )
 
+// ErrInputChannelFull 发送缓冲区已满
+var ErrInputChannelFull = errors.New("producer input channel is full")
+
+// ErrPublishTimeout 同步发送超过 PublishTimeout 仍未确认
+var ErrPublishTimeout = errors.New("timeout publishing message")
+
+// defaultPublishTimeout 未配置 PublishTimeout 时同步发送的最长等待时间
+const defaultPublishTimeout = 30 * time.Second
+
 // Producer Kafka生产者接口
 type Producer interface {
 	PublishUserEvent(ctx context.Context, event interface{}) error
 	PublishUserEventAsync(ctx context.Context, event interface{}) error
+	PublishMessage(ctx context.Context, msg *Message) error
+	PublishBatch(ctx context.Context, msgs []*Message) error
 	Close() error
 }
 
+// Message 已序列化的事件消息（用于 outbox 中继）
+type Message struct {
+	EventType event.EventType
+	Key       string
+	RequestID string
+	Payload   []byte
+}
+
 // KafkaProducer Kafka生产者实现
 type KafkaProducer struct {
-	producer sarama.AsyncProducer
-	config   *config.KafkaClientConfig
-	logger   *zap.Logger
-	wg       sync.WaitGroup
-	closed   chan struct{}
+	producer   sarama.AsyncProducer
+	config     *config.KafkaClientConfig
+	serializer event.Serializer
+	breaker    *circuitBreaker
+	logger     *zap.Logger
+	wg         sync.WaitGroup
+	closed     chan struct{}
 }
 
 // NewKafkaProducer 创建Kafka生产者
 func NewKafkaProducer(cfg *config.KafkaClientConfig, logger *zap.Logger) (Producer, error) {
+	serializer, err := event.NewSerializer(cfg.Serialization)
+	if err != nil {
+		return nil, err
+	}
+
 	producerConfig := cfg.NewProducerConfig()
 
 	producer, err := sarama.NewAsyncProducer(cfg.Brokers, producerConfig)
@@ -38,10 +66,12 @@ func NewKafkaProducer(cfg *config.KafkaClientConfig, logger *zap.Logger) (Produc
 	}
 
 	kp := &KafkaProducer{
-		producer: producer,
-		config:   cfg,
-		logger:   logger,
-		closed:   make(chan struct{}),
+		producer:   producer,
+		config:     cfg,
+		serializer: serializer,
+		breaker:    newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCoolDown),
+		logger:     logger,
+		closed:     make(chan struct{}),
 	}
 
 	// 启动错误和成功处理协程
@@ -52,6 +82,7 @@ func NewKafkaProducer(cfg *config.KafkaClientConfig, logger *zap.Logger) (Produc
 	logger.Info("Kafka producer created successfully",
 		zap.Strings("brokers", cfg.Brokers),
 		zap.String("group_id", cfg.GroupID),
+		zap.String("content_type", serializer.ContentType()),
 	)
 
 	return kp, nil
@@ -63,32 +94,228 @@ func (p *KafkaProducer) PublishUserEvent(ctx context.Context, event interface{})
 	if err != nil {
 		return err
 	}
+	injectTraceContext(ctx, message)
+
+	return p.publishSync(ctx, message)
+}
+
+// PublishMessage 同步发布已序列化的事件消息
+func (p *KafkaProducer) PublishMessage(ctx context.Context, msg *Message) error {
+	message, err := p.newMessage(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	return p.publishSync(ctx, message)
+}
+
+// BatchError 批量发布中部分消息失败，Errors 以消息在批次中的下标为键
+type BatchError struct {
+	Total  int
+	Errors map[int]error
+}
+
+// Error 返回失败数量和下标最小的失败原因
+func (e *BatchError) Error() string {
+	first := -1
+	for i := range e.Errors {
+		if first == -1 || i < first {
+			first = i
+		}
+	}
+	return fmt.Sprintf("failed to publish %d of %d messages: %v", len(e.Errors), e.Total, e.Errors[first])
+}
+
+// Unwrap 返回各条消息的失败原因
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// PublishBatch 批量发布已序列化的事件消息
+//
+// 所有消息先写入发送缓冲区，由生产者的 flush 配置合并为批次发送，再统一等待确认。
+// 同一 key 的消息进入同一分区并按顺序发送。部分消息失败时返回 *BatchError，
+// 熔断器打开时整批返回 ErrCircuitOpen。
+func (p *KafkaProducer) PublishBatch(ctx context.Context, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !p.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	batchErr := &BatchError{Total: len(msgs), Errors: make(map[int]error)}
+	results := make([]chan error, len(msgs))
+
+	// 整批共用一个等待上限，ctx 的截止时间更早时以 ctx 为准
+	timer := time.NewTimer(p.publishTimeout())
+	defer timer.Stop()
+
+	// 写入发送缓冲区
+	for i, msg := range msgs {
+		message, err := p.newMessage(ctx, msg)
+		if err != nil {
+			batchErr.Errors[i] = err
+			continue
+		}
+
+		result := make(chan error, 1)
+		message.Metadata = result
 
-	// 使用同步方式发送
-	select {
-	case p.producer.Input() <- message:
-		// 等待确认
 		select {
-		case success := <-p.producer.Successes():
-			p.logger.Debug("Message published successfully",
-				zap.String("topic", success.Topic),
-				zap.Int32("partition", success.Partition),
-				zap.Int64("offset", success.Offset),
-			)
-			return nil
-		case err := <-p.producer.Errors():
-			p.logger.Error("Failed to publish message",
-				zap.String("topic", err.Msg.Topic),
-				zap.Error(err.Err),
-			)
-			return err.Err
+		case p.producer.Input() <- message:
+			results[i] = result
+			continue
+		case <-ctx.Done():
+			err = p.contextError(ctx)
+		case <-timer.C:
+			err = p.timeoutError()
+		}
+
+		// 剩余消息未发送
+		for j := i; j < len(msgs); j++ {
+			batchErr.Errors[j] = err
+		}
+		break
+	}
+
+	// 等待已写入消息的确认
+	var waitErr error
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if waitErr != nil {
+			batchErr.Errors[i] = waitErr
+			continue
+		}
+
+		select {
+		case err := <-result:
+			if err != nil {
+				batchErr.Errors[i] = err
+			}
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(30 * time.Second):
-			return fmt.Errorf("timeout publishing message")
+			waitErr = p.contextError(ctx)
+			batchErr.Errors[i] = waitErr
+		case <-timer.C:
+			waitErr = p.timeoutError()
+			batchErr.Errors[i] = waitErr
 		}
+	}
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// newMessage 将已序列化的事件消息转换为 Kafka 消息
+func (p *KafkaProducer) newMessage(ctx context.Context, msg *Message) (*sarama.ProducerMessage, error) {
+	// 在发送时写入 published_at
+	publishedAt := time.Now()
+	payload, eventTime, err := event.StampPublished(msg.Payload, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// outbox 中保存的是 JSON，按配置的编码格式转换
+	payload, err = event.Transcode(payload, msg.EventType, p.serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic:     p.config.GetTopicName("user_events"),
+		Key:       sarama.StringEncoder(msg.Key),
+		Value:     sarama.ByteEncoder(payload),
+		Headers:   p.headers(msg.EventType, msg.RequestID),
+		Timestamp: p.config.MessageTimestamp(eventTime, publishedAt),
+	}
+	injectTraceContext(ctx, message)
+
+	return message, nil
+}
+
+// publishSync 发送消息并等待该消息的确认结果。写入缓冲区和等待确认共用一个
+// 等待上限 PublishTimeout，ctx 的截止时间更早时以 ctx 为准
+func (p *KafkaProducer) publishSync(ctx context.Context, message *sarama.ProducerMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !p.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	// 通过 Metadata 关联确认结果，避免与后台处理协程争抢 Successes/Errors
+	result := make(chan error, 1)
+	message.Metadata = result
+
+	timer := time.NewTimer(p.publishTimeout())
+	defer timer.Stop()
+
+	select {
+	case p.producer.Input() <- message:
 	case <-ctx.Done():
-		return ctx.Err()
+		return p.contextError(ctx)
+	case <-timer.C:
+		return p.timeoutError()
+	}
+
+	// 等待确认
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return p.contextError(ctx)
+	case <-timer.C:
+		return p.timeoutError()
+	}
+}
+
+// publishTimeout 返回同步发送的最长等待时间
+func (p *KafkaProducer) publishTimeout() time.Duration {
+	if p.config.PublishTimeout > 0 {
+		return p.config.PublishTimeout
+	}
+	return defaultPublishTimeout
+}
+
+// timeoutError 记录一次发送超时并返回 ErrPublishTimeout
+func (p *KafkaProducer) timeoutError() error {
+	p.recordFailure()
+	return ErrPublishTimeout
+}
+
+// contextError 返回 ctx 的错误，超时计入熔断失败次数，调用方主动取消则不计入
+func (p *KafkaProducer) contextError(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		p.recordFailure()
+	}
+	return err
+}
+
+// recordFailure 记录一次发送失败，熔断器打开时记录日志
+func (p *KafkaProducer) recordFailure() {
+	if p.breaker.failure() {
+		p.logger.Warn("Kafka producer circuit breaker opened, publishes fail fast until the cool-down passes",
+			zap.Duration("cool_down", p.breaker.coolDown),
+		)
+	}
+}
+
+// recordSuccess 记录一次发送成功，熔断器关闭时记录日志
+func (p *KafkaProducer) recordSuccess() {
+	if p.breaker.success() {
+		p.logger.Info("Kafka producer circuit breaker closed")
 	}
 }
 
@@ -98,6 +325,23 @@ func (p *KafkaProducer) PublishUserEventAsync(ctx context.Context, event interfa
 	if err != nil {
 		return err
 	}
+	injectTraceContext(ctx, message)
+
+	return p.enqueue(ctx, message)
+}
+
+// enqueue 将消息写入发送缓冲区，缓冲区已满时按配置的背压策略处理，
+// 熔断器打开时直接丢弃并返回 ErrCircuitOpen
+func (p *KafkaProducer) enqueue(ctx context.Context, message *sarama.ProducerMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !p.breaker.allow() {
+		p.logger.Debug("Kafka producer circuit breaker is open, event dropped",
+			zap.String("topic", message.Topic),
+		)
+		return ErrCircuitOpen
+	}
 
 	select {
 	case p.producer.Input() <- message:
@@ -105,7 +349,32 @@ func (p *KafkaProducer) PublishUserEventAsync(ctx context.Context, event interfa
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return fmt.Errorf("producer input channel is full")
+	}
+
+	switch p.config.Backpressure {
+	case config.BackpressureBlock:
+		timer := time.NewTimer(p.config.BackpressureTimeout)
+		defer timer.Stop()
+
+		select {
+		case p.producer.Input() <- message:
+			return nil
+		case <-ctx.Done():
+			return p.contextError(ctx)
+		case <-timer.C:
+			p.recordFailure()
+			return ErrInputChannelFull
+		}
+	case config.BackpressureDrop:
+		p.recordFailure()
+		metrics.ProducerDroppedTotal.WithLabelValues(message.Topic).Inc()
+		p.logger.Warn("Producer input channel is full, event dropped",
+			zap.String("topic", message.Topic),
+		)
+		return nil
+	default:
+		p.recordFailure()
+		return ErrInputChannelFull
 	}
 }
 
@@ -118,84 +387,75 @@ func (p *KafkaProducer) createMessage(eventData interface{}) (*sarama.ProducerMe
 		headers []sarama.RecordHeader
 	)
 
+	// 在序列化前写入 published_at
+	publishedAt := time.Now()
+	eventTime := publishedAt
+	if e, ok := eventData.(interface{ GetBaseEvent() *event.BaseEvent }); ok {
+		base := e.GetBaseEvent()
+		base.MarkPublished(publishedAt)
+		eventTime = base.EventTime
+	}
+
 	switch e := eventData.(type) {
 	case *event.UserRegisteredEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user registered event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	case *event.UserLoggedInEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user logged in event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	case *event.UserPasswordChangedEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user password changed event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	case *event.UserStatusChangedEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user status changed event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	case *event.UserDeletedEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user deleted event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	case *event.UserUpdatedEvent:
 		topic = p.config.GetTopicName("user_events")
 		key = e.UserID
 		var err error
-		value, err = e.ToJSON()
+		value, err = p.serializer.Marshal(e)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal user updated event: %w", err)
 		}
-		headers = []sarama.RecordHeader{
-			{Key: []byte("event_type"), Value: []byte(e.Type)},
-			{Key: []byte("request_id"), Value: []byte(e.RequestID)},
-		}
+		headers = p.headers(e.Type, e.RequestID)
 
 	default:
 		return nil, fmt.Errorf("unsupported event type: %T", eventData)
@@ -206,10 +466,19 @@ func (p *KafkaProducer) createMessage(eventData interface{}) (*sarama.ProducerMe
 		Key:       sarama.StringEncoder(key),
 		Value:     sarama.ByteEncoder(value),
 		Headers:   headers,
-		Timestamp: time.Now(),
+		Timestamp: p.config.MessageTimestamp(eventTime, publishedAt),
 	}, nil
 }
 
+// headers 创建消息头，content-type 供消费者选择解码器
+func (p *KafkaProducer) headers(eventType event.EventType, requestID string) []sarama.RecordHeader {
+	return []sarama.RecordHeader{
+		{Key: []byte("event_type"), Value: []byte(eventType)},
+		{Key: []byte("request_id"), Value: []byte(requestID)},
+		{Key: []byte(event.HeaderContentType), Value: []byte(p.serializer.ContentType())},
+	}
+}
+
 // handleSuccesses 处理成功消息
 func (p *KafkaProducer) handleSuccesses() {
 	defer p.wg.Done()
@@ -222,6 +491,10 @@ func (p *KafkaProducer) handleSuccesses() {
 				zap.Int32("partition", success.Partition),
 				zap.Int64("offset", success.Offset),
 			)
+			p.recordSuccess()
+			if result, ok := success.Metadata.(chan error); ok {
+				result <- nil
+			}
 		case <-p.closed:
 			return
 		}
@@ -239,6 +512,10 @@ func (p *KafkaProducer) handleErrors() {
 				zap.String("topic", err.Msg.Topic),
 				zap.Error(err.Err),
 			)
+			p.recordFailure()
+			if result, ok := err.Msg.Metadata.(chan error); ok {
+				result <- err.Err
+			}
 		case <-p.closed:
 			return
 		}
@@ -7,9 +7,13 @@ import (
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zhwjimmy/user-center/internal/kafka/config"
 	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -76,3 +80,255 @@ func isKafkaAvailable(addr string) bool {
 	conn.Close()
 	return true
 }
+
+// fullAsyncProducer 模拟发送缓冲区已满的异步生产者
+type fullAsyncProducer struct {
+	sarama.AsyncProducer
+	input chan *sarama.ProducerMessage
+}
+
+func (p *fullAsyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.input
+}
+
+func TestKafkaProducer_PublishUserEventAsync_Backpressure(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		timeout     time.Duration
+		drain       bool
+		expectedErr error
+		dropped     float64
+	}{
+		{
+			name:        "error fails immediately",
+			policy:      config.BackpressureError,
+			expectedErr: ErrInputChannelFull,
+		},
+		{
+			name:    "drop discards the event",
+			policy:  config.BackpressureDrop,
+			dropped: 1,
+		},
+		{
+			name:        "block times out",
+			policy:      config.BackpressureBlock,
+			timeout:     20 * time.Millisecond,
+			expectedErr: ErrInputChannelFull,
+		},
+		{
+			name:    "block succeeds once space frees up",
+			policy:  config.BackpressureBlock,
+			timeout: time.Second,
+			drain:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := "test.backpressure." + tt.policy
+			input := make(chan *sarama.ProducerMessage, 1)
+			input <- &sarama.ProducerMessage{}
+
+			p := &KafkaProducer{
+				producer: &fullAsyncProducer{input: input},
+				config: &config.KafkaClientConfig{
+					Topics:              map[string]string{"user_events": topic},
+					Backpressure:        tt.policy,
+					BackpressureTimeout: tt.timeout,
+				},
+				serializer: event.JSONSerializer{},
+				logger:     zap.NewNop(),
+			}
+
+			if tt.drain {
+				go func() {
+					time.Sleep(20 * time.Millisecond)
+					<-input
+				}()
+			}
+
+			before := testutil.ToFloat64(metrics.ProducerDroppedTotal.WithLabelValues(topic))
+			err := p.PublishUserEventAsync(context.Background(), &event.UserRegisteredEvent{
+				BaseEvent: event.NewBaseEvent(event.UserRegistered, "test-source", "test-request-id", "test-user-id"),
+			})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.dropped, testutil.ToFloat64(metrics.ProducerDroppedTotal.WithLabelValues(topic))-before)
+		})
+	}
+}
+
+func TestKafkaProducer_CreateMessage_Serialization(t *testing.T) {
+	for _, format := range []string{event.FormatJSON, event.FormatProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			serializer, err := event.NewSerializer(format)
+			require.NoError(t, err)
+
+			p := &KafkaProducer{
+				config:     &config.KafkaClientConfig{Topics: map[string]string{"user_events": "user.events"}},
+				serializer: serializer,
+				logger:     zap.NewNop(),
+			}
+
+			message, err := p.createMessage(&event.UserStatusChangedEvent{
+				BaseEvent: event.NewBaseEvent(event.UserStatusChanged, "test-source", "test-request-id", "test-user-id"),
+				Username:  "testuser",
+				OldStatus: "active",
+				NewStatus: "suspended",
+			})
+			require.NoError(t, err)
+
+			var contentType string
+			for _, header := range message.Headers {
+				if string(header.Key) == event.HeaderContentType {
+					contentType = string(header.Value)
+				}
+			}
+			assert.Equal(t, serializer.ContentType(), contentType)
+
+			value, err := message.Value.Encode()
+			require.NoError(t, err)
+
+			var decoded event.UserStatusChangedEvent
+			require.NoError(t, serializer.Unmarshal(value, &decoded))
+			assert.Equal(t, "testuser", decoded.Username)
+			assert.Equal(t, "suspended", decoded.NewStatus)
+			assert.NotNil(t, decoded.PublishedAt)
+		})
+	}
+}
+
+func TestKafkaProducer_PublishBatch(t *testing.T) {
+	now := time.Now()
+	p, stub := newStubbedProducer(t, 0, 0, &now)
+
+	msgs := []*Message{
+		{EventType: event.UserRegistered, Key: "user-1", Payload: []byte(`{}`)},
+		{EventType: event.UserRegistered, Key: "user-2", Payload: []byte(`{}`)},
+		{EventType: event.UserRegistered, Key: "user-3", Payload: []byte(`{}`)},
+	}
+
+	require.NoError(t, p.PublishBatch(context.Background(), msgs))
+	assert.Equal(t, int32(3), stub.sent.Load())
+}
+
+func TestKafkaProducer_PublishBatch_PartialFailure(t *testing.T) {
+	now := time.Now()
+	p, stub := newStubbedProducer(t, 0, 0, &now)
+	stub.failKey = "user-2"
+
+	msgs := []*Message{
+		{EventType: event.UserRegistered, Key: "user-1", Payload: []byte(`{}`)},
+		{EventType: event.UserRegistered, Key: "user-2", Payload: []byte(`{}`)},
+		{EventType: event.UserRegistered, Key: "user-3", Payload: []byte(`not json`)},
+	}
+
+	err := p.PublishBatch(context.Background(), msgs)
+
+	// 所有可发送的消息都已发送，失败按下标返回
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 3, batchErr.Total)
+	require.Len(t, batchErr.Errors, 2)
+	assert.ErrorIs(t, batchErr.Errors[1], errBrokerDown)
+	assert.Error(t, batchErr.Errors[2])
+	assert.ErrorIs(t, err, errBrokerDown)
+	assert.Equal(t, int32(2), stub.sent.Load())
+}
+
+func TestKafkaProducer_PublishRespectsContext(t *testing.T) {
+	msg := &Message{EventType: event.UserRegistered, Key: "user-1", Payload: []byte(`{}`)}
+	publishes := map[string]func(p *KafkaProducer, ctx context.Context) error{
+		"sync": func(p *KafkaProducer, ctx context.Context) error {
+			return p.PublishUserEvent(ctx, testUserEvent())
+		},
+		"message": func(p *KafkaProducer, ctx context.Context) error {
+			return p.PublishMessage(ctx, msg)
+		},
+		"batch": func(p *KafkaProducer, ctx context.Context) error {
+			return p.PublishBatch(ctx, []*Message{msg, msg})
+		},
+		"async": func(p *KafkaProducer, ctx context.Context) error {
+			return p.PublishUserEventAsync(ctx, testUserEvent())
+		},
+	}
+
+	// 缓冲区已满且不会腾出空间，只有 ctx 能让发送返回
+	newProducer := func() *KafkaProducer {
+		input := make(chan *sarama.ProducerMessage, 1)
+		input <- &sarama.ProducerMessage{}
+		return &KafkaProducer{
+			producer: &fullAsyncProducer{input: input},
+			config: &config.KafkaClientConfig{
+				PublishTimeout:      time.Minute,
+				Backpressure:        config.BackpressureBlock,
+				BackpressureTimeout: time.Minute,
+			},
+			serializer: event.JSONSerializer{},
+			logger:     zap.NewNop(),
+		}
+	}
+
+	for name, publish := range publishes {
+		t.Run(name+" canceled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+			}()
+
+			start := time.Now()
+			err := publish(newProducer(), ctx)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+
+		t.Run(name+" deadline", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := publish(newProducer(), ctx)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), time.Second)
+		})
+
+		t.Run(name+" already canceled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			p := newProducer()
+			// 缓冲区有空间时也不应发送
+			<-p.producer.(*fullAsyncProducer).input
+
+			assert.ErrorIs(t, publish(p, ctx), context.Canceled)
+			assert.Empty(t, p.producer.(*fullAsyncProducer).input)
+		})
+	}
+}
+
+func TestKafkaProducer_PublishTimeoutIsUpperBound(t *testing.T) {
+	input := make(chan *sarama.ProducerMessage, 1)
+	input <- &sarama.ProducerMessage{}
+	p := &KafkaProducer{
+		producer:   &fullAsyncProducer{input: input},
+		config:     &config.KafkaClientConfig{PublishTimeout: 20 * time.Millisecond},
+		serializer: event.JSONSerializer{},
+		logger:     zap.NewNop(),
+	}
+
+	start := time.Now()
+	err := p.PublishUserEvent(context.Background(), testUserEvent())
+	assert.ErrorIs(t, err, ErrPublishTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	var batchErr *BatchError
+	err = p.PublishBatch(context.Background(), []*Message{{EventType: event.UserRegistered, Payload: []byte(`{}`)}})
+	require.ErrorAs(t, err, &batchErr)
+	assert.ErrorIs(t, batchErr.Errors[0], ErrPublishTimeout)
+}
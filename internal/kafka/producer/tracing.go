@@ -0,0 +1,51 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+)
+
+// headerCarrier 将 Kafka 消息头适配为 OpenTelemetry TextMapCarrier
+type headerCarrier struct {
+	message *sarama.ProducerMessage
+}
+
+// Get 获取指定消息头的值
+func (c headerCarrier) Get(key string) string {
+	for _, header := range c.message.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Set 设置消息头，已存在时覆盖
+func (c headerCarrier) Set(key, value string) {
+	for i, header := range c.message.Headers {
+		if string(header.Key) == key {
+			c.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.message.Headers = append(c.message.Headers, sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
+	})
+}
+
+// Keys 返回所有消息头的键
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.message.Headers))
+	for _, header := range c.message.Headers {
+		keys = append(keys, string(header.Key))
+	}
+	return keys
+}
+
+// injectTraceContext 将上下文中的链路信息写入消息头
+func injectTraceContext(ctx context.Context, message *sarama.ProducerMessage) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{message: message})
+}
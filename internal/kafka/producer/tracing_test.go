@@ -0,0 +1,41 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectTraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+
+	message := &sarama.ProducerMessage{
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("event_type"), Value: []byte("user.registered")},
+		},
+	}
+	injectTraceContext(ctx, message)
+
+	carrier := headerCarrier{message: message}
+	assert.Equal(t, "user.registered", carrier.Get("event_type"))
+	assert.NotEmpty(t, carrier.Get("traceparent"))
+
+	// 从消息头中还原的链路信息应与发送方一致
+	extracted := trace.SpanContextFromContext(
+		propagation.TraceContext{}.Extract(context.Background(), carrier),
+	)
+	assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+}
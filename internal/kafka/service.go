@@ -22,11 +22,13 @@ type Service interface {
 type KafkaService struct {
 	producer producer.Producer
 	consumer consumer.Consumer
+	dlq      consumer.DLQPublisher
+	replayer *consumer.DLQReplayer
 	logger   *zap.Logger
 }
 
 // NewKafkaService 创建Kafka服务
-func NewKafkaService(cfg *config.KafkaClientConfig, logger *zap.Logger) (Service, error) {
+func NewKafkaService(cfg *config.KafkaClientConfig, notifier consumer.Notifier, webhooks consumer.WebhookDispatcher, lag *consumer.LagMonitor, dedup *consumer.Deduplicator, logins *consumer.LoginHistory, logger *zap.Logger) (Service, error) {
 	// 创建生产者
 	prod, err := producer.NewKafkaProducer(cfg, logger)
 	if err != nil {
@@ -34,11 +36,35 @@ func NewKafkaService(cfg *config.KafkaClientConfig, logger *zap.Logger) (Service
 	}
 
 	// 创建消息处理器
-	handler := consumer.NewUserEventHandler(logger)
+	handler := consumer.NewUserEventHandler(notifier, webhooks, logins, logger)
+
+	// 创建死信发布者和重放消费者
+	var (
+		dlq      consumer.DLQPublisher
+		replayer *consumer.DLQReplayer
+	)
+	if cfg.DLQEnabled {
+		dlq, err = consumer.NewKafkaDLQPublisher(cfg)
+		if err != nil {
+			prod.Close()
+			return nil, err
+		}
+
+		replayer, err = consumer.NewDLQReplayer(cfg, handler, dlq, logger)
+		if err != nil {
+			dlq.Close()
+			prod.Close()
+			return nil, err
+		}
+	}
 
 	// 创建消费者
-	cons, err := consumer.NewKafkaConsumer(cfg, handler, logger)
+	cons, err := consumer.NewKafkaConsumer(cfg, handler, dlq, lag, dedup, logger)
 	if err != nil {
+		if replayer != nil {
+			replayer.Stop()
+			dlq.Close()
+		}
 		prod.Close() // 清理已创建的生产者
 		return nil, fmt.Errorf("failed to create kafka consumer: %w", err)
 	}
@@ -46,6 +72,8 @@ func NewKafkaService(cfg *config.KafkaClientConfig, logger *zap.Logger) (Service
 	return &KafkaService{
 		producer: prod,
 		consumer: cons,
+		dlq:      dlq,
+		replayer: replayer,
 		logger:   logger,
 	}, nil
 }
@@ -69,6 +97,13 @@ func (s *KafkaService) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start kafka consumer: %w", err)
 	}
 
+	// 启动死信重放
+	if s.replayer != nil {
+		if err := s.replayer.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start kafka dlq replayer: %w", err)
+		}
+	}
+
 	s.logger.Info("Kafka service started successfully")
 	return nil
 }
@@ -82,6 +117,16 @@ func (s *KafkaService) Stop() error {
 		s.logger.Error("Failed to stop kafka consumer", zap.Error(err))
 	}
 
+	// 停止死信重放
+	if s.replayer != nil {
+		if err := s.replayer.Stop(); err != nil {
+			s.logger.Error("Failed to stop kafka dlq replayer", zap.Error(err))
+		}
+		if err := s.dlq.Close(); err != nil {
+			s.logger.Error("Failed to close kafka dlq producer", zap.Error(err))
+		}
+	}
+
 	// 停止生产者
 	if err := s.producer.Close(); err != nil {
 		s.logger.Error("Failed to close kafka producer", zap.Error(err))
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakePool is a gorm.ConnPool where writes succeed and reads fail, which is
+// enough for queries to run through the callbacks
+type fakePool struct{}
+
+var errNoRows = errors.New("fake pool returns no rows")
+
+func (fakePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errNoRows
+}
+
+func (fakePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errNoRows
+}
+
+func (fakePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func TestGormPlugin_ObservesRepositoryOperations(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_db_query_duration_seconds",
+	}, []string{"operation"})
+	registry.MustRegister(durations)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: fakePool{}}), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin(durations)))
+
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+	_, _ = repo.Create(ctx, &model.User{Username: "alice", Email: "alice@example.com"})
+	_, _ = repo.GetByID(ctx, "user-1")
+	_, _ = repo.GetByIDs(ctx, []string{"user-1", "user-2"})
+	_, _ = repo.ExistsByEmail(ctx, "alice@example.com")
+	_, _ = repo.Update(ctx, &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"})
+	_ = repo.Delete(ctx, "user-1")
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+
+	counts := make(map[string]uint64)
+	for _, m := range families[0].GetMetric() {
+		require.Len(t, m.GetLabel(), 1)
+		counts[m.GetLabel()[0].GetValue()] = m.GetHistogram().GetSampleCount()
+	}
+	for _, operation := range []string{
+		DBOperationCreate, DBOperationGet, DBOperationList,
+		DBOperationCount, DBOperationUpdate, DBOperationDelete,
+	} {
+		assert.NotZero(t, counts[operation], operation)
+	}
+
+	// Only the fixed operation labels are used
+	for operation := range counts {
+		assert.Contains(t, []string{
+			DBOperationCreate, DBOperationGet, DBOperationList, DBOperationCount,
+			DBOperationUpdate, DBOperationDelete, DBOperationExec,
+		}, operation)
+	}
+}
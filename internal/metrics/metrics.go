@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Login results used as the "result" label of LoginsTotal
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+// Anomalous login reasons used as the "reason" label of AnomalousLoginsTotal
+const (
+	AnomalousLoginNewNetwork   = "new_network"
+	AnomalousLoginNewUserAgent = "new_user_agent"
+)
+
+// DLQ replay results used as the "result" label of DLQReplaysTotal
+const (
+	DLQReplaySuccess   = "success"
+	DLQReplayRequeued  = "requeued"
+	DLQReplayExhausted = "exhausted"
+)
+
+var (
+	// RegistrationsTotal counts successful user registrations
+	RegistrationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "registrations_total",
+		Help:      "Total number of successful user registrations.",
+	})
+
+	// LoginsTotal counts login attempts by result
+	LoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "logins_total",
+		Help:      "Total number of login attempts by result.",
+	}, []string{"result"})
+
+	// PasswordChangesTotal counts successful password changes
+	PasswordChangesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "password_changes_total",
+		Help:      "Total number of successful password changes.",
+	})
+
+	// OperationDuration observes the latency of user-domain operations
+	OperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "usercenter",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of user-domain operations in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// ActiveUsers reports the number of active users
+	ActiveUsers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "usercenter",
+		Name:      "active_users",
+		Help:      "Number of active users, refreshed periodically.",
+	})
+
+	// DLQDepth reports the number of messages waiting in a dead-letter topic
+	DLQDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_dlq_depth",
+		Help:      "Number of messages not yet replayed from a dead-letter topic partition.",
+	}, []string{"topic", "partition"})
+
+	// DLQReplaysTotal counts dead-letter replays by result
+	DLQReplaysTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_dlq_replays_total",
+		Help:      "Total number of dead-letter message replays by result.",
+	}, []string{"result"})
+
+	// ConsumerLag reports how many messages the consumer is behind on a partition
+	ConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_consumer_lag",
+		Help:      "Number of messages the consumer group is behind the high watermark of a partition.",
+	}, []string{"topic", "partition"})
+
+	// ConsumerMessagesTotal counts messages the consumer received on a partition
+	ConsumerMessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_consumer_messages_total",
+		Help:      "Total number of messages consumed from a partition.",
+	}, []string{"topic", "partition"})
+
+	// ConsumerErrorsTotal counts messages whose handler failed on a partition
+	ConsumerErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_consumer_errors_total",
+		Help:      "Total number of consumed messages whose handler returned an error.",
+	}, []string{"topic", "partition"})
+
+	// SessionEvictionsTotal counts sessions signed out because a user exceeded their plan's concurrent session limit
+	SessionEvictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "session_evictions_total",
+		Help:      "Total number of sessions signed out because the plan's concurrent session limit was exceeded.",
+	}, []string{"plan"})
+
+	// DuplicateEventsSkippedTotal counts redelivered events skipped because they were already processed
+	DuplicateEventsSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_duplicate_events_skipped_total",
+		Help:      "Total number of redelivered events skipped because they were already processed.",
+	}, []string{"event_type"})
+
+	// AnomalousLoginsTotal counts logins from a network or user agent not seen in the user's recent history
+	AnomalousLoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "anomalous_logins_total",
+		Help:      "Total number of logins from a network or user agent not seen in the user's recent logins, by reason.",
+	}, []string{"reason"})
+
+	// ProducerDroppedTotal counts events dropped because the async producer buffer was full
+	ProducerDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_producer_dropped_total",
+		Help:      "Total number of events dropped because the async producer buffer was full.",
+	}, []string{"topic"})
+
+	// DBQueryDuration observes the duration of database queries by operation
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "usercenter",
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries in seconds by operation.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// ProducerCircuitOpen reports whether the producer circuit breaker is open
+	ProducerCircuitOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_producer_circuit_open",
+		Help:      "Whether the Kafka producer circuit breaker is open (1) or closed (0).",
+	})
+
+	// ProducerShortCircuitedTotal counts publishes rejected because the producer circuit breaker was open
+	ProducerShortCircuitedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "usercenter",
+		Name:      "kafka_producer_short_circuited_total",
+		Help:      "Total number of publishes rejected because the Kafka producer circuit breaker was open.",
+	})
+)
+
+func init() {
+	Register(prometheus.DefaultRegisterer)
+}
+
+// Register registers the user-domain collectors with reg
+func Register(reg prometheus.Registerer) {
+	reg.MustRegister(
+		RegistrationsTotal,
+		LoginsTotal,
+		PasswordChangesTotal,
+		OperationDuration,
+		DBQueryDuration,
+		ActiveUsers,
+		DLQDepth,
+		DLQReplaysTotal,
+		ConsumerLag,
+		ConsumerMessagesTotal,
+		ConsumerErrorsTotal,
+		ProducerDroppedTotal,
+		ProducerCircuitOpen,
+		ProducerShortCircuitedTotal,
+		DuplicateEventsSkippedTotal,
+		SessionEvictionsTotal,
+		AnomalousLoginsTotal,
+	)
+}
+
+// ObserveOperation records the duration of an operation started at start
+func ObserveOperation(operation string, start time.Time) {
+	OperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Refresher periodically updates gauges computed from the database
+type Refresher struct {
+	userRepo repository.UserRepository
+	interval time.Duration
+	logger   *zap.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewRefresher creates a new gauge refresher
+func NewRefresher(userRepo repository.UserRepository, cfg *config.Config, logger *zap.Logger) *Refresher {
+	return &Refresher{
+		userRepo: userRepo,
+		interval: cfg.Monitoring.Prometheus.RefreshInterval,
+		logger:   logger,
+	}
+}
+
+// Start refreshes the gauges immediately and then on every interval until
+// Stop is called
+func (r *Refresher) Start(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.Refresh(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the refresher and waits for it to exit
+func (r *Refresher) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// Refresh updates the gauges once
+func (r *Refresher) Refresh(ctx context.Context) {
+	count, err := r.userRepo.CountActiveUsers(ctx)
+	if err != nil {
+		r.logger.Error("Failed to refresh active users gauge", zap.Error(err))
+		return
+	}
+
+	ActiveUsers.Set(float64(count))
+}
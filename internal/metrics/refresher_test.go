@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"go.uber.org/zap"
+)
+
+func TestRefresher_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	repo.EXPECT().CountActiveUsers(gomock.Any()).Return(int64(42), nil)
+
+	refresher := NewRefresher(repo, &config.Config{}, zap.NewNop())
+	refresher.Refresh(context.Background())
+
+	assert.Equal(t, float64(42), testutil.ToFloat64(ActiveUsers))
+}
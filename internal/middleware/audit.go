@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"go.uber.org/zap"
+)
+
+// auditWriteTimeout bounds how long writing a single audit entry may take
+const auditWriteTimeout = 5 * time.Second
+
+// AuditStore persists audit log entries
+type AuditStore interface {
+	InsertAuditLog(ctx context.Context, entry *database.AuditLog) error
+}
+
+// auditRule matches requests by method and route pattern. "*" matches any
+// method; a route ending in "*" matches every route with that prefix.
+type auditRule struct {
+	method string
+	route  string
+}
+
+// parseAuditRules parses "METHOD ROUTE" patterns, e.g. "POST /api/v1/users/login"
+// or "DELETE *". Malformed patterns are skipped.
+func parseAuditRules(patterns []string, logger *zap.Logger) []auditRule {
+	rules := make([]auditRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		fields := strings.Fields(pattern)
+		if len(fields) != 2 {
+			logger.Warn("Ignoring malformed audit route pattern", zap.String("pattern", pattern))
+			continue
+		}
+		rules = append(rules, auditRule{method: strings.ToUpper(fields[0]), route: fields[1]})
+	}
+	return rules
+}
+
+// matches reports whether the rule covers a request to route with method
+func (r auditRule) matches(method, route string) bool {
+	if r.method != "*" && r.method != method {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.route, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return r.route == route
+}
+
+// NewAuditMiddleware creates a middleware that records requests matching the
+// configured audit routes to store once they complete. Unmatched routes, such
+// as reads under the default configuration, are not recorded.
+func NewAuditMiddleware(cfg *config.Config, store AuditStore, logger *zap.Logger) gin.HandlerFunc {
+	rules := parseAuditRules(cfg.Audit.Routes, logger)
+
+	return func(c *gin.Context) {
+		c.Next()
+
+		if !cfg.Audit.Enabled || store == nil {
+			return
+		}
+
+		// Unknown routes have no pattern and are never audited
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+
+		method := c.Request.Method
+		audited := false
+		for _, rule := range rules {
+			if rule.matches(method, route) {
+				audited = true
+				break
+			}
+		}
+		if !audited {
+			return
+		}
+
+		details := map[string]interface{}{}
+		if extra, ok := c.Get(response.AuditDetailsKey); ok {
+			for key, value := range extra.(map[string]interface{}) {
+				details[key] = value
+			}
+		}
+		details["status"] = c.Writer.Status()
+
+		entry := &database.AuditLog{
+			UserID:    c.GetString("user_id"),
+			Action:    method + " " + route,
+			Resource:  c.Request.URL.Path,
+			Details:   details,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Timestamp: time.Now().UTC(),
+			RequestID: c.GetString(response.RequestIDKey),
+		}
+
+		// Write in the background so auditing does not delay the response
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+			defer cancel()
+
+			if err := store.InsertAuditLog(ctx, entry); err != nil {
+				logger.Error("Failed to write audit log",
+					zap.String("action", entry.Action),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"go.uber.org/zap"
+)
+
+// fakeAuditStore hands written entries to the test
+type fakeAuditStore struct {
+	entries chan *database.AuditLog
+}
+
+func (s *fakeAuditStore) InsertAuditLog(ctx context.Context, entry *database.AuditLog) error {
+	s.entries <- entry
+	return nil
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Audit: config.AuditConfig{
+		Enabled: true,
+		Routes:  []string{"PUT *", "* /api/v1/admin/*", "POST /api/v1/users/login"},
+	}}
+	store := &fakeAuditStore{entries: make(chan *database.AuditLog, 1)}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		c.Next()
+	})
+	r.Use(NewAuditMiddleware(cfg, store, zap.NewNop()))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/v1/users/:id", ok)
+	r.PUT("/api/v1/users/me", ok)
+	r.GET("/api/v1/admin/users/", ok)
+	r.POST("/api/v1/users/login", ok)
+	r.POST("/api/v1/users/register", ok)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		audited bool
+	}{
+		{name: "read route is not audited", method: http.MethodGet, path: "/api/v1/users/123", audited: false},
+		{name: "mutation is audited", method: http.MethodPut, path: "/api/v1/users/me", audited: true},
+		{name: "any method under prefix is audited", method: http.MethodGet, path: "/api/v1/admin/users/", audited: true},
+		{name: "listed route is audited", method: http.MethodPost, path: "/api/v1/users/login", audited: true},
+		{name: "unlisted route is not audited", method: http.MethodPost, path: "/api/v1/users/register", audited: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			if !tt.audited {
+				select {
+				case entry := <-store.entries:
+					t.Fatalf("unexpected audit entry %s", entry.Action)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case entry := <-store.entries:
+				assert.Equal(t, tt.path, entry.Resource)
+				assert.Equal(t, "user-1", entry.UserID)
+				assert.Equal(t, http.StatusOK, entry.Details["status"])
+			case <-time.After(time.Second):
+				t.Fatal("expected audit entry")
+			}
+		})
+	}
+}
+
+func TestAuditMiddleware_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Audit: config.AuditConfig{Routes: []string{"* *"}}}
+	store := &fakeAuditStore{entries: make(chan *database.AuditLog, 1)}
+
+	r := gin.New()
+	r.Use(NewAuditMiddleware(cfg, store, zap.NewNop()))
+	r.DELETE("/api/v1/admin/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/v1/admin/users/1", nil))
+
+	select {
+	case entry := <-store.entries:
+		t.Fatalf("unexpected audit entry %s", entry.Action)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAuditMiddleware_HandlerDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Audit: config.AuditConfig{Enabled: true, Routes: []string{"DELETE *"}}}
+	store := &fakeAuditStore{entries: make(chan *database.AuditLog, 1)}
+
+	r := gin.New()
+	r.Use(NewAuditMiddleware(cfg, store, zap.NewNop()))
+	r.DELETE("/api/v1/admin/users/:id", func(c *gin.Context) {
+		c.Set(response.AuditDetailsKey, map[string]interface{}{"hard": true, "status": "ignored"})
+		c.Status(http.StatusOK)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/v1/admin/users/1", nil))
+
+	select {
+	case entry := <-store.entries:
+		assert.Equal(t, true, entry.Details["hard"])
+		assert.Equal(t, http.StatusOK, entry.Details["status"])
+	case <-time.After(time.Second):
+		t.Fatal("expected audit entry")
+	}
+}
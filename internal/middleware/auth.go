@@ -5,22 +5,32 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/internal/service"
 	"github.com/zhwjimmy/user-center/pkg/jwt"
+	pkglogger "github.com/zhwjimmy/user-center/pkg/logger"
 	"go.uber.org/zap"
 )
 
 // AuthMiddleware handles JWT authentication
 type AuthMiddleware struct {
 	jwtManager *jwt.JWT
-	logger     *zap.Logger
+	sessions   *service.SessionLimiter
+	// verifiedEmail makes RequireVerifiedEmail reject unverified users
+	verifiedEmail bool
+	logger        *zap.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(jwtManager *jwt.JWT, logger *zap.Logger) *AuthMiddleware {
+func NewAuthMiddleware(jwtManager *jwt.JWT, sessions *service.SessionLimiter, cfg *config.Config, logger *zap.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtManager: jwtManager,
-		logger:     logger,
+		jwtManager:    jwtManager,
+		sessions:      sessions,
+		verifiedEmail: cfg.Auth.EmailVerification.Enforce,
+		logger:        logger,
 	}
 }
 
@@ -30,7 +40,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			m.logger.Warn("Missing authorization header")
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Authorization header is required",
 			})
@@ -42,7 +52,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			m.logger.Warn("Invalid authorization header format")
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Invalid authorization header format",
 			})
@@ -56,7 +66,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		claims, err := m.jwtManager.ValidateToken(token)
 		if err != nil {
 			m.logger.Warn("Invalid JWT token", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Invalid or expired token",
 			})
@@ -64,16 +74,46 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		if !m.sessionActive(c, claims, token) {
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Session has been signed out",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set claims in context
 		c.Set("claims", claims)
+		c.Set("token", token)
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
+		pkglogger.SetRequestLogger(c, pkglogger.WithUserID(pkglogger.FromContext(c, m.logger), claims.UserID))
 
 		c.Next()
 	}
 }
 
+// sessionActive checks that the token's session has not been signed out by the
+// concurrent session limit. Tokens issued before sessions were tracked carry no
+// ID and are not checked; if the session store is unavailable the request is allowed.
+func (m *AuthMiddleware) sessionActive(c *gin.Context, claims *jwt.Claims, token string) bool {
+	if claims.ID == "" {
+		return true
+	}
+
+	active, err := m.sessions.IsActive(c.Request.Context(), claims.UserID, token)
+	if err != nil {
+		m.logger.Error("Failed to check session", zap.String("user_id", claims.UserID), zap.Error(err))
+		return true
+	}
+	if !active {
+		m.logger.Warn("Request with signed out session", zap.String("user_id", claims.UserID))
+	}
+	return active
+}
+
 // OptionalAuth validates JWT token if present but doesn't require it
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -100,11 +140,17 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
+		if !m.sessionActive(c, claims, token) {
+			c.Next()
+			return
+		}
+
 		// Set claims in context
 		c.Set("claims", claims)
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("email", claims.Email)
+		pkglogger.SetRequestLogger(c, pkglogger.WithUserID(pkglogger.FromContext(c, m.logger), claims.UserID))
 
 		c.Next()
 	}
@@ -115,7 +161,7 @@ func (m *AuthMiddleware) RequireActiveUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, exists := c.Get("claims")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Authentication required",
 			})
@@ -129,7 +175,7 @@ func (m *AuthMiddleware) RequireActiveUser() gin.HandlerFunc {
 				zap.String("user_id", userClaims.UserID),
 				zap.String("status", string(userClaims.Status)),
 			)
-			c.JSON(http.StatusForbidden, dto.ErrorResponse{
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
 				Error:   "Forbidden",
 				Message: "Account is not active",
 			})
@@ -141,12 +187,19 @@ func (m *AuthMiddleware) RequireActiveUser() gin.HandlerFunc {
 	}
 }
 
-// AdminOnly ensures the authenticated user has admin privileges
-func (m *AuthMiddleware) AdminOnly() gin.HandlerFunc {
+// RequireVerifiedEmail ensures the authenticated user had verified their email
+// when their token was issued. It lets everyone through unless
+// auth.email_verification.enforce is set.
+func (m *AuthMiddleware) RequireVerifiedEmail() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !m.verifiedEmail {
+			c.Next()
+			return
+		}
+
 		claims, exists := c.Get("claims")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
 				Error:   "Unauthorized",
 				Message: "Authentication required",
 			})
@@ -155,17 +208,78 @@ func (m *AuthMiddleware) AdminOnly() gin.HandlerFunc {
 		}
 
 		userClaims := claims.(*jwt.Claims)
+		if !userClaims.EmailVerified {
+			m.logger.Warn("User with unverified email attempting to access resource",
+				zap.String("user_id", userClaims.UserID),
+			)
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "Email address is not verified",
+				Code:    response.EmailNotVerifiedCode,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
 
-		// Note: This is a simple check. In a real application, you would
-		// check user roles from the database or include roles in JWT claims
-		if userClaims.Email != "admin@example.com" {
+// RequireRole ensures the authenticated user has at least one of roles,
+// as embedded in their token when it was issued
+func (m *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		userClaims := claims.(*jwt.Claims)
+		if !userClaims.HasRole(roles...) {
+			m.logger.Warn("User without required role attempting to access resource",
+				zap.String("user_id", userClaims.UserID),
+				zap.Strings("required_roles", roles),
+				zap.Strings("roles", userClaims.Roles),
+			)
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
+				Error:   "Forbidden",
+				Message: "Insufficient role",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminOnly ensures the authenticated user is an administrator, going by the
+// is_admin claim or, for tokens issued before it existed, the admin role
+func (m *AuthMiddleware) AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Error(c, http.StatusUnauthorized, dto.ErrorResponse{
+				Error:   "Unauthorized",
+				Message: "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		userClaims := claims.(*jwt.Claims)
+		if !userClaims.IsAdmin && !userClaims.HasRole(model.RoleAdmin) {
 			m.logger.Warn("Non-admin user attempting to access admin resource",
 				zap.String("user_id", userClaims.UserID),
-				zap.String("email", userClaims.Email),
 			)
-			c.JSON(http.StatusForbidden, dto.ErrorResponse{
+			response.Error(c, http.StatusForbidden, dto.ErrorResponse{
 				Error:   "Forbidden",
-				Message: "Admin access required",
+				Message: "Insufficient role",
 			})
 			c.Abort()
 			return
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+func TestAuthMiddleware_RequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewAuthMiddleware(jwt.NewJWT("test-secret", "test", 0), nil, &config.Config{}, zap.NewNop())
+
+	tests := []struct {
+		name         string
+		claims       *jwt.Claims
+		expectedRead int
+		expectedEdit int
+	}{
+		{name: "anonymous", expectedRead: http.StatusUnauthorized, expectedEdit: http.StatusUnauthorized},
+		{name: "user", claims: &jwt.Claims{Roles: []string{model.RoleUser}}, expectedRead: http.StatusForbidden, expectedEdit: http.StatusForbidden},
+		{name: "support", claims: &jwt.Claims{Roles: []string{model.RoleSupport, model.RoleUser}}, expectedRead: http.StatusOK, expectedEdit: http.StatusForbidden},
+		{name: "admin", claims: &jwt.Claims{Roles: []string{model.RoleAdmin, model.RoleUser}}, expectedRead: http.StatusOK, expectedEdit: http.StatusOK},
+		{name: "support with admin claim", claims: &jwt.Claims{IsAdmin: true, Roles: []string{model.RoleSupport, model.RoleUser}}, expectedRead: http.StatusOK, expectedEdit: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set("claims", tt.claims)
+				}
+			})
+			admin := r.Group("/admin", m.RequireRole(model.RoleAdmin, model.RoleSupport))
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			admin.GET("/users", ok)
+			admin.DELETE("/users/:id", m.AdminOnly(), ok)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+			assert.Equal(t, tt.expectedRead, w.Code)
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/users/1", nil))
+			assert.Equal(t, tt.expectedEdit, w.Code)
+		})
+	}
+}
+
+func TestAuthMiddleware_RequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		enforce      bool
+		claims       *jwt.Claims
+		expectedCode int
+	}{
+		{name: "verified", enforce: true, claims: &jwt.Claims{UserID: "1", EmailVerified: true}, expectedCode: http.StatusOK},
+		{name: "unverified", enforce: true, claims: &jwt.Claims{UserID: "1"}, expectedCode: http.StatusForbidden},
+		{name: "anonymous", enforce: true, expectedCode: http.StatusUnauthorized},
+		{name: "unverified while not enforced", claims: &jwt.Claims{UserID: "1"}, expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Auth: config.AuthConfig{
+				EmailVerification: config.EmailVerificationConfig{Enforce: tt.enforce},
+			}}
+			m := NewAuthMiddleware(jwt.NewJWT("test-secret", "test", 0), nil, cfg, zap.NewNop())
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set("claims", tt.claims)
+				}
+			})
+			r.PUT("/users/me", m.RequireVerifiedEmail(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/users/me", nil))
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), response.EmailNotVerifiedCode)
+			}
+		})
+	}
+}
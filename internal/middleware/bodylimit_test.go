@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+)
+
+func TestMaxBodyBytesMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(MaxBodyBytesMiddleware(256))
+	r.POST("/register", func(c *gin.Context) {
+		var req dto.RegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ValidationError(c, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	normal, err := json.Marshal(dto.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "password123"})
+	require.NoError(t, err)
+	oversized, err := json.Marshal(dto.RegisterRequest{Username: strings.Repeat("a", 1024), Email: "alice@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		body          io.Reader
+		contentLength int64
+		expectedCode  int
+	}{
+		{name: "normal body", body: bytes.NewReader(normal), contentLength: int64(len(normal)), expectedCode: http.StatusCreated},
+		{name: "oversized body", body: bytes.NewReader(oversized), contentLength: int64(len(oversized)), expectedCode: http.StatusRequestEntityTooLarge},
+		// Chunked bodies declare no length and are cut off while reading
+		{name: "oversized body without length", body: io.MultiReader(bytes.NewReader(oversized)), contentLength: -1, expectedCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/register", tt.body)
+			req.Header.Set("Content-Type", "application/json")
+			req.ContentLength = tt.contentLength
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+
+			if tt.expectedCode == http.StatusRequestEntityTooLarge {
+				var resp dto.ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "PAYLOAD_TOO_LARGE", resp.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// NewCacheControlMiddleware creates a middleware that sets the Cache-Control
+// header on read requests based on the matched route pattern
+func NewCacheControlMiddleware(cfg *config.Config) gin.HandlerFunc {
+	routes := make(map[string]string, len(cfg.CacheControl.Routes))
+	for route, value := range cfg.CacheControl.Routes {
+		// Viper lowercases map keys, so match routes case-insensitively
+		routes[strings.ToLower(route)] = value
+	}
+	defaultValue := cfg.CacheControl.Default
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		value, ok := routes[strings.ToLower(c.FullPath())]
+		if !ok {
+			value = defaultValue
+		}
+
+		if value != "" {
+			c.Header("Cache-Control", value)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func TestCacheControlMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{CacheControl: config.CacheControlConfig{
+		Default: "no-cache",
+		Routes: map[string]string{
+			"/api/v1/users/me":  "no-store",
+			"/api/v1/users/:id": "public, max-age=60",
+		},
+	}}
+
+	r := gin.New()
+	r.Use(NewCacheControlMiddleware(cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/v1/users/me", ok)
+	r.PUT("/api/v1/users/me", ok)
+	r.GET("/api/v1/users/:id", ok)
+	r.GET("/health", ok)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expected string
+	}{
+		{name: "current user is never stored", method: http.MethodGet, path: "/api/v1/users/me", expected: "no-store"},
+		{name: "public profile is briefly cacheable", method: http.MethodGet, path: "/api/v1/users/123", expected: "public, max-age=60"},
+		{name: "unconfigured route uses default", method: http.MethodGet, path: "/health", expected: "no-cache"},
+		{name: "writes are not annotated", method: http.MethodPut, path: "/api/v1/users/me", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expected, w.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// Content encodings supported by the compression middleware, in order of preference
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// incompressibleTypes are content types that are already compressed.
+// Entries ending in "/" match every subtype.
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+}
+
+// compressionWriter buffers the start of the response until minSize bytes
+// are written, then either compresses the rest or passes it through
+type compressionWriter struct {
+	gin.ResponseWriter
+	encoding   string
+	minSize    int
+	buf        bytes.Buffer
+	decided    bool
+	compressor io.WriteCloser
+}
+
+// Write buffers data until the compression decision is made
+func (w *compressionWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers s until the compression decision is made
+func (w *compressionWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends compressed data written so far. Before the decision the data
+// stays buffered, which is bounded by minSize.
+func (w *compressionWriter) Flush() {
+	if !w.decided {
+		return
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide compresses the response if its content type allows it and writes
+// the buffered data
+func (w *compressionWriter) decide() error {
+	w.decided = true
+
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && isCompressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if w.encoding == EncodingGzip {
+			w.compressor = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.compressor, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if w.compressor != nil {
+		_, err := w.compressor.Write(data)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(data)
+	return err
+}
+
+// close writes a response that stayed below minSize as is and finishes the
+// compressed stream otherwise
+func (w *compressionWriter) close() {
+	if !w.decided {
+		w.decided = true
+		if w.buf.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		return
+	}
+	if w.compressor != nil {
+		_ = w.compressor.Close()
+	}
+}
+
+// NewCompressionMiddleware creates a middleware that compresses responses of
+// at least the configured size with gzip or deflate, as negotiated through
+// Accept-Encoding. Already compressed content types are sent as is.
+func NewCompressionMiddleware(cfg *config.Config) gin.HandlerFunc {
+	if !cfg.Compression.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	minSize := cfg.Compression.MinSize
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &compressionWriter{
+			ResponseWriter: original,
+			encoding:       encoding,
+			minSize:        minSize,
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		writer.close()
+	}
+}
+
+// negotiateEncoding picks the preferred supported encoding accepted by the
+// client, ignoring encodings refused with q=0
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(coding))] = true
+	}
+
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate} {
+		if accepted[encoding] || accepted["*"] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// isCompressible reports whether a response of contentType benefits from compression
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	for _, t := range incompressibleTypes {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+)
+
+func newCompressionRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewCompressionMiddleware(cfg))
+	r.GET("/users", func(c *gin.Context) {
+		users := make([]*model.PublicUser, 100)
+		for i := range users {
+			user := &model.User{ID: fmt.Sprintf("user-%d", i), Username: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+			users[i] = user.ToPublicUser()
+		}
+		c.JSON(http.StatusOK, dto.UserListResponse{Users: users, Message: "Users retrieved successfully"})
+	})
+	r.GET("/users/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "user-1"})
+	})
+	r.GET("/avatar", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", make([]byte, 4096))
+	})
+	return r
+}
+
+func getWithEncoding(r *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressionMiddleware_LargeJSON(t *testing.T) {
+	r := newCompressionRouter(&config.Config{Compression: config.CompressionConfig{Enabled: true, MinSize: 1024}})
+
+	t.Run("gzip when supported", func(t *testing.T) {
+		w := getWithEncoding(r, "/users", "br;q=1.0, gzip;q=0.8")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, EncodingGzip, w.Header().Get("Content-Encoding"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		var resp dto.UserListResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		assert.Len(t, resp.Users, 100)
+	})
+
+	t.Run("uncompressed otherwise", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "identity", "gzip;q=0, br"} {
+			w := getWithEncoding(r, "/users", acceptEncoding)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+
+			var resp dto.UserListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), acceptEncoding)
+			assert.Len(t, resp.Users, 100)
+		}
+	})
+}
+
+func TestCompressionMiddleware_Skips(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		path    string
+	}{
+		{name: "small body", enabled: true, path: "/users/me"},
+		{name: "already compressed content type", enabled: true, path: "/avatar"},
+		{name: "disabled", enabled: false, path: "/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCompressionRouter(&config.Config{Compression: config.CompressionConfig{Enabled: tt.enabled, MinSize: 1024}})
+
+			w := getWithEncoding(r, tt.path, "gzip, deflate")
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			assert.NotEmpty(t, w.Body.Bytes())
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"gzip":               EncodingGzip,
+		"deflate":            EncodingDeflate,
+		"deflate, gzip":      EncodingGzip,
+		"GZIP;q=0.5":         EncodingGzip,
+		"gzip;q=0, deflate":  EncodingDeflate,
+		"gzip;q=0.0, br":     "",
+		"*":                  EncodingGzip,
+		"br, identity;q=0.1": "",
+	}
+
+	for acceptEncoding, expected := range tests {
+		assert.Equal(t, expected, negotiateEncoding(acceptEncoding), acceptEncoding)
+	}
+}
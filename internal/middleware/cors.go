@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -8,10 +11,60 @@ import (
 	"github.com/zhwjimmy/user-center/internal/config"
 )
 
-// NewCORSMiddleware creates a new CORS middleware
+// originPattern is an allowed CORS origin. A host starting with "*." matches
+// every subdomain of the rest but not the domain itself. A pattern without a
+// scheme matches any scheme.
+type originPattern struct {
+	scheme string
+	host   string
+}
+
+// parseOriginPatterns parses allowed origins such as "https://app.example.com"
+// or "https://*.example.com"
+func parseOriginPatterns(origins []string) []originPattern {
+	patterns := make([]originPattern, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok {
+			scheme, host = "", origin
+		}
+		patterns = append(patterns, originPattern{scheme: scheme, host: strings.TrimSuffix(host, "/")})
+	}
+	return patterns
+}
+
+// matches reports whether the pattern allows an origin with scheme and host
+func (p originPattern) matches(scheme, host string) bool {
+	if p.scheme != "" && p.scheme != scheme {
+		return false
+	}
+	if domain, ok := strings.CutPrefix(p.host, "*."); ok {
+		return strings.HasSuffix(host, "."+domain)
+	}
+	return p.host == host
+}
+
+// originAllowed reports whether origin matches one of patterns
+func originAllowed(patterns []originPattern, origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if pattern.matches(u.Scheme, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCORSMiddleware creates a new CORS middleware. Allowed origins are echoed
+// back only when they match the configured allowlist. Allowing every origin
+// with "*" disables credentials, since browsers reject credentialed responses
+// for a wildcard origin.
 func NewCORSMiddleware(cfg *config.Config) gin.HandlerFunc {
 	corsConfig := cors.Config{
-		AllowOrigins:     cfg.CORS.AllowOrigins,
 		AllowMethods:     cfg.CORS.AllowMethods,
 		AllowHeaders:     cfg.CORS.AllowHeaders,
 		ExposeHeaders:    cfg.CORS.ExposeHeaders,
@@ -19,5 +72,46 @@ func NewCORSMiddleware(cfg *config.Config) gin.HandlerFunc {
 		MaxAge:           time.Duration(cfg.CORS.MaxAge) * time.Second,
 	}
 
+	allowAll := false
+	for _, origin := range cfg.CORS.AllowOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			allowAll = true
+		}
+	}
+
+	if allowAll {
+		corsConfig.AllowAllOrigins = true
+		corsConfig.AllowCredentials = false
+	} else {
+		patterns := parseOriginPatterns(cfg.CORS.AllowOrigins)
+		corsConfig.AllowOriginFunc = func(origin string) bool {
+			return originAllowed(patterns, origin)
+		}
+	}
+
 	return cors.New(corsConfig)
 }
+
+// ReloadableCORS is a CORS middleware whose configuration can be replaced at
+// runtime, e.g. when the configuration file changes
+type ReloadableCORS struct {
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+// NewReloadableCORS creates a CORS middleware for cfg that can be updated later
+func NewReloadableCORS(cfg *config.Config) *ReloadableCORS {
+	r := &ReloadableCORS{}
+	r.Update(cfg.CORS)
+	return r
+}
+
+// Update replaces the CORS configuration for subsequent requests
+func (r *ReloadableCORS) Update(cors config.CORSConfig) {
+	handler := NewCORSMiddleware(&config.Config{CORS: cors})
+	r.handler.Store(&handler)
+}
+
+// Handle applies the current CORS configuration to the request
+func (r *ReloadableCORS) Handle(c *gin.Context) {
+	(*r.handler.Load())(c)
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func newCORSRouter(cors config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewCORSMiddleware(&config.Config{CORS: cors}))
+	r.GET("/api/v1/users/me", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/api/v1/users/me", nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSMiddleware_Allowlist(t *testing.T) {
+	r := newCORSRouter(config.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "listed origin", origin: "https://app.example.com", allowed: true},
+		{name: "wildcard subdomain", origin: "https://api.example.org", allowed: true},
+		{name: "nested wildcard subdomain", origin: "https://eu.api.example.org", allowed: true},
+		{name: "unlisted origin", origin: "https://evil.com", allowed: false},
+		{name: "scheme mismatch", origin: "http://app.example.com", allowed: false},
+		{name: "wildcard does not match apex", origin: "https://example.org", allowed: false},
+		{name: "suffix without dot", origin: "https://evilexample.org", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(r, http.MethodGet, tt.origin)
+			if !tt.allowed {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Contains(t, w.Header().Values("Vary"), "Origin")
+		})
+	}
+
+	t.Run("preflight is cached for max age", func(t *testing.T) {
+		w := corsRequest(r, http.MethodOptions, "https://api.example.org")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://api.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+}
+
+func TestCORSMiddleware_WildcardRefusesCredentials(t *testing.T) {
+	r := newCORSRouter(config.CORSConfig{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET"},
+		AllowCredentials: true,
+	})
+
+	w := corsRequest(r, http.MethodGet, "https://anywhere.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key of a retryable request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses served from the cache
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL bounds how long a request that never finishes, e.g.
+	// because the instance died, blocks retries with the same key
+	idempotencyLockTTL = time.Minute
+)
+
+// unreplayedHeaders describe how a response was encoded rather than the
+// outcome. The cached body is what the handler wrote, before any compression
+// further out, so replaying them could label it with the wrong encoding or
+// length.
+var unreplayedHeaders = []string{"Content-Encoding", "Content-Length", "Vary"}
+
+// idempotentResponse is the cached outcome of a request. Pending marks a
+// request with the same key that is still being processed.
+type idempotentResponse struct {
+	Pending bool        `json:"pending,omitempty"`
+	Status  int         `json:"status,omitempty"`
+	Header  http.Header `json:"header,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+}
+
+// idempotencyWriter passes the response through and keeps a copy of the body
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write writes the response body and keeps a copy
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the response body and keeps a copy
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// NewIdempotencyMiddleware creates a middleware that makes mutating requests
+// carrying an Idempotency-Key header safe to retry. The first request with a
+// key is processed and its response cached in Redis, keyed by the caller, the
+// key, the route and a hash of the body. Retries get the cached response
+// without being processed again; retries arriving while the first request is
+// still running get 409. Server errors are not cached so they can be retried.
+// When Redis is unavailable requests are processed as if they had no key.
+func NewIdempotencyMiddleware(redis *cache.Redis, cfg *config.Config, logger *zap.Logger) gin.HandlerFunc {
+	if !cfg.Idempotency.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	ttl := cfg.Idempotency.TTL
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Bad Request",
+				Message: "Idempotency-Key must be at most 255 characters",
+			})
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					response.PayloadTooLarge(c, maxBytesErr.Limit)
+				} else {
+					response.Error(c, http.StatusBadRequest, dto.ErrorResponse{
+						Error:   "Bad Request",
+						Message: "Failed to read request body",
+					})
+				}
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		ctx := c.Request.Context()
+		cacheKey := idempotencyCacheKey(c, key, body)
+
+		acquired, err := redis.SetNX(ctx, cacheKey, idempotentResponse{Pending: true}, idempotencyLockTTL)
+		if err != nil {
+			logger.Warn("Idempotency check failed, processing request", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			var cached idempotentResponse
+			if err := redis.Get(ctx, cacheKey, &cached); err != nil || cached.Pending {
+				response.Error(c, http.StatusConflict, dto.ErrorResponse{
+					Error:   "Conflict",
+					Message: "A request with this Idempotency-Key is still being processed",
+				})
+				c.Abort()
+				return
+			}
+
+			for name, values := range cached.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(cached.Status, cached.Header.Get("Content-Type"), cached.Body)
+			c.Abort()
+			return
+		}
+
+		// Only headers set further down the chain belong to the response;
+		// outer middleware sets its own, such as the request ID, on replay
+		before := c.Writer.Header().Clone()
+		original := c.Writer
+		writer := &idempotencyWriter{
+			ResponseWriter: original,
+			body:           &bytes.Buffer{},
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+
+		// The request may have been canceled; the outcome is stored regardless
+		ctx = context.WithoutCancel(ctx)
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			if err := redis.Delete(ctx, cacheKey); err != nil {
+				logger.Warn("Failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+
+		header := http.Header{}
+		for name, values := range writer.Header() {
+			if slices.Contains(unreplayedHeaders, name) {
+				continue
+			}
+			if !slices.Equal(before[name], values) {
+				header[name] = values
+			}
+		}
+		cached := idempotentResponse{
+			Status: status,
+			Header: header,
+			Body:   writer.body.Bytes(),
+		}
+		if err := redis.Set(ctx, cacheKey, cached, ttl); err != nil {
+			logger.Warn("Failed to cache idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// isMutatingMethod reports whether requests with method change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotencyCacheKey scopes key to the caller and the requested path and
+// query, so different users or resources never share responses, and to the
+// body, so a key reused for a different payload is processed as a new request
+func idempotencyCacheKey(c *gin.Context, key string, body []byte) string {
+	var userID string
+	if claims, ok := c.Get("claims"); ok {
+		if userClaims, ok := claims.(*jwt.Claims); ok {
+			userID = userClaims.UserID
+		}
+	}
+
+	bodyHash := sha256.Sum256(body)
+	h := sha256.New()
+	for _, part := range []string{userID, c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, key, hex.EncodeToString(bodyHash[:])} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return cache.IdempotencyKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
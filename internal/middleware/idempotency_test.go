@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// idempotencyTestRouter serves POST and GET /orders behind the idempotency
+// middleware. The handler responds with status and counts its calls; the
+// caller is taken from the X-User header.
+func idempotencyTestRouter(t *testing.T, enabled bool, status *int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Idempotency: config.IdempotencyConfig{Enabled: enabled, TTL: time.Hour}}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("claims", &jwt.Claims{UserID: user})
+		}
+		c.Next()
+	})
+	r.Use(NewIdempotencyMiddleware(redis, cfg, zap.NewNop()))
+	handle := func(c *gin.Context) {
+		*calls++
+		c.Header("Location", "/orders/1")
+		c.JSON(*status, gin.H{"call": *calls})
+	}
+	r.POST("/orders", handle)
+	r.GET("/orders", handle)
+	return r
+}
+
+func doIdempotent(r *gin.Engine, method, key, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware_Replay(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r := idempotencyTestRouter(t, true, &status, &calls)
+
+	first := doIdempotent(r, http.MethodPost, "key-1", "alice", `{"item":"book"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := doIdempotent(r, http.MethodPost, "key-1", "alice", `{"item":"book"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/orders/1", retry.Header().Get("Location"))
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddleware_EncodingHeadersNotReplayed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour}}
+
+	r := gin.New()
+	r.Use(NewIdempotencyMiddleware(redis, cfg, zap.NewNop()))
+	r.POST("/orders", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Content-Length", "2")
+		c.Header("Vary", "Accept-Encoding")
+		c.Header("Location", "/orders/1")
+		c.String(http.StatusCreated, "ok")
+	})
+
+	doIdempotent(r, http.MethodPost, "key-1", "", "")
+	retry := doIdempotent(r, http.MethodPost, "key-1", "", "")
+	require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "ok", retry.Body.String())
+	assert.Equal(t, "/orders/1", retry.Header().Get("Location"))
+	assert.Empty(t, retry.Header().Get("Content-Encoding"))
+	assert.Empty(t, retry.Header().Get("Content-Length"))
+	assert.Empty(t, retry.Header().Get("Vary"))
+}
+
+func TestIdempotencyMiddleware_Scope(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		method  string
+		key     string
+		user    string
+		body    string
+		calls   int
+	}{
+		{name: "same request", enabled: true, method: http.MethodPost, key: "key-1", user: "alice", body: `{"item":"book"}`, calls: 1},
+		{name: "other key", enabled: true, method: http.MethodPost, key: "key-2", user: "alice", body: `{"item":"book"}`, calls: 2},
+		{name: "other body", enabled: true, method: http.MethodPost, key: "key-1", user: "alice", body: `{"item":"pen"}`, calls: 2},
+		{name: "other user", enabled: true, method: http.MethodPost, key: "key-1", user: "bob", body: `{"item":"book"}`, calls: 2},
+		{name: "no key", enabled: true, method: http.MethodPost, user: "alice", body: `{"item":"book"}`, calls: 2},
+		{name: "read request", enabled: true, method: http.MethodGet, key: "key-1", user: "alice", calls: 2},
+		{name: "disabled", method: http.MethodPost, key: "key-1", user: "alice", body: `{"item":"book"}`, calls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, calls := http.StatusCreated, 0
+			r := idempotencyTestRouter(t, tt.enabled, &status, &calls)
+
+			doIdempotent(r, tt.method, "key-1", "alice", `{"item":"book"}`)
+			w := doIdempotent(r, tt.method, tt.key, tt.user, tt.body)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestIdempotencyMiddleware_ScopedToResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour}}
+
+	var updated []string
+	r := gin.New()
+	r.Use(NewIdempotencyMiddleware(redis, cfg, zap.NewNop()))
+	r.PUT("/orders/:id", func(c *gin.Context) {
+		updated = append(updated, c.Param("id")+"?"+c.Request.URL.RawQuery)
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+
+	// One client key reused on the same route for different orders
+	for _, target := range []string{"/orders/1", "/orders/2", "/orders/2?notify=false", "/orders/2"} {
+		req := httptest.NewRequest(http.MethodPut, target, strings.NewReader(`{"status":"shipped"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, target)
+	}
+	assert.Equal(t, []string{"1?", "2?", "2?notify=false"}, updated)
+}
+
+func TestIdempotencyMiddleware_ServerErrorsNotCached(t *testing.T) {
+	status, calls := http.StatusInternalServerError, 0
+	r := idempotencyTestRouter(t, true, &status, &calls)
+
+	w := doIdempotent(r, http.MethodPost, "key-1", "alice", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	status = http.StatusCreated
+	w = doIdempotent(r, http.MethodPost, "key-1", "alice", `{}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_InFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour}}
+
+	r := gin.New()
+	r.Use(NewIdempotencyMiddleware(redis, cfg, zap.NewNop()))
+	var retry *httptest.ResponseRecorder
+	r.POST("/orders", func(c *gin.Context) {
+		// A retry arrives while the first request is still being processed
+		retry = doIdempotent(r, http.MethodPost, "key-1", "", `{}`)
+		c.Status(http.StatusCreated)
+	})
+
+	w := doIdempotent(r, http.MethodPost, "key-1", "", `{}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	require.NotNil(t, retry)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+}
+
+func TestIdempotencyMiddleware_KeyTooLong(t *testing.T) {
+	status, calls := http.StatusCreated, 0
+	r := idempotencyTestRouter(t, true, &status, &calls)
+
+	w := doIdempotent(r, http.MethodPost, strings.Repeat("k", maxIdempotencyKeyLength+1), "", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Zero(t, calls)
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// JSON naming policies supported by the response transformer
+const (
+	JSONNamingSnake = "snake"
+	JSONNamingCamel = "camel"
+)
+
+// jsonNamingWriter buffers the response body so keys can be renamed before
+// it is written to the client
+type jsonNamingWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write buffers the response body
+func (w *jsonNamingWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString buffers the response body
+func (w *jsonNamingWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// NewJSONNamingMiddleware creates a middleware that renames JSON response keys
+// according to the configured naming policy. DTOs keep their snake_case tags;
+// the conversion only happens at the edge.
+func NewJSONNamingMiddleware(cfg *config.Config) gin.HandlerFunc {
+	if cfg.Server.JSONNaming != JSONNamingCamel {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &jsonNamingWriter{
+			ResponseWriter: original,
+			body:           &bytes.Buffer{},
+		}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+
+		body := writer.body.Bytes()
+		if len(body) == 0 {
+			return
+		}
+
+		if strings.Contains(original.Header().Get("Content-Type"), "application/json") {
+			if converted, err := convertJSONKeys(body, snakeToCamel); err == nil {
+				body = converted
+			}
+		}
+
+		_, _ = original.Write(body)
+	}
+}
+
+// convertJSONKeys rewrites every object key in the JSON document using convert
+func convertJSONKeys(data []byte, convert func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(renameKeys(value, convert))
+}
+
+// renameKeys recursively renames map keys in a decoded JSON value
+func renameKeys(value interface{}, convert func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			renamed[convert(key)] = renameKeys(item, convert)
+		}
+		return renamed
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renameKeys(item, convert)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// snakeToCamel converts a snake_case key to camelCase
+func snakeToCamel(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+
+	parts := strings.Split(key, "_")
+	var builder strings.Builder
+	builder.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		builder.WriteString(strings.ToUpper(part[:1]))
+		builder.WriteString(part[1:])
+	}
+	return builder.String()
+}
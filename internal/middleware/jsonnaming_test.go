@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestJSONNamingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		naming      string
+		expectedKey string
+		absentKey   string
+	}{
+		{
+			name:        "snake case keeps tags",
+			naming:      JSONNamingSnake,
+			expectedKey: "first_name",
+			absentKey:   "firstName",
+		},
+		{
+			name:        "camel case renames keys",
+			naming:      JSONNamingCamel,
+			expectedKey: "firstName",
+			absentKey:   "first_name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Server: config.ServerConfig{JSONNaming: tt.naming}}
+
+			r := gin.New()
+			r.Use(NewJSONNamingMiddleware(cfg))
+			r.GET("/users/me", func(c *gin.Context) {
+				user := &model.User{ID: "user-id", Username: "testuser", FirstName: strPtr("Test")}
+				c.JSON(http.StatusOK, dto.UserResponse{
+					User:    user.ToPublicUser(),
+					Message: "User retrieved successfully",
+				})
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var body struct {
+				User map[string]interface{} `json:"user"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "Test", body.User[tt.expectedKey])
+			assert.NotContains(t, body.User, tt.absentKey)
+		})
+	}
+}
+
+func TestSnakeToCamel(t *testing.T) {
+	assert.Equal(t, "id", snakeToCamel("id"))
+	assert.Equal(t, "totalPages", snakeToCamel("total_pages"))
+	assert.Equal(t, "lastLoginAt", snakeToCamel("last_login_at"))
+}
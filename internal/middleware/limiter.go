@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// Limiter takes tokens from token buckets identified by key. A bucket holds
+// up to capacity tokens and refills at refillRate tokens per second.
+type Limiter interface {
+	TakeToken(ctx context.Context, key string, capacity int, refillRate float64, now time.Time) (bool, error)
+}
+
+// NewLimiter returns the rate limit backend selected by rate_limit.store:
+// buckets in memory, only limiting the requests of this instance, or in
+// Redis, shared by every instance
+func NewLimiter(redis *cache.Redis, cfg *config.Config, logger *zap.Logger) Limiter {
+	switch cfg.RateLimit.Store {
+	case "memory":
+		logger.Info("Rate limits are kept in memory and apply per instance")
+		return NewMemoryLimiter()
+	case "redis", "":
+		return redis
+	default:
+		logger.Warn("Unknown rate limit store, using Redis", zap.String("store", cfg.RateLimit.Store))
+		return redis
+	}
+}
+
+// memorySweepInterval is how often full buckets are dropped from memory
+const memorySweepInterval = time.Minute
+
+// tokenBucket is the state of one in-memory bucket
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	capacity int
+	rate     float64
+}
+
+// refill adds the tokens earned since the bucket was last updated
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.capacity), b.tokens+elapsed*b.rate)
+		b.updated = now
+	}
+}
+
+// MemoryLimiter keeps token buckets in memory, for single instances and
+// development. Buckets that have refilled completely are dropped, as a new
+// bucket starts full anyway.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter creates an in-memory limiter without buckets
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// TakeToken takes a token from the bucket at key, creating it full. It
+// returns true if a token was available.
+func (l *MemoryLimiter) TakeToken(ctx context.Context, key string, capacity int, refillRate float64, now time.Time) (bool, error) {
+	if capacity <= 0 || refillRate <= 0 {
+		return false, fmt.Errorf("invalid token bucket parameters: capacity=%d, refill_rate=%f", capacity, refillRate)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(capacity), updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.capacity = capacity
+	bucket.rate = refillRate
+	bucket.refill(now)
+
+	if bucket.tokens < 1 {
+		return false, nil
+	}
+	bucket.tokens--
+	return true, nil
+}
+
+// sweep drops the buckets that are full by now, at most once per
+// memorySweepInterval. l.mu must be held.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memorySweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.capacity) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestMemoryLimiter_Burst(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		allowed, err := l.TakeToken(ctx, "key", 5, 1, now)
+		require.NoError(t, err)
+		assert.True(t, allowed, "request %d", i)
+	}
+	allowed, err := l.TakeToken(ctx, "key", 5, 1, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Buckets are independent
+	allowed, err = l.TakeToken(ctx, "other", 5, 1, now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestMemoryLimiter_Refill(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		allowed, err := l.TakeToken(ctx, "key", 2, 0.5, now)
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	// Half a token per second: one more after two seconds, not after one
+	now = now.Add(time.Second)
+	allowed, err := l.TakeToken(ctx, "key", 2, 0.5, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	now = now.Add(time.Second)
+	allowed, err = l.TakeToken(ctx, "key", 2, 0.5, now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Refilling never exceeds the capacity
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		allowed, err := l.TakeToken(ctx, "key", 2, 0.5, now)
+		require.NoError(t, err)
+		assert.True(t, allowed, "request %d", i)
+	}
+	allowed, err = l.TakeToken(ctx, "key", 2, 0.5, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	_, err = l.TakeToken(ctx, "key", 0, 1, now)
+	assert.Error(t, err)
+}
+
+func TestMemoryLimiter_DropsFullBuckets(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := l.TakeToken(ctx, "idle", 10, 1, now)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err := l.TakeToken(ctx, "busy", 10, 0.01, now)
+		require.NoError(t, err)
+	}
+
+	_, err = l.TakeToken(ctx, "new", 10, 1, now.Add(memorySweepInterval))
+	require.NoError(t, err)
+	assert.NotContains(t, l.buckets, "idle")
+	assert.Contains(t, l.buckets, "busy")
+	assert.Contains(t, l.buckets, "new")
+}
+
+func TestNewLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redis, err := cache.NewRedis(&config.Config{Redis: config.RedisConfig{Addr: mr.Addr()}}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	tests := []struct {
+		store  string
+		memory bool
+	}{
+		{store: "memory", memory: true},
+		{store: "redis"},
+		{store: ""},
+		{store: "memcached"},
+	}
+
+	for _, tt := range tests {
+		limiter := NewLimiter(redis, &config.Config{RateLimit: config.RateLimitConfig{Store: tt.store}}, zap.NewNop())
+		if tt.memory {
+			assert.IsType(t, &MemoryLimiter{}, limiter, tt.store)
+		} else {
+			assert.Same(t, redis, limiter, tt.store)
+		}
+	}
+}
+
+func TestRateLimit_MemoryStore(t *testing.T) {
+	cfg := &config.Config{RateLimit: config.RateLimitConfig{Enabled: true, Rate: 60, Burst: 5, Store: "memory"}}
+	m := NewRateLimitMiddleware(NewLimiter(nil, cfg, zap.NewNop()), cfg, zap.NewNop())
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m.now = clock.Now
+	r := newRateLimitRouter(m.RateLimit())
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	clock.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, doRequest(r))
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
@@ -1,20 +1,115 @@
 package middleware
 
 import (
+	"context"
+	"math/rand/v2"
 	"time"
 
 	"github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/response"
+	pkglogger "github.com/zhwjimmy/user-center/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// NewLoggerMiddleware creates a new logger middleware
-func NewLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
-	return ginzap.GinzapWithConfig(logger, &ginzap.Config{
+// requestLogWriteTimeout bounds how long writing a single request log may take
+const requestLogWriteTimeout = 5 * time.Second
+
+// loggerSkipPaths are probe endpoints that are not logged
+var loggerSkipPaths = []string{"/health", "/ready", "/live"}
+
+// RequestLogStore persists request log entries
+type RequestLogStore interface {
+	InsertLogEntry(ctx context.Context, entry *database.LogEntry) error
+}
+
+// NewLoggerMiddleware creates a new logger middleware. When the MongoDB sink
+// is enabled, a sampled share of requests is also written to store as one
+// structured document each.
+func NewLoggerMiddleware(cfg *config.Config, store RequestLogStore, logger *zap.Logger) gin.HandlerFunc {
+	access := ginzap.GinzapWithConfig(logger, &ginzap.Config{
 		TimeFormat: time.RFC3339,
 		UTC:        true,
-		SkipPaths:  []string{"/health", "/ready", "/live"},
+		SkipPaths:  loggerSkipPaths,
 	})
+
+	sink := cfg.Logging.MongoDB
+	if !sink.Enabled || sink.SampleRate <= 0 || store == nil {
+		return func(c *gin.Context) {
+			setRequestLogger(c, logger)
+			access(c)
+		}
+	}
+
+	skip := make(map[string]bool, len(loggerSkipPaths))
+	for _, path := range loggerSkipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		setRequestLogger(c, logger)
+		access(c)
+
+		if skip[path] || rand.Float64() >= sink.SampleRate {
+			return
+		}
+
+		status := c.Writer.Status()
+		entry := &database.LogEntry{
+			Level:     requestLogLevel(status),
+			Message:   "HTTP request",
+			Timestamp: start.UTC(),
+			RequestID: c.GetString(response.RequestIDKey),
+			UserID:    c.GetString("user_id"),
+			Fields: map[string]interface{}{
+				"method":     c.Request.Method,
+				"path":       path,
+				"route":      c.FullPath(),
+				"status":     status,
+				"latency_ms": time.Since(start).Milliseconds(),
+			},
+		}
+
+		// Write in the background so logging does not delay the response
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), requestLogWriteTimeout)
+			defer cancel()
+
+			if err := store.InsertLogEntry(ctx, entry); err != nil {
+				logger.Error("Failed to write request log",
+					zap.String("path", path),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+}
+
+// setRequestLogger gives the request a child of logger tagged with its
+// request ID; authentication adds the user ID once it is known
+func setRequestLogger(c *gin.Context, logger *zap.Logger) {
+	requestLogger := logger
+	if requestID := c.GetString(response.RequestIDKey); requestID != "" {
+		requestLogger = pkglogger.WithRequestID(logger, requestID)
+	}
+	pkglogger.SetRequestLogger(c, requestLogger)
+}
+
+// requestLogLevel maps a response status to the level of its request log
+func requestLogLevel(status int) string {
+	switch {
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "warn"
+	default:
+		return "info"
+	}
 }
 
 // NewRecoveryMiddleware creates a new recovery middleware
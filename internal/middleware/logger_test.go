@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/response"
+	pkglogger "github.com/zhwjimmy/user-center/pkg/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeRequestLogStore hands written entries to the test
+type fakeRequestLogStore struct {
+	entries chan *database.LogEntry
+}
+
+func (s *fakeRequestLogStore) InsertLogEntry(ctx context.Context, entry *database.LogEntry) error {
+	s.entries <- entry
+	return nil
+}
+
+func newLoggerRouter(sampleRate float64, store RequestLogStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Logging: config.LoggingConfig{
+		MongoDB: config.RequestLogSinkConfig{Enabled: true, SampleRate: sampleRate},
+	}}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(response.RequestIDKey, "req-1")
+		c.Set("user_id", "user-1")
+		c.Next()
+	})
+	r.Use(NewLoggerMiddleware(cfg, store, zap.NewNop()))
+	r.GET("/api/v1/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	r.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestLoggerMiddleware_WritesSampledRequest(t *testing.T) {
+	store := &fakeRequestLogStore{entries: make(chan *database.LogEntry, 1)}
+	r := newLoggerRouter(1, store)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/123", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	select {
+	case entry := <-store.entries:
+		assert.Equal(t, "warn", entry.Level)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, "user-1", entry.UserID)
+		assert.Equal(t, http.MethodGet, entry.Fields["method"])
+		assert.Equal(t, "/api/v1/users/123", entry.Fields["path"])
+		assert.Equal(t, "/api/v1/users/:id", entry.Fields["route"])
+		assert.Equal(t, http.StatusNotFound, entry.Fields["status"])
+		assert.Contains(t, entry.Fields, "latency_ms")
+	case <-time.After(time.Second):
+		t.Fatal("request log was not written")
+	}
+}
+
+func TestLoggerMiddleware_SkipsUnsampledRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate float64
+		path       string
+	}{
+		{name: "zero sample rate", sampleRate: 0, path: "/api/v1/users/123"},
+		{name: "probe endpoint", sampleRate: 1, path: "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeRequestLogStore{entries: make(chan *database.LogEntry, 1)}
+			r := newLoggerRouter(tt.sampleRate, store)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			select {
+			case entry := <-store.entries:
+				t.Fatalf("unexpected request log: %+v", entry)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestLoggerMiddleware_RequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	r := gin.New()
+	r.Use(NewRequestIDMiddleware(&config.Config{Server: config.ServerConfig{RequestIDHeader: "X-Request-ID"}}))
+	r.Use(NewLoggerMiddleware(&config.Config{}, nil, logger))
+	r.GET("/users/:id", func(c *gin.Context) {
+		pkglogger.FromContext(c, zap.NewNop()).Info("handler log")
+		// Services only see the request context
+		pkglogger.FromContext(c.Request.Context(), zap.NewNop()).Info("service log")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, message := range []string{"handler log", "service log"} {
+		entries := logs.FilterMessage(message).All()
+		if assert.Len(t, entries, 1, message) {
+			assert.Equal(t, "req-42", entries[0].ContextMap()["request_id"], message)
+		}
+	}
+}
@@ -1,38 +1,75 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/zhwjimmy/user-center/internal/cache"
 	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
 	"go.uber.org/zap"
 )
 
-// RateLimitMiddleware handles rate limiting
+// RateLimitMiddleware handles rate limiting. Enabled, Rate and Burst of
+// config can be changed at runtime with UpdateLimits and are read under mu.
 type RateLimitMiddleware struct {
-	redis  *cache.Redis
-	config config.RateLimitConfig
-	logger *zap.Logger
+	limiter      Limiter
+	mu           sync.RWMutex
+	config       config.RateLimitConfig
+	tenantLimits map[string]config.TenantLimit
+	logger       *zap.Logger
+	now          func() time.Time
 }
 
-// NewRateLimitMiddleware creates a new rate limit middleware
-func NewRateLimitMiddleware(redis *cache.Redis, cfg *config.Config, logger *zap.Logger) *RateLimitMiddleware {
+// NewRateLimitMiddleware creates a new rate limit middleware keeping its
+// token buckets in limiter
+func NewRateLimitMiddleware(limiter Limiter, cfg *config.Config, logger *zap.Logger) *RateLimitMiddleware {
+	// Viper lowercases map keys, so match tenants case-insensitively
+	tenantLimits := make(map[string]config.TenantLimit, len(cfg.RateLimit.Tenants.Limits))
+	for tenantID, limit := range cfg.RateLimit.Tenants.Limits {
+		tenantLimits[strings.ToLower(tenantID)] = limit
+	}
+
 	return &RateLimitMiddleware{
-		redis:  redis,
-		config: cfg.RateLimit,
-		logger: logger,
+		limiter:      limiter,
+		config:       cfg.RateLimit,
+		tenantLimits: tenantLimits,
+		logger:       logger,
+		now:          time.Now,
 	}
 }
 
+// UpdateLimits replaces whether rate limiting is enabled and the default rate
+// and burst. Requests already being checked finish with the previous limits.
+func (m *RateLimitMiddleware) UpdateLimits(enabled bool, rate, burst int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.config.Enabled = enabled
+	m.config.Rate = rate
+	m.config.Burst = burst
+}
+
+// enabled reports whether rate limiting is enabled
+func (m *RateLimitMiddleware) enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.Enabled
+}
+
 // RateLimit applies rate limiting based on client IP
 func (m *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !m.config.Enabled {
+		if !m.enabled() {
 			c.Next()
 			return
 		}
@@ -59,7 +96,7 @@ func (m *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
 			m.logger.Warn("Rate limit exceeded",
 				zap.String("client_ip", clientIP),
 			)
-			c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{
+			response.Error(c, http.StatusTooManyRequests, dto.ErrorResponse{
 				Error:   "Too Many Requests",
 				Message: "Rate limit exceeded. Please try again later.",
 				Code:    "RATE_LIMIT_EXCEEDED",
@@ -75,7 +112,7 @@ func (m *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
 // RateLimitByUser applies rate limiting based on authenticated user
 func (m *RateLimitMiddleware) RateLimitByUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !m.config.Enabled {
+		if !m.enabled() {
 			c.Next()
 			return
 		}
@@ -107,7 +144,7 @@ func (m *RateLimitMiddleware) RateLimitByUser() gin.HandlerFunc {
 			m.logger.Warn("User rate limit exceeded",
 				zap.Any("user_id", userID),
 			)
-			c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{
+			response.Error(c, http.StatusTooManyRequests, dto.ErrorResponse{
 				Error:   "Too Many Requests",
 				Message: "Rate limit exceeded. Please try again later.",
 				Code:    "RATE_LIMIT_EXCEEDED",
@@ -120,10 +157,65 @@ func (m *RateLimitMiddleware) RateLimitByUser() gin.HandlerFunc {
 	}
 }
 
+// RateLimitByTenant applies a rate limit shared by all users of the
+// authenticated user's tenant. Requests without a tenant are not limited here.
+func (m *RateLimitMiddleware) RateLimitByTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.enabled() || !m.config.Tenants.Enabled {
+			c.Next()
+			return
+		}
+
+		claims, _ := c.Get("claims")
+		userClaims, ok := claims.(*jwt.Claims)
+		if !ok || userClaims.TenantID == "" {
+			c.Next()
+			return
+		}
+		tenantID := userClaims.TenantID
+
+		rate, burst := m.tenantLimit(tenantID)
+		allowed, err := m.checkBucket(c.Request.Context(), fmt.Sprintf("rate_limit:tenant:%s", tenantID), rate, burst)
+		if err != nil {
+			m.logger.Error("Tenant rate limit check failed",
+				zap.String("tenant_id", tenantID),
+				zap.Error(err),
+			)
+			// Allow request if rate limit check fails
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			m.logger.Warn("Tenant rate limit exceeded",
+				zap.String("tenant_id", tenantID),
+			)
+			response.Error(c, http.StatusTooManyRequests, dto.ErrorResponse{
+				Error:   "Too Many Requests",
+				Message: "Rate limit exceeded for your organization. Please try again later.",
+				Code:    "TENANT_RATE_LIMIT_EXCEEDED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tenantLimit returns the rate and burst of tenantID, falling back to the
+// tenant default
+func (m *RateLimitMiddleware) tenantLimit(tenantID string) (int, int) {
+	if limit, ok := m.tenantLimits[strings.ToLower(tenantID)]; ok {
+		return limit.Rate, limit.Burst
+	}
+	return m.config.Tenants.Rate, m.config.Tenants.Burst
+}
+
 // RateLimitCustom applies custom rate limiting with specified parameters
 func (m *RateLimitMiddleware) RateLimitCustom(rate int, window time.Duration, keyFunc func(*gin.Context) string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !m.config.Enabled {
+		if !m.enabled() {
 			c.Next()
 			return
 		}
@@ -147,7 +239,7 @@ func (m *RateLimitMiddleware) RateLimitCustom(rate int, window time.Duration, ke
 			m.logger.Warn("Custom rate limit exceeded",
 				zap.String("key", key),
 			)
-			c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{
+			response.Error(c, http.StatusTooManyRequests, dto.ErrorResponse{
 				Error:   "Too Many Requests",
 				Message: "Rate limit exceeded. Please try again later.",
 				Code:    "RATE_LIMIT_EXCEEDED",
@@ -160,51 +252,192 @@ func (m *RateLimitMiddleware) RateLimitCustom(rate int, window time.Duration, ke
 	}
 }
 
-// checkRateLimit checks if the request is within rate limit
+// checkRateLimit checks if the request is within rate limit.
+// It uses a token bucket that refills at Rate requests per minute and holds
+// up to Burst tokens, so short bursts are allowed without resetting sharply
+// at window boundaries.
 func (m *RateLimitMiddleware) checkRateLimit(ctx context.Context, key string) (bool, error) {
-	window := time.Minute // Default window
+	m.mu.RLock()
+	rate, burst := m.config.Rate, m.config.Burst
+	m.mu.RUnlock()
 
-	// Increment counter
-	count, err := m.redis.IncrementWithExpiry(ctx, key, window)
-	if err != nil {
-		return false, err
+	return m.checkBucket(ctx, key, rate, burst)
+}
+
+// checkBucket takes a token from the bucket at key, which refills at rate
+// requests per minute and holds up to burst tokens (rate if unset)
+func (m *RateLimitMiddleware) checkBucket(ctx context.Context, key string, rate, burst int) (bool, error) {
+	if burst <= 0 {
+		burst = rate
 	}
 
-	// Check if within rate limit
-	return count <= int64(m.config.Rate), nil
+	refillRate := float64(rate) / time.Minute.Seconds()
+	return m.limiter.TakeToken(ctx, key, burst, refillRate, m.now())
 }
 
-// checkCustomRateLimit checks rate limit with custom parameters
+// checkCustomRateLimit checks rate limit with custom parameters.
+// At most rate requests are allowed per window, refilling continuously.
 func (m *RateLimitMiddleware) checkCustomRateLimit(ctx context.Context, key string, rate int, window time.Duration) (bool, error) {
-	// Increment counter
-	count, err := m.redis.IncrementWithExpiry(ctx, key, window)
+	refillRate := float64(rate) / window.Seconds()
+	return m.limiter.TakeToken(ctx, key, rate, refillRate, m.now())
+}
+
+// LoginRateLimit throttles login attempts per client IP, per account and per
+// IP+account pair. Each dimension has its own bucket and the request is
+// rejected as soon as any of them is exhausted, which stops both credential
+// stuffing from one IP and slow brute force against one account from many.
+func (m *RateLimitMiddleware) LoginRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.enabled() {
+			c.Next()
+			return
+		}
+
+		clientIP := c.ClientIP()
+		account := loginAccount(c)
+
+		dimensions := []struct {
+			name  string
+			key   string
+			limit int
+		}{
+			{"ip_account", fmt.Sprintf("login_rate_limit:ip_account:%s:%s", clientIP, account), m.config.Login.PerIPAccount},
+			{"account", fmt.Sprintf("login_rate_limit:account:%s", account), m.config.Login.PerAccount},
+			{"ip", fmt.Sprintf("login_rate_limit:ip:%s", clientIP), m.config.Login.PerIP},
+		}
+
+		for _, dimension := range dimensions {
+			if dimension.limit <= 0 || (account == "" && dimension.name != "ip") {
+				continue
+			}
+
+			allowed, err := m.checkCustomRateLimit(c.Request.Context(), dimension.key, dimension.limit, m.config.Login.Window)
+			if err != nil {
+				m.logger.Error("Login rate limit check failed",
+					zap.String("dimension", dimension.name),
+					zap.Error(err),
+				)
+				// Allow request if rate limit check fails
+				continue
+			}
+
+			if !allowed {
+				m.logger.Warn("Login rate limit exceeded",
+					zap.String("dimension", dimension.name),
+					zap.String("client_ip", clientIP),
+					zap.String("account", account),
+				)
+				response.Error(c, http.StatusTooManyRequests, dto.ErrorResponse{
+					Error:   "Too Many Requests",
+					Message: "Too many login attempts. Please try again later.",
+					Code:    "LOGIN_RATE_LIMIT_EXCEEDED",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// loginAccount reads the account identifier from a login request body,
+// leaving the body intact for the handler
+func loginAccount(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		return false, err
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
 	}
 
-	// Check if within rate limit
-	return count <= int64(rate), nil
+	return strings.ToLower(strings.TrimSpace(req.Email))
 }
 
-// LoginRateLimit applies rate limiting specifically for login attempts
-func (m *RateLimitMiddleware) LoginRateLimit() gin.HandlerFunc {
-	return m.RateLimitCustom(5, 15*time.Minute, func(c *gin.Context) string {
-		// Rate limit by IP for login attempts
-		return fmt.Sprintf("login_rate_limit:%s", c.ClientIP())
-	})
+// routeRateLimit applies the limit configured for route under
+// rate_limit.routes, keyed by keyFunc. Routes whose limit is disabled are
+// not limited.
+func (m *RateLimitMiddleware) routeRateLimit(route string, keyFunc func(*gin.Context) string) gin.HandlerFunc {
+	limit, ok := m.config.RouteLimit(route)
+	if !ok {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return m.RateLimitCustom(limit.Rate, limit.Window, keyFunc)
+}
+
+// RouteRateLimit applies the limits configured under rate_limit.routes for
+// route patterns, such as /api/v1/users/:id, so routes can be limited without
+// code changes. Requests are limited per user when authenticated and per
+// client IP otherwise.
+func (m *RateLimitMiddleware) RouteRateLimit() gin.HandlerFunc {
+	limiters := make(map[string]gin.HandlerFunc)
+	for route := range m.config.Routes {
+		if !strings.HasPrefix(route, "/") {
+			continue
+		}
+		pattern := strings.ToLower(route)
+		limiters[pattern] = m.routeRateLimit(route, func(c *gin.Context) string {
+			if userID, exists := c.Get("user_id"); exists {
+				return fmt.Sprintf("route_rate_limit:%s:user:%v", pattern, userID)
+			}
+			return fmt.Sprintf("route_rate_limit:%s:%s", pattern, c.ClientIP())
+		})
+	}
+
+	return func(c *gin.Context) {
+		limiter, ok := limiters[strings.ToLower(c.FullPath())]
+		if !ok {
+			c.Next()
+			return
+		}
+		limiter(c)
+	}
 }
 
 // RegistrationRateLimit applies rate limiting specifically for registration attempts
 func (m *RateLimitMiddleware) RegistrationRateLimit() gin.HandlerFunc {
-	return m.RateLimitCustom(3, 60*time.Minute, func(c *gin.Context) string {
+	return m.routeRateLimit("register", func(c *gin.Context) string {
 		// Rate limit by IP for registration attempts
 		return fmt.Sprintf("register_rate_limit:%s", c.ClientIP())
 	})
 }
 
+// AvailabilityRateLimit applies rate limiting for username and email
+// availability checks, which could otherwise enumerate registered accounts
+func (m *RateLimitMiddleware) AvailabilityRateLimit() gin.HandlerFunc {
+	return m.routeRateLimit("availability", func(c *gin.Context) string {
+		// Rate limit by IP for availability checks
+		return fmt.Sprintf("availability_rate_limit:%s", c.ClientIP())
+	})
+}
+
+// PhoneCodeRateLimit applies rate limiting for sending phone verification
+// codes, each of which costs a text message
+func (m *RateLimitMiddleware) PhoneCodeRateLimit() gin.HandlerFunc {
+	return m.routeRateLimit("phone_code", func(c *gin.Context) string {
+		// Rate limit by user, falling back to IP
+		if userID, exists := c.Get("user_id"); exists {
+			return fmt.Sprintf("phone_code_rate_limit:user:%v", userID)
+		}
+		return fmt.Sprintf("phone_code_rate_limit:%s", c.ClientIP())
+	})
+}
+
 // PasswordResetRateLimit applies rate limiting for password reset attempts
 func (m *RateLimitMiddleware) PasswordResetRateLimit() gin.HandlerFunc {
-	return m.RateLimitCustom(3, 60*time.Minute, func(c *gin.Context) string {
+	return m.routeRateLimit("password_reset", func(c *gin.Context) string {
 		// Rate limit by IP for password reset attempts
 		return fmt.Sprintf("password_reset_rate_limit:%s", c.ClientIP())
 	})
@@ -0,0 +1,394 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// fakeClock is a manually advanced clock for rate limit tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func setupRateLimitTest(t *testing.T, rate, burst int) (*RateLimitMiddleware, *fakeClock) {
+	return newTestRateLimitMiddleware(t, config.RateLimitConfig{
+		Enabled: true,
+		Rate:    rate,
+		Burst:   burst,
+		Store:   "redis",
+	})
+}
+
+func newTestRateLimitMiddleware(t *testing.T, rateLimitCfg config.RateLimitConfig) (*RateLimitMiddleware, *fakeClock) {
+	mr := miniredis.RunT(t)
+
+	cfg := &config.Config{
+		Redis:     config.RedisConfig{Addr: mr.Addr()},
+		RateLimit: rateLimitCfg,
+	}
+
+	logger := zap.NewNop()
+	redis, err := cache.NewRedis(cfg, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewRateLimitMiddleware(redis, cfg, logger)
+	m.now = clock.Now
+
+	return m, clock
+}
+
+func newRateLimitRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", handler, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func doRequest(r *gin.Engine) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimit_SteadyRateAccepted(t *testing.T) {
+	m, clock := setupRateLimitTest(t, 60, 5)
+	r := newRateLimitRouter(m.RateLimit())
+
+	// One request per second is exactly the configured rate and must never be rejected
+	for i := 0; i < 180; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+		clock.Advance(time.Second)
+	}
+}
+
+func TestRateLimit_BurstHonored(t *testing.T) {
+	m, _ := setupRateLimitTest(t, 60, 5)
+	r := newRateLimitRouter(m.RateLimit())
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
+
+func TestRateLimit_BoundaryBurstRejected(t *testing.T) {
+	m, clock := setupRateLimitTest(t, 60, 5)
+	r := newRateLimitRouter(m.RateLimit())
+
+	// Exhaust the burst right before a minute boundary
+	clock.Advance(59*time.Second + 900*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+
+	// A fixed window would reset here and allow another full burst
+	clock.Advance(200 * time.Millisecond)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	// Tokens come back at the steady rate
+	clock.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, doRequest(r))
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
+
+func TestRateLimitCustom_WindowRefill(t *testing.T) {
+	m, clock := setupRateLimitTest(t, 100, 200)
+	r := newRateLimitRouter(m.RateLimitCustom(3, time.Minute, func(c *gin.Context) string {
+		return "custom:" + c.ClientIP()
+	}))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	clock.Advance(20 * time.Second)
+	assert.Equal(t, http.StatusOK, doRequest(r))
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
+
+func TestAvailabilityRateLimit(t *testing.T) {
+	m, _ := setupRateLimitTest(t, 100, 200)
+	r := newRateLimitRouter(m.AvailabilityRateLimit())
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
+
+func TestPhoneCodeRateLimit(t *testing.T) {
+	m, _ := setupRateLimitTest(t, 100, 200)
+	gin.SetMode(gin.TestMode)
+	userID := "user-1"
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}, m.PhoneCodeRateLimit(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	// Limits are per user
+	userID = "user-2"
+	assert.Equal(t, http.StatusOK, doRequest(r))
+}
+
+func TestRouteLimits_Defaults(t *testing.T) {
+	// Routes without configured limits fall back to the built-in defaults
+	m, clock := setupRateLimitTest(t, 100, 200)
+	r := newRateLimitRouter(m.RegistrationRateLimit())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	clock.Advance(20 * time.Minute)
+	assert.Equal(t, http.StatusOK, doRequest(r))
+
+	r = newRateLimitRouter(m.PasswordResetRateLimit())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+}
+
+func TestRouteLimits_Configured(t *testing.T) {
+	m, clock := newTestRateLimitMiddleware(t, config.RateLimitConfig{
+		Enabled: true,
+		Rate:    100,
+		Burst:   200,
+		Store:   "redis",
+		Routes: map[string]config.RouteLimit{
+			"Register":       {Rate: 5, Window: time.Minute},
+			"password_reset": {Rate: 0, Window: time.Hour},
+		},
+	})
+
+	r := newRateLimitRouter(m.RegistrationRateLimit())
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r))
+
+	clock.Advance(12 * time.Second)
+	assert.Equal(t, http.StatusOK, doRequest(r))
+
+	// A rate of 0 disables the limit
+	r = newRateLimitRouter(m.PasswordResetRateLimit())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, doRequest(r), "request %d", i)
+	}
+}
+
+func TestRouteRateLimit(t *testing.T) {
+	m, _ := newTestRateLimitMiddleware(t, config.RateLimitConfig{
+		Enabled: true,
+		Rate:    100,
+		Burst:   200,
+		Store:   "redis",
+		Routes: map[string]config.RouteLimit{
+			"/items/:id": {Rate: 2, Window: time.Hour},
+		},
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(m.RouteRateLimit())
+	r.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/other", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The limit applies to the route pattern, whatever the parameters
+	assert.Equal(t, http.StatusOK, get("/items/1"))
+	assert.Equal(t, http.StatusOK, get("/items/2"))
+	assert.Equal(t, http.StatusTooManyRequests, get("/items/3"))
+
+	// Routes without a configured limit are not limited
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, get("/other"), "request %d", i)
+	}
+}
+
+func doLogin(r *gin.Engine, ip, email string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"`+email+`","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func newLoginRouter(m *RateLimitMiddleware) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/login", m.LoginRateLimit(), func(c *gin.Context) {
+		// The handler must still see the request body
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   config.LoginThrottleConfig
+		attempt func(i int) (ip, email string)
+		allowed int
+	}{
+		{
+			name:  "per IP across accounts",
+			login: config.LoginThrottleConfig{Window: time.Minute, PerIP: 3, PerAccount: 10, PerIPAccount: 10},
+			attempt: func(i int) (string, string) {
+				return "10.0.0.1", fmt.Sprintf("user%d@example.com", i)
+			},
+			allowed: 3,
+		},
+		{
+			name:  "per account across IPs",
+			login: config.LoginThrottleConfig{Window: time.Minute, PerIP: 10, PerAccount: 3, PerIPAccount: 10},
+			attempt: func(i int) (string, string) {
+				return fmt.Sprintf("10.0.0.%d", i+1), "victim@example.com"
+			},
+			allowed: 3,
+		},
+		{
+			name:  "per IP and account pair",
+			login: config.LoginThrottleConfig{Window: time.Minute, PerIP: 10, PerAccount: 10, PerIPAccount: 3},
+			attempt: func(i int) (string, string) {
+				return "10.0.0.1", "Victim@Example.com"
+			},
+			allowed: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestRateLimitMiddleware(t, config.RateLimitConfig{
+				Enabled: true,
+				Store:   "redis",
+				Login:   tt.login,
+			})
+			r := newLoginRouter(m)
+
+			for i := 0; i < tt.allowed; i++ {
+				ip, email := tt.attempt(i)
+				assert.Equal(t, http.StatusOK, doLogin(r, ip, email), "attempt %d", i)
+			}
+
+			ip, email := tt.attempt(tt.allowed)
+			assert.Equal(t, http.StatusTooManyRequests, doLogin(r, ip, email))
+		})
+	}
+}
+
+func TestLoginRateLimit_OtherDimensionsUnaffected(t *testing.T) {
+	m, clock := newTestRateLimitMiddleware(t, config.RateLimitConfig{
+		Enabled: true,
+		Store:   "redis",
+		Login:   config.LoginThrottleConfig{Window: time.Minute, PerIP: 10, PerAccount: 10, PerIPAccount: 2},
+	})
+	r := newLoginRouter(m)
+
+	assert.Equal(t, http.StatusOK, doLogin(r, "10.0.0.1", "victim@example.com"))
+	assert.Equal(t, http.StatusOK, doLogin(r, "10.0.0.1", "victim@example.com"))
+	assert.Equal(t, http.StatusTooManyRequests, doLogin(r, "10.0.0.1", "victim@example.com"))
+
+	// The same IP can still log in to another account, and the account from another IP
+	assert.Equal(t, http.StatusOK, doLogin(r, "10.0.0.1", "other@example.com"))
+	assert.Equal(t, http.StatusOK, doLogin(r, "10.0.0.2", "victim@example.com"))
+
+	// The pair recovers once the window refills
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, http.StatusOK, doLogin(r, "10.0.0.1", "victim@example.com"))
+}
+
+func TestRateLimitByTenant_IndependentLimits(t *testing.T) {
+	m, _ := newTestRateLimitMiddleware(t, config.RateLimitConfig{
+		Enabled: true,
+		Rate:    100,
+		Burst:   200,
+		Tenants: config.TenantRateLimitConfig{
+			Enabled: true,
+			Rate:    60,
+			Burst:   3,
+			Limits:  map[string]config.TenantLimit{"Tenant-A": {Rate: 60, Burst: 2}},
+		},
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		// Stands in for the auth middleware
+		c.Set("claims", &jwt.Claims{UserID: "user-1", TenantID: c.GetHeader("X-Tenant")})
+	}, m.RateLimitByTenant(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	doTenantRequest := func(tenantID string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", tenantID)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Tenant A exhausts its own override
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, doTenantRequest("Tenant-A"), "tenant A request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doTenantRequest("Tenant-A"))
+
+	// Tenant B keeps its full default budget
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, doTenantRequest("tenant-b"), "tenant B request %d", i)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, doTenantRequest("tenant-b"))
+
+	// Requests without a tenant are not limited per tenant
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, doTenantRequest(""), "request without tenant %d", i)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+)
+
+// NewReadYourWritesMiddleware creates a middleware that sends the database
+// reads of a request to the primary once the request has written, so it sees
+// its own changes however far the read replicas lag behind. It does nothing
+// without replicas or when database.postgres.read_your_writes is off.
+func NewReadYourWritesMiddleware(cfg *config.Config) gin.HandlerFunc {
+	if len(cfg.Database.Postgres.Replicas) == 0 || !cfg.Database.Postgres.ReadYourWrites {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithReadYourWrites(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func TestReadYourWritesMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		replicas       []string
+		readYourWrites bool
+		wantTracked    bool
+	}{
+		{name: "replicas", replicas: []string{"host=replica"}, readYourWrites: true, wantTracked: true},
+		{name: "disabled", replicas: []string{"host=replica"}},
+		{name: "no replicas", readYourWrites: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			cfg := &config.Config{Database: config.DatabaseConfig{Postgres: config.PostgreSQLConfig{
+				Replicas:       tt.replicas,
+				ReadYourWrites: tt.readYourWrites,
+			}}}
+
+			req := httptest.NewRequest(http.MethodPost, "/users", nil)
+			var handlerCtx context.Context
+			r := gin.New()
+			r.Use(NewReadYourWritesMiddleware(cfg))
+			r.POST("/users", func(c *gin.Context) {
+				handlerCtx = c.Request.Context()
+			})
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.wantTracked, handlerCtx != req.Context())
+		})
+	}
+}
@@ -3,9 +3,18 @@ package middleware
 import (
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/response"
 )
 
-// NewRequestIDMiddleware creates a new request ID middleware
-func NewRequestIDMiddleware() gin.HandlerFunc {
-	return requestid.New()
+// NewRequestIDMiddleware creates a new request ID middleware. The request ID
+// is read from (or generated for) the configured header, echoed back in the
+// response and stored in the gin context for error responses.
+func NewRequestIDMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return requestid.New(
+		requestid.WithCustomHeaderStrKey(requestid.HeaderStrKey(cfg.Server.RequestIDHeader)),
+		requestid.WithHandler(func(c *gin.Context, requestID string) {
+			c.Set(response.RequestIDKey, requestID)
+		}),
+	)
 }
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+func TestRequestIDMiddleware_ErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{name: "client supplied request ID", requestID: "req-12345"},
+		{name: "generated request ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Server: config.ServerConfig{
+				RequestIDHeader: "X-Request-ID",
+				MaxURLLength:    10,
+			}}
+
+			r := gin.New()
+			r.Use(NewRequestIDMiddleware(cfg))
+			r.Use(NewURLLengthMiddleware(cfg))
+			r.GET("/*path", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/a-very-long-path", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusRequestURITooLong, w.Code)
+
+			headerID := w.Header().Get("X-Request-ID")
+			require.NotEmpty(t, headerID)
+			if tt.requestID != "" {
+				assert.Equal(t, tt.requestID, headerID)
+			}
+
+			var resp dto.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, headerID, resp.RequestID)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+)
+
+// timeoutWriter drops the response of a handler that finishes after the
+// deadline without having written anything, so the timeout error can be sent
+// instead of the handler's own error
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+// expired reports whether the deadline passed before the response was started
+func (w *timeoutWriter) expired() bool {
+	return !w.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded)
+}
+
+// WriteHeaderNow sends the status unless the deadline has passed
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write writes the body unless the deadline has passed
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the body unless the deadline has passed
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// NewTimeoutMiddleware creates a middleware that gives each request a deadline
+// of timeout. The deadline is set on the request context, so database queries
+// and other downstream calls made with it are canceled once it passes. A
+// handler that has not started its response by then gets a 503. A timeout of
+// zero disables the deadline.
+func NewTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		writer := &timeoutWriter{ResponseWriter: original, ctx: ctx}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		if writer.expired() {
+			response.Error(c, http.StatusServiceUnavailable, dto.ErrorResponse{
+				Error:   "Service Unavailable",
+				Message: fmt.Sprintf("Request did not complete within %s", timeout),
+				Code:    "REQUEST_TIMEOUT",
+			})
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var downstreamErr error
+	r := gin.New()
+	r.Use(NewTimeoutMiddleware(50 * time.Millisecond))
+	r.GET("/slow", func(c *gin.Context) {
+		// Stands in for a repository query run with the request context
+		select {
+		case <-c.Request.Context().Done():
+			downstreamErr = c.Request.Context().Err()
+		case <-time.After(5 * time.Second):
+		}
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "Internal Server Error", Message: "Failed to get user"})
+	})
+	r.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	t.Run("slow handler", func(t *testing.T) {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.ErrorIs(t, downstreamErr, context.DeadlineExceeded)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp dto.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "REQUEST_TIMEOUT", resp.Code)
+		assert.Equal(t, "Request did not complete within 50ms", resp.Message)
+	})
+
+	t.Run("fast handler", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+	})
+}
+
+func TestTimeoutMiddleware_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewTimeoutMiddleware(0))
+	r.GET("/", func(c *gin.Context) {
+		_, hasDeadline := c.Request.Context().Deadline()
+		assert.False(t, hasDeadline)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
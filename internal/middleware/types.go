@@ -3,8 +3,17 @@ package middleware
 import "github.com/gin-gonic/gin"
 
 type (
-	RecoveryMiddleware  gin.HandlerFunc
-	LoggerMiddleware    gin.HandlerFunc
-	RequestIDMiddleware gin.HandlerFunc
-	CORSMiddleware      gin.HandlerFunc
+	RecoveryMiddleware       gin.HandlerFunc
+	LoggerMiddleware         gin.HandlerFunc
+	RequestIDMiddleware      gin.HandlerFunc
+	CORSMiddleware           gin.HandlerFunc
+	JSONNamingMiddleware     gin.HandlerFunc
+	URLLengthMiddleware      gin.HandlerFunc
+	CacheControlMiddleware   gin.HandlerFunc
+	TracingMiddleware        gin.HandlerFunc
+	AuditMiddleware          gin.HandlerFunc
+	CompressionMiddleware    gin.HandlerFunc
+	TimeoutMiddleware        gin.HandlerFunc
+	IdempotencyMiddleware    gin.HandlerFunc
+	ReadYourWritesMiddleware gin.HandlerFunc
 )
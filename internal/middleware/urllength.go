@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/response"
+)
+
+// NewURLLengthMiddleware creates a middleware that rejects requests whose
+// URL (path plus query string) exceeds the configured maximum length
+func NewURLLengthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	maxLength := cfg.Server.MaxURLLength
+
+	return func(c *gin.Context) {
+		if maxLength <= 0 {
+			c.Next()
+			return
+		}
+
+		if len(c.Request.URL.RequestURI()) > maxLength {
+			response.Error(c, http.StatusRequestURITooLong, dto.ErrorResponse{
+				Error:   "URI Too Long",
+				Message: fmt.Sprintf("Request URL must not exceed %d characters", maxLength),
+				Code:    "URI_TOO_LONG",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
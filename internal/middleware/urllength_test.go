@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func TestURLLengthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Server: config.ServerConfig{MaxURLLength: 64}}
+
+	r := gin.New()
+	r.Use(NewURLLengthMiddleware(cfg))
+	r.GET("/users", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedCode int
+	}{
+		{
+			name:         "short query is accepted",
+			url:          "/users?search=john",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "over-long query is rejected",
+			url:          "/users?search=" + strings.Repeat("a", 100),
+			expectedCode: http.StatusRequestURITooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LoginActivity is one login of a user as shown in their activity history.
+// Anomalous marks logins from a network or user agent not seen in the user's
+// recent logins.
+type LoginActivity struct {
+	Time      time.Time `json:"time" example:"2024-01-01T00:00:00Z"`
+	IPAddress string    `json:"ip_address" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0"`
+	Anomalous bool      `json:"anomalous" example:"false"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// NotificationCategory groups emails a user can opt in or out of
+type NotificationCategory string
+
+const (
+	// NotificationSecurity covers security-critical mail and cannot be disabled
+	NotificationSecurity NotificationCategory = "security"
+	// NotificationAccount covers account lifecycle mail such as welcome and status changes
+	NotificationAccount NotificationCategory = "account"
+	// NotificationMarketing covers promotional mail
+	NotificationMarketing NotificationCategory = "marketing"
+)
+
+// NotificationCategories lists all known categories
+var NotificationCategories = []NotificationCategory{
+	NotificationSecurity,
+	NotificationAccount,
+	NotificationMarketing,
+}
+
+// IsValid checks if the category is known
+func (c NotificationCategory) IsValid() bool {
+	switch c {
+	case NotificationSecurity, NotificationAccount, NotificationMarketing:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsMandatory reports whether mail in the category is always sent
+func (c NotificationCategory) IsMandatory() bool {
+	return c == NotificationSecurity
+}
+
+// NotificationPreferences holds per-category email toggles, stored as JSONB.
+// Categories without an entry are enabled.
+type NotificationPreferences map[NotificationCategory]bool
+
+// Allows reports whether mail in the category may be sent
+func (p NotificationPreferences) Allows(category NotificationCategory) bool {
+	if category.IsMandatory() {
+		return true
+	}
+	enabled, ok := p[category]
+	return !ok || enabled
+}
+
+// Effective returns the toggle for every known category
+func (p NotificationPreferences) Effective() NotificationPreferences {
+	effective := make(NotificationPreferences, len(NotificationCategories))
+	for _, category := range NotificationCategories {
+		effective[category] = p.Allows(category)
+	}
+	return effective
+}
+
+// NotificationSettings are how a user wants to be notified: the categories
+// they receive and whether notifications are also texted to them
+type NotificationSettings struct {
+	Preferences NotificationPreferences
+	SMS         bool
+}
+
+// Value implements driver.Valuer
+func (p NotificationPreferences) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (p *NotificationPreferences) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = NotificationPreferences{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported notification preferences type: %T", value)
+	}
+
+	prefs := NotificationPreferences{}
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return fmt.Errorf("failed to decode notification preferences: %w", err)
+	}
+	*p = prefs
+	return nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferences_Allows(t *testing.T) {
+	prefs := NotificationPreferences{
+		NotificationMarketing: false,
+		NotificationSecurity:  false,
+	}
+
+	assert.False(t, prefs.Allows(NotificationMarketing))
+	assert.True(t, prefs.Allows(NotificationAccount), "missing categories are enabled")
+	assert.True(t, prefs.Allows(NotificationSecurity), "security mail is mandatory")
+
+	var empty NotificationPreferences
+	assert.Equal(t, NotificationPreferences{
+		NotificationSecurity:  true,
+		NotificationAccount:   true,
+		NotificationMarketing: true,
+	}, empty.Effective())
+}
+
+func TestNotificationPreferences_ValueScan(t *testing.T) {
+	prefs := NotificationPreferences{NotificationMarketing: false}
+
+	value, err := prefs.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"marketing":false}`, value)
+
+	var scanned NotificationPreferences
+	require.NoError(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, prefs, scanned)
+
+	require.NoError(t, scanned.Scan(nil))
+	assert.Empty(t, scanned)
+
+	assert.Error(t, scanned.Scan(42))
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxEvent represents an event waiting to be relayed to Kafka.
+// Rows are written in the same transaction as the business change
+// so that events are never lost or published for rolled back changes.
+type OutboxEvent struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	EventType   string     `json:"event_type" gorm:"column:event_type;type:varchar(100);not null"`
+	AggregateID string     `json:"aggregate_id" gorm:"column:aggregate_id;type:varchar(100);not null"`
+	RequestID   string     `json:"request_id,omitempty" gorm:"column:request_id;type:varchar(100)"`
+	Payload     string     `json:"payload" gorm:"type:jsonb;not null"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	LastError   *string    `json:"last_error,omitempty" gorm:"column:last_error;type:text"`
+	SentAt      *time.Time `json:"sent_at,omitempty" gorm:"column:sent_at;type:timestamp with time zone;index"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// BeforeCreate generates UUID before creating outbox event
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName returns the table name for OutboxEvent model
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
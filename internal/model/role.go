@@ -0,0 +1,45 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Role names. Every user implicitly has RoleUser.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+	RoleUser    = "user"
+)
+
+// Roles lists the roles users can be given
+var Roles = []string{RoleAdmin, RoleSupport, RoleUser}
+
+// IsValidRole checks if name is a known role
+func IsValidRole(name string) bool {
+	return slices.Contains(Roles, name)
+}
+
+// Role is a named set of permissions that can be given to users
+type Role struct {
+	Name        string    `json:"name" gorm:"primaryKey;type:varchar(50)"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName returns the table name for Role model
+func (Role) TableName() string {
+	return "roles"
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Role      string    `json:"role" gorm:"primaryKey;type:varchar(50)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName returns the table name for UserRole model
+func (UserRole) TableName() string {
+	return "user_roles"
+}
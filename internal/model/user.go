@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,7 +14,7 @@ import (
 type User struct {
 	ID            string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	Username      string         `json:"username" gorm:"uniqueIndex;type:varchar(50);not null"`
-	Email         string         `json:"email" gorm:"uniqueIndex;type:varchar(255);not null"`
+	Email         string         `json:"email" gorm:"uniqueIndex;type:varchar(255);not null"` // stored normalized, see NormalizeEmail
 	PasswordHash  string         `json:"-" gorm:"column:password_hash;type:varchar(255);not null"`
 	FirstName     *string        `json:"first_name,omitempty" gorm:"column:first_name;type:varchar(100)"`
 	LastName      *string        `json:"last_name,omitempty" gorm:"column:last_name;type:varchar(100)"`
@@ -25,6 +28,21 @@ type User struct {
 	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Plan is the subscription plan, which sets the concurrent session limit
+	Plan UserPlan `json:"plan" gorm:"type:varchar(20);not null;default:'free'"`
+
+	// NotificationPreferences holds the user's email opt-outs
+	NotificationPreferences NotificationPreferences `json:"-" gorm:"column:notification_preferences;type:jsonb;not null;default:'{}'"`
+
+	// SMSNotifications is whether notifications are also texted to the verified phone
+	SMSNotifications bool `json:"-" gorm:"column:sms_notifications;not null;default:false"`
+
+	// TenantID is the user's tenant in multitenant deployments, issued in tokens
+	TenantID *string `json:"-" gorm:"column:tenant_id;type:varchar(64);index"`
+
+	// Roles are the roles assigned in user_roles, set when loaded by the role service
+	Roles []string `json:"-" gorm:"-"`
 }
 
 // UserStatus represents user status
@@ -37,14 +55,47 @@ const (
 	UserStatusDeleted   UserStatus = "deleted"
 )
 
+// UserPlan represents a user's subscription plan
+type UserPlan string
+
+const (
+	UserPlanFree UserPlan = "free"
+	UserPlanPro  UserPlan = "pro"
+)
+
 // BeforeCreate generates UUID before creating user
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == "" {
 		u.ID = uuid.New().String()
 	}
+	if u.Plan == "" {
+		u.Plan = UserPlanFree
+	}
 	return nil
 }
 
+// BeforeSave normalizes the email before the user is written. Migration 005
+// adds a unique index on LOWER(email) for rows written outside the application.
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.Email = NormalizeEmail(u.Email)
+	if u.Phone != nil {
+		phone := NormalizePhone(*u.Phone)
+		u.Phone = &phone
+	}
+	return nil
+}
+
+// NormalizeEmail trims and lowercases an email address so that addresses
+// differing only in case are treated as the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone strips spaces and dashes from a phone number
+func NormalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(phone))
+}
+
 // TableName returns the table name for User model
 func (User) TableName() string {
 	return "users"
@@ -80,6 +131,40 @@ func (u *User) ToPublicUser() *PublicUser {
 	}
 }
 
+// ToOwnerView returns the full view of u, shown to the user themselves and
+// to admins
+func (u *User) ToOwnerView() *PublicUser {
+	return u.ToPublicUser()
+}
+
+// ToPublicView returns the view of u shown to other users and anonymous
+// callers, with the fields in OwnerOnlyFields cleared. Write it with
+// PublicViewFields so the cleared fields are left out rather than zero.
+func (u *User) ToPublicView() *PublicUser {
+	return &PublicUser{
+		ID:        u.ID,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		AvatarURL: u.AvatarURL,
+		IsActive:  u.IsActive,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// GetRoles returns the user's effective roles, which are embedded in tokens:
+// the assigned roles, RoleUser, and RoleAdmin for users with the legacy
+// IsAdmin flag
+func (u *User) GetRoles() []string {
+	roles := append([]string{RoleUser}, u.Roles...)
+	if u.IsAdmin {
+		roles = append(roles, RoleAdmin)
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
 // JWT interface methods to avoid circular dependency
 func (u *User) GetID() string {
 	return u.ID
@@ -100,6 +185,21 @@ func (u *User) GetStatus() string {
 	return "inactive"
 }
 
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerified
+}
+
+func (u *User) IsAdministrator() bool {
+	return slices.Contains(u.GetRoles(), RoleAdmin)
+}
+
+func (u *User) GetTenantID() string {
+	if u.TenantID == nil {
+		return ""
+	}
+	return *u.TenantID
+}
+
 // PublicUser represents public user information (without sensitive fields)
 type PublicUser struct {
 	ID            string     `json:"id"`
@@ -117,3 +217,54 @@ type PublicUser struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
+
+// PublicUserFields lists the fields that can be selected with a sparse fieldset
+var PublicUserFields = []string{
+	"id", "username", "email", "first_name", "last_name", "phone", "avatar_url",
+	"is_active", "is_admin", "email_verified", "phone_verified",
+	"last_login_at", "created_at", "updated_at",
+}
+
+// OwnerOnlyFields lists the fields shown only to the user themselves and to admins
+var OwnerOnlyFields = []string{
+	"email", "phone", "is_admin", "email_verified", "phone_verified", "last_login_at",
+}
+
+// PublicViewFields returns the fields of the public view to write for a
+// request of fields: the requested fields, or all of them when fields is
+// nil, without the OwnerOnlyFields.
+func PublicViewFields(fields []string) []string {
+	if fields == nil {
+		fields = PublicUserFields
+	}
+
+	visible := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !slices.Contains(OwnerOnlyFields, field) {
+			visible = append(visible, field)
+		}
+	}
+	return visible
+}
+
+// Select returns only the given fields of the user, keyed by their JSON name.
+// Unset optional fields are omitted as in the full representation.
+func (u *PublicUser) Select(fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
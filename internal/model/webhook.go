@@ -0,0 +1,88 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription posts the user events of EventTypes to URL, signed
+// with Secret. It is disabled after too many consecutive failed deliveries.
+type WebhookSubscription struct {
+	ID                  string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	URL                 string            `json:"url" gorm:"type:varchar(2048);not null"`
+	Secret              string            `json:"-" gorm:"type:varchar(255);not null"`
+	EventTypes          WebhookEventTypes `json:"event_types" gorm:"column:event_types;type:jsonb;not null;default:'[]'"`
+	Active              bool              `json:"active" gorm:"not null;default:true"`
+	ConsecutiveFailures int               `json:"consecutive_failures" gorm:"column:consecutive_failures;not null;default:0"`
+	DisabledAt          *time.Time        `json:"disabled_at,omitempty" gorm:"column:disabled_at;type:timestamp with time zone"`
+	CreatedAt           time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BeforeCreate generates UUID before creating webhook subscription
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// TableName returns the table name for WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Subscribes reports whether events of eventType are posted to the subscription
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	return s.EventTypes.Includes(eventType)
+}
+
+// WebhookEventTypes lists the event types a subscription receives, stored as
+// JSONB. An empty list subscribes to every event type.
+type WebhookEventTypes []string
+
+// Includes reports whether eventType is listed, or the list is empty
+func (t WebhookEventTypes) Includes(eventType string) bool {
+	return len(t) == 0 || slices.Contains(t, eventType)
+}
+
+// Value implements driver.Valuer
+func (t WebhookEventTypes) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (t *WebhookEventTypes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = WebhookEventTypes{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported webhook event types type: %T", value)
+	}
+
+	types := WebhookEventTypes{}
+	if err := json.Unmarshal(data, &types); err != nil {
+		return fmt.Errorf("failed to decode webhook event types: %w", err)
+	}
+	*t = types
+	return nil
+}
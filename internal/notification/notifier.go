@@ -0,0 +1,178 @@
+// Package notification delivers notifications to users over the channels
+// their preferences allow
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"go.uber.org/zap"
+)
+
+// Channel is a way a notification is delivered
+type Channel string
+
+const (
+	// ChannelEmail sends the notification's template as an email
+	ChannelEmail Channel = "email"
+	// ChannelSMS texts the "sms" block of the notification's template to the
+	// user's verified phone
+	ChannelSMS Channel = "sms"
+	// ChannelWebhook posts the notification to the configured webhook
+	ChannelWebhook Channel = "webhook"
+)
+
+// Notification is a message to a user, rendered from Template for each channel
+type Notification struct {
+	UserID   string
+	Email    string
+	Category model.NotificationCategory
+	Template string
+	Data     map[string]string
+}
+
+// UserStore looks up the user a notification is for
+type UserStore interface {
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+}
+
+// Queue enqueues the async tasks delivering each channel, implemented by the
+// task client
+type Queue interface {
+	EnqueueEmail(ctx context.Context, payload task.EmailPayload) error
+	EnqueueSMS(ctx context.Context, payload task.SMSPayload) error
+	EnqueueWebhook(ctx context.Context, payload task.WebhookPayload) error
+}
+
+// webhookBody is the JSON posted to the webhook for a notification
+type webhookBody struct {
+	Event     string                     `json:"event"`
+	Category  model.NotificationCategory `json:"category"`
+	UserID    string                     `json:"user_id"`
+	Data      map[string]string          `json:"data,omitempty"`
+	Timestamp time.Time                  `json:"timestamp"`
+}
+
+// Notifier picks the channels of each notification from the user's
+// preferences and fans it out to them
+type Notifier struct {
+	users   UserStore
+	queue   Queue
+	webhook bool
+	logger  *zap.Logger
+}
+
+// NewNotifier creates a new notifier. Notifications are posted to the webhook
+// only when notifications.webhook.url is set.
+func NewNotifier(cfg *config.Config, users UserStore, queue Queue, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		users:   users,
+		queue:   queue,
+		webhook: cfg.Notifications.Webhook.URL != "",
+		logger:  logger,
+	}
+}
+
+// Notify delivers n over every channel the user allows. A failing channel
+// does not stop the others; their errors are joined. When the user cannot be
+// loaded, security notifications are still emailed to n.Email, e.g. after the
+// account is deleted, and other notifications are not sent since an opt-out
+// cannot be ruled out.
+func (s *Notifier) Notify(ctx context.Context, n *Notification) error {
+	user, err := s.users.GetUserByID(ctx, n.UserID)
+	if err != nil {
+		if !n.Category.IsMandatory() {
+			return fmt.Errorf("failed to load notification recipient: %w", err)
+		}
+		s.logger.Debug("Notification recipient unavailable, emailing only",
+			zap.String("user_id", n.UserID),
+			zap.Error(err),
+		)
+		user = nil
+	}
+
+	channels := s.Channels(n, user)
+	if len(channels) == 0 {
+		s.logger.Debug("Skipping notification, user opted out",
+			zap.String("user_id", n.UserID),
+			zap.String("category", string(n.Category)),
+		)
+		return nil
+	}
+
+	var errs []error
+	for _, channel := range channels {
+		if err := s.dispatch(ctx, channel, n, user); err != nil {
+			s.logger.Error("Failed to dispatch notification",
+				zap.String("user_id", n.UserID),
+				zap.String("template", n.Template),
+				zap.String("channel", string(channel)),
+				zap.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Channels returns the channels n is delivered over. user is nil when it
+// could not be loaded, leaving only email and the webhook.
+func (s *Notifier) Channels(n *Notification, user *model.User) []Channel {
+	var prefs model.NotificationPreferences
+	if user != nil {
+		prefs = user.NotificationPreferences
+	}
+	if !prefs.Allows(n.Category) {
+		return nil
+	}
+
+	var channels []Channel
+	if n.Email != "" {
+		channels = append(channels, ChannelEmail)
+	}
+	if user != nil && user.SMSNotifications && user.PhoneVerified && user.Phone != nil && *user.Phone != "" {
+		channels = append(channels, ChannelSMS)
+	}
+	if s.webhook {
+		channels = append(channels, ChannelWebhook)
+	}
+	return channels
+}
+
+// dispatch enqueues the task delivering n over channel
+func (s *Notifier) dispatch(ctx context.Context, channel Channel, n *Notification, user *model.User) error {
+	switch channel {
+	case ChannelEmail:
+		return s.queue.EnqueueEmail(ctx, task.EmailPayload{
+			To:       n.Email,
+			Template: n.Template,
+			Data:     n.Data,
+		})
+	case ChannelSMS:
+		return s.queue.EnqueueSMS(ctx, task.SMSPayload{
+			To:       *user.Phone,
+			Template: n.Template,
+			Data:     n.Data,
+		})
+	case ChannelWebhook:
+		body, err := json.Marshal(&webhookBody{
+			Event:     n.Template,
+			Category:  n.Category,
+			UserID:    n.UserID,
+			Data:      n.Data,
+			Timestamp: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook body: %w", err)
+		}
+		return s.queue.EnqueueWebhook(ctx, task.WebhookPayload{Event: n.Template, Body: body})
+	default:
+		return fmt.Errorf("unknown notification channel: %s", channel)
+	}
+}
@@ -0,0 +1,209 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"go.uber.org/zap"
+)
+
+// fakeUserStore returns a preset user or error
+type fakeUserStore struct {
+	user *model.User
+	err  error
+}
+
+func (s *fakeUserStore) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	return s.user, s.err
+}
+
+// fakeQueue records enqueued tasks, failing the channels in fail
+type fakeQueue struct {
+	emails   []task.EmailPayload
+	sms      []task.SMSPayload
+	webhooks []task.WebhookPayload
+	fail     map[Channel]error
+}
+
+func (q *fakeQueue) EnqueueEmail(ctx context.Context, payload task.EmailPayload) error {
+	if err := q.fail[ChannelEmail]; err != nil {
+		return err
+	}
+	q.emails = append(q.emails, payload)
+	return nil
+}
+
+func (q *fakeQueue) EnqueueSMS(ctx context.Context, payload task.SMSPayload) error {
+	if err := q.fail[ChannelSMS]; err != nil {
+		return err
+	}
+	q.sms = append(q.sms, payload)
+	return nil
+}
+
+func (q *fakeQueue) EnqueueWebhook(ctx context.Context, payload task.WebhookPayload) error {
+	if err := q.fail[ChannelWebhook]; err != nil {
+		return err
+	}
+	q.webhooks = append(q.webhooks, payload)
+	return nil
+}
+
+func newTestNotifier(users UserStore, webhookURL string) (*Notifier, *fakeQueue) {
+	cfg := &config.Config{}
+	cfg.Notifications.Webhook.URL = webhookURL
+	queue := &fakeQueue{}
+	return NewNotifier(cfg, users, queue, zap.NewNop()), queue
+}
+
+func statusChanged() *Notification {
+	return &Notification{
+		UserID:   "user-1",
+		Email:    "alice@example.com",
+		Category: model.NotificationAccount,
+		Template: task.TemplateStatusChanged,
+		Data:     map[string]string{"username": "alice"},
+	}
+}
+
+func TestNotifier_Channels(t *testing.T) {
+	phone := "+15551234567"
+
+	tests := []struct {
+		name     string
+		category model.NotificationCategory
+		user     *model.User
+		webhook  bool
+		expected []Channel
+	}{
+		{
+			name:     "defaults",
+			category: model.NotificationAccount,
+			user:     &model.User{},
+			expected: []Channel{ChannelEmail},
+		},
+		{
+			name:     "opted out of the category",
+			category: model.NotificationAccount,
+			user:     &model.User{NotificationPreferences: model.NotificationPreferences{model.NotificationAccount: false}},
+			webhook:  true,
+			expected: nil,
+		},
+		{
+			name:     "security ignores opt-outs",
+			category: model.NotificationSecurity,
+			user:     &model.User{NotificationPreferences: model.NotificationPreferences{model.NotificationSecurity: false}},
+			expected: []Channel{ChannelEmail},
+		},
+		{
+			name:     "sms with verified phone",
+			category: model.NotificationAccount,
+			user:     &model.User{SMSNotifications: true, Phone: &phone, PhoneVerified: true},
+			expected: []Channel{ChannelEmail, ChannelSMS},
+		},
+		{
+			name:     "sms with unverified phone",
+			category: model.NotificationAccount,
+			user:     &model.User{SMSNotifications: true, Phone: &phone},
+			expected: []Channel{ChannelEmail},
+		},
+		{
+			name:     "verified phone without sms opt-in",
+			category: model.NotificationAccount,
+			user:     &model.User{Phone: &phone, PhoneVerified: true},
+			expected: []Channel{ChannelEmail},
+		},
+		{
+			name:     "webhook configured",
+			category: model.NotificationAccount,
+			user:     &model.User{},
+			webhook:  true,
+			expected: []Channel{ChannelEmail, ChannelWebhook},
+		},
+		{
+			name:     "security without the user",
+			category: model.NotificationSecurity,
+			webhook:  true,
+			expected: []Channel{ChannelEmail, ChannelWebhook},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhookURL := ""
+			if tt.webhook {
+				webhookURL = "https://hooks.example.com/usercenter"
+			}
+			notifier, _ := newTestNotifier(&fakeUserStore{}, webhookURL)
+
+			n := statusChanged()
+			n.Category = tt.category
+			assert.Equal(t, tt.expected, notifier.Channels(n, tt.user))
+		})
+	}
+}
+
+func TestNotifier_FansOut(t *testing.T) {
+	phone := "+15551234567"
+	users := &fakeUserStore{user: &model.User{ID: "user-1", SMSNotifications: true, Phone: &phone, PhoneVerified: true}}
+	notifier, queue := newTestNotifier(users, "https://hooks.example.com/usercenter")
+
+	require.NoError(t, notifier.Notify(context.Background(), statusChanged()))
+
+	assert.Equal(t, []task.EmailPayload{{
+		To:       "alice@example.com",
+		Template: task.TemplateStatusChanged,
+		Data:     map[string]string{"username": "alice"},
+	}}, queue.emails)
+	assert.Equal(t, []task.SMSPayload{{
+		To:       phone,
+		Template: task.TemplateStatusChanged,
+		Data:     map[string]string{"username": "alice"},
+	}}, queue.sms)
+
+	require.Len(t, queue.webhooks, 1)
+	assert.Equal(t, task.TemplateStatusChanged, queue.webhooks[0].Event)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(queue.webhooks[0].Body, &body))
+	assert.Equal(t, "user-1", body["user_id"])
+	assert.Equal(t, "account", body["category"])
+	assert.Equal(t, map[string]interface{}{"username": "alice"}, body["data"])
+}
+
+func TestNotifier_FailingChannelDoesNotStopOthers(t *testing.T) {
+	phone := "+15551234567"
+	users := &fakeUserStore{user: &model.User{ID: "user-1", SMSNotifications: true, Phone: &phone, PhoneVerified: true}}
+	notifier, queue := newTestNotifier(users, "")
+	queue.fail = map[Channel]error{ChannelEmail: errors.New("redis unavailable")}
+
+	err := notifier.Notify(context.Background(), statusChanged())
+	assert.ErrorContains(t, err, "email: redis unavailable")
+	assert.Empty(t, queue.emails)
+	assert.Len(t, queue.sms, 1)
+}
+
+func TestNotifier_UserUnavailable(t *testing.T) {
+	notifier, queue := newTestNotifier(&fakeUserStore{err: apperrors.ErrUserNotFound}, "")
+	ctx := context.Background()
+
+	// An opt-out cannot be ruled out, so optional notifications are not sent
+	err := notifier.Notify(ctx, statusChanged())
+	assert.ErrorIs(t, err, apperrors.ErrUserNotFound)
+	assert.Empty(t, queue.emails)
+
+	// Security notifications still reach the address of the event, e.g. after deletion
+	n := statusChanged()
+	n.Category = model.NotificationSecurity
+	n.Template = task.TemplateAccountDeleted
+	require.NoError(t, notifier.Notify(ctx, n))
+	require.Len(t, queue.emails, 1)
+	assert.Equal(t, task.TemplateAccountDeleted, queue.emails[0].Template)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAuditLogUnavailable is returned when MongoDB, which stores the audit
+// log, is not connected
+var ErrAuditLogUnavailable = errors.New("audit log store unavailable")
+
+// AuditLogFilter selects audit log entries. Empty fields and zero times do
+// not filter; From is inclusive and To is exclusive.
+type AuditLogFilter struct {
+	UserID   string
+	Action   string
+	Resource string
+	From     time.Time
+	To       time.Time
+	Offset   int
+	Limit    int
+}
+
+// AuditLogRepository defines audit log data access interface
+type AuditLogRepository interface {
+	List(ctx context.Context, filter AuditLogFilter) ([]*database.AuditLog, int64, error)
+}
+
+// auditLogCollection is the part of *mongo.Collection the repository uses
+type auditLogCollection interface {
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+}
+
+// auditLogRepository is the MongoDB implementation
+// of AuditLogRepository interface
+type auditLogRepository struct {
+	collection auditLogCollection
+}
+
+// NewAuditLogRepository creates a new audit log repository. With a nil
+// mongo, which is optional, List returns ErrAuditLogUnavailable.
+func NewAuditLogRepository(mongo *database.MongoDB) AuditLogRepository {
+	repo := &auditLogRepository{}
+	if mongo != nil {
+		repo.collection = mongo.Collection(database.AuditLogsCollection)
+	}
+	return repo
+}
+
+// List returns a page of the entries matching filter, newest first, and the
+// total number of matching entries
+func (r *auditLogRepository) List(ctx context.Context, filter AuditLogFilter) ([]*database.AuditLog, int64, error) {
+	if r.collection == nil {
+		return nil, 0, ErrAuditLogUnavailable
+	}
+
+	query := auditLogQuery(filter)
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(filter.Offset)).
+		SetLimit(int64(filter.Limit))
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]*database.AuditLog, 0, filter.Limit)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode audit logs: %w", err)
+	}
+	return entries, total, nil
+}
+
+// auditLogQuery translates filter into a MongoDB query served by the indexes
+// created by MongoDB.EnsureAuditLogIndexes
+func auditLogQuery(filter AuditLogFilter) bson.M {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.Resource != "" {
+		query["resource"] = filter.Resource
+	}
+
+	timestamp := bson.M{}
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timestamp["$lt"] = filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+	return query
+}
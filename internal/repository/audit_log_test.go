@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeAuditLogCollection records the queries it receives and returns docs
+type fakeAuditLogCollection struct {
+	docs        []interface{}
+	findFilter  interface{}
+	findOpts    *options.FindOptions
+	countFilter interface{}
+}
+
+func (f *fakeAuditLogCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	f.findFilter = filter
+	f.findOpts = options.MergeFindOptions(opts...)
+	return mongo.NewCursorFromDocuments(f.docs, nil, nil)
+}
+
+func (f *fakeAuditLogCollection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	f.countFilter = filter
+	return int64(len(f.docs)), nil
+}
+
+func TestAuditLogRepository_List(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		filter    AuditLogFilter
+		wantQuery bson.M
+	}{
+		{
+			name:      "no filters",
+			filter:    AuditLogFilter{Limit: 20},
+			wantQuery: bson.M{},
+		},
+		{
+			name: "all filters",
+			filter: AuditLogFilter{
+				UserID:   "user-1",
+				Action:   "DELETE /api/v1/admin/users/:id",
+				Resource: "/api/v1/admin/users/user-2",
+				From:     from,
+				To:       to,
+				Offset:   40,
+				Limit:    20,
+			},
+			wantQuery: bson.M{
+				"user_id":   "user-1",
+				"action":    "DELETE /api/v1/admin/users/:id",
+				"resource":  "/api/v1/admin/users/user-2",
+				"timestamp": bson.M{"$gte": from, "$lt": to},
+			},
+		},
+		{
+			name:      "open-ended time range",
+			filter:    AuditLogFilter{From: from, Limit: 20},
+			wantQuery: bson.M{"timestamp": bson.M{"$gte": from}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := &fakeAuditLogCollection{docs: []interface{}{
+				database.AuditLog{ID: "b", Action: "PUT /api/v1/users/me", Timestamp: to},
+				database.AuditLog{ID: "a", Action: "PUT /api/v1/users/me", Timestamp: from},
+			}}
+			repo := &auditLogRepository{collection: collection}
+
+			entries, total, err := repo.List(context.Background(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), total)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "b", entries[0].ID)
+
+			assert.Equal(t, tt.wantQuery, collection.findFilter)
+			assert.Equal(t, tt.wantQuery, collection.countFilter)
+			assert.Equal(t, bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}, collection.findOpts.Sort)
+			assert.Equal(t, int64(tt.filter.Offset), *collection.findOpts.Skip)
+			assert.Equal(t, int64(tt.filter.Limit), *collection.findOpts.Limit)
+		})
+	}
+}
+
+func TestAuditLogRepository_Unavailable(t *testing.T) {
+	repo := NewAuditLogRepository(nil)
+
+	_, _, err := repo.List(context.Background(), AuditLogFilter{Limit: 20})
+	assert.ErrorIs(t, err, ErrAuditLogUnavailable)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository defines outbox event data access interface
+type OutboxRepository interface {
+	Create(ctx context.Context, event *model.OutboxEvent) error
+	ListPending(ctx context.Context, limit, maxAttempts int) ([]*model.OutboxEvent, error)
+	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+	MarkFailed(ctx context.Context, id string, reason string) error
+}
+
+// outboxRepository is the concrete implementation
+// of OutboxRepository interface
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// Create stores a new outbox event, joining the transaction in ctx if any
+func (r *outboxRepository) Create(ctx context.Context, event *model.OutboxEvent) error {
+	if err := dbFromContext(ctx, r.db).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create outbox event: %w", err)
+	}
+	return nil
+}
+
+// ListPending retrieves unsent events in creation order. Inside a transaction
+// the rows are locked so concurrent relays skip them.
+func (r *outboxRepository) ListPending(ctx context.Context, limit, maxAttempts int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+
+	query := dbFromContext(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL")
+	if maxAttempts > 0 {
+		query = query.Where("attempts < ?", maxAttempts)
+	}
+
+	if err := query.Order("created_at ASC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list pending outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// MarkSent marks an event as published
+func (r *outboxRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	if err := dbFromContext(ctx, r.db).Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"sent_at":    sentAt,
+			"last_error": nil,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to mark outbox event as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed publish attempt
+func (r *outboxRepository) MarkFailed(ctx context.Context, id string, reason string) error {
+	if err := dbFromContext(ctx, r.db).Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": reason,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to mark outbox event as failed: %w", err)
+	}
+	return nil
+}
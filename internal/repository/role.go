@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleRepository defines role assignment data access interface
+type RoleRepository interface {
+	ListByUserID(ctx context.Context, userID string) ([]string, error)
+	Assign(ctx context.Context, userID, role string) error
+	Revoke(ctx context.Context, userID, role string) error
+}
+
+// roleRepository is the concrete implementation
+// of RoleRepository interface
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{
+		db: db,
+	}
+}
+
+// ListByUserID returns the names of the roles assigned to a user
+func (r *roleRepository) ListByUserID(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	if err := dbFromContext(ctx, r.db).Model(&model.UserRole{}).
+		Where("user_id = ?", userID).
+		Order("role ASC").
+		Pluck("role", &roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Assign gives a role to a user. Assigning a role the user already has does nothing.
+func (r *roleRepository) Assign(ctx context.Context, userID, role string) error {
+	if err := dbFromContext(ctx, r.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserRole{UserID: userID, Role: role}).Error; err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// Revoke takes a role away from a user. Revoking a role the user does not
+// have does nothing.
+func (r *roleRepository) Revoke(ctx context.Context, userID, role string) error {
+	if err := dbFromContext(ctx, r.db).
+		Where("user_id = ? AND role = ?", userID, role).
+		Delete(&model.UserRole{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	return nil
+}
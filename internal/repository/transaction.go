@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey is the context key for the active transaction
+type txKey struct{}
+
+// Transactor runs functions inside a database transaction.
+// Repositories called with the context passed to fn share the transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// gormTransactor is the GORM implementation of Transactor
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a new transactor
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{
+		db: db,
+	}
+}
+
+// WithinTransaction runs fn in a transaction, committing if it returns nil
+// and rolling back otherwise. Nested calls reuse the outer transaction.
+func (t *gormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// dbFromContext returns the transaction stored in ctx, or db, bound to ctx
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/zhwjimmy/user-center/internal/apperrors"
 	"github.com/zhwjimmy/user-center/internal/dto"
 	"github.com/zhwjimmy/user-center/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository defines user data access interface
@@ -19,10 +24,16 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByPhone(ctx context.Context, phone string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) (*model.User, error)
 	Delete(ctx context.Context, id string) error
+	GetDeletedByID(ctx context.Context, id string) (*model.User, error)
+	Restore(ctx context.Context, id string) error
+	HardDelete(ctx context.Context, id string) error
 	List(ctx context.Context, req *dto.UserListRequest) ([]*model.User, int64, error)
-	Search(ctx context.Context, term string, limit int) ([]*model.User, error)
+	Iterate(ctx context.Context, req *dto.UserListRequest, batchSize int, fn func(users []*model.User) error) error
+	Search(ctx context.Context, term string, withEmail bool, offset, limit int) ([]*model.User, int64, error)
+	SearchFullText(ctx context.Context, query string, withEmail bool, offset, limit int) ([]*model.User, int64, error)
 	GetByIDs(ctx context.Context, ids []string) ([]*model.User, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
@@ -32,6 +43,7 @@ type UserRepository interface {
 	GetUsersByStatus(ctx context.Context, status model.UserStatus) ([]*model.User, error)
 	CountUsers(ctx context.Context) (int64, error)
 	CountActiveUsers(ctx context.Context) (int64, error)
+	WithTransaction(ctx context.Context, fn func(txRepo UserRepository) error) error
 }
 
 // userRepository is the concrete implementation
@@ -49,7 +61,12 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 // Create creates a new user
 func (r *userRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Create(user).Error; err != nil {
+		// Concurrent creates can pass the service's checks; the unique
+		// constraints decide
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.ErrUserExists
+		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 	return user, nil
@@ -58,9 +75,9 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) (*model.U
 // GetByID retrieves a user by ID
 func (r *userRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+	if err := dbFromContext(ctx, r.db).First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
@@ -70,9 +87,9 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*model.User, e
 // GetByEmail retrieves a user by email
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+	if err := dbFromContext(ctx, r.db).Where("email = ?", model.NormalizeEmail(email)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -82,18 +99,33 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.U
 // GetByUsername retrieves a user by username
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user not found")
+	if err := dbFromContext(ctx, r.db).Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
 	return &user, nil
 }
 
+// GetByPhone retrieves a user by phone number
+func (r *userRepository) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
+	var user model.User
+	if err := dbFromContext(ctx, r.db).Where("phone = ?", model.NormalizePhone(phone)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by phone: %w", err)
+	}
+	return &user, nil
+}
+
 // Update updates a user
 func (r *userRepository) Update(ctx context.Context, user *model.User) (*model.User, error) {
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Save(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, apperrors.ErrUserExists
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 	return user, nil
@@ -101,20 +133,95 @@ func (r *userRepository) Update(ctx context.Context, user *model.User) (*model.U
 
 // Delete soft deletes a user
 func (r *userRepository) Delete(ctx context.Context, id string) error {
-	if err := r.db.WithContext(ctx).Delete(&model.User{}, "id = ?", id).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Delete(&model.User{}, "id = ?", id).Error; err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
 }
 
+// HardDelete permanently deletes a user, whether soft-deleted or not. Rows
+// referencing the user, such as their roles, go with it.
+func (r *userRepository) HardDelete(ctx context.Context, id string) error {
+	result := dbFromContext(ctx, r.db).Unscoped().Delete(&model.User{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to hard delete user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrUserNotFound
+	}
+	return nil
+}
+
+// GetDeletedByID retrieves a soft-deleted user by ID
+func (r *userRepository) GetDeletedByID(ctx context.Context, id string) (*model.User, error) {
+	var user model.User
+	if err := dbFromContext(ctx, r.db).Unscoped().Where("deleted_at IS NOT NULL").First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get deleted user by ID: %w", err)
+	}
+	return &user, nil
+}
+
+// Restore undoes the soft delete of a user
+func (r *userRepository) Restore(ctx context.Context, id string) error {
+	result := dbFromContext(ctx, r.db).Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to restore user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrUserNotFound
+	}
+	return nil
+}
+
 // List retrieves users with pagination and filters
 func (r *userRepository) List(ctx context.Context, req *dto.UserListRequest) ([]*model.User, int64, error) {
 	var users []*model.User
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&model.User{})
+	query := applyListFilters(dbFromContext(ctx, r.db).Model(&model.User{}), req)
 
-	// Apply filters
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	// Apply sorting
+	orderClause := fmt.Sprintf("%s %s", req.Sort, strings.ToUpper(req.Order))
+	query = query.Order(orderClause)
+
+	// Apply pagination
+	offset := (req.Page - 1) * req.Size
+	query = query.Offset(offset).Limit(req.Size)
+
+	// Execute query
+	if err := query.Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// Iterate calls fn with successive batches of the users matching the filters
+// of req, ordered by ID. Pagination and sorting in req are ignored.
+func (r *userRepository) Iterate(ctx context.Context, req *dto.UserListRequest, batchSize int, fn func(users []*model.User) error) error {
+	var batch []*model.User
+	query := applyListFilters(dbFromContext(ctx, r.db).Model(&model.User{}), req)
+	result := query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to iterate users: %w", result.Error)
+	}
+	return nil
+}
+
+// applyListFilters applies the search and active filters of a list request
+func applyListFilters(query *gorm.DB, req *dto.UserListRequest) *gorm.DB {
 	if req.Search != "" {
 		searchTerm := "%" + strings.ToLower(req.Search) + "%"
 		query = query.Where(
@@ -132,66 +239,137 @@ func (r *userRepository) List(ctx context.Context, req *dto.UserListRequest) ([]
 		query = query.Where("is_active = ?", *req.IsActive)
 	}
 
-	// Count total records
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to count users: %w", err)
-	}
+	return query
+}
 
-	// Apply sorting
-	orderClause := fmt.Sprintf("%s %s", req.Sort, strings.ToUpper(req.Order))
-	query = query.Order(orderClause)
+// Search returns a page of the users whose username or name, or email when
+// withEmail is set, contains term, newest first, and the total number of matches
+func (r *userRepository) Search(ctx context.Context, term string, withEmail bool, offset, limit int) ([]*model.User, int64, error) {
+	var users []*model.User
+	var total int64
+	searchTerm := "%" + strings.ToLower(term) + "%"
 
-	// Apply pagination
-	offset := (req.Page - 1) * req.Size
-	query = query.Offset(offset).Limit(req.Size)
+	query := dbFromContext(ctx, r.db).Model(&model.User{})
+	if withEmail {
+		query = query.Where(
+			"LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
+			searchTerm, searchTerm, searchTerm, searchTerm,
+		)
+	} else {
+		query = query.Where(
+			"LOWER(username) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
+			searchTerm, searchTerm, searchTerm,
+		)
+	}
 
-	// Execute query
-	if err := query.Find(&users).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	// The ID breaks ties so pages neither overlap nor skip users
+	if err := query.Order("created_at DESC, id").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
 
 	return users, total, nil
 }
 
-// Search searches users by term
-func (r *userRepository) Search(ctx context.Context, term string, limit int) ([]*model.User, error) {
+// Weights of the search_vector parts matched without emails: usernames (A)
+// and names (B), leaving out emails (C)
+const searchWeightsWithoutEmail = "AB"
+
+// minFullTextQueryLength is the shortest query SearchFullText looks up in the
+// search index; shorter ones match too many prefixes to be worth ranking
+const minFullTextQueryLength = 3
+
+// SearchFullText returns a page of the users matching the words of query and
+// the total number of matches, ranking usernames above names and names above
+// emails. Every word must match, either fully or as a prefix. Emails are only
+// matched when withEmail is set. Queries shorter than minFullTextQueryLength
+// fall back to Search.
+func (r *userRepository) SearchFullText(ctx context.Context, query string, withEmail bool, offset, limit int) ([]*model.User, int64, error) {
+	if utf8.RuneCountInString(strings.TrimSpace(query)) < minFullTextQueryLength {
+		return r.Search(ctx, query, withEmail, offset, limit)
+	}
+
+	weights := ""
+	if !withEmail {
+		weights = searchWeightsWithoutEmail
+	}
+	tsQuery := prefixTSQuery(query, weights)
+	if tsQuery == "" {
+		return r.Search(ctx, query, withEmail, offset, limit)
+	}
+
 	var users []*model.User
-	searchTerm := "%" + strings.ToLower(term) + "%"
+	var total int64
+	matches := dbFromContext(ctx, r.db).Model(&model.User{}).
+		Where("search_vector @@ to_tsquery('simple', ?)", tsQuery)
 
-	query := r.db.WithContext(ctx).Where(
-		"LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?",
-		searchTerm, searchTerm, searchTerm, searchTerm,
-	).Limit(limit)
+	if err := matches.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
 
-	if err := query.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("failed to search users: %w", err)
+	err := matches.
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(search_vector, to_tsquery('simple', ?)) DESC, created_at DESC, id",
+			Vars:               []interface{}{tsQuery},
+			WithoutParentheses: true,
+		}}).
+		Offset(offset).
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
 	}
 
-	return users, nil
+	return users, total, nil
+}
+
+// prefixTSQuery turns the words of query into a to_tsquery expression that
+// requires each of them as a prefix, e.g. "Jane Do" becomes "jane:* & do:*".
+// Non-empty weights restrict the words to the parts of those weights, e.g.
+// "jane:*AB". Characters with a meaning in tsquery syntax are dropped.
+func prefixTSQuery(query, weights string) string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@' || r == '.' || r == '_' || r == '-' {
+				return r
+			}
+			return -1
+		}, word)
+		if word != "" {
+			terms = append(terms, word+":*"+weights)
+		}
+	}
+	return strings.Join(terms, " & ")
 }
 
 // GetByIDs retrieves multiple users by IDs
 func (r *userRepository) GetByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
 	var users []*model.User
-	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Where("id IN ?", ids).Find(&users).Error; err != nil {
 		return nil, fmt.Errorf("failed to get users by IDs: %w", err)
 	}
 	return users, nil
 }
 
-// ExistsByEmail checks if a user exists by email
+// ExistsByEmail checks if a user exists by email. Soft-deleted users count,
+// as they keep their email until they are restored or purged.
 func (r *userRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Unscoped().Model(&model.User{}).Where("email = ?", model.NormalizeEmail(email)).Count(&count).Error; err != nil {
 		return false, fmt.Errorf("failed to check user existence by email: %w", err)
 	}
 	return count > 0, nil
 }
 
-// ExistsByUsername checks if a user exists by username
+// ExistsByUsername checks if a user exists by username. Soft-deleted users
+// count, as they keep their username until they are restored or purged.
 func (r *userRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Unscoped().Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
 		return false, fmt.Errorf("failed to check user existence by username: %w", err)
 	}
 	return count > 0, nil
@@ -208,7 +386,7 @@ func (r *userRepository) UpdateStatus(ctx context.Context, id string, status mod
 		isActive = false
 	}
 
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Model(&model.User{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
 		return fmt.Errorf("failed to update user status: %w", err)
 	}
 	return nil
@@ -216,7 +394,7 @@ func (r *userRepository) UpdateStatus(ctx context.Context, id string, status mod
 
 // UpdateActiveStatus updates user active status
 func (r *userRepository) UpdateActiveStatus(ctx context.Context, id string, isActive bool) error {
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Model(&model.User{}).Where("id = ?", id).Update("is_active", isActive).Error; err != nil {
 		return fmt.Errorf("failed to update user active status: %w", err)
 	}
 	return nil
@@ -225,7 +403,7 @@ func (r *userRepository) UpdateActiveStatus(ctx context.Context, id string, isAc
 // GetActiveUsers retrieves all active users
 func (r *userRepository) GetActiveUsers(ctx context.Context) ([]*model.User, error) {
 	var users []*model.User
-	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&users).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Where("is_active = ?", true).Find(&users).Error; err != nil {
 		return nil, fmt.Errorf("failed to get active users: %w", err)
 	}
 	return users, nil
@@ -243,7 +421,7 @@ func (r *userRepository) GetUsersByStatus(ctx context.Context, status model.User
 		isActive = false
 	}
 
-	if err := r.db.WithContext(ctx).Where("is_active = ?", isActive).Find(&users).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Where("is_active = ?", isActive).Find(&users).Error; err != nil {
 		return nil, fmt.Errorf("failed to get users by status: %w", err)
 	}
 	return users, nil
@@ -252,7 +430,7 @@ func (r *userRepository) GetUsersByStatus(ctx context.Context, status model.User
 // CountUsers returns the total number of users
 func (r *userRepository) CountUsers(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Count(&count).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Model(&model.User{}).Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
 	return count, nil
@@ -261,8 +439,17 @@ func (r *userRepository) CountUsers(ctx context.Context) (int64, error) {
 // CountActiveUsers returns the number of active users
 func (r *userRepository) CountActiveUsers(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
+	if err := dbFromContext(ctx, r.db).Model(&model.User{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count active users: %w", err)
 	}
 	return count, nil
 }
+
+// WithTransaction runs fn with a repository bound to a transaction, committing
+// if fn returns nil and rolling back otherwise. Inside a Transactor transaction
+// it runs as a savepoint of the outer transaction.
+func (r *userRepository) WithTransaction(ctx context.Context, fn func(txRepo UserRepository) error) error {
+	return dbFromContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return fn(&userRepository{db: tx})
+	})
+}
@@ -0,0 +1,386 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+)
+
+func newTestUser() *model.User {
+	return &model.User{
+		Username:     testutils.RandomUsername(),
+		Email:        testutils.RandomEmail(),
+		PasswordHash: "hashed-password",
+		IsActive:     true,
+	}
+}
+
+func TestUserRepository_CreateAndGet(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, newTestUser())
+	require.NoError(t, err)
+	require.NotEmpty(t, created.ID)
+
+	byID, err := repo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.Username, byID.Username)
+
+	byEmail, err := repo.GetByEmail(ctx, created.Email)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byEmail.ID)
+
+	exists, err := repo.ExistsByUsername(ctx, created.Username)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestUserRepository_EmailIsCaseInsensitive(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	user := newTestUser()
+	user.Email = "  Mixed.Case@Example.COM "
+	created, err := repo.Create(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, "mixed.case@example.com", created.Email)
+
+	byEmail, err := repo.GetByEmail(ctx, "MIXED.case@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byEmail.ID)
+
+	exists, err := repo.ExistsByEmail(ctx, "Mixed.Case@example.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// The LOWER(email) unique index rejects rows written around the application
+	err = testDB.DB.Exec(
+		"INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)",
+		testutils.RandomUsername(), "MIXED.CASE@EXAMPLE.COM", "hashed-password",
+	).Error
+	assert.Error(t, err)
+}
+
+func TestUserRepository_GetByPhone(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	phone := "+86 138-0000-0000"
+	user := newTestUser()
+	user.Phone = &phone
+	created, err := repo.Create(ctx, user)
+	require.NoError(t, err)
+	require.NotNil(t, created.Phone)
+	assert.Equal(t, "+8613800000000", *created.Phone)
+
+	byPhone, err := repo.GetByPhone(ctx, "+86 13800000000")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byPhone.ID)
+
+	_, err = repo.GetByPhone(ctx, "+86 139-0000-0000")
+	assert.ErrorIs(t, err, apperrors.ErrUserNotFound)
+}
+
+func TestUserRepository_DeleteIsSoft(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, newTestUser())
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, created.ID))
+
+	_, err = repo.GetByID(ctx, created.ID)
+	assert.ErrorIs(t, err, apperrors.ErrUserNotFound)
+
+	var count int64
+	require.NoError(t, testDB.DB.Unscoped().Model(&model.User{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestUserRepository_SoftDeletedUserKeepsEmailAndUsername(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, newTestUser())
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, created.ID))
+
+	// The unique indexes still hold the values, so they are not available
+	exists, err := repo.ExistsByEmail(ctx, created.Email)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.ExistsByUsername(ctx, created.Username)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, repo.HardDelete(ctx, created.ID))
+	exists, err = repo.ExistsByEmail(ctx, created.Email)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = repo.ExistsByUsername(ctx, created.Username)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUserRepository_SoftDeleteIsRecoverableHardDeleteIsNot(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, newTestUser())
+	require.NoError(t, err)
+
+	// A soft-deleted user can be restored
+	require.NoError(t, repo.Delete(ctx, created.ID))
+	require.NoError(t, repo.Restore(ctx, created.ID))
+	_, err = repo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+
+	// A hard-deleted user is gone, even if soft-deleted first
+	require.NoError(t, repo.Delete(ctx, created.ID))
+	require.NoError(t, repo.HardDelete(ctx, created.ID))
+
+	_, err = repo.GetDeletedByID(ctx, created.ID)
+	assert.ErrorIs(t, err, apperrors.ErrUserNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, created.ID), apperrors.ErrUserNotFound)
+	assert.ErrorIs(t, repo.HardDelete(ctx, created.ID), apperrors.ErrUserNotFound)
+
+	var count int64
+	require.NoError(t, testDB.DB.Unscoped().Model(&model.User{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestUserRepository_List(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		user := newTestUser()
+		user.Username = user.Username + string(rune('a'+i))
+		user.Email = string(rune('a'+i)) + user.Email
+		_, err := repo.Create(ctx, user)
+		require.NoError(t, err)
+	}
+
+	users, total, err := repo.List(ctx, &dto.UserListRequest{Page: 1, Size: 2, Sort: "created_at", Order: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, users, 2)
+}
+
+func TestTransactor_Rollback(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	transactor := NewTransactor(testDB.DB)
+	ctx := context.Background()
+
+	user := newTestUser()
+	err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := repo.Create(txCtx, user); err != nil {
+			return err
+		}
+		return assert.AnError
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	exists, err := repo.ExistsByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUserRepository_WithTransaction_Rollback(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	user := newTestUser()
+	err := repo.WithTransaction(ctx, func(txRepo UserRepository) error {
+		if _, err := txRepo.Create(ctx, user); err != nil {
+			return err
+		}
+
+		// The insert is visible inside the transaction
+		exists, err := txRepo.ExistsByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		return assert.AnError
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	exists, err := repo.ExistsByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUserRepository_WithTransaction_NestedInTransactor(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	transactor := NewTransactor(testDB.DB)
+	ctx := context.Background()
+
+	user := newTestUser()
+	err := transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if err := repo.WithTransaction(txCtx, func(txRepo UserRepository) error {
+			_, err := txRepo.Create(txCtx, user)
+			return err
+		}); err != nil {
+			return err
+		}
+		// A later step of the outer transaction fails
+		return assert.AnError
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	exists, err := repo.ExistsByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUserRepository_Create_DuplicateEmail(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	first := newTestUser()
+	_, err := repo.Create(ctx, first)
+	require.NoError(t, err)
+
+	second := newTestUser()
+	second.Email = first.Email
+	_, err = repo.Create(ctx, second)
+	assert.ErrorIs(t, err, apperrors.ErrUserExists)
+}
+
+func TestUserRepository_SearchFullText(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := NewUserRepository(testDB.DB)
+	ctx := context.Background()
+
+	create := func(username, firstName, lastName, email string) *model.User {
+		user := newTestUser()
+		user.Username = username
+		user.FirstName = &firstName
+		user.LastName = &lastName
+		user.Email = email
+		created, err := repo.Create(ctx, user)
+		require.NoError(t, err)
+		return created
+	}
+	byEmail := create("mia_w", "Jordan", "Walker", "mia@example.com")
+	byName := create("jw_1984", "Jordan", "Smith", "js@example.com")
+	byUsername := create("jordan", "Chris", "Miller", "chris@example.com")
+	create("taylor", "Sam", "Taylor", "sam@example.com")
+
+	ids := func(users []*model.User) []string {
+		result := make([]string, 0, len(users))
+		for _, user := range users {
+			result = append(result, user.ID)
+		}
+		return result
+	}
+
+	t.Run("ranks usernames above names", func(t *testing.T) {
+		users, _, err := repo.SearchFullText(ctx, "jordan", true, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, byUsername.ID, users[0].ID)
+		assert.ElementsMatch(t, []string{byEmail.ID, byName.ID}, ids(users[1:]))
+	})
+
+	t.Run("multi-word queries match every word", func(t *testing.T) {
+		users, _, err := repo.SearchFullText(ctx, "Jordan Smi", true, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{byName.ID}, ids(users))
+	})
+
+	t.Run("matches email addresses", func(t *testing.T) {
+		users, _, err := repo.SearchFullText(ctx, "chris@example.com", true, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{byUsername.ID}, ids(users))
+	})
+
+	t.Run("leaves out emails unless asked to", func(t *testing.T) {
+		users, _, err := repo.SearchFullText(ctx, "chris@example.com", false, 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		// The substring fallback too: "ex" is only in emails
+		users, _, err = repo.SearchFullText(ctx, "ex", false, 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		users, _, err = repo.SearchFullText(ctx, "jordan", false, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, users, 3)
+	})
+
+	t.Run("short queries fall back to substring search", func(t *testing.T) {
+		users, _, err := repo.SearchFullText(ctx, "lk", true, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{byEmail.ID}, ids(users))
+	})
+
+	t.Run("respects the limit", func(t *testing.T) {
+		users, total, err := repo.SearchFullText(ctx, "jordan", true, 0, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{byUsername.ID}, ids(users))
+		assert.Equal(t, int64(3), total)
+	})
+
+	t.Run("pages are stable", func(t *testing.T) {
+		for _, query := range []string{"jordan", "am"} {
+			all, total, err := repo.SearchFullText(ctx, query, true, 0, 10)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(all)), total)
+
+			var paged []*model.User
+			for offset := 0; offset < int(total); offset++ {
+				page, pageTotal, err := repo.SearchFullText(ctx, query, true, offset, 1)
+				require.NoError(t, err)
+				assert.Equal(t, total, pageTotal)
+				paged = append(paged, page...)
+			}
+			assert.Equal(t, ids(all), ids(paged), query)
+		}
+	})
+}
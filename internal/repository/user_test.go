@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		weights string
+		want    string
+	}{
+		{query: "alice", want: "alice:*"},
+		{query: "Jane Do", weights: "AB", want: "jane:*AB & do:*AB"},
+		{query: "  Jane   Do ", want: "jane:* & do:*"},
+		{query: "alice@example.com", want: "alice@example.com:*"},
+		{query: "o'brien & (smith | !jones):*", want: "obrien:* & smith:* & jones:*"},
+		{query: "& | !", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, prefixTSQuery(tt.query, tt.weights))
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"gorm.io/gorm"
+)
+
+// WebhookRepository defines webhook subscription data access interface
+type WebhookRepository interface {
+	Create(ctx context.Context, sub *model.WebhookSubscription) error
+	GetByID(ctx context.Context, id string) (*model.WebhookSubscription, error)
+	List(ctx context.Context) ([]*model.WebhookSubscription, error)
+	ListActiveForEvent(ctx context.Context, eventType string) ([]*model.WebhookSubscription, error)
+	Update(ctx context.Context, sub *model.WebhookSubscription) error
+	Delete(ctx context.Context, id string) error
+	RecordDeliveryFailure(ctx context.Context, id string, disableAfter int) (bool, error)
+	ResetDeliveryFailures(ctx context.Context, id string) error
+}
+
+// webhookRepository is the concrete implementation
+// of WebhookRepository interface
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook subscription repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+// Create creates a new webhook subscription
+func (r *webhookRepository) Create(ctx context.Context, sub *model.WebhookSubscription) error {
+	if err := dbFromContext(ctx, r.db).Create(sub).Error; err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a webhook subscription by ID
+func (r *webhookRepository) GetByID(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	if err := dbFromContext(ctx, r.db).First(&sub, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// List returns all webhook subscriptions, oldest first
+func (r *webhookRepository) List(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	var subs []*model.WebhookSubscription
+	if err := dbFromContext(ctx, r.db).Order("created_at ASC, id").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// ListActiveForEvent returns the active subscriptions receiving events of eventType
+func (r *webhookRepository) ListActiveForEvent(ctx context.Context, eventType string) ([]*model.WebhookSubscription, error) {
+	listed, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*model.WebhookSubscription
+	if err := dbFromContext(ctx, r.db).
+		Where("active = ?", true).
+		Where("event_types = '[]'::jsonb OR event_types @> ?::jsonb", string(listed)).
+		Order("created_at ASC, id").
+		Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// Update saves every field of a webhook subscription
+func (r *webhookRepository) Update(ctx context.Context, sub *model.WebhookSubscription) error {
+	result := dbFromContext(ctx, r.db).Save(sub)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", result.Error)
+	}
+	return nil
+}
+
+// Delete deletes a webhook subscription
+func (r *webhookRepository) Delete(ctx context.Context, id string) error {
+	result := dbFromContext(ctx, r.db).Delete(&model.WebhookSubscription{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RecordDeliveryFailure counts a failed delivery and disables the
+// subscription once disableAfter deliveries in a row have failed; zero never
+// disables it. It reports whether this failure disabled the subscription.
+func (r *webhookRepository) RecordDeliveryFailure(ctx context.Context, id string, disableAfter int) (bool, error) {
+	var result struct {
+		Active              bool
+		ConsecutiveFailures int
+	}
+	err := dbFromContext(ctx, r.db).Raw(`
+		UPDATE webhook_subscriptions SET
+			consecutive_failures = consecutive_failures + 1,
+			active = active AND (@max <= 0 OR consecutive_failures + 1 < @max),
+			disabled_at = CASE WHEN active AND @max > 0 AND consecutive_failures + 1 >= @max THEN NOW() ELSE disabled_at END,
+			updated_at = NOW()
+		WHERE id = @id
+		RETURNING active, consecutive_failures`,
+		map[string]interface{}{"id": id, "max": disableAfter},
+	).Scan(&result).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook delivery failure: %w", err)
+	}
+	return !result.Active && disableAfter > 0 && result.ConsecutiveFailures == disableAfter, nil
+}
+
+// ResetDeliveryFailures clears the failure count after a successful delivery
+func (r *webhookRepository) ResetDeliveryFailures(ctx context.Context, id string) error {
+	if err := dbFromContext(ctx, r.db).Model(&model.WebhookSubscription{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Update("consecutive_failures", 0).Error; err != nil {
+		return fmt.Errorf("failed to reset webhook delivery failures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+// RequestIDKey is the gin context key holding the current request ID
+const RequestIDKey = "request_id"
+
+// AuditDetailsKey is the gin context key of a map[string]interface{} whose
+// entries a handler adds to the audit log entry of its request
+const AuditDetailsKey = "audit_details"
+
+// EmailNotVerifiedCode is the error code of requests refused because the
+// user has not verified their email
+const EmailNotVerifiedCode = "EMAIL_NOT_VERIFIED"
+
+// StatusClientClosedRequest is the non-standard status, borrowed from nginx,
+// recorded for requests the client canceled before the response was sent
+const StatusClientClosedRequest = 499
+
+// Error writes an error response, tagging it with the current request ID so
+// clients can quote it and operators can find the matching logs
+func Error(c *gin.Context, status int, resp dto.ErrorResponse) {
+	if resp.RequestID == "" {
+		resp.RequestID = c.GetString(RequestIDKey)
+	}
+	c.JSON(status, resp)
+}
+
+// PayloadTooLarge writes a 413 response for a request body over limit bytes
+func PayloadTooLarge(c *gin.Context, limit int64) {
+	Error(c, http.StatusRequestEntityTooLarge, dto.ErrorResponse{
+		Error:   "Payload Too Large",
+		Message: fmt.Sprintf("Request body must not exceed %d bytes", limit),
+		Code:    "PAYLOAD_TOO_LARGE",
+	})
+}
+
+// BulkStatus maps a bulk operation summary to an HTTP status: 200 when every
+// item succeeded, 400 when every item failed and 207 for a mix
+func BulkStatus(summary dto.BulkSummary) int {
+	switch {
+	case summary.Failed == 0:
+		return http.StatusOK
+	case summary.Succeeded == 0:
+		return http.StatusBadRequest
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// Bulk writes a bulk operation report with a status reflecting its outcome
+func Bulk(c *gin.Context, result *dto.BulkResult) {
+	c.JSON(BulkStatus(result.Summary), result)
+}
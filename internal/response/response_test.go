@@ -0,0 +1,81 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+func TestBulk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		build        func(*dto.BulkResult)
+		expectedCode int
+	}{
+		{
+			name: "all succeeded",
+			build: func(r *dto.BulkResult) {
+				r.AddSuccess(0, "user-1", http.StatusCreated)
+				r.AddSuccess(1, "user-2", http.StatusCreated)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "partial success",
+			build: func(r *dto.BulkResult) {
+				r.AddSuccess(0, "user-1", http.StatusCreated)
+				r.AddFailure(1, http.StatusConflict, "email already exists")
+			},
+			expectedCode: http.StatusMultiStatus,
+		},
+		{
+			name: "all failed",
+			build: func(r *dto.BulkResult) {
+				r.AddFailure(0, http.StatusBadRequest, "invalid email")
+				r.AddFailure(1, http.StatusConflict, "email already exists")
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &dto.BulkResult{}
+			tt.build(result)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			Bulk(c, result)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			var body dto.BulkResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, result.Summary, body.Summary)
+			assert.Len(t, body.Items, 2)
+		})
+	}
+}
+
+func TestError_RequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(RequestIDKey, "req-1")
+
+	Error(c, http.StatusNotFound, dto.ErrorResponse{Error: "Not Found", Message: "User not found"})
+
+	var body dto.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "req-1", body.RequestID)
+}
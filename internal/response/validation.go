@@ -0,0 +1,122 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+// ValidationErrorCode is the error code of request validation failures
+const ValidationErrorCode = "VALIDATION_FAILED"
+
+func init() {
+	// Report fields by their JSON (or query) name rather than the Go field name.
+	// Must run before the first request is validated, as validator caches struct info.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName returns the name a client uses for a struct field
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// ValidationError writes a 400 response for a request binding error. Validator
+// failures are reported per field; other errors such as malformed JSON get a
+// generic message instead of the decoder's internals. A body cut off by the
+// body size limit gets a 413 instead.
+func ValidationError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		PayloadTooLarge(c, tooLarge.Limit)
+		return
+	}
+
+	resp := dto.ValidationErrorResponse{
+		Error:     "Bad Request",
+		Message:   "Invalid request body",
+		Code:      ValidationErrorCode,
+		RequestID: c.GetString(RequestIDKey),
+	}
+
+	if fields := FieldErrors(err); len(fields) > 0 {
+		resp.Message = "Request validation failed"
+		resp.Fields = fields
+	}
+
+	c.JSON(http.StatusBadRequest, resp)
+}
+
+// fieldErrorer is implemented by errors that carry their own field-level details
+type fieldErrorer interface {
+	FieldErrors() []dto.FieldError
+}
+
+// FieldErrors converts validator errors, or errors implementing
+// FieldErrors() []dto.FieldError, into field-level errors; it returns nil for
+// any other error
+func FieldErrors(err error) []dto.FieldError {
+	var fe fieldErrorer
+	if errors.As(err, &fe) {
+		return fe.FieldErrors()
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	fields := make([]dto.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, dto.FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+	return fields
+}
+
+// fieldErrorMessage describes a failed validation rule in plain words
+func fieldErrorMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "min":
+		if isString {
+			return fmt.Sprintf("%s must be at least %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("%s must be at most %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
@@ -0,0 +1,68 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+func TestValidationError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required,min=8"`
+		Order    string `json:"order" binding:"omitempty,oneof=asc desc"`
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedFields []dto.FieldError
+	}{
+		{
+			name: "field errors",
+			body: `{"email":"not-an-email","password":"short","order":"up"}`,
+			expectedFields: []dto.FieldError{
+				{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+				{Field: "password", Rule: "min", Message: "password must be at least 8 characters long"},
+				{Field: "order", Rule: "oneof", Message: "order must be one of: asc, desc"},
+			},
+		},
+		{
+			name: "malformed body",
+			body: `{"email":`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/", func(c *gin.Context) {
+				var req request
+				if err := c.ShouldBindJSON(&req); err != nil {
+					ValidationError(c, err)
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp dto.ValidationErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, ValidationErrorCode, resp.Code)
+			assert.Equal(t, tt.expectedFields, resp.Fields)
+			assert.NotContains(t, resp.Message, "Key:")
+		})
+	}
+}
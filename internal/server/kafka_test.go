@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/kafka"
+	kafkaConfig "github.com/zhwjimmy/user-center/internal/kafka/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/notification"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// recordingNotifier drops notifications
+type recordingNotifier struct{}
+
+func (recordingNotifier) Notify(ctx context.Context, n *notification.Notification) error {
+	return nil
+}
+
+// recordingDispatcher records the event types handed to webhooks
+type recordingDispatcher struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (d *recordingDispatcher) DispatchEvent(ctx context.Context, eventType string, body []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, eventType)
+	return nil
+}
+
+func (d *recordingDispatcher) Events() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.events...)
+}
+
+// newMockKafka starts a mock broker serving one user event on topic
+func newMockKafka(t *testing.T, topic, group string, published event.UserRegisteredEvent) *sarama.MockBroker {
+	body, err := json.Marshal(published)
+	require.NoError(t, err)
+
+	fetch := &sarama.FetchResponse{Version: 11}
+	fetch.AddRecord(topic, 0, nil, sarama.ByteEncoder(body), 0)
+	records := fetch.GetBlock(topic, 0).RecordsSet[0].RecordBatch.Records
+	records[0].Headers = []*sarama.RecordHeader{
+		{Key: []byte("event_type"), Value: []byte(event.UserRegistered)},
+		{Key: []byte(event.HeaderContentType), Value: []byte(event.ContentTypeJSON)},
+	}
+	fetch.SetLastOffsetDelta(topic, 0, 0)
+	fetch.GetBlock(topic, 0).HighWaterMarkOffset = 1
+
+	broker := sarama.NewMockBroker(t, 0)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+		"ApiVersionsRequest":     sarama.NewMockApiVersionsResponse(t),
+		"InitProducerIDRequest":  sarama.NewMockInitProducerIDResponse(t),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).SetCoordinator(sarama.CoordinatorGroup, group, broker),
+		"JoinGroupRequest": sarama.NewMockJoinGroupResponse(t).
+			SetGroupProtocol(sarama.RoundRobinBalanceStrategyName).
+			SetMemberId("member").
+			SetLeaderId("leader"),
+		"SyncGroupRequest": sarama.NewMockSyncGroupResponse(t).SetMemberAssignment(&sarama.ConsumerGroupMemberAssignment{
+			Topics: map[string][]int32{topic: {0}},
+		}),
+		"HeartbeatRequest":    sarama.NewMockHeartbeatResponse(t),
+		"LeaveGroupRequest":   sarama.NewMockLeaveGroupResponse(t),
+		"OffsetFetchRequest":  sarama.NewMockOffsetFetchResponse(t).SetOffset(group, topic, 0, 0, "", sarama.ErrNoError).SetError(sarama.ErrNoError),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(topic, 0, sarama.OffsetOldest, 0).
+			SetOffset(topic, 0, sarama.OffsetNewest, 1),
+		"FetchRequest": sarama.NewMockWrapper(fetch),
+	})
+	return broker
+}
+
+func TestServer_StartConsumesUserEvents(t *testing.T) {
+	const (
+		topic = "user.events"
+		group = "usercenter"
+	)
+	published := event.UserRegisteredEvent{
+		BaseEvent: event.NewBaseEvent(event.UserRegistered, "test", "", "user-1"),
+		Username:  "alice",
+		Email:     "alice@example.com",
+	}
+	broker := newMockKafka(t, topic, group, published)
+
+	logger := zap.NewNop()
+	webhooks := &recordingDispatcher{}
+	kafkaService, err := kafka.NewKafkaService(&kafkaConfig.KafkaClientConfig{
+		Brokers:      []string{broker.Addr()},
+		Topics:       map[string]string{"user_events": topic},
+		GroupID:      group,
+		RetryMax:     1,
+		RetryBackoff: 10 * time.Millisecond,
+		DrainTimeout: time.Second,
+	}, recordingNotifier{}, webhooks, nil, nil, nil, logger)
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	taskClient := task.NewClientFromRedis(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), logger)
+	taskServer, err := task.NewServer(cfg, taskClient, nil, nil, nil, nil, logger)
+	require.NoError(t, err)
+
+	s := &Server{
+		httpServer:   &http.Server{},
+		logger:       logger,
+		kafkaService: kafkaService,
+		outboxRelay:  service.NewOutboxRelay(nil, nil, kafkaService, cfg, logger),
+		refresher:    metrics.NewRefresher(nil, cfg, logger),
+		taskServer:   taskServer,
+		reloader:     NewConfigReloader(cfg, zap.NewAtomicLevel(), nil, nil, logger),
+		tracer:       &tracing.Provider{},
+	}
+
+	require.NoError(t, s.startWorkers(context.Background()))
+
+	// The published event reaches the user event handler, which hands it to webhooks
+	require.Eventually(t, func() bool {
+		return len(webhooks.Events()) > 0
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, string(event.UserRegistered), webhooks.Events()[0])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+}
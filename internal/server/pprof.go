@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// registerProfiling mounts the net/http/pprof handlers under /debug/pprof
+// when profiling is enabled. guards run before every profiling handler.
+func registerProfiling(r gin.IRouter, cfg config.ProfilingConfig, guards ...gin.HandlerFunc) {
+	if !cfg.Enabled {
+		return
+	}
+
+	debug := r.Group("/debug/pprof", guards...)
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+	// Named profiles such as heap, goroutine and allocs
+	debug.GET("/:profile", func(c *gin.Context) {
+		pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+	})
+}
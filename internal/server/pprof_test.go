@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func TestRegisterProfiling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	deny := func(c *gin.Context) { c.AbortWithStatus(http.StatusForbidden) }
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		guards   []gin.HandlerFunc
+		path     string
+		wantCode int
+	}{
+		{name: "disabled index", path: "/debug/pprof/", wantCode: http.StatusNotFound},
+		{name: "disabled profile", path: "/debug/pprof/heap", wantCode: http.StatusNotFound},
+		{name: "enabled index", enabled: true, path: "/debug/pprof/", wantCode: http.StatusOK},
+		{name: "enabled named profile", enabled: true, path: "/debug/pprof/goroutine?debug=1", wantCode: http.StatusOK},
+		{name: "enabled cmdline", enabled: true, path: "/debug/pprof/cmdline", wantCode: http.StatusOK},
+		{name: "guarded", enabled: true, guards: []gin.HandlerFunc{deny}, path: "/debug/pprof/heap", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			registerProfiling(r, config.ProfilingConfig{Enabled: tt.enabled}, tt.guards...)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.wantCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/middleware"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConfigReloader applies a safe subset of the configuration when the
+// configuration file changes: the log level, the default rate limit and CORS.
+// Changes to other settings are logged and take effect on restart.
+type ConfigReloader struct {
+	mu        sync.Mutex
+	current   *config.Config
+	level     zap.AtomicLevel
+	rateLimit *middleware.RateLimitMiddleware
+	cors      *middleware.ReloadableCORS
+	logger    *zap.Logger
+}
+
+// NewConfigReloader creates a new config reloader starting from cfg
+func NewConfigReloader(
+	cfg *config.Config,
+	level zap.AtomicLevel,
+	rateLimit *middleware.RateLimitMiddleware,
+	cors *middleware.ReloadableCORS,
+	logger *zap.Logger,
+) *ConfigReloader {
+	current := *cfg
+	return &ConfigReloader{
+		current:   &current,
+		level:     level,
+		rateLimit: rateLimit,
+		cors:      cors,
+		logger:    logger,
+	}
+}
+
+// Start watches the configuration file and applies changes to it
+func (r *ConfigReloader) Start() {
+	if !config.Watch(r.Apply) {
+		r.logger.Info("No configuration file to watch, config reload disabled")
+	}
+}
+
+// Apply applies the reloadable settings of updated, or logs err when the
+// changed file could not be loaded
+func (r *ConfigReloader) Apply(updated *config.Config, err error) {
+	if err != nil {
+		r.logger.Error("Failed to reload config, keeping the current settings", zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ignored := config.NonReloadableChanges(r.current, updated); len(ignored) > 0 {
+		r.logger.Warn("Config changes that need a restart were ignored",
+			zap.Strings("sections", ignored),
+		)
+	}
+
+	if updated.Logging.Level != r.current.Logging.Level {
+		level, err := zapcore.ParseLevel(updated.Logging.Level)
+		if err != nil {
+			r.logger.Warn("Invalid log level in reloaded config, keeping the current level",
+				zap.String("level", updated.Logging.Level),
+			)
+		} else {
+			r.level.SetLevel(level)
+			r.current.Logging.Level = updated.Logging.Level
+			r.logger.Info("Log level reloaded", zap.String("level", level.String()))
+		}
+	}
+
+	limits := updated.RateLimit
+	if limits.Enabled != r.current.RateLimit.Enabled || limits.Rate != r.current.RateLimit.Rate || limits.Burst != r.current.RateLimit.Burst {
+		r.rateLimit.UpdateLimits(limits.Enabled, limits.Rate, limits.Burst)
+		r.current.RateLimit.Enabled, r.current.RateLimit.Rate, r.current.RateLimit.Burst = limits.Enabled, limits.Rate, limits.Burst
+		r.logger.Info("Rate limit reloaded",
+			zap.Bool("enabled", limits.Enabled),
+			zap.Int("rate", limits.Rate),
+			zap.Int("burst", limits.Burst),
+		)
+	}
+
+	if !reflect.DeepEqual(updated.CORS, r.current.CORS) {
+		r.cors.Update(updated.CORS)
+		r.current.CORS = updated.CORS
+		r.logger.Info("CORS config reloaded")
+	}
+}
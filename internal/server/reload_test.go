@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/middleware"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestConfigReloader_Apply(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{Port: 8080},
+		Logging:   config.LoggingConfig{Level: "info"},
+		RateLimit: config.RateLimitConfig{Enabled: true, Rate: 1, Burst: 1},
+		CORS:      config.CORSConfig{AllowOrigins: []string{"https://app.example.com"}, AllowMethods: []string{"GET"}},
+	}
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core, logs := observer.New(level)
+	logger := zap.New(core)
+
+	redis, _ := testutils.NewMiniRedis(t)
+	rateLimit := middleware.NewRateLimitMiddleware(redis, cfg, zap.NewNop())
+	cors := middleware.NewReloadableCORS(cfg)
+	reloader := NewConfigReloader(cfg, level, rateLimit, cors, logger)
+
+	r := gin.New()
+	r.Use(cors.Handle, rateLimit.RateLimit())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("https://new.example.com").Code)
+	assert.Equal(t, http.StatusOK, get("").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("").Code)
+	assert.False(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	updated := *cfg
+	updated.Server.Port = 9090
+	updated.Logging.Level = "debug"
+	updated.RateLimit = config.RateLimitConfig{Enabled: false}
+	updated.CORS.AllowOrigins = []string{"https://new.example.com"}
+	reloader.Apply(&updated, nil)
+
+	// The level change applies to loggers built before the reload
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	assert.Equal(t, http.StatusOK, get("").Code)
+	w := get("https://new.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://new.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	ignored := logs.FilterMessage("Config changes that need a restart were ignored").All()
+	if assert.Len(t, ignored, 1) {
+		assert.Equal(t, []interface{}{"server"}, ignored[0].ContextMap()["sections"])
+	}
+
+	// An invalid level keeps the current one
+	updated.Logging.Level = "loud"
+	reloader.Apply(&updated, nil)
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -13,16 +15,27 @@ import (
 	"github.com/zhwjimmy/user-center/internal/config"
 	"github.com/zhwjimmy/user-center/internal/handler"
 	"github.com/zhwjimmy/user-center/internal/kafka"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"github.com/zhwjimmy/user-center/internal/middleware"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/tracing"
 	"go.uber.org/zap"
 )
 
 // Server represents the HTTP server
 type Server struct {
 	*gin.Engine
+	httpServer   *http.Server
 	config       *config.Config
 	logger       *zap.Logger
 	kafkaService kafka.Service
+	outboxRelay  *service.OutboxRelay
+	refresher    *metrics.Refresher
+	taskServer   *task.Server
+	tracer       *tracing.Provider
+	reloader     *ConfigReloader
 }
 
 // New creates a new server instance
@@ -30,6 +43,12 @@ func New(
 	cfg *config.Config,
 	logger *zap.Logger,
 	userHandler *handler.UserHandler,
+	roleHandler *handler.RoleHandler,
+	sessionHandler *handler.SessionHandler,
+	phoneVerificationHandler *handler.PhoneVerificationHandler,
+	auditLogHandler *handler.AuditLogHandler,
+	dataExportHandler *handler.DataExportHandler,
+	webhookHandler *handler.WebhookHandler,
 	healthHandler *handler.HealthHandler,
 	authMiddleware *middleware.AuthMiddleware,
 	corsMiddleware middleware.CORSMiddleware,
@@ -37,7 +56,21 @@ func New(
 	requestIDMiddleware middleware.RequestIDMiddleware,
 	loggerMiddleware middleware.LoggerMiddleware,
 	recoveryMiddleware middleware.RecoveryMiddleware,
+	jsonNamingMiddleware middleware.JSONNamingMiddleware,
+	urlLengthMiddleware middleware.URLLengthMiddleware,
+	cacheControlMiddleware middleware.CacheControlMiddleware,
+	tracingMiddleware middleware.TracingMiddleware,
+	auditMiddleware middleware.AuditMiddleware,
+	compressionMiddleware middleware.CompressionMiddleware,
+	timeoutMiddleware middleware.TimeoutMiddleware,
+	idempotencyMiddleware middleware.IdempotencyMiddleware,
+	readYourWritesMiddleware middleware.ReadYourWritesMiddleware,
 	kafkaService kafka.Service,
+	outboxRelay *service.OutboxRelay,
+	metricsRefresher *metrics.Refresher,
+	taskServer *task.Server,
+	tracer *tracing.Provider,
+	reloader *ConfigReloader,
 ) *Server {
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
@@ -48,8 +81,17 @@ func New(
 	// Global middleware
 	r.Use(gin.HandlerFunc(recoveryMiddleware))
 	r.Use(gin.HandlerFunc(requestIDMiddleware))
+	r.Use(gin.HandlerFunc(tracingMiddleware))
 	r.Use(gin.HandlerFunc(loggerMiddleware))
+	r.Use(gin.HandlerFunc(auditMiddleware))
+	r.Use(gin.HandlerFunc(urlLengthMiddleware))
+	r.Use(gin.HandlerFunc(timeoutMiddleware))
+	r.Use(gin.HandlerFunc(readYourWritesMiddleware))
+	// Compress outside JSON naming so its rewritten body is what gets compressed
+	r.Use(gin.HandlerFunc(compressionMiddleware))
 	r.Use(gin.HandlerFunc(corsMiddleware))
+	r.Use(gin.HandlerFunc(jsonNamingMiddleware))
+	r.Use(gin.HandlerFunc(cacheControlMiddleware))
 
 	// Health check routes (no rate limiting or auth)
 	r.GET("/health", healthHandler.Health)
@@ -67,7 +109,10 @@ func New(
 
 	// Public routes with rate limiting
 	public := v1.Group("/")
+	public.Use(middleware.MaxBodyBytesMiddleware(cfg.Server.BodyLimits.Limit("public")))
 	public.Use(rateLimitMiddleware.RateLimit())
+	public.Use(rateLimitMiddleware.RouteRateLimit())
+	public.Use(gin.HandlerFunc(idempotencyMiddleware))
 	{
 		// User registration and login
 		users := public.Group("/users")
@@ -80,74 +125,206 @@ func New(
 				rateLimitMiddleware.LoginRateLimit(),
 				userHandler.Login,
 			)
+			users.POST("/password-setup",
+				rateLimitMiddleware.PasswordResetRateLimit(),
+				userHandler.SetupPassword,
+			)
+			users.GET("/check-availability",
+				rateLimitMiddleware.AvailabilityRateLimit(),
+				userHandler.CheckAvailability,
+			)
+			// Profiles are public; signed-in owners and admins see more
+			users.GET("/:id", authMiddleware.OptionalAuth(), userHandler.GetUser)
 		}
 	}
 
 	// Protected routes (require authentication)
 	protected := v1.Group("/")
+	protected.Use(middleware.MaxBodyBytesMiddleware(cfg.Server.BodyLimits.Limit("protected")))
 	protected.Use(authMiddleware.RequireAuth())
 	protected.Use(authMiddleware.RequireActiveUser())
 	protected.Use(rateLimitMiddleware.RateLimitByUser())
+	protected.Use(rateLimitMiddleware.RateLimitByTenant())
+	protected.Use(rateLimitMiddleware.RouteRateLimit())
+	protected.Use(gin.HandlerFunc(idempotencyMiddleware))
 	{
 		// User management
 		users := protected.Group("/users")
 		{
-			users.GET("/:id", userHandler.GetUser)
+			users.POST("/batch", userHandler.BatchGetUsers)
 			users.GET("/", userHandler.ListUsers)
+			users.GET("/search", userHandler.SearchUsers)
 			users.GET("/me", userHandler.GetCurrentUser)
-			users.PUT("/me", userHandler.UpdateUser)
+			users.PUT("/me", authMiddleware.RequireVerifiedEmail(), userHandler.UpdateUser)
+			users.DELETE("/me", userHandler.DeleteAccount)
 			users.PUT("/me/password", userHandler.ChangePassword)
+			users.GET("/me/export", dataExportHandler.ExportData)
+			users.GET("/me/activity", userHandler.GetLoginActivity)
+			users.GET("/me/notifications", userHandler.GetNotificationPreferences)
+			users.PUT("/me/notifications", userHandler.UpdateNotificationPreferences)
+			users.POST("/me/phone/send-code",
+				rateLimitMiddleware.PhoneCodeRateLimit(),
+				phoneVerificationHandler.SendCode,
+			)
+			users.POST("/me/phone/verify", phoneVerificationHandler.VerifyCode)
 		}
 	}
 
 	// Admin routes (require admin privileges)
 	admin := v1.Group("/admin")
+	admin.Use(middleware.MaxBodyBytesMiddleware(cfg.Server.BodyLimits.Limit("admin")))
 	admin.Use(authMiddleware.RequireAuth())
 	admin.Use(authMiddleware.RequireActiveUser())
-	admin.Use(authMiddleware.AdminOnly())
+	admin.Use(authMiddleware.RequireRole(model.RoleAdmin, model.RoleSupport))
 	admin.Use(rateLimitMiddleware.RateLimitByUser())
+	admin.Use(rateLimitMiddleware.RateLimitByTenant())
+	admin.Use(rateLimitMiddleware.RouteRateLimit())
+	admin.Use(gin.HandlerFunc(idempotencyMiddleware))
 	{
-		// Admin user management
+		// Admin user management; support may only read
+		adminOnly := authMiddleware.AdminOnly()
 		adminUsers := admin.Group("/users")
 		{
 			adminUsers.GET("/", userHandler.ListUsers)
+			adminUsers.POST("/bulk", adminOnly, userHandler.BulkCreateUsers)
+			adminUsers.GET("/export", adminOnly, userHandler.ExportUsers)
+			adminUsers.GET("/lookup", userHandler.LookupUser)
 			adminUsers.GET("/:id", userHandler.GetUser)
+			adminUsers.PUT("/:id/status", adminOnly, userHandler.UpdateUserStatus)
+			adminUsers.DELETE("/:id", adminOnly, userHandler.DeleteUser)
+			adminUsers.POST("/:id/restore", adminOnly, userHandler.RestoreUser)
+			adminUsers.GET("/:id/roles", roleHandler.GetUserRoles)
+			adminUsers.POST("/:id/roles", adminOnly, roleHandler.AssignRole)
+			adminUsers.DELETE("/:id/roles/:role", adminOnly, roleHandler.RevokeRole)
+			// Support may sign out compromised accounts
+			adminUsers.GET("/:id/sessions", sessionHandler.ListSessions)
+			adminUsers.DELETE("/:id/sessions", sessionHandler.RevokeSessions)
 			// Additional admin-only endpoints can be added here
 		}
+
+		admin.GET("/audit-logs", adminOnly, auditLogHandler.ListAuditLogs)
+
+		// Outbound webhook subscriptions carry their signing secrets, so
+		// support may not see them
+		adminWebhooks := admin.Group("/webhooks", adminOnly)
+		{
+			adminWebhooks.GET("", webhookHandler.ListWebhooks)
+			adminWebhooks.POST("", webhookHandler.CreateWebhook)
+			adminWebhooks.GET("/:id", webhookHandler.GetWebhook)
+			adminWebhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			adminWebhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		}
 	}
 
 	// Metrics endpoint for Prometheus
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Profiling is open while debugging and admin-only in release mode
+	var profilingGuards []gin.HandlerFunc
+	if cfg.Server.Mode == gin.ReleaseMode {
+		profilingGuards = []gin.HandlerFunc{
+			authMiddleware.RequireAuth(),
+			authMiddleware.RequireActiveUser(),
+			authMiddleware.AdminOnly(),
+		}
+	}
+	registerProfiling(r, cfg.Profiling, profilingGuards...)
+
 	return &Server{
-		Engine:       r,
+		Engine: r,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+			Handler: r,
+		},
 		config:       cfg,
 		logger:       logger,
 		kafkaService: kafkaService,
+		outboxRelay:  outboxRelay,
+		refresher:    metricsRefresher,
+		taskServer:   taskServer,
+		tracer:       tracer,
+		reloader:     reloader,
 	}
 }
 
-// Start starts the HTTP server
+// Start starts the HTTP server and blocks until it fails or Shutdown is called
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port)
 	s.logger.Info("Starting HTTP server",
-		zap.String("address", addr),
+		zap.String("address", s.httpServer.Addr),
 		zap.String("mode", s.config.Server.Mode),
 	)
-	return s.Run(addr)
+
+	if err := s.startWorkers(context.Background()); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	return s.serve(ln)
+}
+
+// startWorkers starts the background workers the HTTP server relies on
+func (s *Server) startWorkers(ctx context.Context) error {
+	// Consume user events: notifications, webhooks and login alerts.
+	// Kafka is optional and may have been down at startup.
+	if s.kafkaService != nil {
+		if err := s.kafkaService.Start(ctx); err != nil {
+			return err
+		}
+	} else {
+		s.logger.Warn("Kafka unavailable, user events will not be consumed")
+	}
+
+	// Relay events written to the outbox
+	s.outboxRelay.Start(ctx)
+
+	// Keep database-backed gauges up to date
+	s.refresher.Start(ctx)
+
+	// Process async tasks such as email delivery
+	s.taskServer.Start(ctx)
+
+	// Apply log level, rate limit and CORS changes without a restart
+	s.reloader.Start()
+
+	return nil
 }
 
-// Shutdown gracefully shuts down the server
+// serve serves HTTP requests on ln. Stopping through Shutdown is not an error.
+func (s *Server) serve(ln net.Listener) error {
+	if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully shuts down the server. The listener closes right away
+// and in-flight requests drain until ctx is done; background workers and the
+// tracer stop afterwards, so requests can still rely on them while draining.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
 
-	// Create HTTP server instance for graceful shutdown
-	srv := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port),
-		Handler: s.Engine,
+	httpErr := s.httpServer.Shutdown(ctx)
+	if httpErr != nil {
+		s.logger.Error("HTTP server did not drain in time", zap.Error(httpErr))
 	}
 
-	return srv.Shutdown(ctx)
+	// Stop relaying outbox events before the Kafka producer closes
+	s.outboxRelay.Stop()
+
+	// Finish the events being handled and commit their offsets
+	var kafkaErr error
+	if s.kafkaService != nil {
+		kafkaErr = s.kafkaService.Stop()
+	}
+
+	s.refresher.Stop()
+	s.taskServer.Stop()
+
+	// Flush spans still buffered in the exporter
+	return errors.Join(httpErr, kafkaErr, s.tracer.Shutdown(ctx))
 }
 
 // GetLogger returns the logger instance
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/service"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/tracing"
+	"go.uber.org/zap"
+)
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.New()
+	r.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	s := &Server{
+		Engine:      r,
+		httpServer:  &http.Server{Handler: r},
+		logger:      zap.NewNop(),
+		outboxRelay: &service.OutboxRelay{},
+		refresher:   &metrics.Refresher{},
+		taskServer:  &task.Server{},
+		tracer:      &tracing.Provider{},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ln) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(ctx) }()
+
+	// The listener closes before in-flight requests finish
+	require.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned before the in-flight request finished: %v", err)
+	default:
+	}
+
+	close(release)
+	res := <-inFlight
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+
+	assert.NoError(t, <-shutdown)
+	assert.NoError(t, <-served)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AuditLogService handles audit log queries
+type AuditLogService struct {
+	auditLogRepo repository.AuditLogRepository
+	logger       *zap.Logger
+}
+
+// NewAuditLogService creates a new audit log service
+func NewAuditLogService(auditLogRepo repository.AuditLogRepository, logger *zap.Logger) *AuditLogService {
+	return &AuditLogService{
+		auditLogRepo: auditLogRepo,
+		logger:       logger,
+	}
+}
+
+// ListAuditLogs returns a page of audit log entries matching req, newest
+// first, and the total number of matching entries
+func (s *AuditLogService) ListAuditLogs(ctx context.Context, req *dto.AuditLogListRequest) ([]*database.AuditLog, int64, error) {
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		return nil, 0, fmt.Errorf("%w: from must be before to", apperrors.ErrInvalidTimeRange)
+	}
+
+	entries, total, err := s.auditLogRepo.List(ctx, repository.AuditLogFilter{
+		UserID:   req.UserID,
+		Action:   req.Action,
+		Resource: req.Resource,
+		From:     req.From,
+		To:       req.To,
+		Offset:   (req.Page - 1) * req.Size,
+		Limit:    req.Size,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list audit logs", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
@@ -2,46 +2,120 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/task"
 	"github.com/zhwjimmy/user-center/pkg/jwt"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthService handles authentication business logic
 type AuthService struct {
 	userService  *UserService
 	eventService *EventService // New
+	transactor   repository.Transactor
 	jwtManager   *jwt.JWT
-	logger       *zap.Logger
+	policy       *PasswordPolicy
+	emailDomains *EmailDomainPolicy
+	sessions     *SessionLimiter
+	roles        *RoleService
+	emails       EmailQueue
+	redis        *cache.Redis
+	hasher       *PasswordHasher
+	logoutOthers bool
+	// passwordSetup configures the links users created in bulk choose their
+	// password with
+	passwordSetup config.PasswordSetupConfig
+	// verifiedLogin stops users who have not verified their email from logging in
+	verifiedLogin bool
+	logger        *zap.Logger
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(
 	userService *UserService,
 	eventService *EventService, // New
+	transactor repository.Transactor,
 	jwtManager *jwt.JWT,
+	sessions *SessionLimiter,
+	roles *RoleService,
+	emails EmailQueue,
+	redis *cache.Redis,
+	cfg *config.Config,
 	logger *zap.Logger,
-) *AuthService {
+) (*AuthService, error) {
+	hasher, err := NewPasswordHasher(cfg.Security)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordSetup := cfg.Auth.PasswordSetup
+	if passwordSetup.TokenTTL <= 0 {
+		passwordSetup.TokenTTL = defaultPasswordSetupTokenTTL
+	}
+
 	return &AuthService{
-		userService:  userService,
-		eventService: eventService, // New
-		jwtManager:   jwtManager,
-		logger:       logger,
+		userService:   userService,
+		eventService:  eventService, // New
+		transactor:    transactor,
+		jwtManager:    jwtManager,
+		policy:        NewPasswordPolicy(cfg.Security.Password),
+		emailDomains:  NewEmailDomainPolicy(cfg.Auth),
+		sessions:      sessions,
+		roles:         roles,
+		emails:        emails,
+		redis:         redis,
+		hasher:        hasher,
+		logoutOthers:  cfg.Security.LogoutOthersOnPasswordChange,
+		verifiedLogin: cfg.Auth.EmailVerification.RequireForLogin,
+		passwordSetup: passwordSetup,
+		logger:        logger,
+	}, nil
+}
+
+// CheckAvailability reports whether the username or email of req could be
+// registered. An email on a domain registration rejects fails the same way
+// it fails registration.
+func (s *AuthService) CheckAvailability(ctx context.Context, req *dto.CheckAvailabilityRequest) (bool, error) {
+	if req.Email == "" {
+		return s.userService.IsUsernameAvailable(ctx, req.Username)
+	}
+
+	if err := s.emailDomains.Validate("email", req.Email); err != nil {
+		return false, err
 	}
+	return s.userService.IsEmailAvailable(ctx, req.Email)
 }
 
-// Register handles user registration
-func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*model.User, string, error) {
+// Register handles user registration. The welcome email is best effort: when
+// it cannot be enqueued the user is still registered and a warning is returned.
+func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*model.User, string, []string, error) {
+	defer metrics.ObserveOperation("register", time.Now())
+
+	if err := s.emailDomains.Validate("email", req.Email); err != nil {
+		return nil, "", nil, err
+	}
+
+	if err := s.policy.Validate("password", req.Password); err != nil {
+		return nil, "", nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := s.hashPassword(req.Password)
 	if err != nil {
 		s.logger.Error("Failed to hash password", zap.Error(err))
-		return nil, "", fmt.Errorf("failed to process password")
+		return nil, "", nil, fmt.Errorf("failed to process password")
 	}
 
 	// Create user model
@@ -55,54 +129,94 @@ func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 		IsActive:     true,
 	}
 
-	// Create user
-	createdUser, err := s.userService.CreateUser(ctx, user)
+	// Create user and store the registration event in the same transaction
+	var createdUser *model.User
+	err = s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		created, err := s.userService.CreateUser(txCtx, user)
+		if err != nil {
+			s.logger.Error("Failed to create user during registration",
+				zap.String("email", req.Email),
+				zap.String("username", req.Username),
+				zap.Error(err),
+			)
+			if errors.Is(err, apperrors.ErrUserExists) {
+				return apperrors.ErrUserExists
+			}
+			return fmt.Errorf("failed to register user")
+		}
+
+		if err := s.eventService.PublishUserRegisteredEvent(txCtx, created); err != nil {
+			s.logger.Error("Failed to store user registered event",
+				zap.String("user_id", created.ID),
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to register user")
+		}
+
+		createdUser = created
+		return nil
+	})
 	if err != nil {
-		s.logger.Error("Failed to create user during registration",
-			zap.String("email", req.Email),
-			zap.String("username", req.Username),
-			zap.Error(err),
-		)
-		return nil, "", fmt.Errorf("user already exists")
+		return nil, "", nil, err
 	}
 
 	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(createdUser)
+	token, err := s.issueToken(ctx, createdUser)
 	if err != nil {
 		s.logger.Error("Failed to generate token after registration",
 			zap.String("user_id", createdUser.ID),
 			zap.Error(err),
 		)
-		return nil, "", fmt.Errorf("failed to generate token")
+		return nil, "", nil, fmt.Errorf("failed to generate token")
 	}
 
-	// Publish user registration event
-	if err := s.eventService.PublishUserRegisteredEvent(ctx, createdUser); err != nil {
-		s.logger.Error("Failed to publish user registered event",
+	var warnings []string
+	if err := s.enqueueWelcomeEmail(ctx, createdUser); err != nil {
+		s.logger.Error("Failed to enqueue welcome email",
 			zap.String("user_id", createdUser.ID),
 			zap.Error(err),
 		)
-		// Do not return error to avoid affecting main business flow
+		warnings = append(warnings, "Welcome email could not be sent")
 	}
 
+	metrics.RegistrationsTotal.Inc()
+
 	s.logger.Info("User registered successfully",
 		zap.String("user_id", createdUser.ID),
 		zap.String("email", createdUser.Email),
 		zap.String("username", createdUser.Username),
 	)
 
-	return createdUser, token, nil
+	return createdUser, token, warnings, nil
+}
+
+// enqueueWelcomeEmail queues the welcome email for a newly registered user
+func (s *AuthService) enqueueWelcomeEmail(ctx context.Context, user *model.User) error {
+	if s.emails == nil {
+		return nil
+	}
+	return s.emails.EnqueueEmail(ctx, task.EmailPayload{
+		To:       user.Email,
+		Template: task.TemplateWelcome,
+		Data:     map[string]string{"username": user.Username, "email": user.Email},
+	})
 }
 
 // Login handles user login
 func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.User, string, error) {
+	defer metrics.ObserveOperation("login", time.Now())
+
 	// Get user by email
 	user, err := s.userService.GetUserByEmail(ctx, req.Email)
 	if err != nil {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginFailure).Inc()
+		if !errors.Is(err, apperrors.ErrUserNotFound) {
+			return nil, "", fmt.Errorf("failed to get user: %w", err)
+		}
 		s.logger.Warn("Login attempt with non-existent email",
 			zap.String("email", req.Email),
 		)
-		return nil, "", fmt.Errorf("invalid credentials")
+		return nil, "", apperrors.ErrInvalidCredentials
 	}
 
 	// Check if user is active
@@ -112,7 +226,8 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.
 			zap.String("email", req.Email),
 			zap.Bool("is_active", user.IsActive),
 		)
-		return nil, "", fmt.Errorf("account is inactive")
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginFailure).Inc()
+		return nil, "", apperrors.ErrAccountInactive
 	}
 
 	// Verify password
@@ -121,11 +236,23 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.
 			zap.String("user_id", user.ID),
 			zap.String("email", req.Email),
 		)
-		return nil, "", fmt.Errorf("invalid credentials")
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginFailure).Inc()
+		return nil, "", apperrors.ErrInvalidCredentials
+	}
+
+	// Checked after the password so it reveals nothing to password guessers
+	if s.verifiedLogin && !user.EmailVerified {
+		s.logger.Warn("Login attempt with unverified email",
+			zap.String("user_id", user.ID),
+		)
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginFailure).Inc()
+		return nil, "", apperrors.ErrEmailNotVerified
 	}
 
+	s.rehashPassword(ctx, user, req.Password)
+
 	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
+	token, err := s.issueToken(ctx, user)
 	if err != nil {
 		s.logger.Error("Failed to generate token after login",
 			zap.String("user_id", user.ID),
@@ -135,9 +262,9 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.
 	}
 
 	// Publish user login event
-	ipAddress := s.getClientIP(ctx)
-	userAgent := s.getUserAgent(ctx)
-	if err := s.eventService.PublishUserLoggedInEvent(ctx, user, ipAddress, userAgent); err != nil {
+	ipAddress := clientIP(ctx)
+	agent := userAgent(ctx)
+	if err := s.eventService.PublishUserLoggedInEvent(ctx, user, ipAddress, agent); err != nil {
 		s.logger.Error("Failed to publish user logged in event",
 			zap.String("user_id", user.ID),
 			zap.Error(err),
@@ -145,6 +272,8 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.
 		// Do not return error to avoid affecting main business flow
 	}
 
+	metrics.LoginsTotal.WithLabelValues(metrics.LoginSuccess).Inc()
+
 	s.logger.Info("User logged in successfully",
 		zap.String("user_id", user.ID),
 		zap.String("email", user.Email),
@@ -153,8 +282,11 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*model.
 	return user, token, nil
 }
 
-// ChangePassword handles password change
-func (s *AuthService) ChangePassword(ctx context.Context, userID string, req *dto.ChangePasswordRequest) error {
+// ChangePassword handles password change. When configured, the user's other
+// sessions are signed out and only the session of currentToken stays active.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentToken string, req *dto.ChangePasswordRequest) error {
+	defer metrics.ObserveOperation("change_password", time.Now())
+
 	// Get user
 	user, err := s.userService.GetUserByID(ctx, userID)
 	if err != nil {
@@ -166,7 +298,11 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID string, req *dt
 		s.logger.Warn("Invalid old password in change password request",
 			zap.String("user_id", userID),
 		)
-		return fmt.Errorf("invalid old password")
+		return apperrors.ErrInvalidOldPassword
+	}
+
+	if err := s.policy.Validate("new_password", req.NewPassword); err != nil {
+		return err
 	}
 
 	// Hash new password
@@ -179,34 +315,110 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID string, req *dt
 		return fmt.Errorf("failed to process new password")
 	}
 
-	// Update password
+	if err := s.savePassword(ctx, user, hashedPassword); err != nil {
+		return err
+	}
+
+	metrics.PasswordChangesTotal.Inc()
+
+	s.logger.Info("Password changed successfully",
+		zap.String("user_id", userID),
+	)
+
+	if s.logoutOthers {
+		s.revokeOtherSessions(ctx, userID, currentToken)
+	}
+
+	return nil
+}
+
+// savePassword updates the password hash of user and stores the password
+// changed event in the same transaction
+func (s *AuthService) savePassword(ctx context.Context, user *model.User, hashedPassword string) error {
 	user.PasswordHash = hashedPassword
-	_, err = s.userService.userRepo.Update(ctx, user)
+	ipAddress := clientIP(ctx)
+	return s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := s.userService.userRepo.Update(txCtx, user); err != nil {
+			s.logger.Error("Failed to update password",
+				zap.String("user_id", user.ID),
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to update password")
+		}
+
+		if err := s.eventService.PublishUserPasswordChangedEvent(txCtx, user, ipAddress); err != nil {
+			s.logger.Error("Failed to store user password changed event",
+				zap.String("user_id", user.ID),
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to update password")
+		}
+
+		return nil
+	})
+}
+
+// DeleteAccount soft-deletes the user's own account after confirming their
+// password. The deletion confirmation email is sent when the user deleted
+// event is consumed. currentToken is blacklisted and the user's other
+// sessions are signed out; both are best effort once the account is deleted.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID, currentToken string, req *dto.DeleteAccountRequest) error {
+	user, err := s.userService.GetUserByID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to update password",
+		return err
+	}
+
+	if !s.verifyPassword(req.Password, user.PasswordHash) {
+		s.logger.Warn("Invalid password in delete account request",
 			zap.String("user_id", userID),
-			zap.Error(err),
 		)
-		return fmt.Errorf("failed to update password")
+		return apperrors.ErrInvalidPassword
 	}
 
-	// Publish user password changed event
-	ipAddress := s.getClientIP(ctx)
-	if err := s.eventService.PublishUserPasswordChangedEvent(ctx, user, ipAddress); err != nil {
-		s.logger.Error("Failed to publish user password changed event",
+	if err := s.userService.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.sessions.Blacklist(ctx, currentToken); err != nil {
+		s.logger.Error("Failed to blacklist token after account deletion",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+	}
+	if _, err := s.sessions.RevokeOthers(ctx, userID, currentToken); err != nil {
+		s.logger.Error("Failed to sign out other sessions after account deletion",
 			zap.String("user_id", userID),
 			zap.Error(err),
 		)
-		// Do not return error to avoid affecting main business flow
 	}
 
-	s.logger.Info("Password changed successfully",
+	s.logger.Info("Account deleted by its owner",
 		zap.String("user_id", userID),
 	)
 
 	return nil
 }
 
+// revokeOtherSessions signs out every session of userID except the one of
+// currentToken. The password is already changed, so failures are only logged.
+func (s *AuthService) revokeOtherSessions(ctx context.Context, userID, currentToken string) {
+	revoked, err := s.sessions.RevokeOthers(ctx, userID, currentToken)
+	if err != nil {
+		s.logger.Error("Failed to sign out other sessions after password change",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if revoked > 0 {
+		s.logger.Info("Signed out other sessions after password change",
+			zap.String("user_id", userID),
+			zap.Int64("revoked", revoked),
+		)
+	}
+}
+
 // RefreshToken generates a new token from an existing token
 func (s *AuthService) RefreshToken(ctx context.Context, tokenString string) (string, error) {
 	// Validate existing token
@@ -221,8 +433,9 @@ func (s *AuthService) RefreshToken(ctx context.Context, tokenString string) (str
 	if err != nil {
 		s.logger.Warn("User not found during token refresh",
 			zap.String("user_id", claims.UserID),
+			zap.Error(err),
 		)
-		return "", fmt.Errorf("user not found")
+		return "", err
 	}
 
 	// Check if user is still active
@@ -231,11 +444,11 @@ func (s *AuthService) RefreshToken(ctx context.Context, tokenString string) (str
 			zap.String("user_id", user.ID),
 			zap.Bool("is_active", user.IsActive),
 		)
-		return "", fmt.Errorf("account is inactive")
+		return "", apperrors.ErrAccountInactive
 	}
 
 	// Generate new token
-	newToken, err := s.jwtManager.GenerateToken(user)
+	newToken, err := s.issueToken(ctx, user)
 	if err != nil {
 		s.logger.Error("Failed to generate new token during refresh",
 			zap.String("user_id", user.ID),
@@ -251,24 +464,88 @@ func (s *AuthService) RefreshToken(ctx context.Context, tokenString string) (str
 	return newToken, nil
 }
 
+// ReissueToken issues a new token for user after a change to one of its
+// claims, such as the username. Earlier tokens stay valid until they expire.
+func (s *AuthService) ReissueToken(ctx context.Context, user *model.User) (string, error) {
+	return s.issueToken(ctx, user)
+}
+
+// issueToken generates a JWT token carrying the user's roles and starts a
+// session for it, enforcing the user's concurrent session limit
+func (s *AuthService) issueToken(ctx context.Context, user *model.User) (string, error) {
+	if err := s.roles.Load(ctx, user); err != nil {
+		return "", err
+	}
+
+	token, err := s.jwtManager.GenerateToken(user)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.sessions.Start(ctx, user, token); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
 // ValidateToken validates a JWT token and returns user claims
 func (s *AuthService) ValidateToken(tokenString string) (*jwt.Claims, error) {
 	return s.jwtManager.ValidateToken(tokenString)
 }
 
-// hashPassword hashes a password using bcrypt
+// hashPassword hashes a password with the configured algorithm
 func (s *AuthService) hashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedBytes), nil
+	return s.hasher.Hash(password)
 }
 
 // verifyPassword verifies a password against its hash
 func (s *AuthService) verifyPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return s.hasher.Verify(password, hash)
+}
+
+// rehashPassword replaces a hash produced by an older algorithm or weaker
+// parameters once the plaintext is known after a successful login. Failures
+// are logged and leave the old hash in place, which still verifies.
+func (s *AuthService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		s.logger.Error("Failed to rehash password",
+			zap.String("user_id", user.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	// user may come from the cache or a lagging replica; save the hash on
+	// the current row so other columns are not rolled back
+	current, err := s.userService.userRepo.GetByID(database.UsePrimary(ctx), user.ID)
+	if err != nil {
+		s.logger.Error("Failed to load user for rehashed password",
+			zap.String("user_id", user.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	current.PasswordHash = hashedPassword
+	if _, err := s.userService.userRepo.Update(ctx, current); err != nil {
+		s.logger.Error("Failed to store rehashed password",
+			zap.String("user_id", user.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	user.PasswordHash = hashedPassword
+	s.userService.invalidateUserCache(ctx, user.ID)
+
+	s.logger.Info("Password rehashed with current parameters",
+		zap.String("user_id", user.ID),
+	)
 }
 
 // ForgotPassword handles password reset request (placeholder for future implementation)
@@ -304,21 +581,26 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 	// 3. Update user password
 	// 4. Invalidate the reset token
 
+	if err := s.policy.Validate("new_password", newPassword); err != nil {
+		return err
+	}
+
 	s.logger.Info("Password reset attempted", zap.String("token", token))
 
 	// TODO: Implement password reset logic
 	return fmt.Errorf("password reset not implemented")
 }
 
-// 辅助方法
-func (s *AuthService) getClientIP(ctx context.Context) string {
+// clientIP returns the client IP of the request ctx belongs to, if any
+func clientIP(ctx context.Context) string {
 	if ginCtx, ok := ctx.(*gin.Context); ok {
 		return ginCtx.ClientIP()
 	}
 	return ""
 }
 
-func (s *AuthService) getUserAgent(ctx context.Context) string {
+// userAgent returns the User-Agent of the request ctx belongs to, if any
+func userAgent(ctx context.Context) string {
 	if ginCtx, ok := ctx.(*gin.Context); ok {
 		return ginCtx.GetHeader("User-Agent")
 	}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_SentinelErrors(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		setup   func(repo *mock.MockUserRepository, user *model.User)
+		call    func(s *AuthService) error
+		wantErr error
+	}{
+		{
+			name: "login with unknown email",
+			setup: func(repo *mock.MockUserRepository, _ *model.User) {
+				repo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(nil, apperrors.ErrUserNotFound)
+			},
+			call: func(s *AuthService) error {
+				_, _, err := s.Login(context.Background(), &dto.LoginRequest{Email: "bob@example.com", Password: "Password-1"})
+				return err
+			},
+			wantErr: apperrors.ErrInvalidCredentials,
+		},
+		{
+			name: "login with wrong password",
+			setup: func(repo *mock.MockUserRepository, user *model.User) {
+				repo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(user, nil)
+			},
+			call: func(s *AuthService) error {
+				_, _, err := s.Login(context.Background(), &dto.LoginRequest{Email: "alice@example.com", Password: "Password-2"})
+				return err
+			},
+			wantErr: apperrors.ErrInvalidCredentials,
+		},
+		{
+			name: "login to inactive account",
+			setup: func(repo *mock.MockUserRepository, user *model.User) {
+				user.IsActive = false
+				repo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(user, nil)
+			},
+			call: func(s *AuthService) error {
+				_, _, err := s.Login(context.Background(), &dto.LoginRequest{Email: "alice@example.com", Password: "Password-1"})
+				return err
+			},
+			wantErr: apperrors.ErrAccountInactive,
+		},
+		{
+			name: "login when the lookup fails",
+			setup: func(repo *mock.MockUserRepository, _ *model.User) {
+				repo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(nil, errDB)
+			},
+			call: func(s *AuthService) error {
+				_, _, err := s.Login(context.Background(), &dto.LoginRequest{Email: "alice@example.com", Password: "Password-1"})
+				return err
+			},
+			wantErr: errDB,
+		},
+		{
+			name: "change password with wrong old password",
+			setup: func(repo *mock.MockUserRepository, user *model.User) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil)
+			},
+			call: func(s *AuthService) error {
+				return s.ChangePassword(context.Background(), "user-1", "token-1", &dto.ChangePasswordRequest{
+					OldPassword: "Password-2",
+					NewPassword: "New-Password-3",
+				})
+			},
+			wantErr: apperrors.ErrInvalidOldPassword,
+		},
+		{
+			name: "change password of unknown user",
+			setup: func(repo *mock.MockUserRepository, _ *model.User) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+			},
+			call: func(s *AuthService) error {
+				return s.ChangePassword(context.Background(), "user-1", "token-1", &dto.ChangePasswordRequest{
+					OldPassword: "Password-1",
+					NewPassword: "New-Password-3",
+				})
+			},
+			wantErr: apperrors.ErrUserNotFound,
+		},
+		{
+			name: "delete account with wrong password",
+			setup: func(repo *mock.MockUserRepository, user *model.User) {
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil)
+			},
+			call: func(s *AuthService) error {
+				return s.DeleteAccount(context.Background(), "user-1", "token-1", &dto.DeleteAccountRequest{Password: "Password-2"})
+			},
+			wantErr: apperrors.ErrInvalidPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			redis, _ := testutils.NewMiniRedis(t)
+			logger := zap.NewNop()
+
+			cfg := &config.Config{Security: config.SecurityConfig{BcryptCost: 4}}
+			s, err := NewAuthService(newTestUserService(t, repo, logger), NewEventService(testutils.NewFakeOutboxRepository(), logger),
+				testutils.FakeTransactor{}, jwt.NewJWT("test-secret", "test", 0), NewSessionLimiter(redis, nil, cfg, logger), nil, nil, nil, cfg, logger)
+			require.NoError(t, err)
+
+			hash, err := s.hashPassword("Password-1")
+			require.NoError(t, err)
+			user := &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: hash, IsActive: true}
+			tt.setup(repo, user)
+
+			assert.ErrorIs(t, tt.call(s), tt.wantErr)
+		})
+	}
+}
+
+func TestAuthService_Login_EmailVerification(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireForLogin bool
+		verified        bool
+		wantErr         error
+	}{
+		{name: "unverified may log in by default"},
+		{name: "verified", requireForLogin: true, verified: true},
+		{name: "unverified blocked when required", requireForLogin: true, wantErr: apperrors.ErrEmailNotVerified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			logger := zap.NewNop()
+			jwtManager := jwt.NewJWT("test-secret", "test", time.Hour)
+
+			user := &model.User{
+				ID:            "user-1",
+				Email:         "alice@example.com",
+				PasswordHash:  mustHash(t, bcrypt4, "Correct-Horse-42"),
+				IsActive:      true,
+				EmailVerified: tt.verified,
+			}
+			repo.EXPECT().GetByEmail(gomock.Any(), "alice@example.com").Return(user, nil)
+
+			cfg := &config.Config{
+				Security: bcrypt4,
+				Auth: config.AuthConfig{EmailVerification: config.EmailVerificationConfig{
+					RequireForLogin: tt.requireForLogin,
+				}},
+			}
+			s, err := NewAuthService(newTestUserService(t, repo, logger), NewEventService(testutils.NewFakeOutboxRepository(), logger),
+				testutils.FakeTransactor{}, jwtManager, nil, nil, nil, nil, cfg, logger)
+			require.NoError(t, err)
+
+			_, token, err := s.Login(context.Background(), &dto.LoginRequest{Email: "alice@example.com", Password: "Correct-Horse-42"})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, token)
+				return
+			}
+			require.NoError(t, err)
+
+			claims, err := jwtManager.ValidateToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, tt.verified, claims.EmailVerified)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"go.uber.org/zap"
+)
+
+// EmailQueue enqueues emails to be sent asynchronously
+type EmailQueue interface {
+	EnqueueEmail(ctx context.Context, payload task.EmailPayload) error
+}
+
+// BulkCreateUsers creates each user with a random password nobody knows and
+// emails them a one-time link to choose their own, or with the password hash
+// imported from another system. Every row is created in its own transaction, so duplicates and
+// other failures are reported per row without aborting the rest of the batch.
+func (s *AuthService) BulkCreateUsers(ctx context.Context, users []dto.BulkCreateUserRequest) []dto.BulkCreateResult {
+	results := make([]dto.BulkCreateResult, len(users))
+	emails := make(map[string]struct{}, len(users))
+	usernames := make(map[string]struct{}, len(users))
+
+	for i := range users {
+		req := &users[i]
+		result := dto.BulkCreateResult{Index: i, Status: dto.BulkResultFailed}
+
+		email := model.NormalizeEmail(req.Email)
+		if _, ok := emails[email]; ok {
+			result.Error = "email appears more than once in the request"
+			results[i] = result
+			continue
+		}
+		if _, ok := usernames[req.Username]; ok {
+			result.Error = "username appears more than once in the request"
+			results[i] = result
+			continue
+		}
+		emails[email] = struct{}{}
+		usernames[req.Username] = struct{}{}
+
+		user, warnings, err := s.createBulkUser(ctx, req)
+		if err != nil {
+			var domainErr *EmailDomainError
+			if errors.As(err, &domainErr) {
+				result.Fields = domainErr.FieldErrors()
+			}
+			result.Error = err.Error()
+			results[i] = result
+			continue
+		}
+
+		result.Status = dto.BulkResultCreated
+		result.User = user.ToPublicUser()
+		result.Warnings = warnings
+		results[i] = result
+	}
+
+	return results
+}
+
+// createBulkUser creates one user of a bulk request and enqueues their password
+// setup email, unless the request carries an imported password hash. A link
+// that cannot be issued or enqueued is returned as a warning.
+func (s *AuthService) createBulkUser(ctx context.Context, req *dto.BulkCreateUserRequest) (*model.User, []string, error) {
+	if err := s.emailDomains.Validate("email", req.Email); err != nil {
+		return nil, nil, err
+	}
+
+	// Imported hashes are stored as is and upgraded by the hasher on the
+	// user's first login if they do not match the current configuration
+	var hashedPassword string
+	if req.PasswordHash != "" {
+		if err := ValidatePasswordHash(req.PasswordAlgorithm, req.PasswordHash); err != nil {
+			return nil, nil, err
+		}
+		hashedPassword = req.PasswordHash
+	} else {
+		// Never sent anywhere; the user sets their password through the link
+		password, err := newPasswordSetupToken()
+		if err != nil {
+			s.logger.Error("Failed to generate initial password", zap.Error(err))
+			return nil, nil, fmt.Errorf("failed to generate initial password")
+		}
+
+		hashedPassword, err = s.hashPassword(password)
+		if err != nil {
+			s.logger.Error("Failed to hash password", zap.Error(err))
+			return nil, nil, fmt.Errorf("failed to process password")
+		}
+	}
+
+	user := &model.User{
+		Username:     req.Username,
+		Email:        req.Email,
+		PasswordHash: hashedPassword,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Phone:        req.Phone,
+		IsActive:     true,
+	}
+
+	var createdUser *model.User
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		created, err := s.userService.CreateUser(txCtx, user)
+		if err != nil {
+			// Duplicate errors name the conflicting field; hide anything else
+			if errors.Is(err, apperrors.ErrUserExists) {
+				return err
+			}
+			return fmt.Errorf("failed to create user")
+		}
+
+		if err := s.eventService.PublishUserRegisteredEvent(txCtx, created); err != nil {
+			s.logger.Error("Failed to store user registered event",
+				zap.String("user_id", created.ID),
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to create user")
+		}
+
+		createdUser = created
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Users with an imported hash already know their password
+	var warnings []string
+	if s.emails != nil && req.PasswordHash == "" {
+		if err := s.sendPasswordSetupEmail(ctx, createdUser); err != nil {
+			s.logger.Error("Failed to send password setup email",
+				zap.String("user_id", createdUser.ID),
+				zap.Error(err),
+			)
+			warnings = append(warnings, "Password setup email could not be sent")
+		}
+	}
+
+	s.logger.Info("User created in bulk",
+		zap.String("user_id", createdUser.ID),
+		zap.String("email", createdUser.Email),
+	)
+
+	return createdUser, warnings, nil
+}
+
+// sendPasswordSetupEmail issues a password setup link to user and enqueues
+// the email that carries it
+func (s *AuthService) sendPasswordSetupEmail(ctx context.Context, user *model.User) error {
+	link, err := s.passwordSetupLink(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.emails.EnqueueEmail(ctx, task.EmailPayload{
+		To:       user.Email,
+		Template: task.TemplatePasswordSetup,
+		Data: map[string]string{
+			"username":   user.Username,
+			"email":      user.Email,
+			"link":       link,
+			"expires_in": s.passwordSetup.TokenTTL.String(),
+		},
+	})
+}
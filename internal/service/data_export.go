@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"go.uber.org/zap"
+)
+
+// dataExportActivityLimit is the most recent audit entries included in a data export
+const dataExportActivityLimit = 1000
+
+// DataExportService assembles the personal data of a user for self-service export
+type DataExportService struct {
+	userService  *UserService
+	roles        *RoleService
+	sessions     *SessionLimiter
+	auditLogRepo repository.AuditLogRepository
+	logger       *zap.Logger
+}
+
+// NewDataExportService creates a new data export service
+func NewDataExportService(
+	userService *UserService,
+	roles *RoleService,
+	sessions *SessionLimiter,
+	auditLogRepo repository.AuditLogRepository,
+	logger *zap.Logger,
+) *DataExportService {
+	return &DataExportService{
+		userService:  userService,
+		roles:        roles,
+		sessions:     sessions,
+		auditLogRepo: auditLogRepo,
+		logger:       logger,
+	}
+}
+
+// Export returns the personal data of the user with id: their profile, roles,
+// notification preferences, active sessions and recent audited activity.
+// Activity is left out when the audit log is unavailable.
+func (s *DataExportService) Export(ctx context.Context, id string) (*dto.DataExport, error) {
+	user, err := s.userService.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roles.Load(ctx, user); err != nil {
+		return nil, err
+	}
+
+	starts, err := s.sessions.List(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]dto.DataExportSession, len(starts))
+	for i, start := range starts {
+		sessions[i] = dto.DataExportSession{StartedAt: start}
+	}
+
+	entries, _, err := s.auditLogRepo.List(ctx, repository.AuditLogFilter{
+		UserID: id,
+		Limit:  dataExportActivityLimit,
+	})
+	if err != nil && !errors.Is(err, repository.ErrAuditLogUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Warn("Audit log unavailable, exporting user data without activity",
+			zap.String("user_id", id),
+		)
+	}
+	activity := make([]dto.DataExportActivity, len(entries))
+	for i, entry := range entries {
+		activity[i] = dto.DataExportActivity{
+			Action:    entry.Action,
+			Resource:  entry.Resource,
+			Status:    entry.Details["status"],
+			IP:        entry.IP,
+			UserAgent: entry.UserAgent,
+			Timestamp: entry.Timestamp,
+		}
+	}
+
+	s.logger.Info("User data exported",
+		zap.String("user_id", id),
+	)
+
+	return &dto.DataExport{
+		ExportedAt:              time.Now().UTC(),
+		Profile:                 user.ToOwnerView(),
+		Roles:                   user.GetRoles(),
+		NotificationPreferences: user.NotificationPreferences,
+		SMSNotifications:        user.SMSNotifications,
+		Sessions:                sessions,
+		ActivityHistory:         activity,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomainList string
+
+// disposableDomains is the parsed embedded list of disposable email domains
+var disposableDomains = parseDomainList(disposableDomainList)
+
+// EmailDomainError reports that an email address uses a domain that may not register
+type EmailDomainError struct {
+	Field  string
+	Domain string
+}
+
+// Error implements error
+func (e *EmailDomainError) Error() string {
+	return "email domain not allowed: " + e.Domain
+}
+
+// FieldErrors returns the rejection as a field-level error
+func (e *EmailDomainError) FieldErrors() []dto.FieldError {
+	return []dto.FieldError{{
+		Field:   e.Field,
+		Rule:    "email_domain",
+		Message: "email addresses at " + e.Domain + " are not allowed",
+	}}
+}
+
+// EmailDomainPolicy decides which email domains may register
+type EmailDomainPolicy struct {
+	allowed         map[string]struct{}
+	blocked         map[string]struct{}
+	blockDisposable bool
+}
+
+// NewEmailDomainPolicy creates a new email domain policy
+func NewEmailDomainPolicy(cfg config.AuthConfig) *EmailDomainPolicy {
+	return &EmailDomainPolicy{
+		allowed:         domainSet(cfg.AllowedEmailDomains),
+		blocked:         domainSet(cfg.BlockedEmailDomains),
+		blockDisposable: cfg.BlockDisposableEmails,
+	}
+}
+
+// Validate checks the domain of email. A domain matches a list entry if it is
+// the entry or one of its subdomains. When an allow list is configured only
+// its domains may register; the block and disposable lists apply on top.
+func (p *EmailDomainPolicy) Validate(field, email string) error {
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+
+	if len(p.allowed) > 0 && !matchesDomain(p.allowed, domain) {
+		return &EmailDomainError{Field: field, Domain: domain}
+	}
+	if matchesDomain(p.blocked, domain) {
+		return &EmailDomainError{Field: field, Domain: domain}
+	}
+	if p.blockDisposable && matchesDomain(disposableDomains, domain) {
+		return &EmailDomainError{Field: field, Domain: domain}
+	}
+	return nil
+}
+
+// matchesDomain reports whether domain or any of its parent domains is in set
+func matchesDomain(set map[string]struct{}, domain string) bool {
+	for {
+		if _, ok := set[domain]; ok {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// domainSet normalizes a list of domains into a set
+func domainSet(domains []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			set[strings.TrimPrefix(domain, "@")] = struct{}{}
+		}
+	}
+	return set
+}
+
+// parseDomainList parses one domain per line, skipping blank lines and # comments
+func parseDomainList(list string) map[string]struct{} {
+	var domains []string
+	for _, line := range strings.Split(list, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			domains = append(domains, line)
+		}
+	}
+	return domainSet(domains)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+)
+
+func TestEmailDomainPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AuthConfig
+		email   string
+		allowed bool
+	}{
+		{
+			name:    "no lists",
+			email:   "user@mailinator.com",
+			allowed: true,
+		},
+		{
+			name:    "allowed domain",
+			cfg:     config.AuthConfig{AllowedEmailDomains: []string{"example.com"}},
+			email:   "user@Example.com",
+			allowed: true,
+		},
+		{
+			name:    "allowed subdomain",
+			cfg:     config.AuthConfig{AllowedEmailDomains: []string{"example.com"}},
+			email:   "user@eng.example.com",
+			allowed: true,
+		},
+		{
+			name:  "not in allow list",
+			cfg:   config.AuthConfig{AllowedEmailDomains: []string{"example.com"}},
+			email: "user@example.org",
+		},
+		{
+			name:  "lookalike of allowed domain",
+			cfg:   config.AuthConfig{AllowedEmailDomains: []string{"example.com"}},
+			email: "user@badexample.com",
+		},
+		{
+			name:  "blocked domain",
+			cfg:   config.AuthConfig{BlockedEmailDomains: []string{"spam.example"}},
+			email: "user@spam.example",
+		},
+		{
+			name:  "blocked overrides allowed",
+			cfg:   config.AuthConfig{AllowedEmailDomains: []string{"example.com"}, BlockedEmailDomains: []string{"old.example.com"}},
+			email: "user@old.example.com",
+		},
+		{
+			name:  "disposable domain",
+			cfg:   config.AuthConfig{BlockDisposableEmails: true},
+			email: "user@mailinator.com",
+		},
+		{
+			name:    "regular domain with disposable list enabled",
+			cfg:     config.AuthConfig{BlockDisposableEmails: true},
+			email:   "user@example.com",
+			allowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewEmailDomainPolicy(tt.cfg).Validate("email", tt.email)
+			if tt.allowed {
+				assert.NoError(t, err)
+				return
+			}
+
+			var domainErr *EmailDomainError
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, "email", domainErr.FieldErrors()[0].Field)
+			assert.Equal(t, "email_domain", domainErr.FieldErrors()[0].Rule)
+		})
+	}
+}
+
+func TestDisposableDomainsEmbedded(t *testing.T) {
+	assert.Contains(t, disposableDomains, "yopmail.com")
+	assert.NotContains(t, disposableDomains, "")
+	for domain := range disposableDomains {
+		assert.NotContains(t, domain, "#")
+	}
+}
+
+func TestEmailDomainError_FieldErrors(t *testing.T) {
+	err := &EmailDomainError{Field: "email", Domain: "mailinator.com"}
+	assert.Equal(t, []dto.FieldError{{
+		Field:   "email",
+		Rule:    "email_domain",
+		Message: "email addresses at mailinator.com are not allowed",
+	}}, err.FieldErrors())
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"go.uber.org/zap"
+)
+
+// UserDataStore holds the data of users kept outside PostgreSQL: their
+// stored sessions and application logs
+type UserDataStore interface {
+	DeleteUserData(ctx context.Context, userID string) (int64, error)
+}
+
+// ErasureService permanently deletes users, e.g. for GDPR erasure requests
+type ErasureService struct {
+	userRepo     repository.UserRepository
+	eventService *EventService
+	transactor   repository.Transactor
+	cache        *cache.Redis
+	store        UserDataStore
+	logger       *zap.Logger
+}
+
+// NewErasureService creates a new erasure service. store may be nil when
+// MongoDB is not connected, in which case nothing can be erased.
+func NewErasureService(
+	userRepo repository.UserRepository,
+	eventService *EventService,
+	transactor repository.Transactor,
+	cache *cache.Redis,
+	store UserDataStore,
+	logger *zap.Logger,
+) *ErasureService {
+	return &ErasureService{
+		userRepo:     userRepo,
+		eventService: eventService,
+		transactor:   transactor,
+		cache:        cache,
+		store:        store,
+		logger:       logger,
+	}
+}
+
+// EraseUser permanently deletes the user with id, soft-deleted or not, along
+// with their stored sessions, logs and cached data. Sessions and caches are
+// purged first so a failure leaves the user in place to retry; without the
+// session store it fails with apperrors.ErrUserDataUnavailable rather than
+// leave data behind. A user deleted event is published unless the user was
+// already soft-deleted, which published one.
+func (s *ErasureService) EraseUser(ctx context.Context, id string) error {
+	defer metrics.ObserveOperation("erase_user", time.Now())
+
+	if s.store == nil {
+		return apperrors.ErrUserDataUnavailable
+	}
+
+	user, softDeleted, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	purged, err := s.store.DeleteUserData(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to purge user data",
+			zap.String("user_id", id),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := s.cache.PurgeUser(ctx, id); err != nil {
+		return err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.userRepo.HardDelete(txCtx, id); err != nil {
+			return err
+		}
+		if softDeleted {
+			return nil
+		}
+		return s.eventService.PublishUserDeletedEvent(txCtx, user)
+	})
+	if err != nil {
+		s.logger.Error("Failed to erase user",
+			zap.String("user_id", id),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	// A read between the purge and the delete may have cached the user again
+	if err := s.cache.InvalidateUserCache(ctx, id); err != nil {
+		s.logger.Warn("Failed to invalidate user cache",
+			zap.String("user_id", id),
+			zap.Error(err),
+		)
+	}
+
+	s.logger.Info("User erased",
+		zap.String("user_id", id),
+		zap.Int64("documents_purged", purged),
+	)
+
+	return nil
+}
+
+// getUser returns the user with id and whether they are soft-deleted
+func (s *ErasureService) getUser(ctx context.Context, id string) (*model.User, bool, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, apperrors.ErrUserNotFound) {
+		return nil, false, err
+	}
+
+	user, err = s.userRepo.GetDeletedByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
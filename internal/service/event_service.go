@@ -2,142 +2,195 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/zhwjimmy/user-center/internal/kafka"
 	"github.com/zhwjimmy/user-center/internal/kafka/event"
 	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
 	"go.uber.org/zap"
 )
 
-// EventService provides event publishing services
+// EventService provides event publishing services.
+// Events are written to the transactional outbox and relayed to Kafka
+// by the OutboxRelay, so callers inside a transaction get atomic publishing.
 type EventService struct {
-	kafkaService kafka.Service
-	logger       *zap.Logger
+	outboxRepo repository.OutboxRepository
+	logger     *zap.Logger
 }
 
 // NewEventService creates a new event service
-func NewEventService(kafkaService kafka.Service, logger *zap.Logger) *EventService {
+func NewEventService(outboxRepo repository.OutboxRepository, logger *zap.Logger) *EventService {
 	return &EventService{
-		kafkaService: kafkaService,
-		logger:       logger,
+		outboxRepo: outboxRepo,
+		logger:     logger,
 	}
 }
 
+// FieldChange is the change set entry of a field that changed from one value to another
+func FieldChange(from, to interface{}) map[string]interface{} {
+	return map[string]interface{}{"old": from, "new": to}
+}
+
 // PublishUserRegisteredEvent publishes a user registered event
 func (s *EventService) PublishUserRegisteredEvent(ctx context.Context, user *model.User) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
 
 	userEvent := &event.UserRegisteredEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserRegistered,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: s.getStringValue(user.FirstName),
-		LastName:  s.getStringValue(user.LastName),
+		Username:  snapshot.Username,
+		Email:     snapshot.Email,
+		FirstName: snapshot.FirstName,
+		LastName:  snapshot.LastName,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
 }
 
 // PublishUserLoggedInEvent publishes a user logged in event
 func (s *EventService) PublishUserLoggedInEvent(ctx context.Context, user *model.User, ipAddress, userAgent string) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
 
 	userEvent := &event.UserLoggedInEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserLoggedIn,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username:  user.Username,
-		Email:     user.Email,
+		Username:  snapshot.Username,
+		Email:     snapshot.Email,
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
 }
 
 // PublishUserPasswordChangedEvent publishes a user password changed event
 func (s *EventService) PublishUserPasswordChangedEvent(ctx context.Context, user *model.User, ipAddress string) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
 
 	userEvent := &event.UserPasswordChangedEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserPasswordChanged,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username:  user.Username,
-		Email:     user.Email,
+		Username:  snapshot.Username,
+		Email:     snapshot.Email,
 		IPAddress: ipAddress,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
 }
 
 // PublishUserStatusChangedEvent publishes a user status changed event
 func (s *EventService) PublishUserStatusChangedEvent(ctx context.Context, user *model.User, oldStatus, newStatus string) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
 
 	userEvent := &event.UserStatusChangedEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserStatusChanged,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username:  user.Username,
-		Email:     user.Email,
+		Username:  snapshot.Username,
+		Email:     snapshot.Email,
 		OldStatus: oldStatus,
 		NewStatus: newStatus,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
 }
 
 // PublishUserDeletedEvent publishes a user deleted event
 func (s *EventService) PublishUserDeletedEvent(ctx context.Context, user *model.User) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
 
 	userEvent := &event.UserDeletedEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserDeleted,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username: user.Username,
-		Email:    user.Email,
+		Username: snapshot.Username,
+		Email:    snapshot.Email,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
 }
 
-// PublishUserUpdatedEvent publishes a user updated event
+// sensitiveUserFields are user fields whose values never leave the service in
+// a change set
+var sensitiveUserFields = []string{"password", "password_hash"}
+
+// PublishUserUpdatedEvent publishes a user updated event. changes maps each
+// changed field's JSON name to its old and new value, see FieldChange.
+// Sensitive fields are left out.
 func (s *EventService) PublishUserUpdatedEvent(ctx context.Context, user *model.User, changes map[string]interface{}) error {
 	requestID := s.getRequestID(ctx)
+	snapshot := newUserSnapshot(user)
+
+	for _, field := range sensitiveUserFields {
+		delete(changes, field)
+	}
 
 	userEvent := &event.UserUpdatedEvent{
 		BaseEvent: event.NewBaseEvent(
 			event.UserUpdated,
 			"user-center",
 			requestID,
-			user.ID,
+			snapshot.ID,
 		),
-		Username: user.Username,
-		Email:    user.Email,
+		Username: snapshot.Username,
+		Email:    snapshot.Email,
 		Changes:  changes,
 	}
 
-	return s.kafkaService.GetProducer().PublishUserEventAsync(ctx, userEvent)
+	return s.enqueue(ctx, &userEvent.BaseEvent, userEvent)
+}
+
+// enqueue serializes the event and stores it in the outbox
+func (s *EventService) enqueue(ctx context.Context, base *event.BaseEvent, userEvent interface{}) error {
+	payload, err := json.Marshal(userEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", base.Type, err)
+	}
+
+	outboxEvent := &model.OutboxEvent{
+		EventType:   string(base.Type),
+		AggregateID: base.UserID,
+		RequestID:   base.RequestID,
+		Payload:     string(payload),
+	}
+
+	if err := s.outboxRepo.Create(ctx, outboxEvent); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Event stored in outbox",
+		zap.String("outbox_id", outboxEvent.ID),
+		zap.String("event_type", outboxEvent.EventType),
+		zap.String("user_id", outboxEvent.AggregateID),
+	)
+
+	return nil
 }
 
 // getRequestID gets the request ID from context
@@ -150,10 +203,49 @@ func (s *EventService) getRequestID(ctx context.Context) string {
 	return ""
 }
 
-// getStringValue gets the value from a string pointer
-func (s *EventService) getStringValue(ptr *string) string {
+// userSnapshot is a nil-safe copy of the user fields carried by events.
+// Optional pointer fields are converted to their zero values.
+type userSnapshot struct {
+	ID          string
+	Username    string
+	Email       string
+	FirstName   string
+	LastName    string
+	Phone       string
+	AvatarURL   string
+	LastLoginAt time.Time
+}
+
+// newUserSnapshot builds the event view of a user; a nil user yields an empty snapshot
+func newUserSnapshot(user *model.User) userSnapshot {
+	if user == nil {
+		return userSnapshot{}
+	}
+
+	return userSnapshot{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		FirstName:   stringValue(user.FirstName),
+		LastName:    stringValue(user.LastName),
+		Phone:       stringValue(user.Phone),
+		AvatarURL:   stringValue(user.AvatarURL),
+		LastLoginAt: timeValue(user.LastLoginAt),
+	}
+}
+
+// stringValue gets the value from a string pointer
+func stringValue(ptr *string) string {
 	if ptr != nil {
 		return *ptr
 	}
 	return ""
 }
+
+// timeValue gets the value from a time pointer
+func timeValue(ptr *time.Time) time.Time {
+	if ptr != nil {
+		return *ptr
+	}
+	return time.Time{}
+}
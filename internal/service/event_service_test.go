@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+func TestNewUserSnapshot(t *testing.T) {
+	lastLogin := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &model.User{
+		ID:          "user-1",
+		Username:    "testuser",
+		Email:       "test@example.com",
+		FirstName:   strPtr("Test"),
+		Phone:       strPtr("13800000000"),
+		LastLoginAt: &lastLogin,
+	}
+
+	snapshot := newUserSnapshot(user)
+	assert.Equal(t, "user-1", snapshot.ID)
+	assert.Equal(t, "Test", snapshot.FirstName)
+	assert.Equal(t, "", snapshot.LastName)
+	assert.Equal(t, "13800000000", snapshot.Phone)
+	assert.Equal(t, "", snapshot.AvatarURL)
+	assert.Equal(t, lastLogin, snapshot.LastLoginAt)
+
+	assert.Equal(t, userSnapshot{}, newUserSnapshot(nil))
+}
+
+func TestEventService_NilOptionalFields(t *testing.T) {
+	outbox := testutils.NewFakeOutboxRepository()
+	eventService := NewEventService(outbox, zap.NewNop())
+	ctx := context.Background()
+
+	// All optional pointer fields are nil
+	user := &model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}
+
+	require.NoError(t, eventService.PublishUserRegisteredEvent(ctx, user))
+	require.NoError(t, eventService.PublishUserLoggedInEvent(ctx, user, "127.0.0.1", "test-agent"))
+	require.NoError(t, eventService.PublishUserPasswordChangedEvent(ctx, user, "127.0.0.1"))
+	require.NoError(t, eventService.PublishUserStatusChangedEvent(ctx, user, "active", "inactive"))
+	require.NoError(t, eventService.PublishUserUpdatedEvent(ctx, user, map[string]interface{}{}))
+	require.NoError(t, eventService.PublishUserDeletedEvent(ctx, user))
+
+	events := outbox.EventsOfType(string(event.UserRegistered))
+	require.Len(t, events, 1)
+
+	var registered event.UserRegisteredEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &registered))
+	assert.Equal(t, "user-1", registered.UserID)
+	assert.Equal(t, "testuser", registered.Username)
+	assert.Equal(t, "", registered.FirstName)
+	assert.Equal(t, "", registered.LastName)
+}
+
+func TestEventService_UserUpdatedOmitsSensitiveFields(t *testing.T) {
+	outbox := testutils.NewFakeOutboxRepository()
+	eventService := NewEventService(outbox, zap.NewNop())
+
+	user := &model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}
+	require.NoError(t, eventService.PublishUserUpdatedEvent(context.Background(), user, map[string]interface{}{
+		"phone":         FieldChange(nil, "13800000000"),
+		"password_hash": FieldChange("old-hash", "new-hash"),
+	}))
+
+	events := outbox.EventsOfType(string(event.UserUpdated))
+	require.Len(t, events, 1)
+
+	var updated event.UserUpdatedEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &updated))
+	assert.Equal(t, map[string]interface{}{
+		"phone": map[string]interface{}{"old": nil, "new": "13800000000"},
+	}, updated.Changes)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+// Default argon2id parameters, used for unset config values
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+// argon2Params are the tunable argon2id parameters encoded in each hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and
+// verifies hashes of every supported algorithm, so stored hashes can be
+// migrated gradually
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+// NewPasswordHasher creates a password hasher from the security config.
+// An empty algorithm means bcrypt.
+func NewPasswordHasher(cfg config.SecurityConfig) (*PasswordHasher, error) {
+	algorithm := strings.ToLower(cfg.PasswordAlgorithm)
+	switch algorithm {
+	case "":
+		algorithm = HashAlgorithmBcrypt
+	case HashAlgorithmBcrypt, HashAlgorithmArgon2id:
+	default:
+		return nil, fmt.Errorf("unsupported password algorithm: %s", cfg.PasswordAlgorithm)
+	}
+
+	params := argon2Params{
+		memory:      cfg.Argon2.Memory,
+		iterations:  cfg.Argon2.Iterations,
+		parallelism: cfg.Argon2.Parallelism,
+	}
+	if params.memory == 0 {
+		params.memory = defaultArgon2Memory
+	}
+	if params.iterations == 0 {
+		params.iterations = defaultArgon2Iterations
+	}
+	if params.parallelism == 0 {
+		params.parallelism = defaultArgon2Parallelism
+	}
+
+	return &PasswordHasher{
+		algorithm:  algorithm,
+		bcryptCost: bcryptCost(cfg.BcryptCost),
+		argon2:     params,
+	}, nil
+}
+
+// Hash hashes password with the configured algorithm and parameters
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == HashAlgorithmArgon2id {
+		return h.hashArgon2id(password)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify reports whether password matches hash, whichever supported
+// algorithm produced it
+func (h *PasswordHasher) Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, "$"+HashAlgorithmArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether hash was produced by another algorithm or
+// with weaker parameters than the current config
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if h.algorithm == HashAlgorithmArgon2id {
+		params, _, _, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.memory < h.argon2.memory ||
+			params.iterations < h.argon2.iterations ||
+			params.parallelism < h.argon2.parallelism
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < h.bcryptCost
+}
+
+// ValidatePasswordHash checks that hash is a well-formed hash of algorithm,
+// so hashes imported from another system can be stored as is
+func ValidatePasswordHash(algorithm, hash string) error {
+	switch algorithm {
+	case HashAlgorithmBcrypt:
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("password_hash is not a valid bcrypt hash")
+		}
+	case HashAlgorithmArgon2id:
+		_, salt, key, err := decodeArgon2id(hash)
+		if err != nil || len(salt) == 0 || len(key) == 0 {
+			return fmt.Errorf("password_hash is not a valid argon2id hash")
+		}
+	default:
+		return fmt.Errorf("unsupported password algorithm: %s", algorithm)
+	}
+	return nil
+}
+
+// hashArgon2id hashes password in the PHC string format
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (h *PasswordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		HashAlgorithmArgon2id, argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id parses an argon2id hash in the PHC string format
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashAlgorithmArgon2id {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version: %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %s", parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// Cheap hashing parameters to keep the tests fast
+var (
+	bcrypt4        = config.SecurityConfig{BcryptCost: 4}
+	bcrypt5        = config.SecurityConfig{BcryptCost: 5}
+	argon2idWeak   = config.SecurityConfig{PasswordAlgorithm: HashAlgorithmArgon2id, Argon2: config.Argon2Config{Memory: 1024, Iterations: 1, Parallelism: 1}}
+	argon2idStrong = config.SecurityConfig{PasswordAlgorithm: HashAlgorithmArgon2id, Argon2: config.Argon2Config{Memory: 2048, Iterations: 2, Parallelism: 1}}
+)
+
+func mustHash(t *testing.T, cfg config.SecurityConfig, password string) string {
+	t.Helper()
+	hasher, err := NewPasswordHasher(cfg)
+	require.NoError(t, err)
+	hash, err := hasher.Hash(password)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestPasswordHasher(t *testing.T) {
+	bcryptHash := mustHash(t, bcrypt4, "Correct-Horse-42")
+	argonHash := mustHash(t, argon2idWeak, "Correct-Horse-42")
+	assert.True(t, strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	// Every configured algorithm verifies hashes of the others
+	for _, cfg := range []config.SecurityConfig{bcrypt5, argon2idStrong} {
+		hasher, err := NewPasswordHasher(cfg)
+		require.NoError(t, err)
+
+		for _, hash := range []string{bcryptHash, argonHash} {
+			assert.True(t, hasher.Verify("Correct-Horse-42", hash), hash)
+			assert.False(t, hasher.Verify("wrong-password", hash), hash)
+		}
+		assert.False(t, hasher.Verify("Correct-Horse-42", "$argon2id$garbage"))
+	}
+
+	_, err := NewPasswordHasher(config.SecurityConfig{PasswordAlgorithm: "md5"})
+	assert.EqualError(t, err, "unsupported password algorithm: md5")
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	bcrypt4Hash := mustHash(t, bcrypt4, "Correct-Horse-42")
+	bcrypt5Hash := mustHash(t, bcrypt5, "Correct-Horse-42")
+	weakHash := mustHash(t, argon2idWeak, "Correct-Horse-42")
+	strongHash := mustHash(t, argon2idStrong, "Correct-Horse-42")
+
+	tests := []struct {
+		name     string
+		cfg      config.SecurityConfig
+		hash     string
+		expected bool
+	}{
+		{name: "bcrypt at current cost", cfg: bcrypt5, hash: bcrypt5Hash, expected: false},
+		{name: "bcrypt at higher cost", cfg: bcrypt4, hash: bcrypt5Hash, expected: false},
+		{name: "bcrypt at lower cost", cfg: bcrypt5, hash: bcrypt4Hash, expected: true},
+		{name: "argon2id when bcrypt is configured", cfg: bcrypt5, hash: strongHash, expected: true},
+		{name: "bcrypt when argon2id is configured", cfg: argon2idStrong, hash: bcrypt5Hash, expected: true},
+		{name: "argon2id with weaker parameters", cfg: argon2idStrong, hash: weakHash, expected: true},
+		{name: "argon2id with current parameters", cfg: argon2idStrong, hash: strongHash, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(tt.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hasher.NeedsRehash(tt.hash))
+		})
+	}
+}
+
+func TestAuthService_Login_RehashesPassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    config.SecurityConfig
+		current   config.SecurityConfig
+		updateErr error
+		rehashed  bool
+	}{
+		{name: "bcrypt cost bump", stored: bcrypt4, current: bcrypt5, rehashed: true},
+		{name: "bcrypt to argon2id", stored: bcrypt4, current: argon2idWeak, rehashed: true},
+		{name: "argon2id parameter bump", stored: argon2idWeak, current: argon2idStrong, rehashed: true},
+		{name: "already current", stored: argon2idStrong, current: argon2idStrong, rehashed: false},
+		{name: "update fails", stored: bcrypt4, current: bcrypt5, updateErr: errors.New("connection refused"), rehashed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			logger := zap.NewNop()
+
+			oldHash := mustHash(t, tt.stored, "Correct-Horse-42")
+			user := &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: oldHash, IsActive: true}
+			repo.EXPECT().GetByEmail(gomock.Any(), "alice@example.com").Return(user, nil)
+
+			var (
+				storedHash string
+				saved      *model.User
+			)
+			if tt.rehashed || tt.updateErr != nil {
+				// The row on the primary is newer than the one logged in with
+				current := *user
+				current.EmailVerified = true
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(&current, nil)
+				repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) {
+						if tt.updateErr != nil {
+							return nil, tt.updateErr
+						}
+						storedHash = u.PasswordHash
+						saved = u
+						return u, nil
+					})
+			}
+
+			eventService := NewEventService(testutils.NewFakeOutboxRepository(), logger)
+			userService := newTestUserService(t, repo, logger)
+			s, err := NewAuthService(userService, eventService, testutils.FakeTransactor{}, jwt.NewJWT("test-secret", "test", 0), nil, nil, nil, nil,
+				&config.Config{Security: tt.current}, logger)
+			require.NoError(t, err)
+
+			loggedIn, token, err := s.Login(context.Background(), &dto.LoginRequest{Email: "alice@example.com", Password: "Correct-Horse-42"})
+			require.NoError(t, err)
+			assert.NotEmpty(t, token)
+
+			hasher, err := NewPasswordHasher(tt.current)
+			require.NoError(t, err)
+			if tt.rehashed {
+				assert.NotEqual(t, oldHash, storedHash)
+				assert.False(t, hasher.NeedsRehash(storedHash))
+				assert.True(t, hasher.Verify("Correct-Horse-42", storedHash))
+				assert.Equal(t, storedHash, loggedIn.PasswordHash)
+				assert.True(t, saved.EmailVerified)
+			} else {
+				assert.Equal(t, oldHash, loggedIn.PasswordHash)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordHash(t *testing.T) {
+	bcryptHash := mustHash(t, bcrypt4, "Correct-Horse-42")
+	argonHash := mustHash(t, argon2idWeak, "Correct-Horse-42")
+
+	assert.NoError(t, ValidatePasswordHash(HashAlgorithmBcrypt, bcryptHash))
+	assert.NoError(t, ValidatePasswordHash(HashAlgorithmArgon2id, argonHash))
+
+	assert.EqualError(t, ValidatePasswordHash(HashAlgorithmBcrypt, argonHash), "password_hash is not a valid bcrypt hash")
+	assert.EqualError(t, ValidatePasswordHash(HashAlgorithmArgon2id, bcryptHash), "password_hash is not a valid argon2id hash")
+	assert.EqualError(t, ValidatePasswordHash(HashAlgorithmArgon2id, "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5"), "password_hash is not a valid argon2id hash")
+	assert.EqualError(t, ValidatePasswordHash("md5", "5f4dcc3b5aa765d61d8327deb882cf99"), "unsupported password algorithm: md5")
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/kafka"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/kafka/producer"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// OutboxRelay publishes events stored in the outbox to Kafka
+type OutboxRelay struct {
+	outboxRepo   repository.OutboxRepository
+	transactor   repository.Transactor
+	kafkaService kafka.Service
+	config       config.OutboxConfig
+	logger       *zap.Logger
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewOutboxRelay creates a new outbox relay
+func NewOutboxRelay(
+	outboxRepo repository.OutboxRepository,
+	transactor repository.Transactor,
+	kafkaService kafka.Service,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo:   outboxRepo,
+		transactor:   transactor,
+		kafkaService: kafkaService,
+		config:       cfg.Outbox,
+		logger:       logger,
+	}
+}
+
+// Start starts polling the outbox in the background
+func (r *OutboxRelay) Start(ctx context.Context) {
+	if !r.config.Enabled {
+		r.logger.Info("Outbox relay is disabled")
+		return
+	}
+
+	// Kafka is optional and was down at startup; events stay in the outbox
+	if r.kafkaService == nil {
+		r.logger.Warn("Kafka unavailable, outbox events will not be relayed")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.RelayPending(ctx); err != nil {
+					r.logger.Error("Failed to relay outbox events", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	r.logger.Info("Outbox relay started",
+		zap.Duration("poll_interval", r.config.PollInterval),
+		zap.Int("batch_size", r.config.BatchSize),
+	)
+}
+
+// Stop stops the relay and waits for the current batch to finish
+func (r *OutboxRelay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// RelayPending publishes one batch of pending events and marks them as sent.
+// The batch is handed to the producer at once so Kafka acknowledges it in
+// aggregate; events of one user share a partition and keep their order.
+// Events that fail are marked failed and retried on a later poll. It returns
+// the number of events published.
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	sent := 0
+
+	err := r.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		events, err := r.outboxRepo.ListPending(txCtx, r.config.BatchSize, r.config.MaxAttempts)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		msgs := make([]*producer.Message, len(events))
+		for i, outboxEvent := range events {
+			msgs[i] = &producer.Message{
+				EventType: event.EventType(outboxEvent.EventType),
+				Key:       outboxEvent.AggregateID,
+				RequestID: outboxEvent.RequestID,
+				Payload:   []byte(outboxEvent.Payload),
+			}
+		}
+
+		err = r.publish(txCtx, events, msgs)
+		// Kafka is known to be down; leave the events pending without
+		// spending one of their attempts
+		if errors.Is(err, producer.ErrCircuitOpen) {
+			r.logger.Debug("Kafka producer circuit breaker is open, outbox events stay pending")
+			return nil
+		}
+
+		var batchErr *producer.BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			return err
+		}
+
+		for i, outboxEvent := range events {
+			if batchErr != nil {
+				if publishErr, failed := batchErr.Errors[i]; failed {
+					r.logger.Error("Failed to publish outbox event",
+						zap.String("outbox_id", outboxEvent.ID),
+						zap.String("event_type", outboxEvent.EventType),
+						zap.Int("attempts", outboxEvent.Attempts+1),
+						zap.Error(publishErr),
+					)
+					if err := r.outboxRepo.MarkFailed(txCtx, outboxEvent.ID, publishErr.Error()); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			if err := r.outboxRepo.MarkSent(txCtx, outboxEvent.ID, time.Now()); err != nil {
+				return err
+			}
+			sent++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if sent > 0 {
+		r.logger.Debug("Outbox events relayed", zap.Int("count", sent))
+	}
+
+	return sent, nil
+}
+
+// publish sends a batch of outbox events to Kafka inside one span
+func (r *OutboxRelay) publish(ctx context.Context, events []*model.OutboxEvent, msgs []*producer.Message) error {
+	ids := make([]string, len(events))
+	for i, outboxEvent := range events {
+		ids[i] = outboxEvent.ID
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "outbox.publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.StringSlice("outbox.ids", ids),
+			attribute.Int("outbox.batch_size", len(events)),
+		),
+	)
+	defer span.End()
+
+	if err := r.kafkaService.GetProducer().PublishBatch(ctx, msgs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/consumer"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/kafka/producer"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// fakeProducer records published messages and optionally fails, either every
+// message or only those with failKey
+type fakeProducer struct {
+	messages []*producer.Message
+	err      error
+	failKey  string
+}
+
+func (p *fakeProducer) PublishUserEvent(ctx context.Context, e interface{}) error      { return nil }
+func (p *fakeProducer) PublishUserEventAsync(ctx context.Context, e interface{}) error { return nil }
+func (p *fakeProducer) Close() error                                                   { return nil }
+
+func (p *fakeProducer) PublishMessage(ctx context.Context, msg *producer.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	if msg.Key == p.failKey {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+func (p *fakeProducer) PublishBatch(ctx context.Context, msgs []*producer.Message) error {
+	if errors.Is(p.err, producer.ErrCircuitOpen) {
+		return p.err
+	}
+
+	batchErr := &producer.BatchError{Total: len(msgs), Errors: make(map[int]error)}
+	for i, msg := range msgs {
+		if err := p.PublishMessage(ctx, msg); err != nil {
+			batchErr.Errors[i] = err
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// fakeKafkaService exposes a fake producer
+type fakeKafkaService struct {
+	producer *fakeProducer
+}
+
+func (s *fakeKafkaService) GetProducer() producer.Producer  { return s.producer }
+func (s *fakeKafkaService) GetConsumer() consumer.Consumer  { return nil }
+func (s *fakeKafkaService) Start(ctx context.Context) error { return nil }
+func (s *fakeKafkaService) Stop() error                     { return nil }
+
+func newTestOutboxRelay(repo *testutils.FakeOutboxRepository, prod *fakeProducer) *OutboxRelay {
+	cfg := &config.Config{Outbox: config.OutboxConfig{
+		Enabled:      true,
+		PollInterval: time.Second,
+		BatchSize:    10,
+		MaxAttempts:  3,
+	}}
+	return NewOutboxRelay(repo, testutils.FakeTransactor{}, &fakeKafkaService{producer: prod}, cfg, zap.NewNop())
+}
+
+func TestOutboxRelay_RelayPending(t *testing.T) {
+	repo := testutils.NewFakeOutboxRepository()
+	prod := &fakeProducer{}
+	relay := newTestOutboxRelay(repo, prod)
+
+	eventService := NewEventService(repo, zap.NewNop())
+	user := &model.User{ID: "user-1", Username: "testuser", Email: "test@example.com"}
+	require.NoError(t, eventService.PublishUserRegisteredEvent(context.Background(), user))
+
+	sent, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.Len(t, prod.messages, 1)
+	assert.Equal(t, event.UserRegistered, prod.messages[0].EventType)
+	assert.Equal(t, "user-1", prod.messages[0].Key)
+	assert.Contains(t, string(prod.messages[0].Payload), `"username":"testuser"`)
+
+	for _, e := range repo.Events {
+		assert.NotNil(t, e.SentAt)
+	}
+
+	// Sent events are not picked up again
+	sent, err = relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, prod.messages, 1)
+}
+
+func TestOutboxRelay_PublishFailure(t *testing.T) {
+	repo := testutils.NewFakeOutboxRepository()
+	prod := &fakeProducer{err: errors.New("broker unavailable")}
+	relay := newTestOutboxRelay(repo, prod)
+
+	require.NoError(t, repo.Create(context.Background(), &model.OutboxEvent{
+		ID:          "event-1",
+		EventType:   string(event.UserRegistered),
+		AggregateID: "user-1",
+		Payload:     `{}`,
+	}))
+
+	for i := 0; i < 5; i++ {
+		sent, err := relay.RelayPending(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+	}
+
+	// Attempts stop at the configured maximum and the event stays unsent
+	e := repo.Events["event-1"]
+	assert.Nil(t, e.SentAt)
+	assert.Equal(t, 3, e.Attempts)
+	require.NotNil(t, e.LastError)
+	assert.Equal(t, "broker unavailable", *e.LastError)
+}
+
+func TestOutboxRelay_CircuitOpen(t *testing.T) {
+	repo := testutils.NewFakeOutboxRepository()
+	prod := &fakeProducer{err: producer.ErrCircuitOpen}
+	relay := newTestOutboxRelay(repo, prod)
+
+	require.NoError(t, repo.Create(context.Background(), &model.OutboxEvent{
+		ID:          "event-1",
+		EventType:   string(event.UserRegistered),
+		AggregateID: "user-1",
+		Payload:     `{}`,
+	}))
+
+	for i := 0; i < 5; i++ {
+		sent, err := relay.RelayPending(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+	}
+
+	// The event waits for the breaker to close without spending attempts
+	e := repo.Events["event-1"]
+	assert.Nil(t, e.SentAt)
+	assert.Zero(t, e.Attempts)
+
+	prod.err = nil
+	sent, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestOutboxRelay_PartialFailure(t *testing.T) {
+	repo := testutils.NewFakeOutboxRepository()
+	prod := &fakeProducer{failKey: "user-2"}
+	relay := newTestOutboxRelay(repo, prod)
+
+	eventService := NewEventService(repo, zap.NewNop())
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		user := &model.User{ID: id, Username: id, Email: id + "@example.com"}
+		require.NoError(t, eventService.PublishUserRegisteredEvent(context.Background(), user))
+	}
+
+	sent, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Len(t, prod.messages, 2)
+
+	// Only the failed event is retried
+	for _, e := range repo.Events {
+		if e.AggregateID == "user-2" {
+			assert.Nil(t, e.SentAt)
+			assert.Equal(t, 1, e.Attempts)
+		} else {
+			assert.NotNil(t, e.SentAt)
+			assert.Zero(t, e.Attempts)
+		}
+	}
+
+	prod.failKey = ""
+	sent, err = relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
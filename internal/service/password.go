@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// commonPasswords lists well-known passwords rejected when the policy denies common passwords
+var commonPasswords = map[string]struct{}{
+	"123456":      {},
+	"12345678":    {},
+	"123456789":   {},
+	"1234567890":  {},
+	"password":    {},
+	"password1":   {},
+	"password12":  {},
+	"password123": {},
+	"passw0rd":    {},
+	"qwerty":      {},
+	"qwerty123":   {},
+	"qwertyuiop":  {},
+	"abc123":      {},
+	"11111111":    {},
+	"iloveyou":    {},
+	"admin":       {},
+	"admin123":    {},
+	"welcome":     {},
+	"welcome1":    {},
+	"letmein":     {},
+	"monkey":      {},
+	"dragon":      {},
+	"football":    {},
+	"baseball":    {},
+	"sunshine":    {},
+	"princess":    {},
+	"trustno1":    {},
+	"changeme":    {},
+}
+
+// PasswordPolicyError reports every rule a password failed
+type PasswordPolicyError struct {
+	Violations []dto.FieldError
+}
+
+// Error implements error
+func (e *PasswordPolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("password does not meet policy: %s", strings.Join(rules, ", "))
+}
+
+// FieldErrors returns the failed rules as field-level errors
+func (e *PasswordPolicyError) FieldErrors() []dto.FieldError {
+	return e.Violations
+}
+
+// PasswordPolicy checks new passwords against the configured rules
+type PasswordPolicy struct {
+	config config.PasswordPolicyConfig
+}
+
+// NewPasswordPolicy creates a new password policy
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) *PasswordPolicy {
+	return &PasswordPolicy{config: cfg}
+}
+
+// Validate checks password against the policy. field is the request field the
+// password came from and is reported with each violation. It returns a
+// *PasswordPolicyError if any rule fails.
+func (p *PasswordPolicy) Validate(field, password string) error {
+	var violations []dto.FieldError
+	violate := func(rule, message string) {
+		violations = append(violations, dto.FieldError{
+			Field:   field,
+			Rule:    rule,
+			Message: field + " " + message,
+		})
+	}
+
+	if p.config.MinLength > 0 && utf8.RuneCountInString(password) < p.config.MinLength {
+		violate("min", fmt.Sprintf("must be at least %d characters long", p.config.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.config.RequireUppercase && !hasUpper {
+		violate("uppercase", "must contain an uppercase letter")
+	}
+	if p.config.RequireLowercase && !hasLower {
+		violate("lowercase", "must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violate("digit", "must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violate("symbol", "must contain a symbol")
+	}
+	if p.config.DenyCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			violate("common", "is too common")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// bcryptCost returns the configured bcrypt cost clamped to bcrypt's valid range.
+// Zero means unset and uses bcrypt's default.
+func bcryptCost(cost int) int {
+	switch {
+	case cost == 0:
+		return bcrypt.DefaultCost
+	case cost < bcrypt.MinCost:
+		return bcrypt.MinCost
+	case cost > bcrypt.MaxCost:
+		return bcrypt.MaxCost
+	default:
+		return cost
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := NewPasswordPolicy(config.PasswordPolicyConfig{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DenyCommon:       true,
+	})
+
+	tests := []struct {
+		name          string
+		password      string
+		expectedRules []string
+	}{
+		{
+			name:     "strong password",
+			password: "Correct-Horse-42",
+		},
+		{
+			name:          "short password",
+			password:      "Ab1!",
+			expectedRules: []string{"min"},
+		},
+		{
+			name:          "missing character classes",
+			password:      "alllowercaseletters",
+			expectedRules: []string{"uppercase", "digit", "symbol"},
+		},
+		{
+			name:          "common password",
+			password:      "Password123",
+			expectedRules: []string{"symbol", "common"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate("password", tt.password)
+			if tt.expectedRules == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				assert.Equal(t, "password", v.Field)
+				assert.NotEmpty(t, v.Message)
+				rules = append(rules, v.Rule)
+			}
+			assert.Equal(t, tt.expectedRules, rules)
+		})
+	}
+}
+
+func TestPasswordPolicy_ZeroConfigAllowsAnything(t *testing.T) {
+	assert.NoError(t, NewPasswordPolicy(config.PasswordPolicyConfig{}).Validate("password", "x"))
+}
+
+func TestBcryptCost(t *testing.T) {
+	assert.Equal(t, bcrypt.DefaultCost, bcryptCost(0))
+	assert.Equal(t, bcrypt.MinCost, bcryptCost(1))
+	assert.Equal(t, bcrypt.MinCost, bcryptCost(-5))
+	assert.Equal(t, 12, bcryptCost(12))
+	assert.Equal(t, bcrypt.MaxCost, bcryptCost(100))
+}
+
+func TestAuthService_HashPasswordUsesConfiguredCost(t *testing.T) {
+	cfg := &config.Config{Security: config.SecurityConfig{BcryptCost: 5}}
+	s, err := NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	hash, err := s.hashPassword("Correct-Horse-42")
+	require.NoError(t, err)
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	require.NoError(t, err)
+	assert.Equal(t, 5, cost)
+	assert.True(t, s.verifyPassword("Correct-Horse-42", hash))
+}
+
+func TestAuthService_RejectsWeakPasswords(t *testing.T) {
+	cfg := &config.Config{Security: config.SecurityConfig{
+		Password: config.PasswordPolicyConfig{MinLength: 8, RequireDigit: true, DenyCommon: true},
+	}}
+	s, err := NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	_, _, _, err = s.Register(context.Background(), &dto.RegisterRequest{
+		Username: "newuser",
+		Email:    "new@example.com",
+		Password: "password",
+	})
+	var policyErr *PasswordPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []dto.FieldError{
+		{Field: "password", Rule: "digit", Message: "password must contain a digit"},
+		{Field: "password", Rule: "common", Message: "password is too common"},
+	}, policyErr.Violations)
+
+	err = s.ResetPassword(context.Background(), "reset-token", "short")
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, "new_password", policyErr.Violations[0].Field)
+	assert.Equal(t, "min", policyErr.Violations[0].Rule)
+}
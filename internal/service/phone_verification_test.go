@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// fakeSMSQueue records enqueued text messages
+type fakeSMSQueue struct {
+	sent []task.SMSPayload
+}
+
+func (q *fakeSMSQueue) EnqueueSMS(ctx context.Context, payload task.SMSPayload) error {
+	q.sent = append(q.sent, payload)
+	return nil
+}
+
+// lastCode returns the verification code in the last text message sent
+func (q *fakeSMSQueue) lastCode(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, q.sent)
+	code := regexp.MustCompile(`\d{6}`).FindString(q.sent[len(q.sent)-1].Body)
+	require.NotEmpty(t, code)
+	return code
+}
+
+type phoneVerificationEnv struct {
+	service *PhoneVerificationService
+	user    *model.User
+	sms     *fakeSMSQueue
+	outbox  *testutils.FakeOutboxRepository
+	clock   func(time.Duration)
+}
+
+func newPhoneVerificationEnv(t *testing.T) *phoneVerificationEnv {
+	phone := "+15551234567"
+	user := &model.User{ID: "user-1", Username: "alice", Phone: &phone, IsActive: true}
+
+	repo := mock.NewMockUserRepository(gomock.NewController(t))
+	repo.EXPECT().GetByID(gomock.Any(), user.ID).Return(user, nil).AnyTimes()
+	repo.EXPECT().Update(gomock.Any(), user).Return(user, nil).AnyTimes()
+
+	redis, mr := testutils.NewMiniRedis(t)
+	outbox := testutils.NewFakeOutboxRepository()
+	sms := &fakeSMSQueue{}
+	cfg := &config.Config{Auth: config.AuthConfig{PhoneVerification: config.PhoneVerificationConfig{
+		CodeLength:  6,
+		CodeTTL:     5 * time.Minute,
+		MaxAttempts: 3,
+	}}}
+	logger := zap.NewNop()
+
+	return &phoneVerificationEnv{
+		service: NewPhoneVerificationService(repo, NewEventService(outbox, logger), testutils.FakeTransactor{}, redis, sms, cfg, logger),
+		user:    user,
+		sms:     sms,
+		outbox:  outbox,
+		clock:   mr.FastForward,
+	}
+}
+
+func TestPhoneVerificationService_SendAndVerify(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	require.Len(t, env.sms.sent, 1)
+	assert.Equal(t, "+15551234567", env.sms.sent[0].To)
+
+	user, err := env.service.VerifyCode(ctx, env.user.ID, env.sms.lastCode(t))
+	require.NoError(t, err)
+	assert.True(t, user.PhoneVerified)
+	assert.Len(t, env.outbox.EventsOfType(string(event.UserUpdated)), 1)
+
+	// Verified numbers need no more codes
+	assert.ErrorIs(t, env.service.SendCode(ctx, env.user.ID), apperrors.ErrPhoneAlreadyVerified)
+}
+
+func TestPhoneVerificationService_WrongCode(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	code := env.sms.lastCode(t)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	_, err := env.service.VerifyCode(ctx, env.user.ID, wrong)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidVerificationCode)
+	assert.False(t, env.user.PhoneVerified)
+
+	// The right code still works after a wrong guess
+	_, err = env.service.VerifyCode(ctx, env.user.ID, code)
+	require.NoError(t, err)
+	assert.True(t, env.user.PhoneVerified)
+}
+
+func TestPhoneVerificationService_TooManyWrongCodes(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	code := env.sms.lastCode(t)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := env.service.VerifyCode(ctx, env.user.ID, wrong)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidVerificationCode)
+	}
+
+	// The code is discarded once the attempts run out
+	_, err := env.service.VerifyCode(ctx, env.user.ID, code)
+	assert.ErrorIs(t, err, apperrors.ErrVerificationCodeExpired)
+}
+
+func TestPhoneVerificationService_Expiry(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	code := env.sms.lastCode(t)
+
+	env.clock(5*time.Minute + time.Second)
+
+	_, err := env.service.VerifyCode(ctx, env.user.ID, code)
+	assert.ErrorIs(t, err, apperrors.ErrVerificationCodeExpired)
+	assert.False(t, env.user.PhoneVerified)
+}
+
+func TestPhoneVerificationService_NewCodeReplacesOld(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	first := env.sms.lastCode(t)
+	require.NoError(t, env.service.SendCode(ctx, env.user.ID))
+	second := env.sms.lastCode(t)
+
+	if first != second {
+		_, err := env.service.VerifyCode(ctx, env.user.ID, first)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidVerificationCode)
+	}
+	_, err := env.service.VerifyCode(ctx, env.user.ID, second)
+	require.NoError(t, err)
+}
+
+func TestPhoneVerificationService_NoPhone(t *testing.T) {
+	env := newPhoneVerificationEnv(t)
+	env.user.Phone = nil
+
+	assert.ErrorIs(t, env.service.SendCode(context.Background(), env.user.ID), apperrors.ErrPhoneNotSet)
+	assert.Empty(t, env.sms.sent)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RoleService manages the roles assigned to users. Roles are embedded in
+// tokens, so changes apply to tokens issued afterwards.
+type RoleService struct {
+	userService *UserService
+	roleRepo    repository.RoleRepository
+	transactor  repository.Transactor
+	logger      *zap.Logger
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(
+	userService *UserService,
+	roleRepo repository.RoleRepository,
+	transactor repository.Transactor,
+	logger *zap.Logger,
+) *RoleService {
+	return &RoleService{
+		userService: userService,
+		roleRepo:    roleRepo,
+		transactor:  transactor,
+		logger:      logger,
+	}
+}
+
+// Load sets the roles assigned to user. A nil service leaves them unset, so
+// the user only has the implicit and legacy roles.
+func (s *RoleService) Load(ctx context.Context, user *model.User) error {
+	if s == nil {
+		return nil
+	}
+
+	roles, err := s.roleRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	user.Roles = roles
+	return nil
+}
+
+// GetUserRoles returns the effective roles of the user with id
+func (s *RoleService) GetUserRoles(ctx context.Context, id string) ([]string, error) {
+	user, err := s.userService.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Load(ctx, user); err != nil {
+		return nil, err
+	}
+	return user.GetRoles(), nil
+}
+
+// AssignRole gives role to the user with id and returns the user's effective roles
+func (s *RoleService) AssignRole(ctx context.Context, id, role string) ([]string, error) {
+	return s.changeRole(ctx, id, role, true)
+}
+
+// RevokeRole takes role away from the user with id and returns the user's
+// effective roles. The implicit user role cannot be revoked.
+func (s *RoleService) RevokeRole(ctx context.Context, id, role string) ([]string, error) {
+	if role == model.RoleUser {
+		return nil, fmt.Errorf("%w: %s", apperrors.ErrRoleNotRevocable, role)
+	}
+	return s.changeRole(ctx, id, role, false)
+}
+
+// changeRole assigns or revokes role, keeping the legacy IsAdmin flag in step
+// with the admin role
+func (s *RoleService) changeRole(ctx context.Context, id, role string, assign bool) ([]string, error) {
+	if !model.IsValidRole(role) {
+		return nil, fmt.Errorf("%w: %s", apperrors.ErrInvalidRole, role)
+	}
+
+	var user *model.User
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		user, err = s.userService.userRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		if assign {
+			err = s.roleRepo.Assign(txCtx, id, role)
+		} else {
+			err = s.roleRepo.Revoke(txCtx, id, role)
+		}
+		if err != nil {
+			return err
+		}
+
+		if role == model.RoleAdmin && user.IsAdmin != assign {
+			user.IsAdmin = assign
+			if _, err := s.userService.userRepo.Update(txCtx, user); err != nil {
+				return err
+			}
+		}
+
+		return s.Load(txCtx, user)
+	})
+	if err != nil {
+		s.logger.Error("Failed to change user role",
+			zap.String("user_id", id),
+			zap.String("role", role),
+			zap.Bool("assign", assign),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	s.userService.invalidateUserCache(ctx, id)
+
+	s.logger.Info("User role changed",
+		zap.String("user_id", id),
+		zap.String("role", role),
+		zap.Bool("assign", assign),
+	)
+
+	return user.GetRoles(), nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+func TestRoleService_AssignAndRevoke(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	user := &model.User{ID: "user-1", Username: "alice"}
+	repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil).AnyTimes()
+
+	// Only the admin role is mirrored to the legacy flag
+	var saved []bool
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) {
+			saved = append(saved, u.IsAdmin)
+			return u, nil
+		}).Times(2)
+
+	logger := zap.NewNop()
+	roles := testutils.NewFakeRoleRepository()
+	s := NewRoleService(newTestUserService(t, repo, logger), roles, testutils.FakeTransactor{}, logger)
+	ctx := context.Background()
+
+	got, err := s.AssignRole(ctx, "user-1", model.RoleSupport)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support", "user"}, got)
+
+	got, err = s.AssignRole(ctx, "user-1", model.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "support", "user"}, got)
+	assert.True(t, user.IsAdmin)
+
+	got, err = s.RevokeRole(ctx, "user-1", model.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support", "user"}, got)
+	assert.False(t, user.IsAdmin)
+	assert.Equal(t, []bool{true, false}, saved)
+
+	got, err = s.GetUserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support", "user"}, got)
+}
+
+func TestRoleService_InvalidChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	logger := zap.NewNop()
+	s := NewRoleService(newTestUserService(t, repo, logger), testutils.NewFakeRoleRepository(), testutils.FakeTransactor{}, logger)
+
+	_, err := s.AssignRole(context.Background(), "user-1", "superuser")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidRole)
+	assert.EqualError(t, err, "invalid role: superuser")
+
+	_, err = s.RevokeRole(context.Background(), "user-1", model.RoleUser)
+	assert.ErrorIs(t, err, apperrors.ErrRoleNotRevocable)
+}
+
+func TestUser_GetRoles_LegacyAdmin(t *testing.T) {
+	assert.Equal(t, []string{"user"}, (&model.User{}).GetRoles())
+	assert.Equal(t, []string{"admin", "user"}, (&model.User{IsAdmin: true}).GetRoles())
+	assert.Equal(t, []string{"admin", "user"}, (&model.User{IsAdmin: true, Roles: []string{"admin"}}).GetRoles())
+}
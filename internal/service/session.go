@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/metrics"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"go.uber.org/zap"
+)
+
+// defaultSessionTTL is used when no JWT expiry is configured
+const defaultSessionTTL = 24 * time.Hour
+
+// SessionStore keeps a record of each session and the client that started
+// it, for administrators to review
+type SessionStore interface {
+	InsertSession(ctx context.Context, session *database.UserSession) error
+	ListActiveSessions(ctx context.Context, userID string, now time.Time) ([]*database.UserSession, error)
+	DeactivateSessions(ctx context.Context, userID string, ids []string) error
+}
+
+// SessionLimiter tracks each user's active sessions and enforces the
+// concurrent session limit of their plan. When a new session exceeds the
+// limit the oldest sessions are signed out.
+type SessionLimiter struct {
+	redis  *cache.Redis
+	store  SessionStore
+	limits map[string]int
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+// NewSessionLimiter creates a new session limiter. store may be nil when
+// MongoDB is not connected, in which case sessions are not recorded.
+func NewSessionLimiter(redis *cache.Redis, store SessionStore, cfg *config.Config, logger *zap.Logger) *SessionLimiter {
+	ttl := cfg.JWT.Expiry
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+
+	return &SessionLimiter{
+		redis:  redis,
+		store:  store,
+		limits: cfg.Auth.SessionLimits,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Limit returns the maximum number of concurrent sessions for plan. Users
+// without a plan, or on a plan with no configured limit, get the free plan's
+// limit. Zero means unlimited.
+func (s *SessionLimiter) Limit(plan model.UserPlan) int {
+	if limit, ok := s.limits[string(plan)]; ok {
+		return limit
+	}
+	return s.limits[string(model.UserPlanFree)]
+}
+
+// Start records token as a new session of user, signing out the user's
+// oldest sessions if the plan's limit is exceeded. A nil limiter does nothing.
+func (s *SessionLimiter) Start(ctx context.Context, user *model.User, token string) error {
+	if s == nil {
+		return nil
+	}
+
+	plan := user.Plan
+	if plan == "" {
+		plan = model.UserPlanFree
+	}
+
+	now := time.Now()
+	evicted, err := s.redis.AddSession(ctx, user.ID, sessionID(token), now, s.ttl, s.Limit(plan))
+	if err != nil {
+		return err
+	}
+	s.record(ctx, user.ID, token, now)
+
+	if len(evicted) > 0 {
+		metrics.SessionEvictionsTotal.WithLabelValues(string(plan)).Add(float64(len(evicted)))
+		s.logger.Info("Session limit reached, signed out oldest sessions",
+			zap.String("user_id", user.ID),
+			zap.String("plan", string(plan)),
+			zap.Int("evicted", len(evicted)),
+		)
+	}
+
+	return nil
+}
+
+// IsActive checks if token is still an active session of userID and has not
+// been blacklisted. A nil limiter treats every session as active.
+func (s *SessionLimiter) IsActive(ctx context.Context, userID, token string) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	return s.isActive(ctx, userID, sessionID(token), time.Now())
+}
+
+// isActive checks if the session with ID id is still active in Redis and has
+// not been blacklisted
+func (s *SessionLimiter) isActive(ctx context.Context, userID, id string, now time.Time) (bool, error) {
+	blacklisted, err := s.redis.IsTokenBlacklisted(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if blacklisted {
+		return false, nil
+	}
+	return s.redis.IsSessionActive(ctx, userID, id, now, s.ttl)
+}
+
+// Blacklist invalidates token for the rest of its lifetime. A nil limiter
+// does nothing.
+func (s *SessionLimiter) Blacklist(ctx context.Context, token string) error {
+	if s == nil {
+		return nil
+	}
+	return s.redis.BlacklistToken(ctx, sessionID(token), s.ttl)
+}
+
+// List returns the start times of userID's active sessions, oldest first. A
+// nil limiter tracks no sessions.
+func (s *SessionLimiter) List(ctx context.Context, userID string) ([]time.Time, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return s.redis.ListSessionStarts(ctx, userID, time.Now(), s.ttl)
+}
+
+// RevokeOthers signs out every session of userID except the one of token. It
+// returns the number of sessions signed out. A nil limiter does nothing.
+func (s *SessionLimiter) RevokeOthers(ctx context.Context, userID, token string) (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+	return s.redis.RemoveOtherSessions(ctx, userID, sessionID(token))
+}
+
+// record stores the session of token in the session store. Failures are only
+// logged since the store is for review and does not gate access.
+func (s *SessionLimiter) record(ctx context.Context, userID, token string, now time.Time) {
+	if s.store == nil {
+		return
+	}
+
+	err := s.store.InsertSession(ctx, &database.UserSession{
+		ID:        sessionID(token),
+		UserID:    userID,
+		IP:        clientIP(ctx),
+		UserAgent: userAgent(ctx),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+		IsActive:  true,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record session",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListActive returns the recorded active sessions of userID, newest first.
+// Recorded sessions that have since been evicted, revoked or blacklisted are
+// left out and marked inactive in the store. Without a session store it fails
+// with apperrors.ErrUserDataUnavailable.
+func (s *SessionLimiter) ListActive(ctx context.Context, userID string) ([]*database.UserSession, error) {
+	if s == nil || s.store == nil {
+		return nil, apperrors.ErrUserDataUnavailable
+	}
+
+	now := time.Now()
+	sessions, err := s.store.ListActiveSessions(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	active := sessions[:0]
+	var ended []string
+	for _, session := range sessions {
+		ok, err := s.isActive(ctx, userID, session.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			active = append(active, session)
+		} else {
+			ended = append(ended, session.ID)
+		}
+	}
+
+	if len(ended) > 0 {
+		if err := s.store.DeactivateSessions(ctx, userID, ended); err != nil {
+			s.logger.Warn("Failed to mark ended sessions inactive",
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeAll signs out every session of userID, blacklisting the token of each
+// recorded session until it expires. Sessions missing from the store, e.g.
+// started while it was down, are signed out too. It returns the number of
+// recorded sessions revoked. Without a session store it fails with
+// apperrors.ErrUserDataUnavailable.
+func (s *SessionLimiter) RevokeAll(ctx context.Context, userID string) (int, error) {
+	if s == nil || s.store == nil {
+		return 0, apperrors.ErrUserDataUnavailable
+	}
+
+	now := time.Now()
+	sessions, err := s.store.ListActiveSessions(ctx, userID, now)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		if err := s.redis.BlacklistToken(ctx, session.ID, session.ExpiresAt.Sub(now)); err != nil {
+			return 0, err
+		}
+		ids[i] = session.ID
+	}
+
+	// An empty session ID to keep removes them all
+	if _, err := s.redis.RemoveOtherSessions(ctx, userID, ""); err != nil {
+		return 0, err
+	}
+
+	if err := s.store.DeactivateSessions(ctx, userID, ids); err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Signed out all sessions",
+		zap.String("user_id", userID),
+		zap.Int("revoked", len(sessions)),
+	)
+
+	return len(sessions), nil
+}
+
+// sessionID identifies a session by a hash of its token so tokens are not stored
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
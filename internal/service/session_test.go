@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/mock"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"github.com/zhwjimmy/user-center/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+func newTestSessionLimiter(t *testing.T) *SessionLimiter {
+	redis, _ := testutils.NewMiniRedis(t)
+	cfg := &config.Config{Auth: config.AuthConfig{
+		SessionLimits: map[string]int{"free": 1, "pro": 5},
+	}}
+	return NewSessionLimiter(redis, nil, cfg, zap.NewNop())
+}
+
+func TestSessionLimiter_Limit(t *testing.T) {
+	s := newTestSessionLimiter(t)
+
+	assert.Equal(t, 1, s.Limit(model.UserPlanFree))
+	assert.Equal(t, 5, s.Limit(model.UserPlanPro))
+	assert.Equal(t, 1, s.Limit(""), "no plan falls back to free")
+	assert.Equal(t, 1, s.Limit("enterprise"), "unknown plan falls back to free")
+}
+
+func TestSessionLimiter_EnforcesPlanLimit(t *testing.T) {
+	tests := []struct {
+		plan   model.UserPlan
+		limit  int
+		logins int
+	}{
+		{plan: model.UserPlanFree, limit: 1, logins: 3},
+		{plan: model.UserPlanPro, limit: 5, logins: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.plan), func(t *testing.T) {
+			s := newTestSessionLimiter(t)
+			ctx := context.Background()
+			user := &model.User{ID: "user-1", Plan: tt.plan}
+
+			tokens := make([]string, tt.logins)
+			for i := range tokens {
+				tokens[i] = fmt.Sprintf("token-%d", i)
+				require.NoError(t, s.Start(ctx, user, tokens[i]))
+			}
+
+			evicted := tt.logins - tt.limit
+			for i, token := range tokens {
+				active, err := s.IsActive(ctx, user.ID, token)
+				require.NoError(t, err)
+				assert.Equal(t, i >= evicted, active, "session %d", i)
+			}
+		})
+	}
+}
+
+func TestSessionLimiter_Unlimited(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	s := NewSessionLimiter(redis, nil, &config.Config{}, zap.NewNop())
+	ctx := context.Background()
+	user := &model.User{ID: "user-1"}
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Start(ctx, user, fmt.Sprintf("token-%d", i)))
+	}
+
+	active, err := s.IsActive(ctx, user.ID, "token-0")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestSessionLimiter_Nil(t *testing.T) {
+	var s *SessionLimiter
+
+	require.NoError(t, s.Start(context.Background(), &model.User{ID: "user-1"}, "token"))
+	active, err := s.IsActive(context.Background(), "user-1", "token")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestSessionLimiter_RevokeAll(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	store := testutils.NewFakeSessionStore()
+	s := NewSessionLimiter(redis, store, &config.Config{}, zap.NewNop())
+	ctx := context.Background()
+	alice := &model.User{ID: "user-1"}
+	bob := &model.User{ID: "user-2"}
+
+	for _, token := range []string{"alice-1", "alice-2"} {
+		require.NoError(t, s.Start(ctx, alice, token))
+	}
+	require.NoError(t, s.Start(ctx, bob, "bob-1"))
+
+	sessions, err := s.ListActive(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, sessionID("alice-2"), sessions[0].ID, "newest first")
+
+	revoked, err := s.RevokeAll(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+
+	for _, token := range []string{"alice-1", "alice-2"} {
+		active, err := s.IsActive(ctx, alice.ID, token)
+		require.NoError(t, err)
+		assert.False(t, active, token)
+
+		blacklisted, err := redis.IsTokenBlacklisted(ctx, sessionID(token))
+		require.NoError(t, err)
+		assert.True(t, blacklisted, token)
+	}
+
+	sessions, err = s.ListActive(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// Other users stay signed in
+	active, err := s.IsActive(ctx, bob.ID, "bob-1")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestSessionLimiter_ListActive_LeavesOutEndedSessions(t *testing.T) {
+	redis, _ := testutils.NewMiniRedis(t)
+	store := testutils.NewFakeSessionStore()
+	cfg := &config.Config{Auth: config.AuthConfig{SessionLimits: map[string]int{"free": 2}}}
+	s := NewSessionLimiter(redis, store, cfg, zap.NewNop())
+	ctx := context.Background()
+	alice := &model.User{ID: "user-1"}
+
+	// alice-1 is evicted by the limit, alice-2 signed out, alice-4 revoked
+	for _, token := range []string{"alice-1", "alice-2", "alice-3"} {
+		require.NoError(t, s.Start(ctx, alice, token))
+	}
+	require.NoError(t, s.Blacklist(ctx, "alice-2"))
+	require.NoError(t, s.Start(ctx, alice, "alice-4"))
+	_, err := s.RevokeOthers(ctx, alice.ID, "alice-3")
+	require.NoError(t, err)
+
+	sessions, err := s.ListActive(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, sessionID("alice-3"), sessions[0].ID)
+
+	// Ended sessions are marked inactive in the store too
+	recorded, err := store.ListActiveSessions(ctx, alice.ID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, sessionID("alice-3"), recorded[0].ID)
+}
+
+func TestSessionLimiter_WithoutStore(t *testing.T) {
+	s := newTestSessionLimiter(t)
+
+	_, err := s.ListActive(context.Background(), "user-1")
+	assert.ErrorIs(t, err, apperrors.ErrUserDataUnavailable)
+	_, err = s.RevokeAll(context.Background(), "user-1")
+	assert.ErrorIs(t, err, apperrors.ErrUserDataUnavailable)
+}
+
+func TestAuthService_ChangePassword_SignsOutOtherSessions(t *testing.T) {
+	tests := []struct {
+		name         string
+		logoutOthers bool
+	}{
+		{name: "enabled", logoutOthers: true},
+		{name: "disabled", logoutOthers: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			redis, _ := testutils.NewMiniRedis(t)
+			logger := zap.NewNop()
+			ctx := context.Background()
+
+			cfg := &config.Config{Security: config.SecurityConfig{BcryptCost: 4, LogoutOthersOnPasswordChange: tt.logoutOthers}}
+			sessions := NewSessionLimiter(redis, nil, cfg, logger)
+			s, err := NewAuthService(newTestUserService(t, repo, logger), NewEventService(testutils.NewFakeOutboxRepository(), logger),
+				testutils.FakeTransactor{}, jwt.NewJWT("test-secret", "test", 0), sessions, nil, nil, nil, cfg, logger)
+			require.NoError(t, err)
+
+			hash, err := s.hashPassword("Old-Password-1")
+			require.NoError(t, err)
+			user := &model.User{ID: "user-1", Email: "alice@example.com", PasswordHash: hash, IsActive: true}
+			repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil)
+			repo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) { return u, nil })
+
+			tokens := []string{"token-laptop", "token-phone", "token-tablet"}
+			for _, token := range tokens {
+				require.NoError(t, sessions.Start(ctx, user, token))
+			}
+
+			err = s.ChangePassword(ctx, "user-1", "token-phone", &dto.ChangePasswordRequest{
+				OldPassword: "Old-Password-1",
+				NewPassword: "New-Password-2",
+			})
+			require.NoError(t, err)
+
+			for _, token := range tokens {
+				active, err := sessions.IsActive(ctx, "user-1", token)
+				require.NoError(t, err)
+				assert.Equal(t, !tt.logoutOthers || token == "token-phone", active, token)
+			}
+		})
+	}
+}
+
+func TestSessionLimiter_Blacklist(t *testing.T) {
+	s := newTestSessionLimiter(t)
+	ctx := context.Background()
+	user := &model.User{ID: "user-1", Plan: model.UserPlanPro}
+
+	require.NoError(t, s.Start(ctx, user, "token-1"))
+	require.NoError(t, s.Start(ctx, user, "token-2"))
+	require.NoError(t, s.Blacklist(ctx, "token-1"))
+
+	active, err := s.IsActive(ctx, user.ID, "token-1")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	active, err = s.IsActive(ctx, user.ID, "token-2")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "deleted", password: "Password-1"},
+		{name: "wrong password", password: "Password-2", wantErr: "invalid password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			redis, _ := testutils.NewMiniRedis(t)
+			outbox := testutils.NewFakeOutboxRepository()
+			logger := zap.NewNop()
+			ctx := context.Background()
+
+			cfg := &config.Config{Security: config.SecurityConfig{BcryptCost: 4}}
+			sessions := NewSessionLimiter(redis, nil, cfg, logger)
+			events := NewEventService(outbox, logger)
+			userService := NewUserService(repo, events, testutils.FakeTransactor{}, redis, logger)
+			s, err := NewAuthService(userService, events,
+				testutils.FakeTransactor{}, jwt.NewJWT("test-secret", "test", 0), sessions, nil, nil, nil, cfg, logger)
+			require.NoError(t, err)
+
+			hash, err := s.hashPassword("Password-1")
+			require.NoError(t, err)
+			user := &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com", PasswordHash: hash, IsActive: true}
+			repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(user, nil).AnyTimes()
+			if tt.wantErr == "" {
+				repo.EXPECT().Delete(gomock.Any(), "user-1").Return(nil)
+			}
+
+			tokens := []string{"token-laptop", "token-phone"}
+			for _, token := range tokens {
+				require.NoError(t, sessions.Start(ctx, user, token))
+			}
+
+			err = s.DeleteAccount(ctx, "user-1", "token-phone", &dto.DeleteAccountRequest{Password: tt.password})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Empty(t, outbox.EventsOfType(string(event.UserDeleted)))
+			} else {
+				require.NoError(t, err)
+				assert.Len(t, outbox.EventsOfType(string(event.UserDeleted)), 1)
+			}
+
+			// Every session of a deleted account is invalidated
+			for _, token := range tokens {
+				active, err := sessions.IsActive(ctx, "user-1", token)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantErr != "", active, token)
+			}
+		})
+	}
+}
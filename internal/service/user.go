@@ -2,28 +2,47 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/database"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/metrics"
 	"github.com/zhwjimmy/user-center/internal/model"
 	"github.com/zhwjimmy/user-center/internal/repository"
 	"go.uber.org/zap"
 )
 
+// userCacheTTL bounds how long a cached user is served
+const userCacheTTL = 10 * time.Minute
+
 // UserService handles user business logic
 type UserService struct {
-	userRepo repository.UserRepository
-	logger   *zap.Logger
+	userRepo     repository.UserRepository
+	eventService *EventService
+	transactor   repository.Transactor
+	cache        *cache.Redis
+	logger       *zap.Logger
 }
 
 // NewUserService creates a new user service
 func NewUserService(
 	userRepo repository.UserRepository,
+	eventService *EventService,
+	transactor repository.Transactor,
+	cache *cache.Redis,
 	logger *zap.Logger,
 ) *UserService {
 	return &UserService{
-		userRepo: userRepo,
-		logger:   logger,
+		userRepo:     userRepo,
+		eventService: eventService,
+		transactor:   transactor,
+		cache:        cache,
+		logger:       logger,
 	}
 }
 
@@ -43,6 +62,7 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*model.User,
 
 // GetUserByEmail retrieves a user by email
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	email = model.NormalizeEmail(email)
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		s.logger.Error("Failed to get user by email",
@@ -55,6 +75,24 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.
 	return user, nil
 }
 
+// IsUsernameAvailable reports whether no user has username
+func (s *UserService) IsUsernameAvailable(ctx context.Context, username string) (bool, error) {
+	exists, err := s.userRepo.ExistsByUsername(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// IsEmailAvailable reports whether no user has email, compared as registration stores it
+func (s *UserService) IsEmailAvailable(ctx context.Context, email string) (bool, error) {
+	exists, err := s.userRepo.ExistsByEmail(ctx, model.NormalizeEmail(email))
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
 // GetUserByUsername retrieves a user by username
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	user, err := s.userRepo.GetByUsername(ctx, username)
@@ -69,21 +107,53 @@ func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*
 	return user, nil
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
-	// Check if user with email already exists
-	existingUser, err := s.userRepo.GetByEmail(ctx, user.Email)
-	if err == nil && existingUser != nil {
-		return nil, fmt.Errorf("user with email %s already exists", user.Email)
+// GetUserByPhone retrieves a user by phone number
+func (s *UserService) GetUserByPhone(ctx context.Context, phone string) (*model.User, error) {
+	phone = model.NormalizePhone(phone)
+	user, err := s.userRepo.GetByPhone(ctx, phone)
+	if err != nil {
+		s.logger.Error("Failed to get user by phone",
+			zap.String("phone", phone),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	// Check if user with username already exists
-	existingUser, err = s.userRepo.GetByUsername(ctx, user.Username)
-	if err == nil && existingUser != nil {
-		return nil, fmt.Errorf("user with username %s already exists", user.Username)
+	return user, nil
+}
+
+// CreateUser creates a new user. The uniqueness checks and the insert run in
+// one transaction; the unique indexes on users guard against concurrent inserts.
+func (s *UserService) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	user.Email = model.NormalizeEmail(user.Email)
+	if user.Phone != nil && *user.Phone != "" {
+		phone := model.NormalizePhone(*user.Phone)
+		user.Phone = &phone
 	}
 
-	createdUser, err := s.userRepo.Create(ctx, user)
+	var createdUser *model.User
+	err := s.userRepo.WithTransaction(ctx, func(txRepo repository.UserRepository) error {
+		// These checks name the field that is taken. They can race with a
+		// concurrent create, in which case Create fails on the unique
+		// constraints with a plain ErrUserExists.
+		existing, err := txRepo.GetByEmail(ctx, user.Email)
+		if err := uniqueFieldFree(existing, err, "email", user.Email); err != nil {
+			return err
+		}
+		existing, err = txRepo.GetByUsername(ctx, user.Username)
+		if err := uniqueFieldFree(existing, err, "username", user.Username); err != nil {
+			return err
+		}
+		if user.Phone != nil && *user.Phone != "" {
+			existing, err = txRepo.GetByPhone(ctx, *user.Phone)
+			if err := uniqueFieldFree(existing, err, "phone", *user.Phone); err != nil {
+				return err
+			}
+		}
+
+		createdUser, err = txRepo.Create(ctx, user)
+		return err
+	})
 	if err != nil {
 		s.logger.Error("Failed to create user",
 			zap.String("email", user.Email),
@@ -102,46 +172,184 @@ func (s *UserService) CreateUser(ctx context.Context, user *model.User) (*model.
 	return createdUser, nil
 }
 
-// UpdateUser updates user information
+// uniqueFieldFree interprets looking up a user by the unique field with value:
+// nil when no user was found, a *apperrors.UserExistsError when one was, or
+// the lookup error
+func uniqueFieldFree(existing *model.User, err error, field, value string) error {
+	if errors.Is(err, apperrors.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return &apperrors.UserExistsError{Field: field, Value: value}
+	}
+	return nil
+}
+
+// UpdateUser updates user information and publishes a user updated event
+// carrying the fields that changed. An update that changes nothing is not
+// saved and publishes no event. A new username must not be taken.
 func (s *UserService) UpdateUser(ctx context.Context, id string, req *dto.UpdateUserRequest) (*model.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
+	defer metrics.ObserveOperation("update_user", time.Now())
+
+	var updatedUser *model.User
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.userRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		// Update fields, keyed by their JSON name in the change set
+		changes := make(map[string]interface{})
+		if req.Username != nil && *req.Username != user.Username {
+			exists, err := s.userRepo.ExistsByUsername(txCtx, *req.Username)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return &apperrors.UserExistsError{Field: "username", Value: *req.Username}
+			}
+			changes["username"] = FieldChange(user.Username, *req.Username)
+			user.Username = *req.Username
+		}
+		updateField(&user.FirstName, req.FirstName, "first_name", changes)
+		updateField(&user.LastName, req.LastName, "last_name", changes)
+		updateField(&user.AvatarURL, req.Avatar, "avatar_url", changes)
+		updateField(&user.Phone, req.Phone, "phone", changes)
+		if _, ok := changes["phone"]; ok && user.PhoneVerified {
+			// A new number has to be verified again
+			user.PhoneVerified = false
+			changes["phone_verified"] = FieldChange(true, false)
+		}
+
+		if len(changes) == 0 {
+			updatedUser = user
+			return nil
+		}
+
+		updatedUser, err = s.userRepo.Update(txCtx, user)
+		if err != nil {
+			s.logger.Error("Failed to update user",
+				zap.String("user_id", id),
+				zap.Error(err),
+			)
+			return err
+		}
+
+		return s.eventService.PublishUserUpdatedEvent(txCtx, updatedUser, changes)
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.invalidateUserCache(ctx, id)
+
+	s.logger.Info("User updated successfully",
+		zap.String("user_id", updatedUser.ID),
+	)
+
+	return updatedUser, nil
+}
 
-	// Update fields
-	if req.FirstName != nil {
-		user.FirstName = req.FirstName
+// updateField sets *field to value when value is given and differs from the
+// current value, recording the old and new value in changes under name. An
+// unset old value is recorded as nil.
+func updateField(field **string, value *string, name string, changes map[string]interface{}) {
+	if value == nil || (*field != nil && **field == *value) {
+		return
 	}
-	if req.LastName != nil {
-		user.LastName = req.LastName
+
+	var old interface{}
+	if *field != nil {
+		old = **field
 	}
-	if req.Avatar != nil {
-		user.AvatarURL = req.Avatar
+	*field = value
+	changes[name] = FieldChange(old, *value)
+}
+
+// GetNotificationPreferences returns the effective notification preferences of a user
+func (s *UserService) GetNotificationPreferences(ctx context.Context, id string) (*model.NotificationSettings, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if req.Phone != nil {
-		user.Phone = req.Phone
+
+	return &model.NotificationSettings{
+		Preferences: user.NotificationPreferences.Effective(),
+		SMS:         user.SMSNotifications,
+	}, nil
+}
+
+// UpdateNotificationPreferences merges prefs into the user's notification
+// preferences and, when sms is set, opts them in or out of text messages.
+// Unknown categories and disabling security mail are rejected. Text messages
+// are only sent once the phone is verified.
+func (s *UserService) UpdateNotificationPreferences(ctx context.Context, id string, prefs model.NotificationPreferences, sms *bool) (*model.NotificationSettings, error) {
+	for category, enabled := range prefs {
+		if !category.IsValid() {
+			return nil, apperrors.ErrInvalidNotificationCategory
+		}
+		if category.IsMandatory() && !enabled {
+			return nil, apperrors.ErrMandatoryNotification
+		}
 	}
 
-	updatedUser, err := s.userRepo.Update(ctx, user)
+	// Read from the primary: the whole row is saved back below and a lagging
+	// replica would undo newer changes to other columns
+	user, err := s.userRepo.GetByID(database.UsePrimary(ctx), id)
 	if err != nil {
-		s.logger.Error("Failed to update user",
+		return nil, err
+	}
+
+	updated := make(model.NotificationPreferences, len(user.NotificationPreferences)+len(prefs))
+	for category, enabled := range user.NotificationPreferences {
+		updated[category] = enabled
+	}
+	for category, enabled := range prefs {
+		updated[category] = enabled
+	}
+	user.NotificationPreferences = updated
+	if sms != nil {
+		user.SMSNotifications = *sms
+	}
+
+	if _, err := s.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to update notification preferences",
 			zap.String("user_id", id),
 			zap.Error(err),
 		)
 		return nil, err
 	}
+	s.invalidateUserCache(ctx, id)
 
-	s.logger.Info("User updated successfully",
-		zap.String("user_id", updatedUser.ID),
+	s.logger.Info("Notification preferences updated",
+		zap.String("user_id", id),
 	)
 
-	return updatedUser, nil
+	return &model.NotificationSettings{
+		Preferences: updated.Effective(),
+		SMS:         user.SMSNotifications,
+	}, nil
 }
 
-// DeleteUser soft deletes a user
+// DeleteUser soft deletes a user, publishes a user deleted event and
+// invalidates the cached user
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	err := s.userRepo.Delete(ctx, id)
+	defer metrics.ObserveOperation("delete_user", time.Now())
+
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.userRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.userRepo.Delete(txCtx, id); err != nil {
+			return err
+		}
+
+		return s.eventService.PublishUserDeletedEvent(txCtx, user)
+	})
 	if err != nil {
 		s.logger.Error("Failed to delete user",
 			zap.String("user_id", id),
@@ -150,6 +358,8 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 		return err
 	}
 
+	s.invalidateUserCache(ctx, id)
+
 	s.logger.Info("User deleted successfully",
 		zap.String("user_id", id),
 	)
@@ -157,6 +367,107 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	return nil
 }
 
+// RestoreUser undoes the soft delete of a user and reactivates them. It fails
+// with apperrors.ErrUserNotFound if the user does not exist or is not
+// deleted, and with apperrors.ErrUserExists if an active user has since
+// taken their email or username.
+func (s *UserService) RestoreUser(ctx context.Context, id string) (*model.User, error) {
+	var restoredUser *model.User
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.userRepo.GetDeletedByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		// The deleted user is not visible yet, so any match is another user
+		exists, err := s.userRepo.ExistsByEmail(txCtx, user.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &apperrors.UserExistsError{Field: "email", Value: user.Email}
+		}
+
+		exists, err = s.userRepo.ExistsByUsername(txCtx, user.Username)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &apperrors.UserExistsError{Field: "username", Value: user.Username}
+		}
+
+		if err := s.userRepo.Restore(txCtx, id); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.UpdateActiveStatus(txCtx, id, true); err != nil {
+			return err
+		}
+
+		restoredUser, err = s.userRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.eventService.PublishUserStatusChangedEvent(txCtx, restoredUser, string(model.UserStatusDeleted), string(model.UserStatusActive))
+	})
+	if err != nil {
+		s.logger.Error("Failed to restore user",
+			zap.String("user_id", id),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	s.invalidateUserCache(ctx, id)
+
+	s.logger.Info("User restored successfully",
+		zap.String("user_id", id),
+	)
+
+	return restoredUser, nil
+}
+
+// ListLoginActivity returns a page of the user's recent logins, newest first,
+// and how many are kept. Logins are recorded by the Kafka consumer; without
+// Redis there is no history.
+func (s *UserService) ListLoginActivity(ctx context.Context, id string, page, size int) ([]model.LoginActivity, int64, error) {
+	if s.cache == nil {
+		return []model.LoginActivity{}, 0, nil
+	}
+
+	entries, total, err := s.cache.RangeRecent(ctx, cache.LoginActivityKey(id), (page-1)*size, size)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list login activity: %w", err)
+	}
+
+	logins := make([]model.LoginActivity, 0, len(entries))
+	for _, entry := range entries {
+		var login model.LoginActivity
+		if err := json.Unmarshal(entry, &login); err != nil {
+			s.logger.Warn("Skipping malformed login activity entry",
+				zap.String("user_id", id),
+				zap.Error(err),
+			)
+			continue
+		}
+		logins = append(logins, login)
+	}
+
+	return logins, total, nil
+}
+
+// invalidateUserCache drops the cached copy of a changed user. Failures are
+// logged; the entry still expires after userCacheTTL.
+func (s *UserService) invalidateUserCache(ctx context.Context, id string) {
+	if err := s.cache.InvalidateUserCache(ctx, id); err != nil {
+		s.logger.Warn("Failed to invalidate user cache",
+			zap.String("user_id", id),
+			zap.Error(err),
+		)
+	}
+}
+
 // ListUsers retrieves users with pagination and filters
 func (s *UserService) ListUsers(ctx context.Context, req *dto.UserListRequest) ([]*model.User, int64, error) {
 	users, total, err := s.userRepo.List(ctx, req)
@@ -177,34 +488,69 @@ func (s *UserService) ListUsers(ctx context.Context, req *dto.UserListRequest) (
 	return users, total, nil
 }
 
-// UpdateUserStatus updates user status
-func (s *UserService) UpdateUserStatus(ctx context.Context, id string, status model.UserStatus) (*model.User, error) {
-	if !status.IsValid() {
-		return nil, fmt.Errorf("invalid user status: %s", status)
-	}
-
-	user, err := s.userRepo.GetByID(ctx, id)
+// exportBatchSize is how many users are loaded at a time when exporting
+const exportBatchSize = 500
+
+// ExportUsers calls fn with every user matching the filters of req, loading
+// them in batches so memory stays bounded on large tables
+func (s *UserService) ExportUsers(ctx context.Context, req *dto.UserListRequest, fn func(user *model.User) error) error {
+	err := s.userRepo.Iterate(ctx, req, exportBatchSize, func(users []*model.User) error {
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		s.logger.Error("Failed to export users", zap.Error(err))
+		return err
 	}
 
-	// Update user status based on the status enum
-	switch status {
-	case model.UserStatusActive:
-		user.IsActive = true
-	case model.UserStatusInactive, model.UserStatusSuspended, model.UserStatusDeleted:
-		user.IsActive = false
+	return nil
+}
+
+// UpdateUserStatus updates user status and publishes a status changed event
+func (s *UserService) UpdateUserStatus(ctx context.Context, id string, status model.UserStatus) (*model.User, error) {
+	defer metrics.ObserveOperation("update_user_status", time.Now())
+
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid user status: %s", status)
 	}
 
-	updatedUser, err := s.userRepo.Update(ctx, user)
+	var updatedUser *model.User
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		user, err := s.userRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+
+		oldStatus := user.GetStatus()
+
+		// Update user status based on the status enum
+		switch status {
+		case model.UserStatusActive:
+			user.IsActive = true
+		case model.UserStatusInactive, model.UserStatusSuspended, model.UserStatusDeleted:
+			user.IsActive = false
+		}
+
+		updatedUser, err = s.userRepo.Update(txCtx, user)
+		if err != nil {
+			s.logger.Error("Failed to update user status",
+				zap.String("user_id", id),
+				zap.String("status", string(status)),
+				zap.Error(err),
+			)
+			return err
+		}
+
+		return s.eventService.PublishUserStatusChangedEvent(txCtx, updatedUser, oldStatus, string(status))
+	})
 	if err != nil {
-		s.logger.Error("Failed to update user status",
-			zap.String("user_id", id),
-			zap.String("status", string(status)),
-			zap.Error(err),
-		)
 		return nil, err
 	}
+	s.invalidateUserCache(ctx, id)
 
 	s.logger.Info("User status updated successfully",
 		zap.String("user_id", updatedUser.ID),
@@ -216,7 +562,7 @@ func (s *UserService) UpdateUserStatus(ctx context.Context, id string, status mo
 
 // ActivateUser activates a user account
 func (s *UserService) ActivateUser(ctx context.Context, id string) (*model.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
+	user, err := s.userRepo.GetByID(database.UsePrimary(ctx), id)
 	if err != nil {
 		return nil, err
 	}
@@ -231,6 +577,7 @@ func (s *UserService) ActivateUser(ctx context.Context, id string) (*model.User,
 		)
 		return nil, err
 	}
+	s.invalidateUserCache(ctx, id)
 
 	s.logger.Info("User activated successfully",
 		zap.String("user_id", updatedUser.ID),
@@ -241,7 +588,7 @@ func (s *UserService) ActivateUser(ctx context.Context, id string) (*model.User,
 
 // DeactivateUser deactivates a user account
 func (s *UserService) DeactivateUser(ctx context.Context, id string) (*model.User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
+	user, err := s.userRepo.GetByID(database.UsePrimary(ctx), id)
 	if err != nil {
 		return nil, err
 	}
@@ -256,6 +603,7 @@ func (s *UserService) DeactivateUser(ctx context.Context, id string) (*model.Use
 		)
 		return nil, err
 	}
+	s.invalidateUserCache(ctx, id)
 
 	s.logger.Info("User deactivated successfully",
 		zap.String("user_id", updatedUser.ID),
@@ -264,21 +612,102 @@ func (s *UserService) DeactivateUser(ctx context.Context, id string) (*model.Use
 	return updatedUser, nil
 }
 
-// SearchUsers searches users by term
-func (s *UserService) SearchUsers(ctx context.Context, term string, limit int) ([]*model.User, error) {
-	users, err := s.userRepo.Search(ctx, term, limit)
+// GetUsersByIDs retrieves the users with the given IDs, in the order given.
+// IDs that do not exist are left out of the result and duplicates are
+// returned once. Cached users are read in one round trip and only the misses
+// are loaded from the database, after which they are cached in one round trip.
+// Cached users carry only their JSON fields, so the password hash and
+// notification preferences are not set.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []string) ([]*model.User, error) {
+	// Look each ID up once
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, dup := seen[id]; !dup {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+	ids = unique
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = cache.UserCacheKeyPrefix + id
+	}
+
+	cached, err := s.cache.GetMany(ctx, keys)
+	if err != nil {
+		// Fall back to the database for every ID
+		s.logger.Warn("Failed to read cached users",
+			zap.Int("count", len(ids)),
+			zap.Error(err),
+		)
+	}
+
+	found := make(map[string]*model.User, len(ids))
+	var missing []string
+	for i, id := range ids {
+		if data, ok := cached[keys[i]]; ok {
+			var user model.User
+			if err := json.Unmarshal(data, &user); err == nil {
+				found[id] = &user
+				continue
+			}
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		users, err := s.userRepo.GetByIDs(ctx, missing)
+		if err != nil {
+			s.logger.Error("Failed to get users by IDs",
+				zap.Int("count", len(missing)),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+
+		items := make(map[string]cache.CacheItem, len(users))
+		for _, user := range users {
+			found[user.ID] = user
+			items[cache.UserCacheKeyPrefix+user.ID] = cache.CacheItem{Value: user, Expiration: userCacheTTL}
+		}
+		if err := s.cache.SetMany(ctx, items); err != nil {
+			s.logger.Warn("Failed to cache users",
+				zap.Int("count", len(items)),
+				zap.Error(err),
+			)
+		}
+	}
+
+	users := make([]*model.User, 0, len(found))
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+// SearchUsers returns the page of req of the users matching its query, best
+// matches first, and the total number of matches. Emails are only searched
+// when withEmail is set.
+func (s *UserService) SearchUsers(ctx context.Context, req *dto.UserSearchRequest, withEmail bool) ([]*model.User, int64, error) {
+	users, total, err := s.userRepo.SearchFullText(ctx, req.Query, withEmail, (req.Page-1)*req.Size, req.Size)
 	if err != nil {
 		s.logger.Error("Failed to search users",
-			zap.String("term", term),
+			zap.String("query", req.Query),
 			zap.Error(err),
 		)
-		return nil, err
+		return nil, 0, err
 	}
 
 	s.logger.Debug("Users searched successfully",
-		zap.String("term", term),
+		zap.String("query", req.Query),
 		zap.Int("count", len(users)),
+		zap.Int64("total", total),
 	)
 
-	return users, nil
+	return users, total, nil
 }
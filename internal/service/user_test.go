@@ -2,18 +2,42 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
 	"github.com/zhwjimmy/user-center/internal/dto"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
 	"github.com/zhwjimmy/user-center/internal/mock"
 	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/testutils"
 	"go.uber.org/zap"
 )
 
 func strPtr(s string) *string { return &s }
 
+// newTestUserService creates a user service backed by the given repository,
+// an in-memory outbox and miniredis
+func newTestUserService(t testing.TB, repo repository.UserRepository, logger *zap.Logger) *UserService {
+	redis, _ := testutils.NewMiniRedis(t)
+	return NewUserService(repo, NewEventService(testutils.NewFakeOutboxRepository(), logger), testutils.FakeTransactor{}, redis, logger)
+}
+
+// passThroughTransaction makes WithTransaction run fn against repo itself
+func passThroughTransaction(repo *mock.MockUserRepository) {
+	repo.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repository.UserRepository) error) error {
+			return fn(repo)
+		}).AnyTimes()
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -36,10 +60,10 @@ func TestUserService_CreateUser(t *testing.T) {
 			setupMock: func(repo *mock.MockUserRepository) {
 				// Check if user with email already exists
 				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").
-					Return(nil, assert.AnError) // User not found by email
+					Return(nil, apperrors.ErrUserNotFound) // User not found by email
 				// Check if user with username already exists
 				repo.EXPECT().GetByUsername(gomock.Any(), "testuser").
-					Return(nil, assert.AnError) // User not found by username
+					Return(nil, apperrors.ErrUserNotFound) // User not found by username
 				// Create user
 				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(&model.User{
 					ID:           "test-user-id",
@@ -63,22 +87,56 @@ func TestUserService_CreateUser(t *testing.T) {
 			setupMock: func(repo *mock.MockUserRepository) {
 				// Check if user with email already exists
 				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").
-					Return(nil, assert.AnError) // User not found by email
+					Return(nil, apperrors.ErrUserNotFound) // User not found by email
 				// Check if user with username already exists
 				repo.EXPECT().GetByUsername(gomock.Any(), "testuser").
-					Return(nil, assert.AnError) // User not found by username
+					Return(nil, apperrors.ErrUserNotFound) // User not found by username
 				// Create user fails
 				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
 			},
 		},
+		{
+			name: "phone already exists",
+			user: &model.User{
+				Username:     "otheruser",
+				Email:        "other@example.com",
+				PasswordHash: "hashedpassword",
+				Phone:        strPtr("+86 138-0000-0000"),
+			},
+			expectedError: true,
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByEmail(gomock.Any(), "other@example.com").
+					Return(nil, apperrors.ErrUserNotFound)
+				repo.EXPECT().GetByUsername(gomock.Any(), "otheruser").
+					Return(nil, apperrors.ErrUserNotFound)
+				// Lookup uses the normalized phone
+				repo.EXPECT().GetByPhone(gomock.Any(), "+8613800000000").
+					Return(&model.User{ID: "existing-id", Phone: strPtr("+8613800000000")}, nil)
+			},
+		},
+		{
+			name: "email differing only in case already exists",
+			user: &model.User{
+				Username:     "otheruser",
+				Email:        "  Test@Example.COM ",
+				PasswordHash: "hashedpassword",
+			},
+			expectedError: true,
+			setupMock: func(repo *mock.MockUserRepository) {
+				// Lookup uses the normalized email
+				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").
+					Return(&model.User{ID: "existing-id", Email: "test@example.com"}, nil)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
+			passThroughTransaction(mockRepo)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.CreateUser(context.Background(), tt.user)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -94,6 +152,90 @@ func TestUserService_CreateUser(t *testing.T) {
 	}
 }
 
+func TestUserService_CreateUser_FailingStepRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mock.NewMockUserRepository(ctrl)
+
+	// The transaction rolls back when fn returns an error
+	var txErr error
+	mockRepo.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repository.UserRepository) error) error {
+			txErr = fn(mockRepo)
+			return txErr
+		})
+	mockRepo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(nil, apperrors.ErrUserNotFound)
+	mockRepo.EXPECT().GetByUsername(gomock.Any(), "testuser").Return(nil, apperrors.ErrUserNotFound)
+	// A concurrent insert won the race and the unique index rejected this one
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, apperrors.ErrUserExists)
+
+	service := newTestUserService(t, mockRepo, zap.NewNop())
+	result, err := service.CreateUser(context.Background(), &model.User{
+		Username:     "testuser",
+		Email:        "test@example.com",
+		PasswordHash: "hashedpassword",
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, apperrors.ErrUserExists)
+	assert.ErrorIs(t, txErr, apperrors.ErrUserExists)
+}
+
+func TestUserService_CreateUser_UniqueChecks(t *testing.T) {
+	lookupErr := errors.New("failed to get user by email: connection refused")
+
+	tests := []struct {
+		name      string
+		setupMock func(repo *mock.MockUserRepository)
+		wantErr   error
+		wantMsg   string
+	}{
+		{
+			name: "email taken",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(&model.User{ID: "user-2"}, nil)
+			},
+			wantErr: apperrors.ErrUserExists,
+			wantMsg: "user with email test@example.com already exists",
+		},
+		{
+			name: "username taken",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(nil, apperrors.ErrUserNotFound)
+				repo.EXPECT().GetByUsername(gomock.Any(), "testuser").Return(&model.User{ID: "user-2"}, nil)
+			},
+			wantErr: apperrors.ErrUserExists,
+			wantMsg: "user with username testuser already exists",
+		},
+		{
+			name: "lookup failure is not mistaken for a free email",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByEmail(gomock.Any(), "test@example.com").Return(nil, lookupErr)
+			},
+			wantErr: lookupErr,
+			wantMsg: lookupErr.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			passThroughTransaction(repo)
+			tt.setupMock(repo)
+
+			service := newTestUserService(t, repo, zap.NewNop())
+			result, err := service.CreateUser(context.Background(), &model.User{
+				Username: "testuser",
+				Email:    "test@example.com",
+			})
+
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.EqualError(t, err, tt.wantMsg)
+		})
+	}
+}
+
 func TestUserService_GetUserByID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -141,7 +283,7 @@ func TestUserService_GetUserByID(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.GetUserByID(context.Background(), tt.userID)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -204,7 +346,7 @@ func TestUserService_GetUserByEmail(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.GetUserByEmail(context.Background(), tt.email)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -219,6 +361,51 @@ func TestUserService_GetUserByEmail(t *testing.T) {
 	}
 }
 
+func TestUserService_GetUserByPhone(t *testing.T) {
+	tests := []struct {
+		name          string
+		phone         string
+		expectedError bool
+		setupMock     func(*mock.MockUserRepository)
+	}{
+		{
+			name:  "successful user retrieval by phone",
+			phone: "138 0000-0000",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByPhone(gomock.Any(), "13800000000").
+					Return(&model.User{ID: "test-user-id", Phone: strPtr("13800000000")}, nil)
+			},
+		},
+		{
+			name:          "user not found by phone",
+			phone:         "13900000000",
+			expectedError: true,
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByPhone(gomock.Any(), "13900000000").
+					Return(nil, apperrors.ErrUserNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRepo := mock.NewMockUserRepository(ctrl)
+			tt.setupMock(mockRepo)
+			service := newTestUserService(t, mockRepo, zap.NewNop())
+
+			result, err := service.GetUserByPhone(context.Background(), tt.phone)
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "test-user-id", result.ID)
+		})
+	}
+}
+
 func TestUserService_UpdateUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -284,7 +471,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.UpdateUser(context.Background(), tt.userID, tt.req)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -300,6 +487,159 @@ func TestUserService_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestUserService_UpdateUser_PublishesChanges(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             *dto.UpdateUserRequest
+		expectedChanges map[string]interface{}
+	}{
+		{
+			name: "no-op update",
+			req: &dto.UpdateUserRequest{
+				FirstName: strPtr("Alice"),
+				Phone:     strPtr("+1234567890"),
+			},
+		},
+		{
+			name: "real change",
+			req: &dto.UpdateUserRequest{
+				FirstName: strPtr("Alice"),
+				LastName:  strPtr("Smith"),
+				Avatar:    strPtr("https://example.com/alice.png"),
+			},
+			expectedChanges: map[string]interface{}{
+				"last_name":  map[string]interface{}{"old": "Doe", "new": "Smith"},
+				"avatar_url": map[string]interface{}{"old": nil, "new": "https://example.com/alice.png"},
+			},
+		},
+		{
+			name: "new phone needs verifying again",
+			req: &dto.UpdateUserRequest{
+				Phone: strPtr("+1987654321"),
+			},
+			expectedChanges: map[string]interface{}{
+				"phone":          map[string]interface{}{"old": "+1234567890", "new": "+1987654321"},
+				"phone_verified": map[string]interface{}{"old": true, "new": false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(&model.User{
+				ID:        "user-1",
+				Username:  "alice",
+				Email:     "alice@example.com",
+				FirstName: strPtr("Alice"),
+				LastName:  strPtr("Doe"),
+				Phone:     strPtr("+1234567890"),
+				// Verified numbers stay verified unless they change
+				PhoneVerified: true,
+			}, nil)
+			if tt.expectedChanges != nil {
+				repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+						return user, nil
+					})
+			}
+
+			outbox := testutils.NewFakeOutboxRepository()
+			redis, _ := testutils.NewMiniRedis(t)
+			logger := zap.NewNop()
+			service := NewUserService(repo, NewEventService(outbox, logger), testutils.FakeTransactor{}, redis, logger)
+
+			_, err := service.UpdateUser(context.Background(), "user-1", tt.req)
+			require.NoError(t, err)
+
+			events := outbox.EventsOfType(string(event.UserUpdated))
+			if tt.expectedChanges == nil {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			var updated event.UserUpdatedEvent
+			require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &updated))
+			assert.Equal(t, tt.expectedChanges, updated.Changes)
+		})
+	}
+}
+
+func TestUserService_UpdateUser_Username(t *testing.T) {
+	tests := []struct {
+		name            string
+		username        string
+		taken           bool
+		expectedChanges map[string]interface{}
+		expectedError   bool
+	}{
+		{
+			name:            "successful change",
+			username:        "alice2",
+			expectedChanges: map[string]interface{}{"username": map[string]interface{}{"old": "alice", "new": "alice2"}},
+		},
+		{
+			name:          "duplicate rejected",
+			username:      "bob",
+			taken:         true,
+			expectedError: true,
+		},
+		{
+			name:     "unchanged is a no-op",
+			username: "alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			repo.EXPECT().GetByID(gomock.Any(), "user-1").Return(&model.User{
+				ID:       "user-1",
+				Username: "alice",
+				Email:    "alice@example.com",
+			}, nil)
+			if tt.username != "alice" {
+				repo.EXPECT().ExistsByUsername(gomock.Any(), tt.username).Return(tt.taken, nil)
+			}
+			if tt.expectedChanges != nil {
+				repo.EXPECT().Update(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, user *model.User) (*model.User, error) {
+						return user, nil
+					})
+			}
+
+			outbox := testutils.NewFakeOutboxRepository()
+			redis, _ := testutils.NewMiniRedis(t)
+			logger := zap.NewNop()
+			service := NewUserService(repo, NewEventService(outbox, logger), testutils.FakeTransactor{}, redis, logger)
+
+			user, err := service.UpdateUser(context.Background(), "user-1", &dto.UpdateUserRequest{Username: &tt.username})
+			events := outbox.EventsOfType(string(event.UserUpdated))
+			if tt.expectedError {
+				assert.ErrorContains(t, err, "already exists")
+				assert.Empty(t, events)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.username, user.Username)
+
+			if tt.expectedChanges == nil {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			var updated event.UserUpdatedEvent
+			require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &updated))
+			assert.Equal(t, tt.expectedChanges, updated.Changes)
+			assert.Equal(t, tt.username, updated.Username)
+		})
+	}
+}
+
 func TestUserService_DeleteUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -314,6 +654,8 @@ func TestUserService_DeleteUser(t *testing.T) {
 			userID:        "test-user-id",
 			expectedError: false,
 			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), "test-user-id").
+					Return(&model.User{ID: "test-user-id", Username: "testuser"}, nil)
 				repo.EXPECT().Delete(gomock.Any(), "test-user-id").Return(nil)
 			},
 		},
@@ -322,7 +664,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 			userID:        "non-existent-id",
 			expectedError: true,
 			setupMock: func(repo *mock.MockUserRepository) {
-				repo.EXPECT().Delete(gomock.Any(), "non-existent-id").Return(assert.AnError)
+				repo.EXPECT().GetByID(gomock.Any(), "non-existent-id").Return(nil, assert.AnError)
 			},
 		},
 	}
@@ -332,7 +674,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			err := service.DeleteUser(context.Background(), tt.userID)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -343,6 +685,80 @@ func TestUserService_DeleteUser(t *testing.T) {
 	}
 }
 
+func TestUserService_RestoreUser(t *testing.T) {
+	deleted := func() *model.User {
+		return &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"}
+	}
+
+	tests := []struct {
+		name          string
+		setupMock     func(*mock.MockUserRepository)
+		expectedError string
+	}{
+		{
+			name: "restores and reactivates a deleted user",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(deleted(), nil)
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "alice@example.com").Return(false, nil)
+				repo.EXPECT().ExistsByUsername(gomock.Any(), "alice").Return(false, nil)
+				repo.EXPECT().Restore(gomock.Any(), "user-1").Return(nil)
+				repo.EXPECT().UpdateActiveStatus(gomock.Any(), "user-1", true).Return(nil)
+				repo.EXPECT().GetByID(gomock.Any(), "user-1").
+					Return(&model.User{ID: "user-1", Username: "alice", Email: "alice@example.com", IsActive: true}, nil)
+			},
+		},
+		{
+			name: "user that is not deleted",
+			setupMock: func(repo *mock.MockUserRepository) {
+				// Also the case for a second restore of the same user
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(nil, apperrors.ErrUserNotFound)
+			},
+			expectedError: "user not found",
+		},
+		{
+			name: "email taken by a newer user",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(deleted(), nil)
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "alice@example.com").Return(true, nil)
+			},
+			expectedError: "user with email alice@example.com already exists",
+		},
+		{
+			name: "username taken by a newer user",
+			setupMock: func(repo *mock.MockUserRepository) {
+				repo.EXPECT().GetDeletedByID(gomock.Any(), "user-1").Return(deleted(), nil)
+				repo.EXPECT().ExistsByEmail(gomock.Any(), "alice@example.com").Return(false, nil)
+				repo.EXPECT().ExistsByUsername(gomock.Any(), "alice").Return(true, nil)
+			},
+			expectedError: "user with username alice already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := mock.NewMockUserRepository(ctrl)
+			tt.setupMock(repo)
+
+			outbox := testutils.NewFakeOutboxRepository()
+			redis, _ := testutils.NewMiniRedis(t)
+			logger := zap.NewNop()
+			service := NewUserService(repo, NewEventService(outbox, logger), testutils.FakeTransactor{}, redis, logger)
+
+			user, err := service.RestoreUser(context.Background(), "user-1")
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.Empty(t, outbox.EventsOfType(string(event.UserStatusChanged)))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, user.IsActive)
+			assert.Len(t, outbox.EventsOfType(string(event.UserStatusChanged)), 1)
+		})
+	}
+}
+
 func TestUserService_ListUsers(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -413,7 +829,7 @@ func TestUserService_ListUsers(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			users, total, err := service.ListUsers(context.Background(), tt.req)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -482,7 +898,7 @@ func TestUserService_ActivateUser(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.ActivateUser(context.Background(), tt.userID)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -549,7 +965,7 @@ func TestUserService_DeactivateUser(t *testing.T) {
 			mockRepo := mock.NewMockUserRepository(ctrl)
 			tt.setupMock(mockRepo)
 			logger := zap.NewNop()
-			service := NewUserService(mockRepo, logger)
+			service := newTestUserService(t, mockRepo, logger)
 			result, err := service.DeactivateUser(context.Background(), tt.userID)
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -569,7 +985,7 @@ func BenchmarkUserService_CreateUser(b *testing.B) {
 	defer ctrl.Finish()
 	mockRepo := mock.NewMockUserRepository(ctrl)
 	logger := zap.NewNop()
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(b, mockRepo, logger)
 
 	user := &model.User{
 		Username:     "benchmarkuser",
@@ -577,8 +993,8 @@ func BenchmarkUserService_CreateUser(b *testing.B) {
 		PasswordHash: "hashedpassword",
 	}
 
-	mockRepo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).AnyTimes()
-	mockRepo.EXPECT().GetByUsername(gomock.Any(), gomock.Any()).Return(nil, assert.AnError).AnyTimes()
+	mockRepo.EXPECT().GetByEmail(gomock.Any(), gomock.Any()).Return(nil, apperrors.ErrUserNotFound).AnyTimes()
+	mockRepo.EXPECT().GetByUsername(gomock.Any(), gomock.Any()).Return(nil, apperrors.ErrUserNotFound).AnyTimes()
 	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(user, nil).AnyTimes()
 
 	b.ResetTimer()
@@ -592,7 +1008,7 @@ func BenchmarkUserService_GetUserByID(b *testing.B) {
 	defer ctrl.Finish()
 	mockRepo := mock.NewMockUserRepository(ctrl)
 	logger := zap.NewNop()
-	service := NewUserService(mockRepo, logger)
+	service := newTestUserService(b, mockRepo, logger)
 
 	user := &model.User{
 		ID:       "benchmark-user-id",
@@ -608,3 +1024,56 @@ func BenchmarkUserService_GetUserByID(b *testing.B) {
 		_, _ = service.GetUserByID(context.Background(), "benchmark-user-id")
 	}
 }
+
+func TestUserService_GetUsersByIDs_CachesMisses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockUserRepository(ctrl)
+	passThroughTransaction(repo)
+	redis, mr := testutils.NewMiniRedis(t)
+	logger := zap.NewNop()
+	service := NewUserService(repo, NewEventService(testutils.NewFakeOutboxRepository(), logger), testutils.FakeTransactor{}, redis, logger)
+	ctx := context.Background()
+
+	alice := &model.User{ID: "user-1", Username: "alice", PasswordHash: "secret"}
+	bob := &model.User{ID: "user-2", Username: "bob"}
+	carol := &model.User{ID: "user-3", Username: "carol"}
+
+	// Everything misses: one database query, then cached
+	repo.EXPECT().GetByIDs(gomock.Any(), []string{"user-1", "user-2", "missing"}).Return([]*model.User{bob, alice}, nil)
+	users, err := service.GetUsersByIDs(ctx, []string{"user-1", "user-2", "missing"})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice", users[0].Username)
+	assert.Equal(t, "bob", users[1].Username)
+	assert.True(t, mr.Exists(cache.UserCacheKeyPrefix+"user-1"))
+	assert.NotContains(t, mustGet(t, mr, cache.UserCacheKeyPrefix+"user-1"), "secret")
+
+	// Partial hit: only the uncached IDs are queried
+	repo.EXPECT().GetByIDs(gomock.Any(), []string{"user-3", "missing"}).Return([]*model.User{carol}, nil)
+	users, err = service.GetUsersByIDs(ctx, []string{"user-3", "user-1", "missing", "user-2", "user-1"})
+	require.NoError(t, err)
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		usernames[i] = user.Username
+	}
+	assert.Equal(t, []string{"carol", "alice", "bob"}, usernames)
+
+	// Full hit: no database query
+	users, err = service.GetUsersByIDs(ctx, []string{"user-2", "user-3"})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	// A changed user is dropped from the cache
+	repo.EXPECT().GetByID(gomock.Any(), "user-2").Return(bob, nil)
+	repo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *model.User) (*model.User, error) { return u, nil })
+	_, err = service.UpdateUser(ctx, "user-2", &dto.UpdateUserRequest{FirstName: strPtr("Bob")})
+	require.NoError(t, err)
+	assert.False(t, mr.Exists(cache.UserCacheKeyPrefix+"user-2"))
+}
+
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	t.Helper()
+	value, err := mr.Get(key)
+	require.NoError(t, err)
+	return value
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/kafka/event"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"github.com/zhwjimmy/user-center/internal/repository"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"go.uber.org/zap"
+)
+
+// WebhookQueue enqueues webhook deliveries to be posted asynchronously
+type WebhookQueue interface {
+	EnqueueWebhook(ctx context.Context, payload task.WebhookPayload) error
+}
+
+// WebhookUpdate holds the changes to a webhook subscription; nil fields are
+// left unchanged
+type WebhookUpdate struct {
+	URL        *string
+	EventTypes *[]string
+	Active     *bool
+}
+
+// WebhookService manages outbound webhook subscriptions and queues user
+// events for delivery to them
+type WebhookService struct {
+	repo   repository.WebhookRepository
+	queue  WebhookQueue
+	logger *zap.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo repository.WebhookRepository, queue WebhookQueue, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// CreateWebhook subscribes rawURL to eventTypes, or to every event type when
+// none are given. A random secret is generated when secret is empty.
+func (s *WebhookService) CreateWebhook(ctx context.Context, rawURL string, eventTypes []string, secret string) (*model.WebhookSubscription, error) {
+	if err := validateWebhook(rawURL, eventTypes); err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	sub := &model.WebhookSubscription{
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: model.WebhookEventTypes(eventTypes),
+		Active:     true,
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = model.WebhookEventTypes{}
+	}
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook subscription created",
+		zap.String("subscription_id", sub.ID),
+		zap.Strings("event_types", sub.EventTypes),
+	)
+	return sub, nil
+}
+
+// ListWebhooks returns every webhook subscription, oldest first
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	return s.repo.List(ctx)
+}
+
+// GetWebhook returns the webhook subscription with id
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// UpdateWebhook applies update to the webhook subscription with id.
+// Re-enabling a subscription clears its delivery failures.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (*model.WebhookSubscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		sub.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		sub.EventTypes = model.WebhookEventTypes(*update.EventTypes)
+		if sub.EventTypes == nil {
+			sub.EventTypes = model.WebhookEventTypes{}
+		}
+	}
+	if err := validateWebhook(sub.URL, sub.EventTypes); err != nil {
+		return nil, err
+	}
+
+	if update.Active != nil {
+		if *update.Active && !sub.Active {
+			sub.ConsecutiveFailures = 0
+			sub.DisabledAt = nil
+		}
+		sub.Active = *update.Active
+	}
+
+	if err := s.repo.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Webhook subscription updated",
+		zap.String("subscription_id", sub.ID),
+		zap.Bool("active", sub.Active),
+	)
+	return sub, nil
+}
+
+// DeleteWebhook deletes the webhook subscription with id. Deliveries already
+// queued for it are dropped.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("Webhook subscription deleted", zap.String("subscription_id", id))
+	return nil
+}
+
+// DispatchEvent queues body, the JSON encoding of a user event of eventType,
+// for delivery to every active subscription receiving it. Every subscription
+// is tried even if queueing for one fails.
+func (s *WebhookService) DispatchEvent(ctx context.Context, eventType string, body []byte) error {
+	subs, err := s.repo.ListActiveForEvent(ctx, eventType)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		if err := s.queue.EnqueueWebhook(ctx, task.WebhookPayload{
+			SubscriptionID: sub.ID,
+			Event:          eventType,
+			Body:           body,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateWebhook checks that rawURL is an absolute http or https URL not
+// targeting an internal address and that every event type is known
+func validateWebhook(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", apperrors.ErrInvalidWebhook)
+	}
+
+	// Host names resolving to internal addresses are refused when delivering
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must not target an internal address", apperrors.ErrInvalidWebhook)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && task.IsInternalAddress(addr) {
+		return fmt.Errorf("%w: url must not target an internal address", apperrors.ErrInvalidWebhook)
+	}
+
+	for _, eventType := range eventTypes {
+		if _, err := event.NewEvent(event.EventType(eventType)); err != nil {
+			return fmt.Errorf("%w: unknown event type %s", apperrors.ErrInvalidWebhook, eventType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/task"
+	"github.com/zhwjimmy/user-center/internal/testutils"
+	"go.uber.org/zap"
+)
+
+// fakeWebhookQueue records queued webhook deliveries
+type fakeWebhookQueue struct {
+	queued []task.WebhookPayload
+	err    error
+}
+
+func (q *fakeWebhookQueue) EnqueueWebhook(ctx context.Context, payload task.WebhookPayload) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.queued = append(q.queued, payload)
+	return nil
+}
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	repo := testutils.NewFakeWebhookRepository()
+	s := NewWebhookService(repo, &fakeWebhookQueue{}, zap.NewNop())
+	ctx := context.Background()
+
+	sub, err := s.CreateWebhook(ctx, "https://crm.example.com/hooks", []string{"user.registered"}, "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.ID)
+	assert.Len(t, sub.Secret, 64)
+	assert.True(t, sub.Active)
+	assert.Equal(t, []string{"user.registered"}, []string(sub.EventTypes))
+
+	sub, err = s.CreateWebhook(ctx, "http://203.0.113.10:9000/hooks", nil, "given-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "given-secret", sub.Secret)
+	assert.NotNil(t, sub.EventTypes)
+
+	for _, tt := range []struct {
+		url        string
+		eventTypes []string
+	}{
+		{url: "ftp://crm.example.com/hooks"},
+		{url: "/hooks"},
+		{url: "https://crm.example.com/hooks", eventTypes: []string{"user.exploded"}},
+		{url: "http://localhost:9000/hooks"},
+		{url: "http://api.localhost/hooks"},
+		{url: "http://127.0.0.1/hooks"},
+		{url: "http://10.0.0.5/hooks"},
+		{url: "http://192.168.1.1/hooks"},
+		{url: "http://169.254.169.254/latest/meta-data"},
+		{url: "http://0.0.0.0/hooks"},
+		{url: "http://[::1]/hooks"},
+		{url: "http://[fe80::1]/hooks"},
+		{url: "http://[fd00::1]/hooks"},
+		{url: "http://[::ffff:127.0.0.1]/hooks"},
+	} {
+		_, err := s.CreateWebhook(ctx, tt.url, tt.eventTypes, "")
+		assert.ErrorIs(t, err, apperrors.ErrInvalidWebhook, tt.url)
+	}
+	assert.Len(t, repo.Subscriptions, 2)
+}
+
+func TestWebhookService_UpdateWebhook(t *testing.T) {
+	repo := testutils.NewFakeWebhookRepository()
+	s := NewWebhookService(repo, &fakeWebhookQueue{}, zap.NewNop())
+	ctx := context.Background()
+
+	sub, err := s.CreateWebhook(ctx, "https://crm.example.com/hooks", nil, "")
+	require.NoError(t, err)
+
+	// Disabled after failed deliveries
+	disabled, err := repo.RecordDeliveryFailure(ctx, sub.ID, 1)
+	require.NoError(t, err)
+	require.True(t, disabled)
+
+	eventTypes := []string{"user.deleted"}
+	active := true
+	sub, err = s.UpdateWebhook(ctx, sub.ID, WebhookUpdate{EventTypes: &eventTypes, Active: &active})
+	require.NoError(t, err)
+	assert.True(t, sub.Active)
+	assert.Zero(t, sub.ConsecutiveFailures)
+	assert.Nil(t, sub.DisabledAt)
+	assert.Equal(t, []string{"user.deleted"}, []string(sub.EventTypes))
+	assert.Equal(t, "https://crm.example.com/hooks", sub.URL)
+
+	badURL := "crm.example.com"
+	_, err = s.UpdateWebhook(ctx, sub.ID, WebhookUpdate{URL: &badURL})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidWebhook)
+
+	_, err = s.UpdateWebhook(ctx, "missing", WebhookUpdate{Active: &active})
+	assert.ErrorIs(t, err, apperrors.ErrWebhookNotFound)
+}
+
+func TestWebhookService_DispatchEvent(t *testing.T) {
+	repo := testutils.NewFakeWebhookRepository()
+	queue := &fakeWebhookQueue{}
+	s := NewWebhookService(repo, queue, zap.NewNop())
+	ctx := context.Background()
+
+	all, err := s.CreateWebhook(ctx, "https://all.example.com/hooks", nil, "")
+	require.NoError(t, err)
+	deletions, err := s.CreateWebhook(ctx, "https://deletions.example.com/hooks", []string{"user.deleted"}, "")
+	require.NoError(t, err)
+	disabled, err := s.CreateWebhook(ctx, "https://disabled.example.com/hooks", nil, "")
+	require.NoError(t, err)
+	_, err = repo.RecordDeliveryFailure(ctx, disabled.ID, 1)
+	require.NoError(t, err)
+
+	body := []byte(`{"user_id":"user-1"}`)
+	require.NoError(t, s.DispatchEvent(ctx, "user.registered", body))
+	require.Len(t, queue.queued, 1)
+	assert.Equal(t, task.WebhookPayload{SubscriptionID: all.ID, Event: "user.registered", Body: body}, queue.queued[0])
+
+	queue.queued = nil
+	require.NoError(t, s.DispatchEvent(ctx, "user.deleted", body))
+	require.Len(t, queue.queued, 2)
+	assert.Equal(t, all.ID, queue.queued[0].SubscriptionID)
+	assert.Equal(t, deletions.ID, queue.queued[1].SubscriptionID)
+
+	queue.err = errors.New("redis unavailable")
+	assert.ErrorContains(t, s.DispatchEvent(ctx, "user.deleted", body), "redis unavailable")
+}
+
+func TestWebhookService_DeleteWebhook(t *testing.T) {
+	repo := testutils.NewFakeWebhookRepository()
+	s := NewWebhookService(repo, &fakeWebhookQueue{}, zap.NewNop())
+	ctx := context.Background()
+
+	sub, err := s.CreateWebhook(ctx, "https://crm.example.com/hooks", nil, "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteWebhook(ctx, sub.ID))
+	assert.ErrorIs(t, s.DeleteWebhook(ctx, sub.ID), apperrors.ErrWebhookNotFound)
+
+	_, err = s.GetWebhook(ctx, sub.ID)
+	assert.ErrorIs(t, err, apperrors.ErrWebhookNotFound)
+
+	subs, err := s.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+}
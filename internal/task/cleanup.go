@@ -0,0 +1,39 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SessionStore removes sessions that have expired
+type SessionStore interface {
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+// CleanupHandler processes cleanup:expired tasks. Blacklisted tokens expire
+// through their Redis TTL, so only stored sessions need purging.
+type CleanupHandler struct {
+	sessions SessionStore
+	logger   *zap.Logger
+}
+
+// NewCleanupHandler creates a new cleanup handler
+func NewCleanupHandler(sessions SessionStore, logger *zap.Logger) *CleanupHandler {
+	return &CleanupHandler{
+		sessions: sessions,
+		logger:   logger,
+	}
+}
+
+// ProcessTask deletes sessions that expired before now
+func (h *CleanupHandler) ProcessTask(ctx context.Context, task *Task) error {
+	deleted, err := h.sessions.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	h.logger.Info("Expired sessions purged", zap.Int64("deleted", deleted))
+	return nil
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// Client enqueues tasks for the task server
+type Client struct {
+	redis  *redis.Client
+	logger *zap.Logger
+}
+
+// NewClient creates a new task client connected to the task Redis
+func NewClient(cfg *config.Config, logger *zap.Logger) (*Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Task.Redis.Addr,
+		Password: cfg.Task.Redis.Password,
+		DB:       cfg.Task.Redis.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to task Redis: %w", err)
+	}
+
+	return &Client{
+		redis:  client,
+		logger: logger,
+	}, nil
+}
+
+// NewClientFromRedis creates a task client on an existing Redis connection
+func NewClientFromRedis(client *redis.Client, logger *zap.Logger) *Client {
+	return &Client{
+		redis:  client,
+		logger: logger,
+	}
+}
+
+// Enqueue adds task to queue
+func (c *Client) Enqueue(ctx context.Context, queue string, task *Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	if err := c.redis.LPush(ctx, queueKey(queue), data).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue %s task: %w", task.Type, err)
+	}
+
+	c.logger.Debug("Task enqueued",
+		zap.String("type", task.Type),
+		zap.String("queue", queue),
+	)
+	return nil
+}
+
+// EnqueueEmail enqueues an email:send task on the email queue
+func (c *Client) EnqueueEmail(ctx context.Context, payload EmailPayload) error {
+	task, err := NewEmailTask(payload)
+	if err != nil {
+		return err
+	}
+	return c.Enqueue(ctx, QueueEmail, task)
+}
+
+// EnqueueSMS enqueues an sms:send task on the notification queue
+func (c *Client) EnqueueSMS(ctx context.Context, payload SMSPayload) error {
+	task, err := NewSMSTask(payload)
+	if err != nil {
+		return err
+	}
+	return c.Enqueue(ctx, QueueNotification, task)
+}
+
+// EnqueueWebhook enqueues a webhook:send task on the notification queue
+func (c *Client) EnqueueWebhook(ctx context.Context, payload WebhookPayload) error {
+	task, err := NewWebhookTask(payload)
+	if err != nil {
+		return err
+	}
+	return c.Enqueue(ctx, QueueNotification, task)
+}
+
+// Close closes the Redis connection
+func (c *Client) Close() error {
+	return c.redis.Close()
+}
@@ -0,0 +1,131 @@
+package task
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	"text/template"
+
+	"go.uber.org/zap"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Email templates, named after their file in templates/
+const (
+	TemplateWelcome         = "welcome"
+	TemplatePasswordChanged = "password_changed"
+	TemplateStatusChanged   = "status_changed"
+	TemplateAccountDeleted  = "account_deleted"
+	TemplatePasswordSetup   = "password_setup"
+	TemplateNewDeviceLogin  = "new_device_login"
+)
+
+// messageTemplate is a parsed template file. Each file defines "subject" and
+// "text" blocks, executed as plain text, and an "html" block, executed with
+// contextual escaping. Notifications that may be texted also define an "sms"
+// block, executed as plain text.
+type messageTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// EmailHandler processes email:send tasks
+type EmailHandler struct {
+	mailer    Mailer
+	templates map[string]*messageTemplate
+	logger    *zap.Logger
+}
+
+// NewEmailHandler creates a new email handler with the embedded templates
+func NewEmailHandler(mailer Mailer, logger *zap.Logger) (*EmailHandler, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailHandler{
+		mailer:    mailer,
+		templates: templates,
+		logger:    logger,
+	}, nil
+}
+
+// loadTemplates parses the embedded templates, keyed by file name without extension
+func loadTemplates() (map[string]*messageTemplate, error) {
+	files, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+
+	templates := make(map[string]*messageTemplate, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".tmpl")
+		filePath := path.Join("templates", file.Name())
+
+		text, err := template.New(name).Option("missingkey=zero").ParseFS(templateFS, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		html, err := htmltemplate.New(name).Option("missingkey=zero").ParseFS(templateFS, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		templates[name] = &messageTemplate{text: text, html: html}
+	}
+	return templates, nil
+}
+
+// ProcessTask renders the email described by task and sends it
+func (h *EmailHandler) ProcessTask(ctx context.Context, task *Task) error {
+	var payload EmailPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal email payload: %w", err)
+	}
+
+	email, err := h.render(&payload)
+	if err != nil {
+		return err
+	}
+
+	if err := h.mailer.Send(ctx, email); err != nil {
+		return err
+	}
+
+	h.logger.Info("Email sent",
+		zap.String("email", payload.To),
+		zap.String("template", payload.Template),
+	)
+	return nil
+}
+
+// render executes the subject, text and HTML bodies of the payload's template
+func (h *EmailHandler) render(payload *EmailPayload) (*Email, error) {
+	tmpl, ok := h.templates[payload.Template]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template: %s", payload.Template)
+	}
+
+	var subject, text, html strings.Builder
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", payload.Data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", payload.Template, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", payload.Data); err != nil {
+		return nil, fmt.Errorf("failed to render text body of %s: %w", payload.Template, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "html", payload.Data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML body of %s: %w", payload.Template, err)
+	}
+
+	return &Email{
+		To:      payload.To,
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,134 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// Email is a rendered message ready to send. HTML may be empty to send a
+// plain text email.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends rendered emails
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// NewMailer creates a mailer for the configured email provider. Without one,
+// it creates an SMTP mailer, or a no-op mailer when no SMTP host is configured.
+func NewMailer(cfg *config.Config, logger *zap.Logger) Mailer {
+	switch provider := cfg.Notifications.Email.Provider; provider {
+	case "ses":
+		return NewSESMailer(cfg.Notifications.Email.SES, nil)
+	case "smtp", "":
+		if cfg.SMTP.Host == "" {
+			logger.Info("SMTP host not configured, emails will only be logged")
+			return NewNoopMailer(logger)
+		}
+		return NewSMTPMailer(cfg.SMTP)
+	default:
+		logger.Warn("Unknown email provider, emails will only be logged",
+			zap.String("provider", provider),
+		)
+		return NewNoopMailer(logger)
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	config config.SMTPConfig
+}
+
+// NewSMTPMailer creates a new SMTP mailer
+func NewSMTPMailer(cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: cfg}
+}
+
+// Send sends email via SMTP
+func (m *SMTPMailer) Send(ctx context.Context, email *Email) error {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	// The envelope sender is the bare address, From may carry a display name
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	if err := smtp.SendMail(addr, auth, from.Address, []string{email.To}, m.message(email)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message builds the RFC 5322 message for email, a multipart/alternative one
+// when it has an HTML part
+func (m *SMTPMailer) message(email *Email) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + m.config.From + "\r\n")
+	b.WriteString("To: " + email.To + "\r\n")
+	b.WriteString("Subject: " + email.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if email.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(email.Text)
+		return b.Bytes()
+	}
+
+	var parts bytes.Buffer
+	w := multipart.NewWriter(&parts)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	} {
+		// Writing to a bytes.Buffer cannot fail
+		pw, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		_, _ = pw.Write([]byte(part.body))
+	}
+	_ = w.Close()
+
+	b.WriteString("Content-Type: multipart/alternative; boundary=" + w.Boundary() + "\r\n")
+	b.WriteString("\r\n")
+	b.Write(parts.Bytes())
+	return b.Bytes()
+}
+
+// NoopMailer only logs emails, for environments without an SMTP server
+type NoopMailer struct {
+	logger *zap.Logger
+}
+
+// NewNoopMailer creates a new no-op mailer
+func NewNoopMailer(logger *zap.Logger) *NoopMailer {
+	return &NoopMailer{logger: logger}
+}
+
+// Send logs email instead of sending it
+func (m *NoopMailer) Send(ctx context.Context, email *Email) error {
+	m.logger.Debug("Sending email",
+		zap.String("email", email.To),
+		zap.String("subject", email.Subject),
+	)
+	return nil
+}
@@ -0,0 +1,421 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// pollTimeout bounds how long a worker blocks waiting for a task, so Stop is noticed promptly
+const pollTimeout = time.Second
+
+const (
+	// serverTTL is how long a task server counts as running after its last
+	// heartbeat. The tasks it was working on are returned to their queues
+	// once it is gone.
+	serverTTL = 30 * time.Second
+
+	// heartbeatInterval is how often a server renews its heartbeat and looks
+	// for tasks abandoned by servers that are gone
+	heartbeatInterval = 10 * time.Second
+)
+
+// HandlerFunc processes a task
+type HandlerFunc func(ctx context.Context, task *Task) error
+
+// periodicTask is a task enqueued on the default queue every interval
+type periodicTask struct {
+	taskType string
+	interval time.Duration
+}
+
+// Server runs workers that take tasks off the configured queues and enqueues
+// periodic tasks
+type Server struct {
+	// id names the processing lists of this server
+	id       string
+	client   *Client
+	redis    *redis.Client
+	queues   []string
+	workers  int
+	handlers map[string]HandlerFunc
+	retries  map[string]retryPolicy
+	periodic []periodicTask
+	logger   *zap.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a new task server processing tasks enqueued through client.
+// Expired sessions are purged every task.cleanup_interval unless it is zero
+// or there is no session store. Webhook deliveries are retried with backoff
+// per notifications.webhook.
+func NewServer(cfg *config.Config, client *Client, mailer Mailer, sms SMSSender, sessions SessionStore, webhooks WebhookStore, logger *zap.Logger) (*Server, error) {
+	emailHandler, err := NewEmailHandler(mailer, logger)
+	if err != nil {
+		return nil, err
+	}
+	smsHandler, err := NewSMSHandler(sms, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	queues := cfg.Task.Queues
+	if len(queues) == 0 {
+		queues = []string{QueueDefault, QueueEmail, QueueNotification}
+	}
+
+	workers := cfg.Task.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	webhookRetry := retryPolicy{
+		maxAttempts: cfg.Notifications.Webhook.MaxAttempts,
+		backoff:     cfg.Notifications.Webhook.RetryBackoff,
+	}
+	if webhookRetry.maxAttempts < 1 {
+		webhookRetry.maxAttempts = maxAttempts
+	}
+
+	var periodic []periodicTask
+	if cfg.Task.CleanupInterval > 0 && sessions != nil {
+		periodic = append(periodic, periodicTask{taskType: TypeCleanupExpired, interval: cfg.Task.CleanupInterval})
+	}
+
+	return &Server{
+		id:      uuid.NewString(),
+		client:  client,
+		redis:   client.redis,
+		queues:  queues,
+		workers: workers,
+		handlers: map[string]HandlerFunc{
+			TypeEmailSend:      emailHandler.ProcessTask,
+			TypeSMSSend:        smsHandler.ProcessTask,
+			TypeWebhookSend:    NewWebhookHandler(cfg.Notifications.Webhook, webhooks, nil, logger).ProcessTask,
+			TypeCleanupExpired: NewCleanupHandler(sessions, logger).ProcessTask,
+		},
+		retries: map[string]retryPolicy{
+			TypeWebhookSend: webhookRetry,
+		},
+		periodic: periodic,
+		logger:   logger,
+	}, nil
+}
+
+// Start starts the workers in the background
+func (s *Server) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+
+	// Announce the server before taking tasks, so other servers leave its
+	// processing lists alone
+	if err := s.heartbeat(ctx); err != nil {
+		s.logger.Error("Failed to register task server", zap.Error(err))
+	}
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for ctx.Err() == nil {
+				if _, err := s.processNext(ctx, pollTimeout); err != nil && ctx.Err() == nil {
+					s.logger.Error("Failed to take task", zap.Error(err))
+					time.Sleep(pollTimeout)
+				}
+			}
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.promoteScheduled(ctx)
+	}()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.keepAlive(ctx)
+	}()
+
+	for _, p := range s.periodic {
+		s.wg.Add(1)
+		go func(p periodicTask) {
+			defer s.wg.Done()
+			s.schedule(ctx, p)
+		}(p)
+	}
+
+	s.logger.Info("Task server started",
+		zap.Strings("queues", s.queues),
+		zap.Int("workers", s.workers),
+	)
+}
+
+// Stop stops the workers and waits for in-flight tasks to finish
+func (s *Server) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+
+	// The processing lists are empty now
+	if err := s.redis.Del(context.Background(), serverKey(s.id)).Err(); err != nil {
+		s.logger.Warn("Failed to unregister task server", zap.Error(err))
+	}
+	s.logger.Info("Task server stopped")
+}
+
+// schedule enqueues p on the default queue every interval until ctx is done
+func (s *Server) schedule(ctx context.Context, p periodicTask) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.client.Enqueue(ctx, QueueDefault, &Task{Type: p.taskType}); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to enqueue periodic task",
+					zap.String("type", p.taskType),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// promoteScheduledScript moves up to ARGV[2] tasks due by ARGV[1] from the
+// scheduled set back to their queues
+var promoteScheduledScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, member in ipairs(due) do
+	redis.call('ZREM', KEYS[1], member)
+	local sep = string.find(member, '\n', 1, true)
+	redis.call('LPUSH', string.sub(member, 1, sep - 1), string.sub(member, sep + 1))
+end
+return #due
+`)
+
+// promoteScheduled moves tasks waiting to be retried back to their queues
+// once they are due, until ctx is done
+func (s *Server) promoteScheduled(ctx context.Context) {
+	ticker := time.NewTicker(pollTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.promoteDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to promote scheduled tasks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// promoteDue moves the tasks due by now back to their queues and returns how many were moved
+func (s *Server) promoteDue(ctx context.Context, now time.Time) (int, error) {
+	moved, err := promoteScheduledScript.Run(ctx, s.redis, []string{scheduledKey}, now.UnixMilli(), 100).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote scheduled tasks: %w", err)
+	}
+	return moved, nil
+}
+
+// heartbeat marks the server as running for serverTTL
+func (s *Server) heartbeat(ctx context.Context) error {
+	return s.redis.Set(ctx, serverKey(s.id), time.Now().UnixMilli(), serverTTL).Err()
+}
+
+// keepAlive renews the heartbeat of the server and returns abandoned tasks
+// to their queues every heartbeatInterval, until ctx is done
+func (s *Server) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.recoverAbandoned(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to recover abandoned tasks", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to renew task server heartbeat", zap.Error(err))
+			}
+		}
+	}
+}
+
+// recoverScript moves every task in the processing list KEYS[1] back to the
+// queue KEYS[2], keeping the oldest first in line
+var recoverScript = redis.NewScript(`
+local moved = 0
+while redis.call('LMOVE', KEYS[1], KEYS[2], 'LEFT', 'RIGHT') do
+	moved = moved + 1
+end
+return moved
+`)
+
+// recoverAbandoned returns the tasks left in the processing lists of servers
+// that are no longer running to their queues and returns how many were moved
+func (s *Server) recoverAbandoned(ctx context.Context) (int, error) {
+	recovered := 0
+	iter := s.redis.Scan(ctx, 0, queueKey("*")+processingInfix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		processing := iter.Val()
+		i := strings.LastIndex(processing, processingInfix)
+		queue, owner := processing[:i], processing[i+len(processingInfix):]
+
+		running, err := s.redis.Exists(ctx, serverKey(owner)).Result()
+		if err != nil {
+			return recovered, fmt.Errorf("failed to check task server %s: %w", owner, err)
+		}
+		if running > 0 {
+			continue
+		}
+
+		moved, err := recoverScript.Run(ctx, s.redis, []string{processing, queue}).Int()
+		if err != nil {
+			return recovered, fmt.Errorf("failed to recover tasks of server %s: %w", owner, err)
+		}
+		if moved > 0 {
+			s.logger.Warn("Returned abandoned tasks to their queue",
+				zap.String("queue", queue),
+				zap.String("server", owner),
+				zap.Int("tasks", moved),
+			)
+		}
+		recovered += moved
+	}
+	if err := iter.Err(); err != nil {
+		return recovered, fmt.Errorf("failed to list processing tasks: %w", err)
+	}
+	return recovered, nil
+}
+
+// retryPolicy returns how tasks of taskType are retried
+func (s *Server) retryPolicy(taskType string) retryPolicy {
+	if policy, ok := s.retries[taskType]; ok {
+		return policy
+	}
+	return retryPolicy{maxAttempts: maxAttempts}
+}
+
+// take moves the next task into the processing list of the server and
+// returns it with its queue. Queues are tried in priority order; when all are
+// empty it waits up to timeout for a task on the first one, and tasks that
+// arrive on the others meanwhile are taken by the next call. It returns
+// redis.Nil if no task arrived.
+func (s *Server) take(ctx context.Context, timeout time.Duration) (string, string, error) {
+	for _, queue := range s.queues {
+		data, err := s.redis.LMove(ctx, queueKey(queue), processingKey(queue, s.id), "RIGHT", "LEFT").Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		return queue, data, err
+	}
+
+	queue := s.queues[0]
+	data, err := s.redis.BLMove(ctx, queueKey(queue), processingKey(queue, s.id), "RIGHT", "LEFT", timeout).Result()
+	return queue, data, err
+}
+
+// processNext waits up to timeout for a task and processes it. It reports
+// whether a task was taken; failing tasks are retried per their retry policy.
+// The task stays in the processing list of the server until it is done with,
+// so it is recovered if the server dies while processing it.
+func (s *Server) processNext(ctx context.Context, timeout time.Duration) (bool, error) {
+	queue, data, err := s.take(ctx, timeout)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	key, processing := queueKey(queue), processingKey(queue, s.id)
+
+	// Stop cancels ctx while tasks are processed; what happens to the task
+	// must still be recorded
+	done := context.WithoutCancel(ctx)
+
+	var task Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		s.logger.Error("Dropping malformed task", zap.String("queue", key), zap.Error(err))
+		return true, s.finish(done, processing, data)
+	}
+
+	err = s.handle(ctx, &task)
+	if err == nil {
+		return true, s.finish(done, processing, data)
+	}
+
+	policy := s.retryPolicy(task.Type)
+	task.Attempts++
+	if task.Attempts >= policy.maxAttempts {
+		s.logger.Error("Task failed, giving up",
+			zap.String("type", task.Type),
+			zap.Int("attempts", task.Attempts),
+			zap.Error(err),
+		)
+		return true, s.finish(done, processing, data)
+	}
+
+	delay := policy.delay(task.Attempts)
+	s.logger.Warn("Task failed, requeueing",
+		zap.String("type", task.Type),
+		zap.Int("attempts", task.Attempts),
+		zap.Duration("retry_in", delay),
+		zap.Error(err),
+	)
+	requeued, _ := json.Marshal(&task)
+	_, err = s.redis.TxPipelined(done, func(pipe redis.Pipeliner) error {
+		pipe.LRem(done, processing, 1, data)
+		if delay > 0 {
+			pipe.ZAdd(done, scheduledKey, redis.Z{
+				Score:  float64(time.Now().Add(delay).UnixMilli()),
+				Member: key + "\n" + string(requeued),
+			})
+		} else {
+			pipe.LPush(done, key, requeued)
+		}
+		return nil
+	})
+	if err != nil {
+		return true, fmt.Errorf("failed to requeue %s task: %w", task.Type, err)
+	}
+	return true, nil
+}
+
+// finish removes a task that needs no more processing from processing
+func (s *Server) finish(ctx context.Context, processing, data string) error {
+	if err := s.redis.LRem(ctx, processing, 1, data).Err(); err != nil {
+		return fmt.Errorf("failed to remove finished task: %w", err)
+	}
+	return nil
+}
+
+// handle dispatches task to the handler registered for its type
+func (s *Server) handle(ctx context.Context, task *Task) error {
+	handler, ok := s.handlers[task.Type]
+	if !ok {
+		return fmt.Errorf("no handler for task type %s", task.Type)
+	}
+	return handler(ctx, task)
+}
@@ -0,0 +1,205 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// sesSendPath is the path of the SES v2 SendEmail operation
+const sesSendPath = "/v2/email/outbound-emails"
+
+// SESMailer sends emails through the AWS SES v2 API, signing requests with
+// AWS Signature Version 4
+type SESMailer struct {
+	config   config.SESConfig
+	signer   *sigV4Signer
+	client   *http.Client
+	endpoint string
+	now      func() time.Time
+}
+
+// NewSESMailer creates a new SES mailer. client may be nil to use a client
+// with a 10 second timeout.
+func NewSESMailer(cfg config.SESConfig, client *http.Client) *SESMailer {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &SESMailer{
+		config: cfg,
+		signer: &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			sessionToken:    cfg.SessionToken,
+			region:          cfg.Region,
+			service:         "ses",
+		},
+		client:   client,
+		endpoint: fmt.Sprintf("https://email.%s.amazonaws.com", cfg.Region),
+		now:      time.Now,
+	}
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesBody struct {
+	Text *sesContent `json:"Text,omitempty"`
+	HTML *sesContent `json:"Html,omitempty"`
+}
+
+type sesSendEmailRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    sesBody    `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Send sends email via SES. Errors never include the account credentials.
+func (m *SESMailer) Send(ctx context.Context, email *Email) error {
+	var body sesSendEmailRequest
+	body.FromEmailAddress = m.config.From
+	body.Destination.ToAddresses = []string{email.To}
+	body.Content.Simple.Subject = sesContent{Data: email.Subject, Charset: "UTF-8"}
+	body.Content.Simple.Body.Text = &sesContent{Data: email.Text, Charset: "UTF-8"}
+	if email.HTML != "" {
+		body.Content.Simple.Body.HTML = &sesContent{Data: email.HTML, Charset: "UTF-8"}
+	}
+
+	payload, err := json.Marshal(&body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SES request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint+sesSendPath, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build SES request: %w", err)
+	}
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	m.signer.sign(req, payloadHash, m.now())
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var sesErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&sesErr); err != nil || sesErr.Message == "" {
+		return fmt.Errorf("failed to send email: ses responded with status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("failed to send email: ses responded with status %d: %s", resp.StatusCode, sesErr.Message)
+}
+
+// sigV4Signer signs requests with AWS Signature Version 4
+type sigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	// sessionToken is set for temporary credentials
+	sessionToken string
+	region       string
+	service      string
+}
+
+// sign adds the X-Amz-Date and Authorization headers to req, whose body
+// hashes to payloadHash, and X-Amz-Security-Token for temporary credentials.
+// The signature covers the host and every header set on req.
+func (s *sigV4Signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/" + s.service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
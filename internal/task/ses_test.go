@@ -0,0 +1,125 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+func newTestSESMailer(status int, body string, requests *[]*http.Request) *SESMailer {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+	m := NewSESMailer(config.SESConfig{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret-key",
+		From:            "no-reply@usercenter.local",
+	}, client)
+	m.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return m
+}
+
+func TestSESMailer_Send(t *testing.T) {
+	var requests []*http.Request
+	m := newTestSESMailer(http.StatusOK, `{"MessageId":"abc"}`, &requests)
+
+	email := &Email{To: "alice@example.com", Subject: "Hello", Text: "Hi alice", HTML: "<p>Hi alice</p>"}
+	require.NoError(t, m.Send(context.Background(), email))
+
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://email.eu-west-1.amazonaws.com/v2/email/outbound-emails", req.URL.String())
+	assert.Equal(t, "20240102T030405Z", req.Header.Get("X-Amz-Date"))
+
+	auth := req.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(auth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/eu-west-1/ses/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="), auth)
+	assert.NotContains(t, auth, "secret-key")
+
+	var body sesSendEmailRequest
+	require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+	assert.Equal(t, "no-reply@usercenter.local", body.FromEmailAddress)
+	assert.Equal(t, []string{"alice@example.com"}, body.Destination.ToAddresses)
+	assert.Equal(t, "Hello", body.Content.Simple.Subject.Data)
+	assert.Equal(t, "Hi alice", body.Content.Simple.Body.Text.Data)
+	assert.Equal(t, "<p>Hi alice</p>", body.Content.Simple.Body.HTML.Data)
+}
+
+// TestSigV4Signer_TestSuite checks signatures against the AWS Signature
+// Version 4 test suite
+func TestSigV4Signer_TestSuite(t *testing.T) {
+	const sessionToken = "AQoDYXdzEPT//////////wEXAMPLEtc764bNrC9SAPBSM22wDOk4x4HIZ8j4FZTwdQWLWsKWHGBuFqwAeMicRXmxfpSPfIeoIYRqTflfKD8YUuwthAx7mSEI/qkPpKPi/kMcGdQrmGdeehM4IC1NtBmUpp2wUE8phUZampKsburEDy0KPkyQDYwT7WZ0wq5VSXDvp75YU9HFvlRd8Tx6q6fE8YQcHNVXAkiY9q6d+xo0rKwT38xVqr7ZD0u0iPPkUL64lIZbqBAz+scqKmlzm8FDrypNC9Yjc8fPOLn9FX9KSYvKTr4rvx3iSIlTJabIQwj2ICCR/oLxBA=="
+
+	tests := []struct {
+		name          string
+		method        string
+		sessionToken  string
+		signedHeaders string
+		signature     string
+	}{
+		{name: "get-vanilla", method: http.MethodGet, signedHeaders: "host;x-amz-date", signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "post-vanilla", method: http.MethodPost, signedHeaders: "host;x-amz-date", signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{name: "post-sts-header-before", method: http.MethodPost, sessionToken: sessionToken, signedHeaders: "host;x-amz-date;x-amz-security-token", signature: "85d96828115b5dc0cfc3bd16ad9e210dd772bbebba041836c64533a82be05ead"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := &sigV4Signer{
+				accessKeyID:     "AKIDEXAMPLE",
+				secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+				sessionToken:    tt.sessionToken,
+				region:          "us-east-1",
+				service:         "service",
+			}
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com/", nil)
+			require.NoError(t, err)
+
+			signer.sign(req, sha256Hex(nil), time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+			assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+			assert.Equal(t, tt.sessionToken, req.Header.Get("X-Amz-Security-Token"))
+			assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+				"SignedHeaders="+tt.signedHeaders+", Signature="+tt.signature, req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestSESMailer_SendWithSessionToken(t *testing.T) {
+	var requests []*http.Request
+	m := newTestSESMailer(http.StatusOK, `{"MessageId":"abc"}`, &requests)
+	m.signer.sessionToken = "session-token"
+
+	require.NoError(t, m.Send(context.Background(), &Email{To: "alice@example.com", Subject: "Hello", Text: "Hi alice"}))
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "session-token", requests[0].Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, requests[0].Header.Get("Authorization"),
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token, ")
+}
+
+func TestSESMailer_SendFails(t *testing.T) {
+	var requests []*http.Request
+	m := newTestSESMailer(http.StatusBadRequest, `{"message":"Email address is not verified."}`, &requests)
+
+	err := m.Send(context.Background(), &Email{To: "alice@example.com", Subject: "Hello", Text: "Hi alice"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.Contains(t, err.Error(), "Email address is not verified.")
+	assert.NotContains(t, err.Error(), "secret-key")
+}
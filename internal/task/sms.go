@@ -0,0 +1,109 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+)
+
+// SMSSender sends text messages
+type SMSSender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewSMSSender creates a sender for the configured SMS provider, or a no-op
+// sender when none is configured
+func NewSMSSender(cfg *config.Config, logger *zap.Logger) SMSSender {
+	switch provider := cfg.Notifications.SMS.Provider; provider {
+	case "twilio":
+		return NewTwilioSMSSender(cfg.Notifications.SMS.Twilio, nil)
+	case "":
+		logger.Info("No SMS provider configured, text messages will only be logged")
+	default:
+		logger.Warn("Unknown SMS provider, text messages will only be logged",
+			zap.String("provider", provider),
+		)
+	}
+	return NewNoopSMSSender(logger)
+}
+
+// NoopSMSSender only logs text messages, for environments without an SMS provider
+type NoopSMSSender struct {
+	logger *zap.Logger
+}
+
+// NewNoopSMSSender creates a new no-op SMS sender
+func NewNoopSMSSender(logger *zap.Logger) *NoopSMSSender {
+	return &NoopSMSSender{logger: logger}
+}
+
+// Send logs the text message instead of sending it. The body is left out
+// since it may hold a verification code.
+func (s *NoopSMSSender) Send(ctx context.Context, to, body string) error {
+	s.logger.Debug("Sending text message", zap.String("phone", to))
+	return nil
+}
+
+// SMSHandler processes sms:send tasks
+type SMSHandler struct {
+	sender    SMSSender
+	templates map[string]*messageTemplate
+	logger    *zap.Logger
+}
+
+// NewSMSHandler creates a new SMS handler with the embedded templates
+func NewSMSHandler(sender SMSSender, logger *zap.Logger) (*SMSHandler, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SMSHandler{
+		sender:    sender,
+		templates: templates,
+		logger:    logger,
+	}, nil
+}
+
+// ProcessTask sends the text message described by task
+func (h *SMSHandler) ProcessTask(ctx context.Context, task *Task) error {
+	var payload SMSPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal SMS payload: %w", err)
+	}
+
+	body, err := h.render(&payload)
+	if err != nil {
+		return err
+	}
+
+	if err := h.sender.Send(ctx, payload.To, body); err != nil {
+		return err
+	}
+
+	h.logger.Info("Text message sent", zap.String("phone", payload.To))
+	return nil
+}
+
+// render returns the text of the message, rendering the payload's template
+// when it has one
+func (h *SMSHandler) render(payload *SMSPayload) (string, error) {
+	if payload.Template == "" {
+		return payload.Body, nil
+	}
+
+	tmpl, ok := h.templates[payload.Template]
+	if !ok || tmpl.text.Lookup("sms") == nil {
+		return "", fmt.Errorf("unknown SMS template: %s", payload.Template)
+	}
+
+	var body strings.Builder
+	if err := tmpl.text.ExecuteTemplate(&body, "sms", payload.Data); err != nil {
+		return "", fmt.Errorf("failed to render SMS of %s: %w", payload.Template, err)
+	}
+	return strings.TrimSpace(body.String()), nil
+}
@@ -0,0 +1,132 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Task types
+const (
+	// TypeEmailSend renders an email template and sends it
+	TypeEmailSend = "email:send"
+	// TypeSMSSend sends a text message
+	TypeSMSSend = "sms:send"
+	// TypeWebhookSend posts a notification to the configured webhook or a user
+	// event to a webhook subscription
+	TypeWebhookSend = "webhook:send"
+	// TypeCleanupExpired purges expired sessions; it is scheduled periodically
+	TypeCleanupExpired = "cleanup:expired"
+)
+
+// Queues tasks are enqueued to
+const (
+	QueueDefault      = "default"
+	QueueEmail        = "email"
+	QueueNotification = "notification"
+)
+
+// maxAttempts is how many times a failing task is processed before it is
+// dropped, unless its type has its own retry policy
+const maxAttempts = 3
+
+// retryPolicy is how a failing task is retried: it is processed up to
+// maxAttempts times, waiting backoff before the first retry and twice as long
+// before each next one. Without a backoff it is requeued right away.
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// delay returns how long to wait before retrying a task that failed attempts times
+func (p retryPolicy) delay(attempts int) time.Duration {
+	if p.backoff <= 0 || attempts < 1 {
+		return 0
+	}
+	return p.backoff * time.Duration(1<<(attempts-1))
+}
+
+// Task is a unit of work stored in a Redis-backed queue
+type Task struct {
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
+	Attempts int             `json:"attempts"`
+}
+
+// EmailPayload is the payload of an email:send task
+type EmailPayload struct {
+	To       string            `json:"to"`
+	Template string            `json:"template"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// NewEmailTask creates an email:send task
+func NewEmailTask(payload EmailPayload) (*Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal email payload: %w", err)
+	}
+	return &Task{Type: TypeEmailSend, Payload: data}, nil
+}
+
+// SMSPayload is the payload of an sms:send task. The text is Body, or the
+// "sms" block of Template rendered with Data when Template is set.
+type SMSPayload struct {
+	To       string            `json:"to"`
+	Body     string            `json:"body,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// NewSMSTask creates an sms:send task
+func NewSMSTask(payload SMSPayload) (*Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SMS payload: %w", err)
+	}
+	return &Task{Type: TypeSMSSend, Payload: data}, nil
+}
+
+// WebhookPayload is the payload of a webhook:send task. Body is posted to the
+// webhook subscription with SubscriptionID, or to the notification webhook
+// when it is empty.
+type WebhookPayload struct {
+	SubscriptionID string          `json:"subscription_id,omitempty"`
+	Event          string          `json:"event"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// NewWebhookTask creates a webhook:send task
+func NewWebhookTask(payload WebhookPayload) (*Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return &Task{Type: TypeWebhookSend, Payload: data}, nil
+}
+
+// queueKey returns the Redis list holding the tasks of queue
+func queueKey(queue string) string {
+	return "usercenter:tasks:" + queue
+}
+
+// processingKey returns the Redis list holding the tasks of queue that the
+// task server with id is working on
+func processingKey(queue, id string) string {
+	return queueKey(queue) + processingInfix + id
+}
+
+// processingInfix separates the queue key from the server ID in processing
+// list keys
+const processingInfix = ":processing:"
+
+// serverKey returns the Redis key whose presence shows that the task server
+// with id is running
+func serverKey(id string) string {
+	return "usercenter:tasks:servers:" + id
+}
+
+// scheduledKey is the Redis sorted set holding tasks waiting to be retried,
+// scored by when they are due in Unix milliseconds. Members are the key of
+// the queue the task goes back to, a newline and the task.
+const scheduledKey = "usercenter:tasks:scheduled"
@@ -0,0 +1,455 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testMailer records sent emails instead of delivering them
+type testMailer struct {
+	sent []*Email
+	err  error
+}
+
+func (m *testMailer) Send(ctx context.Context, email *Email) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+// testSMSSender records sent text messages instead of delivering them
+type testSMSSender struct {
+	sent []SMSPayload
+}
+
+func (s *testSMSSender) Send(ctx context.Context, to, body string) error {
+	s.sent = append(s.sent, SMSPayload{To: to, Body: body})
+	return nil
+}
+
+// testSessionStore counts expired session purges
+type testSessionStore struct {
+	mu     sync.Mutex
+	purges int
+}
+
+func (s *testSessionStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purges++
+	return 1, nil
+}
+
+func (s *testSessionStore) Purges() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.purges
+}
+
+func newTestServer(t *testing.T, mailer Mailer) (*Client, *Server, *miniredis.Miniredis) {
+	return newTestServerWithSMS(t, mailer, NewNoopSMSSender(zap.NewNop()))
+}
+
+func newTestServerWithSMS(t *testing.T, mailer Mailer, sms SMSSender) (*Client, *Server, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{Task: config.TaskConfig{
+		Redis:   config.RedisConfig{Addr: mr.Addr()},
+		Queues:  []string{QueueDefault, QueueEmail, QueueNotification},
+		Workers: 1,
+	}}
+
+	client, err := NewClient(cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	server, err := NewServer(cfg, client, mailer, sms, &testSessionStore{}, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	return client, server, mr
+}
+
+func TestEnqueueAndProcessEmail(t *testing.T) {
+	mailer := &testMailer{}
+	client, server, mr := newTestServer(t, mailer)
+	ctx := context.Background()
+
+	require.NoError(t, client.EnqueueEmail(ctx, EmailPayload{
+		To:       "alice@example.com",
+		Template: TemplateWelcome,
+		Data:     map[string]string{"username": "alice", "email": "alice@example.com"},
+	}))
+
+	items, err := mr.List(queueKey(QueueEmail))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(items[0]), &task))
+	assert.Equal(t, TypeEmailSend, task.Type)
+
+	processed, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+	assert.Equal(t, "Welcome to User Center", mailer.sent[0].Subject)
+	assert.Contains(t, mailer.sent[0].Text, "Hi alice,")
+	assert.Contains(t, mailer.sent[0].HTML, "<p>Hi alice,</p>")
+	assert.False(t, mr.Exists(queueKey(QueueEmail)))
+}
+
+func TestEnqueueAndProcessSMS(t *testing.T) {
+	sms := &testSMSSender{}
+	client, server, mr := newTestServerWithSMS(t, &testMailer{}, sms)
+	ctx := context.Background()
+
+	require.NoError(t, client.EnqueueSMS(ctx, SMSPayload{To: "+15551234567", Body: "Your code is 123456"}))
+	assert.True(t, mr.Exists(queueKey(QueueNotification)))
+
+	processed, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	assert.Equal(t, []SMSPayload{{To: "+15551234567", Body: "Your code is 123456"}}, sms.sent)
+	assert.False(t, mr.Exists(queueKey(QueueNotification)))
+}
+
+func TestSMSHandler_Templates(t *testing.T) {
+	sms := &testSMSSender{}
+	h, err := NewSMSHandler(sms, zap.NewNop())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	payload, err := json.Marshal(SMSPayload{
+		To:       "+15551234567",
+		Template: TemplateNewDeviceLogin,
+		Data:     map[string]string{"ip_address": "198.51.100.5"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, h.ProcessTask(ctx, &Task{Type: TypeSMSSend, Payload: payload}))
+	require.Len(t, sms.sent, 1)
+	assert.Contains(t, sms.sent[0].Body, "from 198.51.100.5.")
+
+	// The temporary password must not be texted, so password setup has no SMS
+	payload, err = json.Marshal(SMSPayload{To: "+15551234567", Template: TemplatePasswordSetup})
+	require.NoError(t, err)
+	assert.EqualError(t, h.ProcessTask(ctx, &Task{Type: TypeSMSSend, Payload: payload}), "unknown SMS template: password_setup")
+}
+
+func TestNewSMSSender_NoProvider(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	sender := NewSMSSender(&config.Config{}, zap.New(core))
+	require.IsType(t, &NoopSMSSender{}, sender)
+
+	client, server, _ := newTestServerWithSMS(t, &testMailer{}, sender)
+	ctx := context.Background()
+
+	require.NoError(t, client.EnqueueSMS(ctx, SMSPayload{To: "+15551234567", Body: "Your code is 123456"}))
+	processed, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	// The code must never reach the logs
+	sent := logs.FilterMessage("Sending text message").All()
+	require.Len(t, sent, 1)
+	assert.Equal(t, map[string]interface{}{"phone": "+15551234567"}, sent[0].ContextMap())
+}
+
+func TestServer_RequeuesFailedTasks(t *testing.T) {
+	mailer := &testMailer{err: errors.New("smtp unavailable")}
+	client, server, mr := newTestServer(t, mailer)
+	ctx := context.Background()
+
+	require.NoError(t, client.EnqueueEmail(ctx, EmailPayload{To: "alice@example.com", Template: TemplateWelcome}))
+
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		_, err := server.processNext(ctx, time.Second)
+		require.NoError(t, err)
+
+		items, err := mr.List(queueKey(QueueEmail))
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+
+		var task Task
+		require.NoError(t, json.Unmarshal([]byte(items[0]), &task))
+		assert.Equal(t, attempt, task.Attempts)
+	}
+
+	// The last attempt drops the task
+	_, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.False(t, mr.Exists(queueKey(QueueEmail)))
+}
+
+// funcMailer sends emails with a function
+type funcMailer func(ctx context.Context, email *Email) error
+
+func (f funcMailer) Send(ctx context.Context, email *Email) error {
+	return f(ctx, email)
+}
+
+func TestServer_KeepsTaskUntilProcessed(t *testing.T) {
+	var (
+		server     *Server
+		mr         *miniredis.Miniredis
+		processing []string
+	)
+	client, server, mr := newTestServer(t, funcMailer(func(ctx context.Context, email *Email) error {
+		processing, _ = mr.List(processingKey(QueueEmail, server.id))
+		return nil
+	}))
+	ctx := context.Background()
+
+	require.NoError(t, client.EnqueueEmail(ctx, EmailPayload{To: "alice@example.com", Template: TemplateWelcome}))
+
+	processed, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	// The task was kept aside while it was processed, then removed
+	assert.Len(t, processing, 1)
+	assert.False(t, mr.Exists(processingKey(QueueEmail, server.id)))
+	assert.False(t, mr.Exists(queueKey(QueueEmail)))
+}
+
+func TestServer_RequeuesTaskInterruptedByStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, server, mr := newTestServer(t, funcMailer(func(ctx context.Context, email *Email) error {
+		// Stop is called while the email is sent
+		cancel()
+		return ctx.Err()
+	}))
+
+	require.NoError(t, client.EnqueueEmail(context.Background(), EmailPayload{To: "alice@example.com", Template: TemplateWelcome}))
+
+	_, err := server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+
+	items, err := mr.List(queueKey(QueueEmail))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(items[0]), &task))
+	assert.Equal(t, 1, task.Attempts)
+	assert.False(t, mr.Exists(processingKey(QueueEmail, server.id)))
+}
+
+func TestServer_RecoversAbandonedTasks(t *testing.T) {
+	_, server, mr := newTestServer(t, &testMailer{})
+	ctx := context.Background()
+
+	// A server that is gone left two tasks behind; a running one is busy
+	// with a third
+	mr.Lpush(processingKey(QueueEmail, "gone"), `{"type":"older"}`)
+	mr.Lpush(processingKey(QueueEmail, "gone"), `{"type":"newer"}`)
+	mr.Lpush(processingKey(QueueEmail, "running"), `{"type":"busy"}`)
+	require.NoError(t, mr.Set(serverKey("running"), "1"))
+	mr.Lpush(queueKey(QueueEmail), `{"type":"queued"}`)
+
+	recovered, err := server.recoverAbandoned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recovered)
+
+	// Tasks are taken from the right, so the abandoned ones are next
+	items, err := mr.List(queueKey(QueueEmail))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"type":"queued"}`, `{"type":"newer"}`, `{"type":"older"}`}, items)
+	assert.False(t, mr.Exists(processingKey(QueueEmail, "gone")))
+	busy, err := mr.List(processingKey(QueueEmail, "running"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"type":"busy"}`}, busy)
+
+	// A started server is running too
+	server.Start(ctx)
+	assert.True(t, mr.Exists(serverKey(server.id)))
+	server.Stop()
+	assert.False(t, mr.Exists(serverKey(server.id)))
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := retryPolicy{maxAttempts: 5, backoff: 30 * time.Second}
+	assert.Equal(t, 30*time.Second, policy.delay(1))
+	assert.Equal(t, time.Minute, policy.delay(2))
+	assert.Equal(t, 4*time.Minute, policy.delay(4))
+
+	assert.Zero(t, retryPolicy{maxAttempts: 5}.delay(3))
+}
+
+func TestServer_SchedulesWebhookRetries(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{
+		Task: config.TaskConfig{
+			Redis:   config.RedisConfig{Addr: mr.Addr()},
+			Queues:  []string{QueueNotification},
+			Workers: 1,
+		},
+		Notifications: config.NotificationsConfig{
+			Webhook: config.WebhookConfig{MaxAttempts: 2, RetryBackoff: time.Minute},
+		},
+	}
+
+	client, err := NewClient(cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	// Without a webhook store every subscription delivery fails
+	server, err := NewServer(cfg, client, &testMailer{}, NewNoopSMSSender(zap.NewNop()), nil, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, client.EnqueueWebhook(ctx, WebhookPayload{
+		SubscriptionID: "sub-1",
+		Event:          "user.registered",
+		Body:           json.RawMessage(`{}`),
+	}))
+
+	_, err = server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+
+	// The retry waits out the backoff in the scheduled set
+	assert.False(t, mr.Exists(queueKey(QueueNotification)))
+	scheduled, err := mr.ZMembers(scheduledKey)
+	require.NoError(t, err)
+	require.Len(t, scheduled, 1)
+
+	moved, err := server.promoteDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+
+	moved, err = server.promoteDue(ctx, time.Now().Add(time.Minute+time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	items, err := mr.List(queueKey(QueueNotification))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	var task Task
+	require.NoError(t, json.Unmarshal([]byte(items[0]), &task))
+	assert.Equal(t, 1, task.Attempts)
+
+	// The last attempt drops the task
+	_, err = server.processNext(ctx, time.Second)
+	require.NoError(t, err)
+	assert.False(t, mr.Exists(queueKey(QueueNotification)))
+	assert.False(t, mr.Exists(scheduledKey))
+}
+
+func TestServer_ProcessNextTimesOutOnEmptyQueue(t *testing.T) {
+	_, server, _ := newTestServer(t, &testMailer{})
+
+	processed, err := server.processNext(context.Background(), 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestServer_SchedulesCleanup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{Task: config.TaskConfig{
+		Redis:           config.RedisConfig{Addr: mr.Addr()},
+		Queues:          []string{QueueDefault},
+		Workers:         1,
+		CleanupInterval: 10 * time.Millisecond,
+	}}
+
+	client, err := NewClient(cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	sessions := &testSessionStore{}
+	server, err := NewServer(cfg, client, &testMailer{}, NewNoopSMSSender(zap.NewNop()), sessions, nil, zap.NewNop())
+	require.NoError(t, err)
+
+	server.Start(context.Background())
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool { return sessions.Purges() >= 2 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_CleanupDisabled(t *testing.T) {
+	_, server, _ := newTestServer(t, &testMailer{})
+	assert.Empty(t, server.periodic)
+}
+
+func TestEmailHandler_Templates(t *testing.T) {
+	h, err := NewEmailHandler(&testMailer{}, zap.NewNop())
+	require.NoError(t, err)
+
+	for _, name := range []string{TemplateWelcome, TemplatePasswordChanged, TemplateStatusChanged, TemplateAccountDeleted, TemplatePasswordSetup, TemplateNewDeviceLogin} {
+		email, err := h.render(&EmailPayload{To: "alice@example.com", Template: name, Data: map[string]string{"username": "alice"}})
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, email.Subject, name)
+		assert.Contains(t, email.Text, "alice", name)
+		assert.Contains(t, email.HTML, "alice", name)
+	}
+
+	_, err = h.render(&EmailPayload{To: "alice@example.com", Template: "missing"})
+	assert.EqualError(t, err, "unknown email template: missing")
+}
+
+func TestEmailHandler_EscapesHTML(t *testing.T) {
+	h, err := NewEmailHandler(&testMailer{}, zap.NewNop())
+	require.NoError(t, err)
+
+	email, err := h.render(&EmailPayload{To: "alice@example.com", Template: TemplateWelcome, Data: map[string]string{"username": "<b>alice</b>"}})
+	require.NoError(t, err)
+
+	assert.Contains(t, email.Text, "Hi <b>alice</b>,")
+	assert.Contains(t, email.HTML, "Hi &lt;b&gt;alice&lt;/b&gt;,")
+	assert.NotContains(t, email.HTML, "<b>")
+}
+
+func TestSMTPMailer_Message(t *testing.T) {
+	m := NewSMTPMailer(config.SMTPConfig{From: "User Center <no-reply@usercenter.local>"})
+
+	plain := string(m.message(&Email{To: "alice@example.com", Subject: "Hello", Text: "Hi alice"}))
+	assert.Contains(t, plain, "Content-Type: text/plain; charset=UTF-8\r\n\r\nHi alice")
+
+	multipart := string(m.message(&Email{To: "alice@example.com", Subject: "Hello", Text: "Hi alice", HTML: "<p>Hi alice</p>"}))
+	assert.Contains(t, multipart, "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(t, multipart, "Content-Type: text/plain; charset=UTF-8\r\n\r\nHi alice")
+	assert.Contains(t, multipart, "Content-Type: text/html; charset=UTF-8\r\n\r\n<p>Hi alice</p>")
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		smtpHost string
+		expected Mailer
+	}{
+		{name: "no provider or SMTP host", expected: &NoopMailer{}},
+		{name: "no provider with SMTP host", smtpHost: "smtp.example.com", expected: &SMTPMailer{}},
+		{name: "smtp", provider: "smtp", smtpHost: "smtp.example.com", expected: &SMTPMailer{}},
+		{name: "smtp without host", provider: "smtp", expected: &NoopMailer{}},
+		{name: "ses", provider: "ses", expected: &SESMailer{}},
+		{name: "unknown provider", provider: "carrier-pigeon", smtpHost: "smtp.example.com", expected: &NoopMailer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Notifications.Email.Provider = tt.provider
+			cfg.SMTP.Host = tt.smtpHost
+
+			assert.IsType(t, tt.expected, NewMailer(cfg, zap.NewNop()))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+const twilioBaseURL = "https://api.twilio.com"
+
+// TwilioSMSSender sends text messages through the Twilio Messages API
+type TwilioSMSSender struct {
+	config  config.TwilioConfig
+	client  *http.Client
+	baseURL string
+}
+
+// NewTwilioSMSSender creates a new Twilio SMS sender. client may be nil to use
+// a client with a 10 second timeout.
+func NewTwilioSMSSender(cfg config.TwilioConfig, client *http.Client) *TwilioSMSSender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &TwilioSMSSender{
+		config:  cfg,
+		client:  client,
+		baseURL: twilioBaseURL,
+	}
+}
+
+// twilioError is the body Twilio responds with when a request fails
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send sends body to the phone number to. Errors never include the account
+// credentials.
+func (s *TwilioSMSSender) Send(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", s.config.From)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, url.PathEscape(s.config.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build Twilio request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(s.config.AccountSID, s.config.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds only the account SID, never the auth token
+		return fmt.Errorf("failed to send text message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var twErr twilioError
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&twErr); err != nil || twErr.Message == "" {
+		return fmt.Errorf("failed to send text message: twilio responded with status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("failed to send text message: twilio responded with status %d: %s (code %d)",
+		resp.StatusCode, twErr.Message, twErr.Code)
+}
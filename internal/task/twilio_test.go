@@ -0,0 +1,85 @@
+package task
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/config"
+)
+
+// roundTripFunc stubs the transport of an http.Client
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestTwilioSender(status int, body string, requests *[]*http.Request) *TwilioSMSSender {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+	return NewTwilioSMSSender(config.TwilioConfig{
+		AccountSID: "AC123",
+		AuthToken:  "secret-token",
+		From:       "+15550000000",
+	}, client)
+}
+
+func TestTwilioSMSSender_Send(t *testing.T) {
+	var requests []*http.Request
+	sender := newTestTwilioSender(http.StatusCreated, `{"sid":"SM123","status":"queued"}`, &requests)
+
+	require.NoError(t, sender.Send(context.Background(), "+15551234567", "Your code is 123456"))
+
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://api.twilio.com/2010-04-01/Accounts/AC123/Messages.json", req.URL.String())
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+
+	user, pass, ok := req.BasicAuth()
+	require.True(t, ok)
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "secret-token", pass)
+
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	form, err := url.ParseQuery(string(body))
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"To":   {"+15551234567"},
+		"From": {"+15550000000"},
+		"Body": {"Your code is 123456"},
+	}, form)
+}
+
+func TestTwilioSMSSender_SendFails(t *testing.T) {
+	var requests []*http.Request
+	sender := newTestTwilioSender(http.StatusBadRequest,
+		`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`, &requests)
+
+	err := sender.Send(context.Background(), "not-a-number", "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.Contains(t, err.Error(), "not a valid phone number")
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestNewSMSSender_Twilio(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Notifications.SMS.Provider = "twilio"
+	cfg.Notifications.SMS.Twilio = config.TwilioConfig{AccountSID: "AC123", AuthToken: "secret-token", From: "+15550000000"}
+
+	assert.IsType(t, &TwilioSMSSender{}, NewSMSSender(cfg, nil))
+}
@@ -0,0 +1,220 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"go.uber.org/zap"
+)
+
+// Headers of webhook requests
+const (
+	WebhookEventHeader     = "X-UserCenter-Event"
+	WebhookSignatureHeader = "X-UserCenter-Signature"
+)
+
+// SignWebhook returns the signature of body sent in the
+// X-UserCenter-Signature header: "sha256=" and the hex HMAC-SHA256 of the
+// body keyed with secret
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsInternalAddress reports whether addr is a loopback, private, link-local
+// or unspecified address, which webhook subscriptions may not target so they
+// cannot be used to reach services inside the network
+func IsInternalAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast()
+}
+
+// refuseInternal is a net.Dialer Control function refusing connections to
+// internal addresses. It sees the resolved address, so host names that
+// resolve, or are later rebound, to internal addresses are refused too.
+func refuseInternal(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook target %s: %w", address, err)
+	}
+	if IsInternalAddress(addrPort.Addr()) {
+		return fmt.Errorf("webhook target %s is an internal address", address)
+	}
+	return nil
+}
+
+// newSubscriptionClient returns the client subscription deliveries are posted
+// with. It connects directly, bypassing any proxy, so every address it dials
+// is checked by refuseInternal.
+func newSubscriptionClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseInternal,
+	}).DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// WebhookStore holds webhook subscriptions and their delivery failures,
+// implemented by the webhook repository
+type WebhookStore interface {
+	GetByID(ctx context.Context, id string) (*model.WebhookSubscription, error)
+	RecordDeliveryFailure(ctx context.Context, id string, disableAfter int) (bool, error)
+	ResetDeliveryFailures(ctx context.Context, id string) error
+}
+
+// WebhookHandler processes webhook:send tasks
+type WebhookHandler struct {
+	config config.WebhookConfig
+	store  WebhookStore
+	// client posts to the configured notification webhook, subscriptionClient
+	// to subscriptions, whose URLs come from the API
+	client             *http.Client
+	subscriptionClient *http.Client
+	logger             *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler. store may be nil, in which
+// case subscription deliveries fail; client may be nil to use clients with a
+// 10 second timeout, the one for subscriptions refusing internal addresses.
+// A given client is used for every delivery.
+func NewWebhookHandler(cfg config.WebhookConfig, store WebhookStore, client *http.Client, logger *zap.Logger) *WebhookHandler {
+	subscriptionClient := client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+		subscriptionClient = newSubscriptionClient()
+	}
+	return &WebhookHandler{
+		config:             cfg,
+		store:              store,
+		client:             client,
+		subscriptionClient: subscriptionClient,
+		logger:             logger,
+	}
+}
+
+// ProcessTask posts the body described by task to its webhook subscription,
+// or to the configured notification webhook. Tasks for deleted or disabled
+// subscriptions, or enqueued before the notification webhook was unset, are
+// dropped.
+func (h *WebhookHandler) ProcessTask(ctx context.Context, task *Task) error {
+	var payload WebhookPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal webhook payload: %w", err)
+	}
+
+	if payload.SubscriptionID != "" {
+		return h.deliver(ctx, &payload)
+	}
+
+	if h.config.URL == "" {
+		h.logger.Debug("Webhook not configured, dropping notification", zap.String("event", payload.Event))
+		return nil
+	}
+
+	if err := h.post(ctx, h.client, h.config.URL, h.config.Secret, &payload); err != nil {
+		return err
+	}
+
+	h.logger.Info("Webhook posted", zap.String("event", payload.Event))
+	return nil
+}
+
+// deliver posts payload to its subscription, counting failures towards
+// disabling the subscription
+func (h *WebhookHandler) deliver(ctx context.Context, payload *WebhookPayload) error {
+	if h.store == nil {
+		return fmt.Errorf("webhook subscriptions unavailable")
+	}
+
+	sub, err := h.store.GetByID(ctx, payload.SubscriptionID)
+	if errors.Is(err, apperrors.ErrWebhookNotFound) {
+		h.logger.Debug("Webhook subscription deleted, dropping delivery",
+			zap.String("subscription_id", payload.SubscriptionID),
+		)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !sub.Active {
+		h.logger.Debug("Webhook subscription disabled, dropping delivery",
+			zap.String("subscription_id", sub.ID),
+		)
+		return nil
+	}
+
+	postErr := h.post(ctx, h.subscriptionClient, sub.URL, sub.Secret, payload)
+	if postErr == nil {
+		if err := h.store.ResetDeliveryFailures(ctx, sub.ID); err != nil {
+			h.logger.Warn("Failed to reset webhook delivery failures",
+				zap.String("subscription_id", sub.ID),
+				zap.Error(err),
+			)
+		}
+		h.logger.Info("Webhook delivered",
+			zap.String("subscription_id", sub.ID),
+			zap.String("event", payload.Event),
+		)
+		return nil
+	}
+
+	disabled, err := h.store.RecordDeliveryFailure(ctx, sub.ID, h.config.DisableAfter)
+	if err != nil {
+		h.logger.Warn("Failed to record webhook delivery failure",
+			zap.String("subscription_id", sub.ID),
+			zap.Error(err),
+		)
+	}
+	if disabled {
+		h.logger.Warn("Webhook subscription disabled after repeated failures",
+			zap.String("subscription_id", sub.ID),
+			zap.Int("failures", h.config.DisableAfter),
+		)
+	}
+	return postErr
+}
+
+// post sends payload to url with client, signing it when secret is set
+func (h *WebhookHandler) post(ctx context.Context, client *http.Client, url, secret string, payload *WebhookPayload) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload.Body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, payload.Event)
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, payload.Body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post webhook: endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
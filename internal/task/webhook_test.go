@@ -0,0 +1,193 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"go.uber.org/zap"
+)
+
+// testWebhookStore keeps webhook subscriptions in memory
+type testWebhookStore struct {
+	subs map[string]*model.WebhookSubscription
+}
+
+func (s *testWebhookStore) GetByID(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	sub, ok := s.subs[id]
+	if !ok {
+		return nil, apperrors.ErrWebhookNotFound
+	}
+	return sub, nil
+}
+
+func (s *testWebhookStore) RecordDeliveryFailure(ctx context.Context, id string, disableAfter int) (bool, error) {
+	sub := s.subs[id]
+	sub.ConsecutiveFailures++
+	if disableAfter > 0 && sub.ConsecutiveFailures >= disableAfter {
+		sub.Active = false
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *testWebhookStore) ResetDeliveryFailures(ctx context.Context, id string) error {
+	s.subs[id].ConsecutiveFailures = 0
+	return nil
+}
+
+func newTestWebhookHandler(cfg config.WebhookConfig, status int, requests *[]*http.Request) *WebhookHandler {
+	return newTestWebhookHandlerWithStore(cfg, nil, status, requests)
+}
+
+func newTestWebhookHandlerWithStore(cfg config.WebhookConfig, store WebhookStore, status int, requests *[]*http.Request) *WebhookHandler {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})}
+	return NewWebhookHandler(cfg, store, client, zap.NewNop())
+}
+
+func webhookTask(t *testing.T, body string) *Task {
+	payload, err := json.Marshal(WebhookPayload{Event: TemplateStatusChanged, Body: json.RawMessage(body)})
+	require.NoError(t, err)
+	return &Task{Type: TypeWebhookSend, Payload: payload}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// echo -n '{"user_id":"user-1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=d21e2d0c8bdfc1216cad0172227a4d7eebf9b090409bcdbdfe614cb4f001d847",
+		SignWebhook("secret", []byte(`{"user_id":"user-1"}`)))
+}
+
+func TestWebhookHandler_Posts(t *testing.T) {
+	var requests []*http.Request
+	h := newTestWebhookHandler(config.WebhookConfig{URL: "https://hooks.example.com/usercenter", Secret: "secret"}, http.StatusNoContent, &requests)
+
+	body := `{"user_id":"user-1"}`
+	require.NoError(t, h.ProcessTask(context.Background(), webhookTask(t, body)))
+
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://hooks.example.com/usercenter", req.URL.String())
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, TemplateStatusChanged, req.Header.Get(WebhookEventHeader))
+	assert.Equal(t, SignWebhook("secret", []byte(body)), req.Header.Get(WebhookSignatureHeader))
+
+	sent, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, body, string(sent))
+}
+
+func TestWebhookHandler_Failures(t *testing.T) {
+	var requests []*http.Request
+
+	// Failed posts are retried by the task server
+	h := newTestWebhookHandler(config.WebhookConfig{URL: "https://hooks.example.com/usercenter"}, http.StatusBadGateway, &requests)
+	assert.EqualError(t, h.ProcessTask(context.Background(), webhookTask(t, `{}`)),
+		"failed to post webhook: endpoint responded with status 502")
+	assert.Empty(t, requests[0].Header.Get(WebhookSignatureHeader))
+
+	// Tasks left over after the webhook is unset are dropped
+	requests = nil
+	h = newTestWebhookHandler(config.WebhookConfig{}, http.StatusOK, &requests)
+	assert.NoError(t, h.ProcessTask(context.Background(), webhookTask(t, `{}`)))
+	assert.Empty(t, requests)
+}
+
+func subscriptionTask(t *testing.T, id, body string) *Task {
+	payload, err := json.Marshal(WebhookPayload{SubscriptionID: id, Event: "user.registered", Body: json.RawMessage(body)})
+	require.NoError(t, err)
+	return &Task{Type: TypeWebhookSend, Payload: payload}
+}
+
+func TestWebhookHandler_DeliversToSubscription(t *testing.T) {
+	store := &testWebhookStore{subs: map[string]*model.WebhookSubscription{
+		"sub-1": {ID: "sub-1", URL: "https://crm.example.com/hooks", Secret: "sub-secret", Active: true, ConsecutiveFailures: 3},
+	}}
+	var requests []*http.Request
+	h := newTestWebhookHandlerWithStore(config.WebhookConfig{DisableAfter: 5}, store, http.StatusOK, &requests)
+
+	body := `{"user_id":"user-1"}`
+	require.NoError(t, h.ProcessTask(context.Background(), subscriptionTask(t, "sub-1", body)))
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "https://crm.example.com/hooks", requests[0].URL.String())
+	assert.Equal(t, "user.registered", requests[0].Header.Get(WebhookEventHeader))
+	assert.Equal(t, SignWebhook("sub-secret", []byte(body)), requests[0].Header.Get(WebhookSignatureHeader))
+	assert.Zero(t, store.subs["sub-1"].ConsecutiveFailures)
+}
+
+func TestWebhookHandler_DisablesFailingSubscription(t *testing.T) {
+	store := &testWebhookStore{subs: map[string]*model.WebhookSubscription{
+		"sub-1": {ID: "sub-1", URL: "https://crm.example.com/hooks", Secret: "sub-secret", Active: true},
+	}}
+	var requests []*http.Request
+	h := newTestWebhookHandlerWithStore(config.WebhookConfig{DisableAfter: 2}, store, http.StatusInternalServerError, &requests)
+
+	for i := 0; i < 2; i++ {
+		assert.Error(t, h.ProcessTask(context.Background(), subscriptionTask(t, "sub-1", `{}`)))
+	}
+	assert.False(t, store.subs["sub-1"].Active)
+	assert.Equal(t, 2, store.subs["sub-1"].ConsecutiveFailures)
+
+	// Deliveries to disabled or deleted subscriptions are dropped
+	requests = nil
+	assert.NoError(t, h.ProcessTask(context.Background(), subscriptionTask(t, "sub-1", `{}`)))
+	assert.NoError(t, h.ProcessTask(context.Background(), subscriptionTask(t, "missing", `{}`)))
+	assert.Empty(t, requests)
+}
+
+func TestWebhookHandler_RefusesInternalSubscriptionTargets(t *testing.T) {
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	// Dialing checks the resolved address, whatever the URL passed validation with
+	store := &testWebhookStore{subs: map[string]*model.WebhookSubscription{
+		"sub-1": {ID: "sub-1", URL: srv.URL, Active: true},
+	}}
+	h := NewWebhookHandler(config.WebhookConfig{URL: srv.URL}, store, nil, zap.NewNop())
+
+	err := h.ProcessTask(context.Background(), subscriptionTask(t, "sub-1", `{}`))
+	assert.ErrorContains(t, err, "is an internal address")
+	assert.Equal(t, 1, store.subs["sub-1"].ConsecutiveFailures)
+	assert.Zero(t, received.Load())
+
+	// The configured notification webhook is trusted
+	require.NoError(t, h.ProcessTask(context.Background(), webhookTask(t, `{}`)))
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestIsInternalAddress(t *testing.T) {
+	for addr, internal := range map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.0.1":      true,
+		"169.254.169.254":  true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:127.0.0.1": true,
+		"203.0.113.10":     false,
+		"2001:db8::1":      false,
+	} {
+		assert.Equal(t, internal, IsInternalAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
@@ -0,0 +1,140 @@
+//go:build integration
+
+package testutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	redisclient "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	postgrescontainer "github.com/testcontainers/testcontainers-go/modules/postgres"
+	rediscontainer "github.com/testcontainers/testcontainers-go/modules/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	postgresImage = "postgres:15-alpine"
+	redisImage    = "redis:7-alpine"
+	mongoImage    = "mongo:7"
+)
+
+// SetupTestDB starts a Postgres container, applies the repository migrations
+// and returns a connected database
+func SetupTestDB(t *testing.T) *TestDB {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := postgrescontainer.Run(ctx, postgresImage,
+		postgrescontainer.WithDatabase("usercenter_test"),
+		postgrescontainer.WithUsername("testuser"),
+		postgrescontainer.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	require.NoError(t, err)
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, RunMigrations(sqlDB))
+
+	cleanup := func() {
+		sqlDB.Close()
+		_ = container.Terminate(ctx)
+	}
+
+	return &TestDB{
+		DB:      db,
+		Cleanup: cleanup,
+	}
+}
+
+// SetupTestRedis starts a Redis container and returns a connected client
+func SetupTestRedis(t *testing.T) *TestRedis {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := rediscontainer.Run(ctx, redisImage)
+	require.NoError(t, err)
+
+	uri, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	opts, err := redisclient.ParseURL(uri)
+	require.NoError(t, err)
+
+	client := redisclient.NewClient(opts)
+	require.NoError(t, client.Ping(ctx).Err())
+
+	cleanup := func() {
+		client.Close()
+		_ = container.Terminate(ctx)
+	}
+
+	return &TestRedis{
+		Client:  client,
+		Cleanup: cleanup,
+	}
+}
+
+// TestMongo holds a MongoDB connection to a test container
+type TestMongo struct {
+	DB      *database.MongoDB
+	Cleanup func()
+}
+
+// SetupTestMongo starts a MongoDB container and returns a connected database
+func SetupTestMongo(t *testing.T) *TestMongo {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        mongoImage,
+			ExposedPorts: []string{"27017/tcp"},
+			WaitingFor:   wait.ForListeningPort("27017/tcp").WithStartupTimeout(60 * time.Second),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+
+	endpoint, err := container.PortEndpoint(ctx, "27017/tcp", "mongodb")
+	require.NoError(t, err)
+
+	cfg := &config.Config{Database: config.DatabaseConfig{MongoDB: config.MongoDBConfig{
+		URI:      endpoint,
+		Database: "usercenter_test",
+	}}}
+	db, err := database.NewMongoDB(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	cleanup := func() {
+		_ = db.Close(ctx)
+		_ = container.Terminate(ctx)
+	}
+
+	return &TestMongo{
+		DB:      db,
+		Cleanup: cleanup,
+	}
+}
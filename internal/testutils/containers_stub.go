@@ -0,0 +1,19 @@
+//go:build !integration
+
+package testutils
+
+import "testing"
+
+// SetupTestDB requires Docker and is only available with the integration
+// build tag
+func SetupTestDB(t *testing.T) *TestDB {
+	t.Skip("SetupTestDB requires the integration build tag")
+	return nil
+}
+
+// SetupTestRedis requires Docker and is only available with the integration
+// build tag
+func SetupTestRedis(t *testing.T) *TestRedis {
+	t.Skip("SetupTestRedis requires the integration build tag")
+	return nil
+}
@@ -0,0 +1,344 @@
+package testutils
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/zhwjimmy/user-center/internal/apperrors"
+	"github.com/zhwjimmy/user-center/internal/cache"
+	"github.com/zhwjimmy/user-center/internal/config"
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/internal/model"
+	"go.uber.org/zap"
+)
+
+// FakeTransactor runs functions without a real transaction
+type FakeTransactor struct{}
+
+// WithinTransaction calls fn with the given context
+func (FakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// FakeOutboxRepository is an in-memory OutboxRepository
+type FakeOutboxRepository struct {
+	mu     sync.Mutex
+	Events map[string]*model.OutboxEvent
+}
+
+// NewFakeOutboxRepository creates an empty in-memory outbox
+func NewFakeOutboxRepository() *FakeOutboxRepository {
+	return &FakeOutboxRepository{Events: make(map[string]*model.OutboxEvent)}
+}
+
+// Create stores an outbox event
+func (r *FakeOutboxRepository) Create(ctx context.Context, e *model.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	r.Events[e.ID] = e
+	return nil
+}
+
+// ListPending returns unsent events in creation order
+func (r *FakeOutboxRepository) ListPending(ctx context.Context, limit, maxAttempts int) ([]*model.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []*model.OutboxEvent
+	for _, e := range r.Events {
+		if e.SentAt == nil && (maxAttempts <= 0 || e.Attempts < maxAttempts) {
+			pending = append(pending, e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// MarkSent marks an event as published
+func (r *FakeOutboxRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Events[id].SentAt = &sentAt
+	return nil
+}
+
+// MarkFailed records a failed publish attempt
+func (r *FakeOutboxRepository) MarkFailed(ctx context.Context, id string, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Events[id].Attempts++
+	r.Events[id].LastError = &reason
+	return nil
+}
+
+// EventsOfType returns stored events with the given type
+func (r *FakeOutboxRepository) EventsOfType(eventType string) []*model.OutboxEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*model.OutboxEvent
+	for _, e := range r.Events {
+		if e.EventType == eventType {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// FakeRoleRepository is an in-memory RoleRepository
+type FakeRoleRepository struct {
+	mu    sync.Mutex
+	Roles map[string][]string
+}
+
+// NewFakeRoleRepository creates an in-memory role repository without assignments
+func NewFakeRoleRepository() *FakeRoleRepository {
+	return &FakeRoleRepository{Roles: make(map[string][]string)}
+}
+
+// ListByUserID returns the roles assigned to a user in name order
+func (r *FakeRoleRepository) ListByUserID(ctx context.Context, userID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	roles := append([]string(nil), r.Roles[userID]...)
+	sort.Strings(roles)
+	return roles, nil
+}
+
+// Assign gives a role to a user
+func (r *FakeRoleRepository) Assign(ctx context.Context, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, assigned := range r.Roles[userID] {
+		if assigned == role {
+			return nil
+		}
+	}
+	r.Roles[userID] = append(r.Roles[userID], role)
+	return nil
+}
+
+// Revoke takes a role away from a user
+func (r *FakeRoleRepository) Revoke(ctx context.Context, userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	roles := r.Roles[userID][:0]
+	for _, assigned := range r.Roles[userID] {
+		if assigned != role {
+			roles = append(roles, assigned)
+		}
+	}
+	r.Roles[userID] = roles
+	return nil
+}
+
+// FakeSessionStore is an in-memory session store
+type FakeSessionStore struct {
+	mu       sync.Mutex
+	Sessions map[string]*database.UserSession
+}
+
+// NewFakeSessionStore creates an empty in-memory session store
+func NewFakeSessionStore() *FakeSessionStore {
+	return &FakeSessionStore{Sessions: make(map[string]*database.UserSession)}
+}
+
+// InsertSession stores a session
+func (s *FakeSessionStore) InsertSession(ctx context.Context, session *database.UserSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sessions[session.ID] = session
+	return nil
+}
+
+// ListActiveSessions returns the active, unexpired sessions of a user, newest first
+func (s *FakeSessionStore) ListActiveSessions(ctx context.Context, userID string, now time.Time) ([]*database.UserSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []*database.UserSession{}
+	for _, session := range s.Sessions {
+		if session.UserID == userID && session.IsActive && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// DeactivateSessions marks the sessions of a user with the given IDs inactive
+func (s *FakeSessionStore) DeactivateSessions(ctx context.Context, userID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if session, ok := s.Sessions[id]; ok && session.UserID == userID {
+			session.IsActive = false
+		}
+	}
+	return nil
+}
+
+// NewMiniRedis creates a Redis cache backed by an in-process miniredis server
+func NewMiniRedis(t testing.TB) (*cache.Redis, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	redis, err := cache.NewRedis(&config.Config{Redis: config.RedisConfig{Addr: mr.Addr()}}, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	return redis, mr
+}
+
+// FakeWebhookRepository is an in-memory WebhookRepository
+type FakeWebhookRepository struct {
+	mu            sync.Mutex
+	Subscriptions []*model.WebhookSubscription
+}
+
+// NewFakeWebhookRepository creates an in-memory webhook repository without subscriptions
+func NewFakeWebhookRepository() *FakeWebhookRepository {
+	return &FakeWebhookRepository{}
+}
+
+// Create stores a copy of a webhook subscription, generating its ID
+func (r *FakeWebhookRepository) Create(ctx context.Context, sub *model.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub.ID == "" {
+		sub.ID = uuid.New().String()
+	}
+	stored := *sub
+	r.Subscriptions = append(r.Subscriptions, &stored)
+	return nil
+}
+
+// GetByID returns a copy of the webhook subscription with id
+func (r *FakeWebhookRepository) GetByID(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sub := range r.Subscriptions {
+		if sub.ID == id {
+			found := *sub
+			return &found, nil
+		}
+	}
+	return nil, apperrors.ErrWebhookNotFound
+}
+
+// List returns copies of every webhook subscription in creation order
+func (r *FakeWebhookRepository) List(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := make([]*model.WebhookSubscription, 0, len(r.Subscriptions))
+	for _, sub := range r.Subscriptions {
+		found := *sub
+		subs = append(subs, &found)
+	}
+	return subs, nil
+}
+
+// ListActiveForEvent returns copies of the active subscriptions receiving eventType
+func (r *FakeWebhookRepository) ListActiveForEvent(ctx context.Context, eventType string) ([]*model.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var subs []*model.WebhookSubscription
+	for _, sub := range r.Subscriptions {
+		if sub.Active && sub.Subscribes(eventType) {
+			found := *sub
+			subs = append(subs, &found)
+		}
+	}
+	return subs, nil
+}
+
+// Update replaces a stored webhook subscription
+func (r *FakeWebhookRepository) Update(ctx context.Context, sub *model.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, stored := range r.Subscriptions {
+		if stored.ID == sub.ID {
+			updated := *sub
+			r.Subscriptions[i] = &updated
+			return nil
+		}
+	}
+	return apperrors.ErrWebhookNotFound
+}
+
+// Delete removes the webhook subscription with id
+func (r *FakeWebhookRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, sub := range r.Subscriptions {
+		if sub.ID == id {
+			r.Subscriptions = append(r.Subscriptions[:i], r.Subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return apperrors.ErrWebhookNotFound
+}
+
+// RecordDeliveryFailure counts a failed delivery, disabling the subscription
+// after disableAfter in a row
+func (r *FakeWebhookRepository) RecordDeliveryFailure(ctx context.Context, id string, disableAfter int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sub := range r.Subscriptions {
+		if sub.ID != id {
+			continue
+		}
+		sub.ConsecutiveFailures++
+		if sub.Active && disableAfter > 0 && sub.ConsecutiveFailures >= disableAfter {
+			now := time.Now()
+			sub.Active = false
+			sub.DisabledAt = &now
+			return true, nil
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+// ResetDeliveryFailures clears the failure count of the subscription with id
+func (r *FakeWebhookRepository) ResetDeliveryFailures(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sub := range r.Subscriptions {
+		if sub.ID == id {
+			sub.ConsecutiveFailures = 0
+		}
+	}
+	return nil
+}
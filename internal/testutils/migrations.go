@@ -0,0 +1,25 @@
+package testutils
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zhwjimmy/user-center/internal/database"
+	"github.com/zhwjimmy/user-center/migrations"
+	"go.uber.org/zap"
+)
+
+// NewMigrator returns a migrator for the repository migrations
+func NewMigrator(db *sql.DB) (*database.Migrator, error) {
+	return database.NewMigrator(db, migrations.FS, zap.NewNop())
+}
+
+// RunMigrations applies every repository migration to db
+func RunMigrations(db *sql.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
@@ -1,7 +1,6 @@
 package testutils
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 	"testing"
@@ -10,12 +9,6 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	redisclient "github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
-
-	// Temporarily comment out testcontainers imports to fix build issues
-	// "github.com/testcontainers/testcontainers-go"
-	// postgrescontainer "github.com/testcontainers/testcontainers-go/modules/postgres"
-	// rediscontainer "github.com/testcontainers/testcontainers-go/modules/redis"
-	// "github.com/testcontainers/testcontainers-go/wait"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -34,13 +27,6 @@ type TestRedis struct {
 	Cleanup func()
 }
 
-// SetupTestDB creates a test database using testcontainers
-// Temporarily disabled due to dependency issues
-func SetupTestDB(t *testing.T) *TestDB {
-	t.Skip("SetupTestDB temporarily disabled due to testcontainers dependency issues")
-	return nil
-}
-
 // SetupMockDB creates a mock database for unit tests
 func SetupMockDB(t *testing.T) *TestDB {
 	sqlDB, mock, err := sqlmock.New()
@@ -49,7 +35,8 @@ func SetupMockDB(t *testing.T) *TestDB {
 	db, err := gorm.Open(postgres.New(postgres.Config{
 		Conn: sqlDB,
 	}), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
 	})
 	require.NoError(t, err)
 
@@ -64,70 +51,6 @@ func SetupMockDB(t *testing.T) *TestDB {
 	}
 }
 
-// SetupTestRedis creates a test Redis instance using testcontainers
-// Temporarily disabled due to dependency issues
-func SetupTestRedis(t *testing.T) *TestRedis {
-	t.Skip("SetupTestRedis temporarily disabled due to testcontainers dependency issues")
-	return nil
-}
-
-// createTestTables creates the necessary tables for testing
-func createTestTables(db *sql.DB) error {
-	// Create users table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(50) UNIQUE NOT NULL,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			password_hash VARCHAR(255) NOT NULL,
-			first_name VARCHAR(100),
-			last_name VARCHAR(100),
-			phone VARCHAR(20),
-			avatar_url TEXT,
-			is_active BOOLEAN DEFAULT true,
-			is_admin BOOLEAN DEFAULT false,
-			email_verified BOOLEAN DEFAULT false,
-			phone_verified BOOLEAN DEFAULT false,
-			status VARCHAR(20) DEFAULT 'active',
-			last_login_at TIMESTAMP WITH TIME ZONE,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)
-	`)
-	if err != nil {
-		return err
-	}
-
-	// Create user_sessions table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS user_sessions (
-			id SERIAL PRIMARY KEY,
-			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-			token_hash VARCHAR(255) UNIQUE NOT NULL,
-			refresh_token_hash VARCHAR(255) UNIQUE NOT NULL,
-			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)
-	`)
-	if err != nil {
-		return err
-	}
-
-	// Create indexes
-	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`)
-	if err != nil {
-		return err
-	}
-
-	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)`)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // LoadTestConfig loads test configuration
 func LoadTestConfig() map[string]interface{} {
 	return map[string]interface{}{
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhwjimmy/user-center/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// instrumentationName identifies spans created by this service
+const instrumentationName = "github.com/zhwjimmy/user-center"
+
+// Provider owns the global tracer provider and flushes it on shutdown
+type Provider struct {
+	tp     *sdktrace.TracerProvider
+	logger *zap.Logger
+}
+
+// NewProvider initializes OpenTelemetry tracing. When tracing is disabled the
+// global no-op tracer provider is left in place.
+func NewProvider(cfg *config.Config, logger *zap.Logger) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	tracingCfg := cfg.Monitoring.Tracing
+	if !tracingCfg.Enabled {
+		logger.Info("Tracing disabled")
+		return &Provider{logger: logger}, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(tracingCfg.Endpoint),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracingCfg.Service),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+
+	logger.Info("Tracing enabled",
+		zap.String("endpoint", tracingCfg.Endpoint),
+		zap.String("service", tracingCfg.Service),
+	)
+
+	return &Provider{tp: tp, logger: logger}, nil
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+
+	if err := p.tp.Shutdown(ctx); err != nil {
+		p.logger.Error("Failed to shut down tracer provider", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Tracer returns the service tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Emails are stored trimmed and lowercased by the application. Existing rows
+-- are normalized here; the migration fails if two accounts differ only in the
+-- case of their email, and those must be merged by hand before re-running it.
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+-- Enforce case-insensitive uniqueness even for rows written outside the application
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_email_lower;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Phone numbers are stored without spaces or dashes by the application
+UPDATE users SET phone = REGEXP_REPLACE(phone, '[[:space:]-]', '', 'g') WHERE phone ~ '[[:space:]-]';
+CREATE INDEX IF NOT EXISTS idx_users_phone ON users(phone);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_phone;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-category email opt-outs, e.g. {"marketing": false}. Security mail is always sent.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_preferences JSONB NOT NULL DEFAULT '{}'::jsonb;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS notification_preferences;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Subscription plan; sets how many concurrent sessions a user may hold.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS plan VARCHAR(20) NOT NULL DEFAULT 'free';
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS plan;
+-- +goose StatementEnd
\ No newline at end of file
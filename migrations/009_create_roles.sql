@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to user administration'),
+    ('support', 'Read access to user administration'),
+    ('user', 'Regular user')
+ON CONFLICT (name) DO NOTHING;
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles(name),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+-- Users flagged as admin keep their access as the admin role
+INSERT INTO user_roles (user_id, role)
+SELECT id, 'admin' FROM users WHERE is_admin
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full-text search over usernames, names and emails. The simple configuration
+-- keeps names intact instead of stemming them as English words; usernames
+-- rank above names, which rank above emails.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(username, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(first_name, '') || ' ' || coalesce(last_name, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(email, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
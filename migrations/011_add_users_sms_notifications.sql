@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Opt-in to also receive notifications by text message on the verified phone.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sms_notifications BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS sms_notifications;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tenant of the user in multitenant deployments; issued in tokens as tenant_id.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users (tenant_id);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+// Package migrations embeds the versioned SQL migrations of the database
+// schema. Files follow the goose format: NNN_description.sql with
+// "-- +goose Up" and "-- +goose Down" sections.
+package migrations
+
+import "embed"
+
+// FS holds the migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,10 +1,22 @@
 package jwt
 
 import (
+	"crypto/rsa"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
 )
 
 // UserStatus represents user status in JWT claims
@@ -23,25 +35,127 @@ type Claims struct {
 	Username string     `json:"username"`
 	Email    string     `json:"email"`
 	Status   UserStatus `json:"status"`
+	// EmailVerified reports whether the user had verified their email when
+	// the token was issued
+	EmailVerified bool `json:"email_verified"`
+	// IsAdmin reports whether the user was an administrator when the token
+	// was issued
+	IsAdmin bool `json:"is_admin"`
+	// TenantID identifies the user's tenant when multitenancy is enabled
+	TenantID string `json:"tenant_id,omitempty"`
+	// Roles are the user's roles when the token was issued
+	Roles []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// HasRole reports whether the claims carry any of roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
 // JWT handles JWT token operations
 type JWT struct {
+	method jwt.SigningMethod
 	secret string
 	issuer string
 	expiry time.Duration
+
+	// RS256 only: the key new tokens are signed with, and the public keys
+	// accepted for verification, both identified by the "kid" header
+	signingKey   *rsa.PrivateKey
+	signingKeyID string
+	publicKeys   map[string]*rsa.PublicKey
 }
 
-// NewJWT creates a new JWT manager
+// NewJWT creates a new JWT manager that signs tokens with HS256 and a shared secret
 func NewJWT(secret, issuer string, expiry time.Duration) *JWT {
 	return &JWT{
+		method: jwt.SigningMethodHS256,
 		secret: secret,
 		issuer: issuer,
 		expiry: expiry,
 	}
 }
 
+// NewRS256JWT creates a new JWT manager that signs tokens with signingKey under
+// keyID. Tokens are verified with the public key named by their "kid" header;
+// publicKeys holds the keys of previous signing keys so tokens they issued stay
+// valid until expiry. The signing key's own public key is accepted unless
+// publicKeys publishes a different key under keyID, which Check reports.
+func NewRS256JWT(signingKey *rsa.PrivateKey, keyID string, publicKeys map[string]*rsa.PublicKey, issuer string, expiry time.Duration) (*JWT, error) {
+	if signingKey == nil {
+		return nil, fmt.Errorf("signing key is required")
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("signing key ID is required")
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(publicKeys)+1)
+	for kid, key := range publicKeys {
+		keys[kid] = key
+	}
+	if _, ok := keys[keyID]; !ok {
+		keys[keyID] = &signingKey.PublicKey
+	}
+
+	return &JWT{
+		method:       jwt.SigningMethodRS256,
+		issuer:       issuer,
+		expiry:       expiry,
+		signingKey:   signingKey,
+		signingKeyID: keyID,
+		publicKeys:   keys,
+	}, nil
+}
+
+// LoadRSAPrivateKey reads a PEM-encoded RSA private key from path
+func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadRSAPublicKeys reads every "<kid>.pem" file in dir as a PEM-encoded RSA
+// public key, keyed by kid. An empty dir yields no keys.
+func LoadRSAPublicKeys(dir string) (map[string]*rsa.PublicKey, error) {
+	keys := make(map[string]*rsa.PublicKey)
+	if dir == "" {
+		return keys, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list verification keys: %w", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read verification key: %w", err)
+		}
+
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse verification key %s: %w", path, err)
+		}
+		keys[strings.TrimSuffix(filepath.Base(path), ".pem")] = key
+	}
+
+	return keys, nil
+}
+
 // GenerateToken generates a JWT token for a user
 // User interface to avoid circular dependency
 type User interface {
@@ -49,6 +163,15 @@ type User interface {
 	GetUsername() string
 	GetEmail() string
 	GetStatus() string
+	IsEmailVerified() bool
+	IsAdministrator() bool
+	// GetTenantID returns the user's tenant, or "" without multitenancy
+	GetTenantID() string
+}
+
+// RoleHolder is implemented by users whose roles are embedded in their tokens
+type RoleHolder interface {
+	GetRoles() []string
 }
 
 // GenerateToken generates a JWT token for a user
@@ -69,31 +192,73 @@ func (j *JWT) GenerateToken(user User) (string, error) {
 	}
 
 	claims := &Claims{
-		UserID:   user.GetID(),
-		Username: user.GetUsername(),
-		Email:    user.GetEmail(),
-		Status:   status,
+		UserID:        user.GetID(),
+		Username:      user.GetUsername(),
+		Email:         user.GetEmail(),
+		Status:        status,
+		EmailVerified: user.IsEmailVerified(),
+		IsAdmin:       user.IsAdministrator(),
+		TenantID:      user.GetTenantID(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    j.issuer,
 			Subject:   user.GetID(),
+			ID:        uuid.NewString(), // unique per token so each login is a distinct session
 		},
 	}
+	if holder, ok := user.(RoleHolder); ok {
+		claims.Roles = holder.GetRoles()
+	}
+
+	return j.sign(claims)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// sign signs claims with the configured key
+func (j *JWT) sign(claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(j.method, claims)
+	if j.signingKey != nil {
+		token.Header["kid"] = j.signingKeyID
+		return token.SignedString(j.signingKey)
+	}
 	return token.SignedString([]byte(j.secret))
 }
 
+// Check signs a probe token and verifies it, so a misconfigured key is
+// detected before any user tries to log in
+func (j *JWT) Check() error {
+	if j.signingKey == nil && j.secret == "" {
+		return fmt.Errorf("JWT secret is empty")
+	}
+
+	now := time.Now()
+	token, err := j.sign(&Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    j.issuer,
+			Subject:   "self-check",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sign test token: %w", err)
+	}
+
+	if _, err := j.ValidateToken(token); err != nil {
+		if j.signingKey != nil {
+			return fmt.Errorf("failed to verify test token signed with key %q: %w", j.signingKeyID, err)
+		}
+		return fmt.Errorf("failed to verify test token: %w", err)
+	}
+
+	return nil
+}
+
 // ValidateToken validates a JWT token and returns claims
 func (j *JWT) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(j.secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.verificationKey,
+		jwt.WithValidMethods([]string{j.method.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -104,3 +269,17 @@ func (j *JWT) ValidateToken(tokenString string) (*Claims, error) {
 
 	return nil, fmt.Errorf("invalid token")
 }
+
+// verificationKey returns the key a token must be verified with
+func (j *JWT) verificationKey(token *jwt.Token) (interface{}, error) {
+	if j.signingKey == nil {
+		return []byte(j.secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.publicKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	return key, nil
+}
@@ -1,10 +1,12 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/gin-gonic/gin"
 	"github.com/zhwjimmy/user-center/internal/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -88,8 +90,8 @@ func WithRequestID(logger *zap.Logger, requestID string) *zap.Logger {
 }
 
 // WithUserID adds user ID to logger
-func WithUserID(logger *zap.Logger, userID uint) *zap.Logger {
-	return logger.With(zap.Uint("user_id", userID))
+func WithUserID(logger *zap.Logger, userID string) *zap.Logger {
+	return logger.With(zap.String("user_id", userID))
 }
 
 // WithFields adds multiple fields to logger
@@ -100,3 +102,32 @@ func WithFields(logger *zap.Logger, fields map[string]interface{}) *zap.Logger {
 	}
 	return logger.With(zapFields...)
 }
+
+// contextKey is the context key of the request logger
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// SetRequestLogger makes logger the logger of the request handled by c, so
+// that FromContext returns it for c and for the request context.
+func SetRequestLogger(c *gin.Context, logger *zap.Logger) {
+	c.Request = c.Request.WithContext(NewContext(c.Request.Context(), logger))
+}
+
+// FromContext returns the request logger carried by ctx, which may be a
+// *gin.Context or a request context, or fallback when there is none
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return fallback
+		}
+		ctx = c.Request.Context()
+	}
+	if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}